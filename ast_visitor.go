@@ -5,37 +5,111 @@ import (
 	"go/ast"
 	"go/token"
 	"go/types"
+	"maps"
 	"os"
+	"slices"
 	"strings"
+	"sync"
 
 	"golang.org/x/tools/go/packages"
 )
 
 // PosLookup maps file:line:col to node IDs, enabling SSA→AST position mapping.
+//
+// All lookups are safe for concurrent use. WalkAST fills one set per package
+// and merges them in package order (see merge), so which package registers a
+// position shared by several (test variants share files) does not depend on
+// worker scheduling; later phases only read. In streaming mode the merged
+// PosLookup and FuncLookup keep their entries on disk (see diskLookup).
 type PosLookup struct {
-	m map[string]string // "file:line:col" → nodeID
+	mu    sync.RWMutex
+	m     map[string]string // "file:line:col" → nodeID
+	decls map[string]string // "file:line:col" of a name → parameter/result/local nodeID
+	disk  *diskLookup       // if set, holds m ("m") and decls ("aux") instead
 }
 
 func NewPosLookup() *PosLookup {
-	return &PosLookup{m: make(map[string]string)}
+	return &PosLookup{m: make(map[string]string), decls: make(map[string]string)}
 }
 
 // Set records a node ID for a position. First-wins: if a position is already mapped,
 // later calls are ignored. This preserves statement-level nodes that SSA references.
 func (pl *PosLookup) Set(file string, line, col int, id string) {
 	key := fmt.Sprintf("%s:%d:%d", file, line, col)
+	if pl.disk != nil {
+		pl.disk.put("m", map[string]string{key: id}, false)
+		return
+	}
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
 	if _, exists := pl.m[key]; !exists {
 		pl.m[key] = id
 	}
 }
 
 func (pl *PosLookup) Get(file string, line, col int) string {
-	return pl.m[fmt.Sprintf("%s:%d:%d", file, line, col)]
+	key := fmt.Sprintf("%s:%d:%d", file, line, col)
+	if pl.disk != nil {
+		return pl.disk.get("m", key)
+	}
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+	return pl.m[key]
+}
+
+// SetDecl records the node a variable is declared by, at the position of
+// its name. Unlike Set it is not shadowed by a statement starting at the
+// same position, as in x := f().
+func (pl *PosLookup) SetDecl(file string, line, col int, id string) {
+	key := fmt.Sprintf("%s:%d:%d", file, line, col)
+	if pl.disk != nil {
+		pl.disk.put("aux", map[string]string{key: id}, false)
+		return
+	}
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	if _, exists := pl.decls[key]; !exists {
+		pl.decls[key] = id
+	}
+}
+
+// GetDecl returns the node of the variable declared at a position, or "".
+func (pl *PosLookup) GetDecl(file string, line, col int) string {
+	key := fmt.Sprintf("%s:%d:%d", file, line, col)
+	if pl.disk != nil {
+		return pl.disk.get("aux", key)
+	}
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+	return pl.decls[key]
+}
+
+// merge adds the positions of o that pl does not have yet, as if o's Set and
+// SetDecl calls were replayed after pl's.
+func (pl *PosLookup) merge(o *PosLookup) {
+	if pl.disk != nil {
+		pl.disk.put("m", o.m, false)
+		pl.disk.put("aux", o.decls, false)
+		return
+	}
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	for k, id := range o.m {
+		if _, exists := pl.m[k]; !exists {
+			pl.m[k] = id
+		}
+	}
+	for k, id := range o.decls {
+		if _, exists := pl.decls[k]; !exists {
+			pl.decls[k] = id
+		}
+	}
 }
 
 // DefLookup maps types.Object (declaration) to node IDs for REF edges.
 type DefLookup struct {
-	m map[types.Object]string
+	mu sync.RWMutex
+	m  map[types.Object]string
 }
 
 func NewDefLookup() *DefLookup {
@@ -44,7 +118,9 @@ func NewDefLookup() *DefLookup {
 
 func (dl *DefLookup) Set(obj types.Object, id string) {
 	if obj != nil {
+		dl.mu.Lock()
 		dl.m[obj] = id
+		dl.mu.Unlock()
 	}
 }
 
@@ -52,24 +128,85 @@ func (dl *DefLookup) Get(obj types.Object) string {
 	if obj == nil {
 		return ""
 	}
+	dl.mu.RLock()
+	defer dl.mu.RUnlock()
 	return dl.m[obj]
 }
 
+// merge replays o's Set calls after dl's.
+func (dl *DefLookup) merge(o *DefLookup) {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	maps.Copy(dl.m, o.m)
+}
+
 // FuncLookup maps function positions to node IDs for parent tracking.
 type FuncLookup struct {
-	m map[string]string // "file:line:col" → funcNodeID
+	mu    sync.RWMutex
+	m     map[string]string // "file:line:col" → funcNodeID
+	lines map[string]string // "file:line" → first function registered on that line
+	disk  *diskLookup       // if set, holds m ("m") and lines ("aux") instead
 }
 
 func NewFuncLookup() *FuncLookup {
-	return &FuncLookup{m: make(map[string]string)}
+	return &FuncLookup{m: make(map[string]string), lines: make(map[string]string)}
 }
 
 func (fl *FuncLookup) Set(file string, line, col int, id string) {
-	fl.m[fmt.Sprintf("%s:%d:%d", file, line, col)] = id
+	key := fmt.Sprintf("%s:%d:%d", file, line, col)
+	lineKey := fmt.Sprintf("%s:%d", file, line)
+	if fl.disk != nil {
+		fl.disk.put("m", map[string]string{key: id}, true)
+		fl.disk.put("aux", map[string]string{lineKey: id}, false)
+		return
+	}
+	fl.mu.Lock()
+	fl.m[key] = id
+	if _, exists := fl.lines[lineKey]; !exists {
+		fl.lines[lineKey] = id
+	}
+	fl.mu.Unlock()
+}
+
+// GetLine returns the function starting on a line, or "". A declaration is
+// registered before the literals in its body, so it wins over closures on
+// the same line.
+func (fl *FuncLookup) GetLine(file string, line int) string {
+	key := fmt.Sprintf("%s:%d", file, line)
+	if fl.disk != nil {
+		return fl.disk.get("aux", key)
+	}
+	fl.mu.RLock()
+	defer fl.mu.RUnlock()
+	return fl.lines[key]
 }
 
 func (fl *FuncLookup) Get(file string, line, col int) string {
-	return fl.m[fmt.Sprintf("%s:%d:%d", file, line, col)]
+	key := fmt.Sprintf("%s:%d:%d", file, line, col)
+	if fl.disk != nil {
+		return fl.disk.get("m", key)
+	}
+	fl.mu.RLock()
+	defer fl.mu.RUnlock()
+	return fl.m[key]
+}
+
+// merge replays o's Set calls after fl's: positions take o's function, lines
+// keep the first one registered.
+func (fl *FuncLookup) merge(o *FuncLookup) {
+	if fl.disk != nil {
+		fl.disk.put("m", o.m, true)
+		fl.disk.put("aux", o.lines, false)
+		return
+	}
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	maps.Copy(fl.m, o.m)
+	for k, id := range o.lines {
+		if _, exists := fl.lines[k]; !exists {
+			fl.lines[k] = id
+		}
+	}
 }
 
 // WalkAST walks the AST of all packages, producing CPG nodes and AST edges.
@@ -80,16 +217,44 @@ func WalkAST(pkgs []*packages.Package, fset *token.FileSet, cpg *CPG, prog *Prog
 	posLookup := NewPosLookup()
 	funcLookup := NewFuncLookup()
 	defLookup := NewDefLookup()
-
-	var nodeCount, edgeCount int
-	var skippedFiles int
-
-	for _, pkg := range pkgs {
+	if cpg.sink != nil {
+		// Streaming: each package's positions go to disk once it is merged.
+		posLookup.disk, funcLookup.disk = cpg.sink.newDiskLookup(), cpg.sink.newDiskLookup()
+	}
+
+	// Packages are walked in parallel into per-package shards (see runSharded),
+	// each with its own lookups, merged in package order with the shard.
+	type walkStats struct{ nodes, edges, skipped int }
+	type walkLookups struct {
+		pos  *PosLookup
+		fn   *FuncLookup
+		defs *DefLookup
+		rows []PackageLookup // with -incremental, recorded in the DB
+	}
+	stats := make([]walkStats, len(pkgs))
+	pending := make([][]pendingRef, len(pkgs))
+	lookups := make([]walkLookups, len(pkgs))
+	restored := make([]bool, len(pkgs))
+
+	runShardedMerge(len(pkgs), cpg, func(pi int, shard *CPG) {
+		pkg := pkgs[pi]
+		posLookup, funcLookup, defLookup := NewPosLookup(), NewFuncLookup(), NewDefLookup()
+		lookups[pi] = walkLookups{pos: posLookup, fn: funcLookup, defs: defLookup}
 		relPkg := modSet.RelPkg(pkg.PkgPath)
 
+		// Incremental: the previous DB holds the graph of an unchanged
+		// package, so only its lookups are needed, by later phases and by
+		// changed packages that refer to its declarations.
+		if incr.SkipPkg(pkg.PkgPath) {
+			restorePackageLookups(pkg, incr.previousLookups(relPkg), posLookup, funcLookup, defLookup)
+			restored[pi] = true
+			return
+		}
+		var nodeCount, edgeCount, skippedFiles int
+
 		// Create package node
 		pkgID := PkgID(pkg.PkgPath)
-		cpg.AddNode(Node{
+		shard.AddNode(Node{
 			ID:      pkgID,
 			Kind:    "package",
 			Name:    pkg.Name,
@@ -97,10 +262,11 @@ func WalkAST(pkgs []*packages.Package, fset *token.FileSet, cpg *CPG, prog *Prog
 		})
 		nodeCount++
 
-		// Import edges: package → imported package (internal modules only)
-		for impPath := range pkg.Imports {
+		// Import edges: package → imported package (internal modules only),
+		// in sorted order so output does not depend on map iteration.
+		for _, impPath := range slices.Sorted(maps.Keys(pkg.Imports)) {
 			if modSet.IsKnownPkg(impPath) {
-				cpg.AddEdge(Edge{Source: pkgID, Target: PkgID(impPath), Kind: "imports"})
+				shard.AddEdge(Edge{Source: pkgID, Target: PkgID(impPath), Kind: "imports"})
 				edgeCount++
 			}
 		}
@@ -149,7 +315,7 @@ func WalkAST(pkgs []*packages.Package, fset *token.FileSet, cpg *CPG, prog *Prog
 			if file.End().IsValid() {
 				fileProps["loc"] = fset.Position(file.End()).Line
 			}
-			cpg.AddNode(Node{
+			shard.AddNode(Node{
 				ID:         fileID,
 				Kind:       "file",
 				Name:       BaseName(relFile),
@@ -159,15 +325,14 @@ func WalkAST(pkgs []*packages.Package, fset *token.FileSet, cpg *CPG, prog *Prog
 				Properties: fileProps,
 			})
 			nodeCount++
-			cpg.AddEdge(Edge{Source: pkgID, Target: fileID, Kind: "ast"})
+			shard.AddEdge(Edge{Source: pkgID, Target: fileID, Kind: "ast"})
 			edgeCount++
 
 			// Read source content for the sources table
-			if _, ok := cpg.Sources[relFile]; !ok {
-				content, err := os.ReadFile(absFile)
-				if err == nil {
-					cpg.Sources[relFile] = string(content)
-				}
+			var source string
+			if content, err := os.ReadFile(absFile); err == nil {
+				source = string(content)
+				shard.AddSource(relFile, source)
 			}
 
 			// Walk AST of this file
@@ -177,14 +342,17 @@ func WalkAST(pkgs []*packages.Package, fset *token.FileSet, cpg *CPG, prog *Prog
 				relFile:     relFile,
 				fileID:      fileID,
 				fset:        fset,
-				cpg:         cpg,
+				cpg:         shard,
 				posLookup:   posLookup,
 				funcLookup:  funcLookup,
 				defLookup:   defLookup,
-				source:      cpg.Sources[relFile],
+				source:      source,
 				parentStack: []string{fileID},
 				initIDs:     &initFuncIDs,
+				pendingRefs: &pending[pi],
 				scopeNodes:  make(map[string]bool),
+				stableIDs:   make(map[string]string),
+				ordinals:    make(map[string]int),
 			}
 			ast.Walk(v, file)
 
@@ -199,17 +367,18 @@ func WalkAST(pkgs []*packages.Package, fset *token.FileSet, cpg *CPG, prog *Prog
 				if len(text) > 200 {
 					text = text[:200] + "..."
 				}
-				cpg.AddNode(Node{
-					ID:      cID,
-					Kind:    "comment",
-					Name:    text,
-					File:    relFile,
-					Line:    cLine,
-					Col:     cCol,
-					EndLine: v.endLine(cg.End()),
-					Package: relPkg,
+				shard.AddNode(Node{
+					ID:       cID,
+					Kind:     "comment",
+					Name:     text,
+					File:     relFile,
+					Line:     cLine,
+					Col:      cCol,
+					EndLine:  v.endLine(cg.End()),
+					Package:  relPkg,
+					StableID: v.nextPathID("comment"),
 				})
-				cpg.AddEdge(Edge{Source: fileID, Target: cID, Kind: "ast"})
+				shard.AddEdge(Edge{Source: fileID, Target: cID, Kind: "ast"})
 				nodeCount += 1
 				edgeCount += 1
 			}
@@ -220,12 +389,51 @@ func WalkAST(pkgs []*packages.Package, fset *token.FileSet, cpg *CPG, prog *Prog
 
 		// Chain init() functions within this package in source order
 		for i := 1; i < len(initFuncIDs); i++ {
-			cpg.AddEdge(Edge{
+			shard.AddEdge(Edge{
 				Source: initFuncIDs[i-1], Target: initFuncIDs[i], Kind: "init_order",
 				Properties: map[string]any{"order": i},
 			})
 			edgeCount++
 		}
+		stats[pi] = walkStats{nodeCount, edgeCount, skippedFiles}
+		if flagIncremental {
+			lookups[pi].rows = packageLookups(pkg, relPkg, posLookup, funcLookup, defLookup)
+		}
+	}, func(pi int) {
+		posLookup.merge(lookups[pi].pos)
+		funcLookup.merge(lookups[pi].fn)
+		defLookup.merge(lookups[pi].defs)
+		if rows := lookups[pi].rows; len(rows) > 0 {
+			cpg.AddPackageLookups(rows)
+		}
+		lookups[pi] = walkLookups{}
+		if cpg.sink != nil {
+			// Streaming: link what is declared by now rather than holding
+			// every package's references until the end.
+			pending[pi] = resolveRefs(pending[pi], defLookup, cpg, &stats[pi].edges)
+		}
+	})
+
+	incr.closePrevious()
+
+	var nodeCount, edgeCount, skippedFiles, restoredPkgs int
+	for pi, st := range stats {
+		nodeCount += st.nodes
+		edgeCount += st.edges
+		skippedFiles += st.skipped
+		if restored[pi] {
+			prog.Verbose("  reused: %s", pkgs[pi].PkgPath)
+			restoredPkgs++
+		}
+	}
+	if restoredPkgs > 0 {
+		prog.Log("Incremental: restored the lookups of %d unchanged packages instead of walking them", restoredPkgs)
+	}
+
+	// Resolve declaration links deferred during the walk, now that every
+	// package has registered its declarations.
+	for _, refs := range pending {
+		resolveRefs(refs, defLookup, cpg, &edgeCount)
 	}
 
 	// Emit has_method edges: type_decl → function for each method.
@@ -258,20 +466,61 @@ type astVisitor struct {
 	deferIDs []string
 	// initIDs collects init() function node IDs for ordering.
 	initIDs *[]string
+	// pendingRefs collects declaration links resolved after all packages are walked.
+	pendingRefs *[]pendingRef
 	// scopeNodes tracks node IDs that introduce a new lexical scope (functions and blocks).
 	scopeNodes map[string]bool
-	nodeCount  int
-	edgeCount  int
+	// stableIDs maps the node IDs of this file to their line-independent IDs;
+	// ordinals counts the children of each kind under a stable parent.
+	stableIDs map[string]string
+	ordinals  map[string]int
+	nodeCount int
+	edgeCount int
 }
 
 func (v *astVisitor) currentParent() string {
 	return v.parentStack[len(v.parentStack)-1]
 }
 
+// stableParent returns the line-independent ID of the current parent. The
+// file node at the bottom of the stack already has one as its ID.
+func (v *astVisitor) stableParent() string {
+	p := v.currentParent()
+	if s, ok := v.stableIDs[p]; ok {
+		return s
+	}
+	return p
+}
+
+// nextPathID returns the structural ID of the next child of a kind under
+// the current parent.
+func (v *astVisitor) nextPathID(kind string) string {
+	parent := v.stableParent()
+	key := parent + "/" + kind
+	i := v.ordinals[key]
+	v.ordinals[key] = i + 1
+	return PathID(parent, kind, i)
+}
+
+// memberID returns the ID of a named member of the current parent, or a
+// structural ID for blank names, which may repeat.
+func (v *astVisitor) memberID(kind, name string) string {
+	if name == "_" {
+		return v.nextPathID(kind)
+	}
+	return MemberID(v.stableParent(), name)
+}
+
 func (v *astVisitor) addNodeAndEdge(n Node) {
 	n.Package = v.relPkg
 	n.File = v.relFile
 	n.ParentFunction = v.curFunc
+	if s, ok := v.stableIDs[n.ID]; ok {
+		n.StableID = s
+	} else if n.StableID == "" {
+		n.StableID = v.nextPathID(n.Kind)
+	}
+	v.stableIDs[n.ID] = n.StableID
 
 	// Add nesting depth for statement/expression nodes inside functions.
 	// Depth 0 = direct function body, 1 = inside one control structure, etc.
@@ -297,6 +546,10 @@ func (v *astVisitor) addNodeAndEdge(n Node) {
 	// Register in position lookup
 	if n.Line > 0 {
 		v.posLookup.Set(n.File, n.Line, n.Col, n.ID)
+		switch n.Kind {
+		case "parameter", "result", "local":
+			v.posLookup.SetDecl(n.File, n.Line, n.Col, n.ID)
+		}
 	}
 }
 
@@ -386,12 +639,9 @@ func (v *astVisitor) Visit(node ast.Node) ast.Visitor {
 		// branch_target edge: break/continue/goto with label → labeled statement
 		if n.Label != nil {
 			if obj := v.pkg.TypesInfo.Uses[n.Label]; obj != nil {
-				if targetID := v.defLookup.Get(obj); targetID != "" {
-					bLine, bCol := v.pos(n.TokPos)
-					branchID := StmtID(v.relPkg, BaseName(v.relFile), bLine, bCol, "branch")
-					v.cpg.AddEdge(Edge{Source: branchID, Target: targetID, Kind: "branch_target"})
-					v.edgeCount++
-				}
+				bLine, bCol := v.pos(n.TokPos)
+				branchID := StmtID(v.relPkg, BaseName(v.relFile), bLine, bCol, "branch")
+				v.linkDecl(branchID, obj, "branch_target")
 			}
 		}
 	case *ast.LabeledStmt:
@@ -498,6 +748,14 @@ func (v *astVisitor) visitFuncDecl(n *ast.FuncDecl) ast.Visitor {
 	if n.Type.TypeParams != nil && n.Type.TypeParams.NumFields() > 0 {
 		node.Properties["generic"] = true
 	}
+	if strings.HasSuffix(v.relFile, "_test.go") {
+		if obj := v.pkg.TypesInfo.Defs[n.Name]; obj != nil {
+			sig, _ := obj.Type().(*types.Signature)
+			if kind := testKind(name, sig); kind != "" {
+				node.Properties["test_kind"] = kind
+			}
+		}
+	}
 	// Signature analysis: return types and context parameter
 	if obj := v.pkg.TypesInfo.Defs[n.Name]; obj != nil {
 		if sig, ok := obj.Type().(*types.Signature); ok {
@@ -524,6 +782,12 @@ func (v *astVisitor) visitFuncDecl(n *ast.FuncDecl) ast.Visitor {
 	if sig := v.codeSnippet(n.Pos(), n.Type.End(), 200); sig != "" {
 		node.Properties["code"] = sig
 	}
+	// init and blank functions may repeat in a package: number them per file
+	if (name == "init" && recv == "") || name == "_" {
+		node.StableID = v.nextPathID(name)
+	} else {
+		node.StableID = SymbolID(v.relPkg, recv, name)
+	}
 	v.addNodeAndEdge(node)
 	v.emitDocEdge(funcID, n.Doc)
 
@@ -1012,6 +1276,10 @@ func (v *astVisitor) visitGenDecl(n *ast.GenDecl) {
 				}
 				line, col := v.pos(name.Pos())
 				id := StmtID(v.relPkg, BaseName(v.relFile), line, col, "local")
+				var stableID string
+				if v.curFunc == "" {
+					stableID = SymbolID(v.relPkg, "", name.Name)
+				}
 
 				var typeInfo string
 				if obj := v.pkg.TypesInfo.Defs[name]; obj != nil {
@@ -1030,6 +1298,7 @@ func (v *astVisitor) visitGenDecl(n *ast.GenDecl) {
 						"decl":     n.Tok.String(),
 						"exported": token.IsExported(name.Name),
 					},
+					StableID: stableID,
 				})
 				// Initializer edge: var/const → RHS expression
 				if i < len(vs.Values) {
@@ -1084,6 +1353,12 @@ func (v *astVisitor) visitTypeSpec(n *ast.TypeSpec) {
 		props["generic"] = true
 	}
 
+	// Types declared inside functions get a structural ID
+	var stableID string
+	if v.curFunc == "" {
+		stableID = SymbolID(v.relPkg, "", n.Name.Name)
+	}
+
 	v.addNodeAndEdge(Node{
 		ID:         id,
 		Kind:       "type_decl",
@@ -1093,6 +1368,7 @@ func (v *astVisitor) visitTypeSpec(n *ast.TypeSpec) {
 		EndLine:    el,
 		TypeInfo:   typeInfo,
 		Properties: props,
+		StableID:   stableID,
 	})
 
 	v.emitDocEdge(id, n.Doc)
@@ -1179,9 +1455,15 @@ func (v *astVisitor) visitField(field *ast.Field) {
 
 	id := StmtID(v.relPkg, BaseName(v.relFile), line, col, "field")
 
-	// Register field definition for REF edges
-	if len(field.Names) > 0 {
-		v.defLookup.Set(v.pkg.TypesInfo.Defs[field.Names[0]], id)
+	// Register field definitions for REF edges. Every name of a grouped
+	// field (X, Y int) maps to the one field node, also by position so SSA
+	// field accesses can find it.
+	for i, ident := range field.Names {
+		v.defLookup.Set(v.pkg.TypesInfo.Defs[ident], id)
+		if i > 0 {
+			nameLine, nameCol := v.pos(ident.Pos())
+			v.posLookup.Set(v.relFile, nameLine, nameCol, id)
+		}
 	}
 
 	props := map[string]any{
@@ -1207,6 +1489,7 @@ func (v *astVisitor) visitField(field *ast.Field) {
 		Col:        col,
 		TypeInfo:   typeInfo,
 		Properties: props,
+		StableID:   v.memberID("field", name),
 	})
 	v.emitDocEdge(id, field.Doc)
 }
@@ -1266,6 +1549,7 @@ func (v *astVisitor) visitFieldList(fl *ast.FieldList, kind string) {
 				Col:        col,
 				TypeInfo:   typeInfo,
 				Properties: props,
+				StableID:   v.memberID(kind, name.Name),
 			})
 			v.defLookup.Set(v.pkg.TypesInfo.Defs[name], id)
 		}
@@ -1347,10 +1631,7 @@ func (v *astVisitor) visitIdent(n *ast.Ident) {
 	v.emitEvalType(id, n)
 
 	// REF edge: identifier → declaration
-	if declID := v.defLookup.Get(obj); declID != "" {
-		v.cpg.AddEdge(Edge{Source: id, Target: declID, Kind: "ref"})
-		v.edgeCount++
-	}
+	v.linkDecl(id, obj, "ref")
 }
 
 // visitSelectorExpr creates a node for field/method access (x.Field).
@@ -1411,15 +1692,9 @@ func (v *astVisitor) visitSelectorExpr(n *ast.SelectorExpr) string {
 
 	// REF edge: selector → field/method declaration
 	if obj := v.pkg.TypesInfo.Uses[n.Sel]; obj != nil {
-		if declID := v.defLookup.Get(obj); declID != "" {
-			v.cpg.AddEdge(Edge{Source: id, Target: declID, Kind: "ref"})
-			v.edgeCount++
-		}
+		v.linkDecl(id, obj, "ref")
 	} else if sel, ok := v.pkg.TypesInfo.Selections[n]; ok {
-		if declID := v.defLookup.Get(sel.Obj()); declID != "" {
-			v.cpg.AddEdge(Edge{Source: id, Target: declID, Kind: "ref"})
-			v.edgeCount++
-		}
+		v.linkDecl(id, sel.Obj(), "ref")
 	}
 
 	return id
@@ -1534,10 +1809,53 @@ func (v *astVisitor) emitEvalType(nodeID string, expr ast.Expr) {
 	if !ok {
 		return
 	}
-	tObj := named.Obj()
-	if typeID := v.defLookup.Get(tObj); typeID != "" && typeID != nodeID {
-		v.cpg.AddEdge(Edge{Source: nodeID, Target: typeID, Kind: "eval_type"})
-		v.edgeCount++
+	v.linkDecl(nodeID, named.Obj(), "eval_type")
+}
+
+// pendingRef is a declaration link whose target may not be registered yet.
+type pendingRef struct {
+	source string
+	obj    types.Object
+	kind   string
+}
+
+// resolveRefs adds the edges of refs whose declaration is registered,
+// counting them in edgeCount, and returns the others.
+func resolveRefs(refs []pendingRef, defLookup *DefLookup, cpg *CPG, edgeCount *int) []pendingRef {
+	var unresolved []pendingRef
+	for _, r := range refs {
+		declID := defLookup.Get(r.obj)
+		if declID == "" {
+			unresolved = append(unresolved, r)
+			continue
+		}
+		if declID != r.source {
+			cpg.AddEdge(Edge{Source: r.source, Target: declID, Kind: r.kind})
+			*edgeCount++
+		}
+	}
+	return unresolved
+}
+
+// linkDecl emits a kind edge from id to obj's declaration node. Declarations
+// in other packages may be walked concurrently, and same-package ones may come
+// later in the walk, so unresolved links are deferred until WalkAST has seen
+// every package. This keeps the result independent of walk order.
+func (v *astVisitor) linkDecl(id string, obj types.Object, kind string) {
+	if obj == nil || obj.Pkg() == nil {
+		return
+	}
+	if obj.Pkg() == v.pkg.Types {
+		if declID := v.defLookup.Get(obj); declID != "" {
+			if declID != id {
+				v.cpg.AddEdge(Edge{Source: id, Target: declID, Kind: kind})
+				v.edgeCount++
+			}
+			return
+		}
+	}
+	if v.pendingRefs != nil {
+		*v.pendingRefs = append(*v.pendingRefs, pendingRef{source: id, obj: obj, kind: kind})
 	}
 }
 
@@ -1632,6 +1950,9 @@ func emitHasMethodEdges(pkgs []*packages.Package, _ *token.FileSet, defLookup *D
 	seen := make(map[methodEdge]bool)
 
 	for _, pkg := range pkgs {
+		if incr.SkipPkg(pkg.PkgPath) {
+			continue // edges reused from the previous DB
+		}
 		scope := pkg.Types.Scope()
 		for _, name := range scope.Names() {
 			obj := scope.Lookup(name)
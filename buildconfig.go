@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// BuildConfig is one GOOS/GOARCH/build-tag combination analyzed with -configs.
+type BuildConfig struct {
+	Name   string // canonical form, e.g. "windows/amd64" or "linux/amd64:tags=foo+bar"
+	GOOS   string
+	GOARCH string
+	Tags   []string // in addition to the global build tags
+}
+
+// activeBuild is the configuration currently being loaded, or nil for the
+// host configuration. Set by main before each load.
+var activeBuild *BuildConfig
+
+// ParseBuildConfigs parses a -configs value: a comma-separated list of
+// entries of the form "GOOS/GOARCH", "tags=t1+t2" (host platform) or
+// "GOOS/GOARCH:tags=t1+t2".
+func ParseBuildConfigs(spec string) ([]BuildConfig, error) {
+	var configs []BuildConfig
+	var errs []error
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		bc, err := parseBuildConfig(entry)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if seen[bc.Name] {
+			errs = append(errs, fmt.Errorf("duplicate configuration %q", bc.Name))
+			continue
+		}
+		seen[bc.Name] = true
+		configs = append(configs, bc)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+func parseBuildConfig(entry string) (BuildConfig, error) {
+	bc := BuildConfig{GOOS: runtime.GOOS, GOARCH: runtime.GOARCH}
+
+	platform, tags := entry, ""
+	if strings.HasPrefix(entry, "tags=") {
+		platform, tags = "", entry
+	} else if p, t, ok := strings.Cut(entry, ":"); ok {
+		platform, tags = p, t
+	}
+
+	if platform != "" {
+		goos, goarch, ok := strings.Cut(platform, "/")
+		if !ok || !isPlatformWord(goos) || !isPlatformWord(goarch) {
+			return bc, fmt.Errorf("configuration %q: want GOOS/GOARCH", entry)
+		}
+		bc.GOOS, bc.GOARCH = goos, goarch
+	}
+	if tags != "" {
+		list, ok := strings.CutPrefix(tags, "tags=")
+		if !ok || list == "" {
+			return bc, fmt.Errorf("configuration %q: want tags=t1+t2", entry)
+		}
+		for _, t := range strings.Split(list, "+") {
+			if t == "" || strings.ContainsAny(t, " \t") {
+				return bc, fmt.Errorf("configuration %q: invalid tag %q", entry, t)
+			}
+			bc.Tags = append(bc.Tags, t)
+		}
+		slices.Sort(bc.Tags)
+		bc.Tags = slices.Compact(bc.Tags)
+	}
+
+	bc.Name = bc.GOOS + "/" + bc.GOARCH
+	if len(bc.Tags) > 0 {
+		bc.Name += ":tags=" + strings.Join(bc.Tags, "+")
+	}
+	return bc, nil
+}
+
+func isPlatformWord(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// effectiveBuildTags returns the global build tags plus those of the active
+// configuration.
+func effectiveBuildTags() []string {
+	if activeBuild == nil {
+		return buildTags
+	}
+	return append(slices.Clone(buildTags), activeBuild.Tags...)
+}
+
+// goCommandEnv returns environ adjusted for the active configuration.
+func goCommandEnv(environ []string) []string {
+	if activeBuild == nil {
+		return environ
+	}
+	environ = replaceEnv(environ, "GOOS", activeBuild.GOOS)
+	return replaceEnv(environ, "GOARCH", activeBuild.GOARCH)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseBuildConfigs(t *testing.T) {
+	host := runtime.GOOS + "/" + runtime.GOARCH
+	tests := []struct {
+		spec string
+		want []string // config names
+	}{
+		{"", nil},
+		{"linux/amd64", []string{"linux/amd64"}},
+		{" linux/amd64 , windows/arm64 ,", []string{"linux/amd64", "windows/arm64"}},
+		{"tags=foo", []string{host + ":tags=foo"}},
+		{"linux/amd64:tags=b+a+b", []string{"linux/amd64:tags=a+b"}},
+		{"linux/amd64,linux/amd64:tags=x", []string{"linux/amd64", "linux/amd64:tags=x"}},
+	}
+	for _, tt := range tests {
+		configs, err := ParseBuildConfigs(tt.spec)
+		if err != nil {
+			t.Errorf("ParseBuildConfigs(%q): %v", tt.spec, err)
+			continue
+		}
+		var names []string
+		for _, c := range configs {
+			names = append(names, c.Name)
+		}
+		if !slices.Equal(names, tt.want) {
+			t.Errorf("ParseBuildConfigs(%q) = %v, want %v", tt.spec, names, tt.want)
+		}
+	}
+}
+
+func TestParseBuildConfigFields(t *testing.T) {
+	configs, err := ParseBuildConfigs("darwin/arm64:tags=netgo+osusergo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := configs[0]
+	if got.GOOS != "darwin" || got.GOARCH != "arm64" || !slices.Equal(got.Tags, []string{"netgo", "osusergo"}) {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestParseBuildConfigsErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []string
+	}{
+		{"linux", []string{`"linux": want GOOS/GOARCH`}},
+		{"Linux/amd64", []string{"want GOOS/GOARCH"}},
+		{"linux/amd64:foo", []string{"want tags=t1+t2"}},
+		{"tags=", []string{"want tags=t1+t2"}},
+		{"tags=a++b", []string{`invalid tag ""`}},
+		{"linux/amd64,linux/amd64", []string{`duplicate configuration "linux/amd64"`}},
+		{"linux/amd64:tags=a+b,linux/amd64:tags=b+a", []string{"duplicate configuration"}},
+		// Every bad entry is reported, not just the first.
+		{"linux,windows/amd64:x", []string{`"linux"`, `"windows/amd64:x"`}},
+	}
+	for _, tt := range tests {
+		_, err := ParseBuildConfigs(tt.spec)
+		if err == nil {
+			t.Errorf("ParseBuildConfigs(%q) succeeded, want error", tt.spec)
+			continue
+		}
+		for _, w := range tt.want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("ParseBuildConfigs(%q) error %q does not mention %q", tt.spec, err, w)
+			}
+		}
+	}
+}
@@ -1,13 +1,88 @@
 package main
 
 import (
+	"cmp"
+	"fmt"
 	"go/token"
+	"go/types"
+	"slices"
+	"strings"
 
 	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/callgraph/rta"
+	"golang.org/x/tools/go/callgraph/static"
 	"golang.org/x/tools/go/callgraph/vta"
+	"golang.org/x/tools/go/ssa"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
 )
 
-// BuildCallGraph constructs a VTA call graph and emits call/call_site edges.
+// callGraphAlgos are the supported call graph construction algorithms, from
+// least to most precise for interface dispatch.
+var callGraphAlgos = []string{"static", "cha", "rta", "vta"}
+
+// Call graph settings, set by main before any pipeline phase runs:
+// callGraphAlgo builds the call edges, and callGraphCompare lists extra
+// algorithms whose edges are recorded for comparison (-callgraph-compare).
+var (
+	callGraphAlgo    = "vta"
+	callGraphCompare []string
+)
+
+// buildCallGraphFor runs one call graph algorithm over the whole program.
+func buildCallGraphFor(algo string, ssaResult *SSAResult, fset *token.FileSet, prog *Progress) *callgraph.Graph {
+	var cg *callgraph.Graph
+	switch algo {
+	case "static":
+		cg = static.CallGraph(ssaResult.Prog)
+	case "cha":
+		cg = cha.CallGraph(ssaResult.Prog)
+	case "rta":
+		roots := rtaRoots(ssaResult, fset)
+		prog.Verbose("RTA: %d roots", len(roots))
+		if res := rta.Analyze(roots, true); res != nil {
+			cg = res.CallGraph
+		} else {
+			cg = callgraph.New(nil)
+		}
+	default:
+		cg = vta.CallGraph(ssaResult.AllFuncs, nil)
+	}
+	cg.DeleteSyntheticNodes()
+	return cg
+}
+
+// rtaRoots returns the entry points for RTA: main and package initializers
+// of the analyzed modules, plus their test functions when tests are loaded.
+func rtaRoots(ssaResult *SSAResult, fset *token.FileSet) []*ssa.Function {
+	var roots []*ssa.Function
+	for _, pkg := range ssaResult.Prog.AllPackages() {
+		if !modSet.IsKnownPkg(pkg.Pkg.Path()) {
+			continue
+		}
+		if fn := pkg.Func("init"); fn != nil {
+			roots = append(roots, fn)
+		}
+		if pkg.Pkg.Name() == "main" {
+			if fn := pkg.Func("main"); fn != nil {
+				roots = append(roots, fn)
+			}
+		}
+	}
+	for _, fn := range ssaResult.KnownFuncs(fset) {
+		if fn.Parent() == nil && testKind(fn.Name(), fn.Signature) != "" &&
+			strings.HasSuffix(fset.Position(fn.Pos()).Filename, "_test.go") {
+			roots = append(roots, fn)
+		}
+	}
+	return roots
+}
+
+// BuildCallGraph constructs the call graph selected by -callgraph and emits
+// call/call_site edges. With -callgraph-compare it also records the edges of
+// each compared algorithm in cpg.CallGraphEdges. Finally it summarizes the
+// known-module functions and the external callees it created stubs for.
 func BuildCallGraph(
 	ssaResult *SSAResult,
 	fset *token.FileSet,
@@ -16,14 +91,40 @@ func BuildCallGraph(
 	cpg *CPG,
 	prog *Progress,
 ) {
-	prog.Log("Building VTA call graph...")
+	prog.Log("Building %s call graph...", strings.ToUpper(callGraphAlgo))
 
-	cg := vta.CallGraph(ssaResult.AllFuncs, nil)
-	cg.DeleteSyntheticNodes()
+	cg := buildCallGraphFor(callGraphAlgo, ssaResult, fset, prog)
+	for _, algo := range callGraphCompare {
+		other := cg
+		if algo != callGraphAlgo {
+			prog.Log("Building %s call graph for comparison...", strings.ToUpper(algo))
+			other = buildCallGraphFor(algo, ssaResult, fset, prog)
+		}
+		n := recordCallGraphEdges(algo, other, fset, funcLookup, cpg)
+		prog.Log("%s: %d function→function edges", strings.ToUpper(algo), n)
+	}
+
+	// Incremental: the call edges of unchanged functions whose calls are
+	// all static are copied from the previous DB. Other callers in
+	// unchanged packages may dispatch to code that changed. RTA drops
+	// whatever changed code no longer reaches, so it reuses nothing.
+	var reused map[string]bool
+	if incr != nil && callGraphAlgo != "rta" {
+		callers := make([]*ssa.Function, 0, len(cg.Nodes))
+		for fn := range cg.Nodes {
+			if fn != nil {
+				callers = append(callers, fn)
+			}
+		}
+		reused = incr.reusedFuncIDs(callers, fset, funcLookup, staticCallsOnly)
+		incr.callers = reused
+	}
 
 	var callEdges, callSiteEdges, paramInEdges, paramOutEdges, callToReturnEdges int
 	var vtaTotal, vtaProm, vtaMatched, stubCount int
 	stubs := make(map[string]bool) // track created stub nodes
+	var stubFuncs []*ssa.Function
+	ifaces := newIfaceIndex(ssaResult.Prog)
 
 	_ = callgraph.GraphVisitEdges(cg, func(edge *callgraph.Edge) error {
 		caller := edge.Caller.Func
@@ -59,18 +160,21 @@ func BuildCallGraph(
 			pkgPath := callee.Pkg.Pkg.Path()
 			stubID := "ext::" + callee.String()
 			if !stubs[stubID] {
+				props := map[string]any{
+					"external":  true,
+					"full_name": callee.String(),
+				}
+				addStubSignature(props, callee, ifaces)
 				cpg.AddNode(Node{
-					ID:       stubID,
-					Kind:     "function",
-					Name:     callee.Name(),
-					Package:  modSet.RelPkg(pkgPath),
-					TypeInfo: callee.Signature.String(),
-					Properties: map[string]any{
-						"external":  true,
-						"full_name": callee.String(),
-					},
+					ID:         stubID,
+					Kind:       "function",
+					Name:       callee.Name(),
+					Package:    modSet.RelPkg(pkgPath),
+					TypeInfo:   callee.Signature.String(),
+					Properties: props,
 				})
 				stubs[stubID] = true
+				stubFuncs = append(stubFuncs, callee)
 				stubCount++
 			}
 			calleeID = stubID
@@ -79,6 +183,9 @@ func BuildCallGraph(
 			return nil
 		}
 		vtaMatched++
+		if reused[callerID] {
+			return nil
+		}
 
 		// Determine if this is a dynamic (interface) dispatch
 		props := map[string]any{}
@@ -187,41 +294,291 @@ func BuildCallGraph(
 		return nil
 	})
 
-	prog.Log("VTA: %d total edges, %d known-module pairs, %d matched to AST, %d external stubs", vtaTotal, vtaProm, vtaMatched, stubCount)
+	prog.Log("%s: %d total edges, %d known-module pairs, %d matched to AST, %d external stubs",
+		strings.ToUpper(callGraphAlgo), vtaTotal, vtaProm, vtaMatched, stubCount)
 	prog.Log("Created %d call, %d call_site, %d param_in, %d param_out, %d call_to_return edges", callEdges, callSiteEdges, paramInEdges, paramOutEdges, callToReturnEdges)
+
+	slices.SortFunc(stubFuncs, func(a, b *ssa.Function) int { return cmp.Compare(a.String(), b.String()) })
+	SummarizeFunctions(ssaResult, stubFuncs, fset, funcLookup, cpg, prog)
+}
+
+// staticCallsOnly reports whether every call in fn has a static callee.
+func staticCallsOnly(fn *ssa.Function) bool {
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			if call, ok := instr.(ssa.CallInstruction); ok && call.Common().StaticCallee() == nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// addStubSignature records the full signature of an external callee and, for
+// methods, its receiver: the declared type ("*Request", as for module methods)
+// and the interfaces the receiver implements that declare the method. Taint
+// specs and flow semantics match methods on either (see spec_keys).
+func addStubSignature(props map[string]any, fn *ssa.Function, ifaces ifaceIndex) {
+	if obj := fn.Object(); obj != nil {
+		props["signature"] = types.ObjectString(obj, nil)
+	}
+	recv := fn.Signature.Recv()
+	if recv == nil {
+		return
+	}
+	named, ok := deref(recv.Type()).(*types.Named)
+	if !ok {
+		return
+	}
+	name := named.Obj().Name()
+	if _, ptr := recv.Type().(*types.Pointer); ptr {
+		name = "*" + name
+	}
+	props["receiver"] = name
+	props["receiver_type"] = types.TypeString(recv.Type(), nil)
+	if impl := ifaces.implementedBy(recv.Type(), fn.Name()); len(impl) > 0 {
+		props["implements"] = impl
+	}
 }
 
+// ifaceIndex maps a method name to the exported, non-generic package-level
+// interfaces that declare it, across every package of the SSA program.
+type ifaceIndex map[string][]*types.TypeName
+
+func newIfaceIndex(prog *ssa.Program) ifaceIndex {
+	idx := make(ifaceIndex)
+	for _, pkg := range prog.AllPackages() {
+		scope := pkg.Pkg.Scope()
+		for _, name := range scope.Names() {
+			tn, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || !tn.Exported() || tn.IsAlias() {
+				continue
+			}
+			named, ok := tn.Type().(*types.Named)
+			if !ok || named.TypeParams().Len() > 0 {
+				continue
+			}
+			iface, ok := named.Underlying().(*types.Interface)
+			if !ok {
+				continue
+			}
+			for i := range iface.NumMethods() {
+				m := iface.Method(i).Name()
+				idx[m] = append(idx[m], tn)
+			}
+		}
+	}
+	return idx
+}
+
+// implementedBy returns the interfaces declaring method that recv (or, for
+// value receivers, *recv) implements, as {package, type} pairs sorted by
+// package and name.
+func (idx ifaceIndex) implementedBy(recv types.Type, method string) []map[string]any {
+	var impl []map[string]any
+	for _, tn := range idx[method] {
+		iface := tn.Type().Underlying().(*types.Interface)
+		if !types.Implements(recv, iface) {
+			if _, ptr := recv.(*types.Pointer); ptr || !types.Implements(types.NewPointer(recv), iface) {
+				continue
+			}
+		}
+		impl = append(impl, map[string]any{
+			"package": modSet.RelPkg(tn.Pkg().Path()),
+			"type":    tn.Name(),
+		})
+	}
+	slices.SortFunc(impl, func(a, b map[string]any) int {
+		return cmp.Or(cmp.Compare(a["package"].(string), b["package"].(string)),
+			cmp.Compare(a["type"].(string), b["type"].(string)))
+	})
+	return impl
+}
+
+// recordCallGraphEdges adds the function→function edges of cg to the CPG
+// under algo, resolving endpoints like BuildCallGraph
+// (known-module functions by position, external callees as ext:: stubs).
+func recordCallGraphEdges(algo string, cg *callgraph.Graph, fset *token.FileSet, funcLookup *FuncLookup, cpg *CPG) int {
+	seen := make(map[CallGraphEdge]bool)
+	_ = callgraph.GraphVisitEdges(cg, func(edge *callgraph.Edge) error {
+		caller, callee := edge.Caller.Func, edge.Callee.Func
+		if caller.Pkg == nil || !modSet.IsKnownPkg(caller.Pkg.Pkg.Path()) {
+			return nil // only edges leaving analyzed code are comparable
+		}
+		callerID := ssaFuncNodeID(caller, fset, funcLookup)
+		if callerID == "" {
+			return nil
+		}
+		calleeID := ssaFuncNodeID(callee, fset, funcLookup)
+		if calleeID == "" {
+			if callee.Pkg == nil || modSet.IsKnownPkg(callee.Pkg.Pkg.Path()) {
+				return nil
+			}
+			calleeID = "ext::" + callee.String()
+		}
+		e := CallGraphEdge{
+			Source:  callerID,
+			Target:  calleeID,
+			Algo:    algo,
+			Dynamic: edge.Site != nil && edge.Site.Common().IsInvoke(),
+		}
+		if !seen[e] {
+			seen[e] = true
+			cpg.AddCallGraphEdge(e)
+		}
+		return nil
+	})
+	return len(seen)
+}
+
+// callGraphEdgesDDL creates the table of compared call graph edges; the
+// streaming sink creates it with its first batch.
+const callGraphEdgesDDL = `
+CREATE TABLE IF NOT EXISTS callgraph_edges (
+    source TEXT NOT NULL,
+    target TEXT NOT NULL,
+    algo TEXT NOT NULL,
+    dynamic INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (source, target, algo)
+) WITHOUT ROWID;
+CREATE INDEX IF NOT EXISTS idx_callgraph_edges_algo ON callgraph_edges(algo);
+`
+
+// writeCallGraphComparison stores the compared call graphs (already on disk
+// in streaming mode) and summarizes, per algorithm, the edges no other
+// compared algorithm found.
+func writeCallGraphComparison(conn *sqlite.Conn, edges []CallGraphEdge, prog *Progress) (err error) {
+	if err := sqlitex.ExecuteScript(conn, callGraphEdgesDDL, nil); err != nil {
+		return fmt.Errorf("create callgraph_edges: %w", err)
+	}
+
+	endFn, err := sqlitex.ImmediateTransaction(conn)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer endFn(&err)
+
+	if err := insertCallGraphEdges(conn, edges); err != nil {
+		return err
+	}
+
+	summary := `
+-- Edges found by exactly one of the compared algorithms
+CREATE VIEW v_callgraph_unique AS
+SELECT c.algo, c.source, src.name AS source_name, c.target,
+       COALESCE(tgt.name, c.target) AS target_name, c.dynamic
+FROM callgraph_edges c
+LEFT JOIN nodes src ON src.id = c.source
+LEFT JOIN nodes tgt ON tgt.id = c.target
+WHERE NOT EXISTS (
+  SELECT 1 FROM callgraph_edges o
+  WHERE o.source = c.source AND o.target = c.target AND o.algo != c.algo
+);
+
+CREATE TABLE callgraph_comparison AS
+SELECT c.algo,
+       COUNT(*) AS edges,
+       SUM(c.dynamic) AS dynamic_edges,
+       COALESCE(u.unique_edges, 0) AS unique_edges,
+       COALESCE(u.unique_dynamic_edges, 0) AS unique_dynamic_edges
+FROM callgraph_edges c
+LEFT JOIN (
+  SELECT algo, COUNT(*) AS unique_edges, SUM(dynamic) AS unique_dynamic_edges
+  FROM v_callgraph_unique GROUP BY algo
+) u ON u.algo = c.algo
+GROUP BY c.algo;
+`
+	if err := sqlitex.ExecuteScript(conn, summary, nil); err != nil {
+		return fmt.Errorf("callgraph comparison: %w", err)
+	}
+
+	var total int
+	err = sqlitex.ExecuteTransient(conn, `SELECT COUNT(*) FROM callgraph_edges`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			total = stmt.ColumnInt(0)
+			return nil
+		}})
+	if err != nil {
+		return fmt.Errorf("count callgraph edges: %w", err)
+	}
+	prog.Log("Stored %d call graph edges across %d algorithms for comparison", total, len(callGraphCompare))
+	return nil
+}
+
+// insertCallGraphEdges writes compared call graph edges; an edge found by
+// several configurations is dynamic if any of them dispatches it.
+func insertCallGraphEdges(conn *sqlite.Conn, edges []CallGraphEdge) error {
+	stmt, err := conn.Prepare(`INSERT INTO callgraph_edges (source, target, algo, dynamic) VALUES (?, ?, ?, ?)
+ON CONFLICT (source, target, algo) DO UPDATE SET dynamic = MAX(dynamic, excluded.dynamic)`)
+	if err != nil {
+		return fmt.Errorf("prepare callgraph edge insert: %w", err)
+	}
+	defer func() { _ = stmt.Finalize() }()
+	for _, e := range edges {
+		stmt.BindText(1, e.Source)
+		stmt.BindText(2, e.Target)
+		stmt.BindText(3, e.Algo)
+		stmt.BindBool(4, e.Dynamic)
+		if _, err := stmt.Step(); err != nil {
+			return fmt.Errorf("insert callgraph edge %s→%s: %w", e.Source, e.Target, err)
+		}
+		_ = stmt.Reset()
+	}
+	return nil
+}
+
+// fanInOutScript counts fan-in and fan-out over the call edges on disk, the
+// way ComputeFanInOut does in memory: existing metrics rows are updated and
+// call endpoints without one get a row with only fan-in/out.
+const fanInOutScript = `
+CREATE TEMP TABLE fan (id TEXT PRIMARY KEY, fan_in INTEGER NOT NULL, fan_out INTEGER NOT NULL) WITHOUT ROWID;
+INSERT INTO temp.fan
+SELECT id, SUM(fan_in), SUM(fan_out) FROM (
+  SELECT target AS id, 1 AS fan_in, 0 AS fan_out FROM edges WHERE kind = 'call'
+  UNION ALL
+  SELECT source, 0, 1 FROM edges WHERE kind = 'call')
+GROUP BY id;
+UPDATE metrics SET
+  fan_in = COALESCE((SELECT fan_in FROM temp.fan WHERE id = metrics.function_id), 0),
+  fan_out = COALESCE((SELECT fan_out FROM temp.fan WHERE id = metrics.function_id), 0);
+INSERT OR IGNORE INTO metrics (function_id, cyclomatic_complexity, fan_in, fan_out, loc, num_params)
+SELECT id, 0, fan_in, fan_out, 0, 0 FROM temp.fan;
+DROP TABLE temp.fan;`
+
 // ComputeFanInOut calculates fan-in, fan-out, and recursion from the call graph edges.
 // Must be called after BuildCallGraph has populated call edges.
 // For call targets that have no AST-derived Metrics entry (e.g., external stubs),
 // a minimal Metrics record is created so fan-in data is not silently lost.
 // Also detects direct and mutual recursion by finding self-referencing call edges
 // and marking involved function nodes with a "recursive" property.
-func ComputeFanInOut(cpg *CPG) {
+func ComputeFanInOut(cpg *CPG, prog *Progress) {
+	// Streaming: metrics rows are on disk, and so is the counting.
+	if cpg.sink != nil {
+		if err := cpg.sink.fanInOut(); err != nil {
+			prog.Log("Warning: fan-in/out: %v", err)
+		}
+		return
+	}
+
 	fanIn := make(map[string]int)      // target → count
 	fanOut := make(map[string]int)     // source → count
 	recursive := make(map[string]bool) // functions with self-referencing call edges
 
-	for _, e := range cpg.Edges {
-		if e.Kind != "call" {
-			continue
-		}
-		fanOut[e.Source]++
-		fanIn[e.Target]++
+	err := cpg.EdgesOfKind("call", func(source, target string) {
+		fanOut[source]++
+		fanIn[target]++
 		// Direct recursion: function calls itself
-		if e.Source == e.Target {
-			recursive[e.Source] = true
+		if source == target {
+			recursive[source] = true
 		}
+	})
+	if err != nil {
+		prog.Log("Warning: reading call edges for fan-in/out: %v", err)
 	}
 
-	// Mark recursive functions in node properties
-	for i := range cpg.Nodes {
-		if cpg.Nodes[i].Kind == "function" && recursive[cpg.Nodes[i].ID] {
-			if cpg.Nodes[i].Properties == nil {
-				cpg.Nodes[i].Properties = map[string]any{}
-			}
-			cpg.Nodes[i].Properties["recursive"] = true
-		}
+	// Mark recursive functions in node properties (call sources are always functions)
+	for id := range recursive {
+		cpg.SetNodeProperty(id, "recursive", true)
 	}
 
 	// Update existing metrics entries
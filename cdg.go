@@ -23,22 +23,24 @@ func ExtractCDG(
 ) {
 	prog.Log("Extracting CDG (control dependence)...")
 
-	var cdgEdges, domEdges, pdomEdges, cdgFuncs int
+	type cdgStats struct{ cdgEdges, domEdges, pdomEdges, cdgFuncs int }
+	funcs := ssaResult.KnownFuncs(fset)
+	stats := make([]cdgStats, len(funcs))
 
-	for fn := range ssaResult.AllFuncs {
-		if fn.Pkg == nil || fn.Synthetic != "" {
-			continue
-		}
-		if !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) {
-			continue
+	runSharded(len(funcs), cpg, func(fi int, shard *CPG) {
+		fn := funcs[fi]
+		var cdgEdges, domEdges, pdomEdges, cdgFuncs int
+		defer func() { stats[fi] = cdgStats{cdgEdges, domEdges, pdomEdges, cdgFuncs} }()
+		if incr.SkipPkg(fn.Pkg.Pkg.Path()) {
+			return // reused from the previous DB
 		}
 		if len(fn.Blocks) < 2 {
-			continue
+			return
 		}
 
 		funcNodeID := ssaFuncNodeID(fn, fset, funcLookup)
 		if funcNodeID == "" {
-			continue
+			return
 		}
 
 		n := len(fn.Blocks)
@@ -64,7 +66,7 @@ func ExtractCDG(
 
 				w := v
 				for w != -1 && w != stop {
-					cpg.AddEdge(Edge{
+					shard.AddEdge(Edge{
 						Source: blockIDs[u],
 						Target: blockIDs[w],
 						Kind:   "cdg",
@@ -77,7 +79,7 @@ func ExtractCDG(
 		// Dominator edges (from SSA's built-in dominator tree)
 		for _, block := range fn.Blocks {
 			for _, child := range block.Dominees() {
-				cpg.AddEdge(Edge{
+				shard.AddEdge(Edge{
 					Source: blockIDs[block.Index],
 					Target: blockIDs[child.Index],
 					Kind:   "dom",
@@ -89,7 +91,7 @@ func ExtractCDG(
 		// Post-dominator edges (from our computed pdom tree)
 		for i := 0; i < n; i++ {
 			if ipdom[i] >= 0 && ipdom[i] < n {
-				cpg.AddEdge(Edge{
+				shard.AddEdge(Edge{
 					Source: blockIDs[ipdom[i]],
 					Target: blockIDs[i],
 					Kind:   "pdom",
@@ -99,6 +101,14 @@ func ExtractCDG(
 		}
 
 		cdgFuncs++
+	})
+
+	var cdgEdges, domEdges, pdomEdges, cdgFuncs int
+	for _, st := range stats {
+		cdgEdges += st.cdgEdges
+		domEdges += st.domEdges
+		pdomEdges += st.pdomEdges
+		cdgFuncs += st.cdgFuncs
 	}
 
 	prog.Log("Created %d CDG, %d dom, %d pdom edges across %d functions", cdgEdges, domEdges, pdomEdges, cdgFuncs)
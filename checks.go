@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// CheckResult holds one bounds or nil check the Go compiler reported.
+type CheckResult struct {
+	RelFile string
+	Line    int
+	Col     int
+	Kind    string // "bounds_check", "nil_check", "nil_check_removed"
+	Detail  string // bounds_check: "index" or "slice"
+	NodeID  string // CPG node at the position, set by ResolveChecks
+}
+
+// RunCheckDiagnostics runs `go build` with the compiler's bounds check
+// (-d=ssa/check_bce/debug=1) and nil check (-d=nil) diagnostics on each
+// module directory and collects the checks left in the generated code, and
+// the nil checks the optimizer removed.
+func RunCheckDiagnostics(prog *Progress) []CheckResult {
+	prog.Log("Running Go bounds/nil check diagnostics across %d modules...", len(modSet.Dirs()))
+
+	var allResults []CheckResult
+	for _, mod := range modSet.Dirs() {
+		allResults = append(allResults, runChecksForDir(mod.Dir, mod.Prefix, prog)...)
+	}
+
+	prog.Log("Check diagnostics: %d annotations total", len(allResults))
+	return allResults
+}
+
+// ResolveChecks sets the NodeID of each result from the position lookup of
+// the build configuration the compiler ran in. Bounds checks sit at the
+// "[" of index and slice expressions, like their nodes. A nil check on a
+// field access sits at the ".", one column before the selector node, and
+// one on *p at the "*", before p; those fall back to the next column.
+func ResolveChecks(results []CheckResult, posLookup *PosLookup, prog *Progress) {
+	resolved := 0
+	for i := range results {
+		r := &results[i]
+		r.NodeID = posLookup.Get(r.RelFile, r.Line, r.Col)
+		if r.NodeID == "" && r.Kind != "bounds_check" {
+			r.NodeID = posLookup.Get(r.RelFile, r.Line, r.Col+1)
+		}
+		if r.NodeID != "" {
+			resolved++
+		}
+	}
+	prog.Log("Check diagnostics: resolved %d of %d annotations to nodes", resolved, len(results))
+}
+
+func runChecksForDir(dir, prefix string, prog *Progress) []CheckResult {
+	args := []string{"build", "-gcflags=-d=ssa/check_bce/debug=1,nil"}
+	if tags := effectiveBuildTags(); len(tags) > 0 {
+		args = append(args, "-tags="+strings.Join(tags, ","))
+	}
+	cmd := exec.Command("go", append(args, "./...")...)
+	cmd.Dir = dir
+	cmd.Env = goCommandEnv(replaceEnv(os.Environ(), "GOFLAGS", "-buildvcs=false"))
+	cmd.Stdout = nil // discard
+
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		prog.Verbose("Check diagnostics for %s: failed to create stderr pipe: %v", dir, err)
+		return nil
+	}
+	if err := cmd.Start(); err != nil {
+		prog.Verbose("Check diagnostics for %s: failed to start: %v", dir, err)
+		return nil
+	}
+
+	var results []CheckResult
+	scanner := bufio.NewScanner(stderrPipe)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		if r, ok := parseCheckLine(scanner.Text(), prefix); ok {
+			results = append(results, r)
+		}
+	}
+
+	_ = cmd.Wait()
+	return results
+}
+
+// parseCheckLine parses one line of check_bce/nil diagnostics from the
+// compiler, prefixing the file path for non-primary modules. Other lines
+// (package headers, escape analysis output) are rejected.
+func parseCheckLine(text, prefix string) (CheckResult, bool) {
+	if strings.HasPrefix(text, "#") || strings.HasPrefix(text, "/") {
+		return CheckResult{}, false
+	}
+	m := escapeLineRe.FindStringSubmatch(text)
+	if m == nil {
+		return CheckResult{}, false
+	}
+
+	var kind, detail string
+	switch m[4] {
+	case "Found IsInBounds":
+		kind, detail = "bounds_check", "index"
+	case "Found IsSliceInBounds":
+		kind, detail = "bounds_check", "slice"
+	case "generated nil check":
+		kind = "nil_check"
+	case "removed nil check":
+		kind = "nil_check_removed"
+	default:
+		return CheckResult{}, false
+	}
+
+	relFile := m[1]
+	if prefix != "" {
+		relFile = prefix + "/" + relFile
+	}
+	line, _ := strconv.Atoi(m[2])
+	col, _ := strconv.Atoi(m[3])
+	return CheckResult{RelFile: relFile, Line: line, Col: col, Kind: kind, Detail: detail}, true
+}
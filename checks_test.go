@@ -0,0 +1,166 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// checkOutput is `go build -gcflags=-d=ssa/check_bce/debug=1,nil` output for
+//
+//	a.go:
+//	  5: func Sum(xs []int) int {
+//	  7: 	for i := 0; i < 10; i++ {
+//	  8: 		s += xs[i]
+//	 10: 	return s + xs[0]
+//	 14: 	return xs[i:]        (in Tail)
+//	 18: 	return t.n           (in Get)
+//	 22: 	return *p            (in Deref)
+//	b.go:
+//	  4: 	return &t.n          (in Addr)
+const checkOutput = `# example.com/chk
+./a.go:8:10: Found IsInBounds
+./a.go:10:15: Found IsInBounds
+./a.go:14:11: Found IsSliceInBounds
+./a.go:18:10: removed nil check
+./a.go:22:9: removed nil check
+./b.go:4:11: generated nil check
+./a.go:5:6: can inline Sum with cost 20 as: func([]int) int { ... }
+`
+
+func parseCheckOutput(prefix string) []CheckResult {
+	var results []CheckResult
+	for _, line := range strings.Split(checkOutput, "\n") {
+		if r, ok := parseCheckLine(line, prefix); ok {
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
+func TestParseCheckLine(t *testing.T) {
+	want := []CheckResult{
+		{RelFile: "lib/a.go", Line: 8, Col: 10, Kind: "bounds_check", Detail: "index"},
+		{RelFile: "lib/a.go", Line: 10, Col: 15, Kind: "bounds_check", Detail: "index"},
+		{RelFile: "lib/a.go", Line: 14, Col: 11, Kind: "bounds_check", Detail: "slice"},
+		{RelFile: "lib/a.go", Line: 18, Col: 10, Kind: "nil_check_removed"},
+		{RelFile: "lib/a.go", Line: 22, Col: 9, Kind: "nil_check_removed"},
+		{RelFile: "lib/b.go", Line: 4, Col: 11, Kind: "nil_check"},
+	}
+	if got := parseCheckOutput("lib"); !slices.Equal(got, want) {
+		t.Errorf("parsed checks =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestApplyCheckDiagnostics(t *testing.T) {
+	conn, err := openDB(filepath.Join(t.TempDir(), "cpg.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	err = sqlitex.ExecuteScript(conn, `
+INSERT INTO nodes (id, kind, name, file, line, col, end_line, parent_function) VALUES
+  ('fn:Sum', 'function', 'Sum', 'a.go', 5, 1, 11, NULL),
+  ('for:Sum', 'for', 'for', 'a.go', 7, 2, 9, 'fn:Sum'),
+  ('idx:8', 'index', 'xs[i]', 'a.go', 8, 10, 8, 'fn:Sum'),
+  ('idx:10', 'index', 'xs[0]', 'a.go', 10, 15, 10, 'fn:Sum'),
+  ('fn:Tail', 'function', 'Tail', 'a.go', 13, 1, 15, NULL),
+  ('slice:14', 'slice', 'xs[i:]', 'a.go', 14, 11, 14, 'fn:Tail'),
+  ('fn:Get', 'function', 'Get', 'a.go', 17, 1, 19, NULL),
+  ('sel:18', 'selector', 't.n', 'a.go', 18, 11, 18, 'fn:Get'),
+  ('fn:Deref', 'function', 'Deref', 'a.go', 21, 1, 23, NULL),
+  ('star:22', 'deref', '*p', 'a.go', 22, 10, 22, 'fn:Deref'),
+  ('fn:Addr', 'function', 'Addr', 'b.go', 3, 1, 5, NULL),
+  ('sel:b4', 'selector', 't.n', 'b.go', 4, 12, 4, 'fn:Addr');
+INSERT INTO metrics (function_id) VALUES ('fn:Sum'), ('fn:Tail'), ('fn:Get'), ('fn:Deref'), ('fn:Addr');
+CREATE TABLE node_properties (node_id TEXT NOT NULL, key TEXT NOT NULL, value TEXT NOT NULL);`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	posLookup := NewPosLookup()
+	for _, p := range []struct {
+		file      string
+		line, col int
+		id        string
+	}{
+		{"a.go", 8, 10, "idx:8"},
+		{"a.go", 10, 15, "idx:10"},
+		{"a.go", 14, 11, "slice:14"},
+		{"a.go", 18, 11, "sel:18"},
+		{"a.go", 22, 10, "star:22"},
+		{"b.go", 4, 12, "sel:b4"},
+	} {
+		posLookup.Set(p.file, p.line, p.col, p.id)
+	}
+	results := parseCheckOutput("")
+	prog := NewProgress(false)
+	ResolveChecks(results, posLookup, prog)
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.NodeID)
+	}
+	// Nil checks on field accesses and dereferences resolve at the next column.
+	wantIDs := []string{"idx:8", "idx:10", "slice:14", "sel:18", "star:22", "sel:b4"}
+	if !slices.Equal(ids, wantIDs) {
+		t.Fatalf("resolved node IDs = %v, want %v", ids, wantIDs)
+	}
+
+	if err := applyCheckDiagnostics(conn, results, prog); err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []string
+	err = sqlitex.ExecuteTransient(conn,
+		`SELECT node_id || ' ' || function_id || ' ' || kind || ' ' || COALESCE(detail, '-') || ' ' || in_loop
+		 FROM compiler_checks ORDER BY node_id`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			rows = append(rows, stmt.ColumnText(0))
+			return nil
+		}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRows := []string{
+		"idx:10 fn:Sum bounds_check index 0",
+		"idx:8 fn:Sum bounds_check index 1",
+		"sel:18 fn:Get nil_check_removed - 0",
+		"sel:b4 fn:Addr nil_check - 0",
+		"slice:14 fn:Tail bounds_check slice 0",
+		"star:22 fn:Deref nil_check_removed - 0",
+	}
+	if !slices.Equal(rows, wantRows) {
+		t.Errorf("compiler_checks =\n%s\nwant\n%s", strings.Join(rows, "\n"), strings.Join(wantRows, "\n"))
+	}
+
+	got := make(map[string]string)
+	err = sqlitex.ExecuteTransient(conn, `
+SELECT function_id, bounds_checks || '/' || bounds_checks_in_loops FROM metrics
+UNION ALL
+SELECT node_id, key || '=' || value FROM node_properties`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			got[stmt.ColumnText(0)] = stmt.ColumnText(1)
+			return nil
+		}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"fn:Sum":   "2/1",
+		"fn:Tail":  "1/0",
+		"fn:Get":   "0/0",
+		"idx:8":    "bounds_check=index",
+		"slice:14": "bounds_check=slice",
+		"sel:18":   "nil_check=removed",
+		"sel:b4":   "nil_check=generated",
+	}
+	for id, w := range want {
+		if got[id] != w {
+			t.Errorf("%s = %q, want %q", id, got[id], w)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"cpg-gen/internal/cpgdiff"
+)
+
+func main() {
+	format := flag.String("format", "markdown", "Output format: markdown or json")
+	outPath := flag.String("o", "", "Write the report to this file instead of stdout")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: cpg-diff [flags] old.db new.db\n\nCompares two CPG databases: functions, calls, metrics, findings and package dependencies.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *format != "markdown" && *format != "json" {
+		log.Fatalf("unknown -format %q (want markdown or json)", *format)
+	}
+	for _, path := range flag.Args() {
+		if _, err := os.Stat(path); err != nil {
+			log.Fatalf("database: %v", err)
+		}
+	}
+
+	report, err := cpgdiff.Compare(flag.Arg(0), flag.Arg(1))
+	if err != nil {
+		log.Fatalf("compare: %v", err)
+	}
+
+	var out []byte
+	if *format == "json" {
+		if out, err = json.MarshalIndent(report, "", "  "); err != nil {
+			log.Fatalf("encode: %v", err)
+		}
+		out = append(out, '\n')
+	} else {
+		out = []byte(report.Markdown())
+	}
+
+	if *outPath == "" {
+		_, err = os.Stdout.Write(out)
+	} else {
+		err = os.WriteFile(*outPath, out, 0o644)
+	}
+	if err != nil {
+		log.Fatalf("write report: %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"cpg-gen/internal/cpgdiff"
+)
+
+// The test binary runs as cpg-diff when this variable is set.
+const runMainEnv = "CPG_DIFF_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) != "" {
+		os.Args = append(os.Args[:1], flagArgs()...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// flagArgs returns the arguments after "--" that cpgDiff passed.
+func flagArgs() []string {
+	for i, a := range os.Args {
+		if a == "--" {
+			return os.Args[i+1:]
+		}
+	}
+	return nil
+}
+
+func cpgDiff(t *testing.T, args ...string) ([]byte, error) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], append([]string{"-test.run=^$", "--"}, args...)...)
+	cmd.Env = append(os.Environ(), runMainEnv+"=1")
+	return cmd.Output()
+}
+
+func writeDB(t *testing.T, path, script string) {
+	t.Helper()
+	conn, err := sqlite.OpenConn(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	err = sqlitex.ExecuteScript(conn, `
+CREATE TABLE nodes (id TEXT PRIMARY KEY, kind TEXT, name TEXT, package TEXT, file TEXT, line INTEGER,
+  type_info TEXT, parent_function TEXT);
+CREATE TABLE edges (source TEXT, target TEXT, kind TEXT);
+CREATE TABLE metrics (function_id TEXT PRIMARY KEY, cyclomatic_complexity INTEGER, loc INTEGER,
+  fan_in INTEGER, fan_out INTEGER, num_params INTEGER);
+CREATE TABLE findings (category TEXT, severity TEXT, node_id TEXT, file TEXT, line INTEGER, message TEXT);
+`+script, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCPGDiff(t *testing.T) {
+	dir := t.TempDir()
+	oldPath, newPath := filepath.Join(dir, "old.db"), filepath.Join(dir, "new.db")
+	writeDB(t, oldPath, `INSERT INTO nodes VALUES ('a::F@a.go:1:1', 'function', 'F', 'a', 'a.go', 1, 'func()', NULL);`)
+	writeDB(t, newPath, `INSERT INTO nodes VALUES ('a::G@a.go:1:1', 'function', 'G', 'a', 'a.go', 1, 'func()', NULL);`)
+
+	out, err := cpgDiff(t, "-format", "json", oldPath, newPath)
+	if err != nil {
+		t.Fatalf("cpg-diff: %v", err)
+	}
+	var r cpgdiff.Report
+	if err := json.Unmarshal(out, &r); err != nil {
+		t.Fatalf("decode report: %v\n%s", err, out)
+	}
+	if len(r.AddedFunctions) != 1 || r.AddedFunctions[0].Key != "a.G" ||
+		len(r.RemovedFunctions) != 1 || r.RemovedFunctions[0].Key != "a.F" {
+		t.Errorf("report = %+v, want a.G added and a.F removed", r)
+	}
+
+	mdPath := filepath.Join(dir, "diff.md")
+	if _, err := cpgDiff(t, "-o", mdPath, oldPath, newPath); err != nil {
+		t.Fatalf("cpg-diff -o: %v", err)
+	}
+	if md, err := os.ReadFile(mdPath); err != nil || len(md) == 0 || md[0] != '#' {
+		t.Errorf("Markdown report = %q, %v", md, err)
+	}
+
+	for _, args := range [][]string{
+		{oldPath},
+		{"-format", "xml", oldPath, newPath},
+		{oldPath, filepath.Join(dir, "missing.db")},
+	} {
+		var exit *exec.ExitError
+		if _, err := cpgDiff(t, args...); !errors.As(err, &exit) {
+			t.Errorf("cpg-diff %q: err = %v, want a non-zero exit", args, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import "slices"
+
+const (
+	// maxCoChangeFiles skips commits changing more Go files than this:
+	// bulk renames, reformatting and vendoring say nothing about coupling.
+	maxCoChangeFiles = 50
+
+	// minCoChangeCount is the number of shared commits a pair needs before
+	// it is recorded.
+	minCoChangeCount = 2
+)
+
+// CoChange records how often Target changed in the same commit as Source.
+type CoChange struct {
+	Source     string // file path or function ID
+	Target     string
+	Count      int     // commits changing both
+	Confidence float64 // Count / commits changing Source
+}
+
+// FileCoChanges counts the commits of the history window that change each
+// pair of files.
+func FileCoChanges(history *GitHistory) []CoChange {
+	var sets [][]string
+	for _, c := range history.Commits {
+		if len(c.Files) <= maxCoChangeFiles {
+			sets = append(sets, c.Files)
+		}
+	}
+	return computeCoChanges(sets)
+}
+
+// FunctionCoChanges counts the commits of the history window whose hunks
+// touch each pair of functions. Hunks are in working tree line numbers, so
+// they are matched against the current function ranges; edits to lines
+// deleted since are not attributed to any function.
+func FunctionCoChanges(history *GitHistory, funcs map[string]FileRange) []CoChange {
+	type funcRange struct {
+		id string
+		LineRange
+	}
+	byFile := make(map[string][]funcRange)
+	for id, fr := range funcs {
+		byFile[fr.RelFile] = append(byFile[fr.RelFile], funcRange{id, fr.LineRange})
+	}
+
+	var sets [][]string
+	for _, c := range history.Commits {
+		if len(c.Files) > maxCoChangeFiles {
+			continue
+		}
+		var ids []string
+		for _, h := range c.Hunks {
+			for _, f := range byFile[h.RelFile] {
+				if h.Start <= f.End && f.Start <= h.End {
+					ids = append(ids, f.id)
+				}
+			}
+		}
+		if len(ids) > 0 {
+			sets = append(sets, ids)
+		}
+	}
+	return computeCoChanges(sets)
+}
+
+// computeCoChanges counts, for each pair of entities, the change sets
+// containing both, and returns both directions of every pair seen at least
+// minCoChangeCount times.
+func computeCoChanges(sets [][]string) []CoChange {
+	type pair struct{ a, b string }
+	changes := make(map[string]int)
+	pairs := make(map[pair]int)
+	for _, set := range sets {
+		set = slices.Clone(set)
+		slices.Sort(set)
+		set = slices.Compact(set)
+		for i, a := range set {
+			changes[a]++
+			for _, b := range set[i+1:] {
+				pairs[pair{a, b}]++
+			}
+		}
+	}
+
+	var results []CoChange
+	for p, n := range pairs {
+		if n < minCoChangeCount {
+			continue
+		}
+		results = append(results,
+			CoChange{Source: p.a, Target: p.b, Count: n, Confidence: float64(n) / float64(changes[p.a])},
+			CoChange{Source: p.b, Target: p.a, Count: n, Confidence: float64(n) / float64(changes[p.b])})
+	}
+	return results
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestComputeCoChanges(t *testing.T) {
+	sets := [][]string{
+		{"a", "b", "c"},
+		{"b", "a", "a"}, // duplicates count once
+		{"a"},
+		{"c", "d"},
+	}
+	got := computeCoChanges(sets)
+	slices.SortFunc(got, func(x, y CoChange) int {
+		return strings.Compare(x.Source+x.Target, y.Source+y.Target)
+	})
+	want := []CoChange{
+		{Source: "a", Target: "b", Count: 2, Confidence: 2.0 / 3},
+		{Source: "b", Target: "a", Count: 2, Confidence: 1},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("computeCoChanges = %+v, want %+v", got, want)
+	}
+}
+
+func TestFunctionCoChanges(t *testing.T) {
+	funcs := map[string]FileRange{
+		"fn:A": {RelFile: "a.go", LineRange: LineRange{Start: 3, End: 10}},
+		"fn:B": {RelFile: "a.go", LineRange: LineRange{Start: 12, End: 20}},
+		"fn:C": {RelFile: "c.go", LineRange: LineRange{Start: 1, End: 5}},
+	}
+	bulk := make([]string, maxCoChangeFiles+1)
+	history := &GitHistory{Commits: []GitCommit{
+		{Hash: "1", Files: []string{"a.go", "c.go"}, Hunks: []GitHunk{
+			{RelFile: "a.go", LineRange: LineRange{Start: 10, End: 12}}, // spans A and B
+			{RelFile: "c.go", LineRange: LineRange{Start: 2, End: 2}},
+		}},
+		{Hash: "2", Files: []string{"a.go", "c.go"}, Hunks: []GitHunk{
+			{RelFile: "a.go", LineRange: LineRange{Start: 5, End: 5}},
+			{RelFile: "c.go", LineRange: LineRange{Start: 4, End: 4}},
+		}},
+		{Hash: "3", Files: []string{"a.go"}, Hunks: []GitHunk{
+			{RelFile: "a.go", LineRange: LineRange{Start: 11, End: 11}}, // between functions
+		}},
+		{Hash: "4", Files: bulk, Hunks: []GitHunk{
+			{RelFile: "a.go", LineRange: LineRange{Start: 15, End: 15}},
+			{RelFile: "c.go", LineRange: LineRange{Start: 1, End: 1}},
+		}},
+	}}
+
+	counts := make(map[string]int)
+	for _, p := range FunctionCoChanges(history, funcs) {
+		counts[p.Source+"→"+p.Target] = p.Count
+	}
+	want := map[string]int{"fn:A→fn:C": 2, "fn:C→fn:A": 2}
+	if len(counts) != len(want) {
+		t.Errorf("pairs = %v, want %v", counts, want)
+	}
+	for k, n := range want {
+		if counts[k] != n {
+			t.Errorf("%s: count = %d, want %d", k, counts[k], n)
+		}
+	}
+}
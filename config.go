@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the declarative project configuration loaded from -config.
+// Relative paths are resolved against the directory containing the config
+// file, so the same cpg.yaml works regardless of the caller's working dir.
+type Config struct {
+	// Modules lists the modules to analyze. The first entry is the primary
+	// module; the rest are equivalent to -modules entries.
+	Modules []ModuleConfig `yaml:"modules"`
+
+	// Include/Exclude are package import path globs. A trailing "/..." matches
+	// the package and everything below it, as with the go command.
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+
+	// GeneratedPatterns are file name globs treated as generated code.
+	GeneratedPatterns []string `yaml:"generated_patterns"`
+	SkipTests         *bool    `yaml:"skip_tests"`
+	SkipGenerated     *bool    `yaml:"skip_generated"`
+
+	BuildTags []string `yaml:"build_tags"`
+
+	// Configs lists build configurations to analyze and merge, in -configs
+	// syntax (e.g. "windows/amd64", "linux/arm64:tags=foo+bar").
+	Configs []string `yaml:"configs"`
+
+	// Phases restricts the optional pipeline phases to run. Empty means all
+	// except the opt-in ones (see optInPhases).
+	Phases []string `yaml:"phases"`
+
+	// GitWindow is the number of most recent commits analyzed for git
+	// history and co-change (default 500).
+	GitWindow *int `yaml:"git_window"`
+
+	// Analyzers selects the go/analysis passes to run, by name. Empty
+	// means all built-in ones.
+	Analyzers []string `yaml:"analyzers"`
+
+	TaintSpecs    []string `yaml:"taint_specs"`
+	FlowSemantics []string `yaml:"flow_semantics"`
+}
+
+// ModuleConfig describes one module entry in the config file.
+type ModuleConfig struct {
+	Dir    string `yaml:"dir"`
+	Module string `yaml:"module"` // optional; read from go.mod when empty
+	Prefix string `yaml:"prefix"`
+}
+
+// optionalPhases are the pipeline phases that can be toggled via Config.Phases.
+// Loading, AST, SSA and CFG/DFG extraction always run since every later phase
+// depends on them.
+var optionalPhases = []string{
+	"cdg",
+	"channels",
+	"panics",
+	"errcheck",
+	"callgraph",
+	"pointsto",
+	"types",
+	"metrics",
+	"escape",
+	"checks",
+	"vet",
+	"git",
+}
+
+// optInPhases are optional phases that are off unless listed in
+// Config.Phases or enabled by their own flag, because they are expensive
+// and no other phase consumes their output.
+var optInPhases = []string{
+	"pointsto",
+}
+
+// Generated-file patterns and build tags, set by main before any pipeline
+// phase runs (alongside flagSkipTests/flagSkipGenerated).
+var (
+	generatedPatterns = []string{"*.pb.go"}
+	buildTags         []string
+)
+
+// LoadConfig reads and validates a config file. All validation problems are
+// reported together so a broken config can be fixed in one pass.
+func LoadConfig(configPath string) (*Config, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+
+	var cfg Config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) { // io.EOF: empty file
+		return nil, fmt.Errorf("parsing config %s: %w", configPath, err)
+	}
+
+	base, err := filepath.Abs(filepath.Dir(configPath))
+	if err != nil {
+		return nil, fmt.Errorf("resolving config dir: %w", err)
+	}
+	cfg.resolvePaths(base)
+
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s:\n%w", configPath, err)
+	}
+	return &cfg, nil
+}
+
+func (c *Config) resolvePaths(base string) {
+	abs := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(base, p)
+	}
+	for i := range c.Modules {
+		c.Modules[i].Dir = abs(c.Modules[i].Dir)
+	}
+	for i := range c.TaintSpecs {
+		c.TaintSpecs[i] = abs(c.TaintSpecs[i])
+	}
+	for i := range c.FlowSemantics {
+		c.FlowSemantics[i] = abs(c.FlowSemantics[i])
+	}
+}
+
+func (c *Config) validate() error {
+	var errs []error
+	addErr := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("  "+format, args...))
+	}
+
+	seenPrefix := make(map[string]bool, len(c.Modules))
+	for i := range c.Modules {
+		m := &c.Modules[i]
+		if m.Dir == "" {
+			addErr("modules[%d]: dir is required", i)
+			continue
+		}
+		if st, err := os.Stat(m.Dir); err != nil || !st.IsDir() {
+			addErr("modules[%d]: dir %s does not exist", i, m.Dir)
+			continue
+		}
+		if m.Module == "" {
+			modPath, err := modulePathFromGoMod(m.Dir)
+			if err != nil || modPath == "" {
+				addErr("modules[%d]: no module path in %s", i, filepath.Join(m.Dir, "go.mod"))
+				continue
+			}
+			m.Module = modPath
+		}
+		if i > 0 && m.Prefix == "" {
+			addErr("modules[%d]: prefix is required for non-primary module %s", i, m.Module)
+		}
+		if seenPrefix[m.Prefix] {
+			addErr("modules[%d]: duplicate prefix %q", i, m.Prefix)
+		}
+		seenPrefix[m.Prefix] = true
+	}
+
+	for _, g := range c.Include {
+		if err := checkPkgGlob(g); err != nil {
+			addErr("include: %v", err)
+		}
+	}
+	for _, g := range c.Exclude {
+		if err := checkPkgGlob(g); err != nil {
+			addErr("exclude: %v", err)
+		}
+	}
+	for _, g := range c.GeneratedPatterns {
+		if _, err := filepath.Match(g, ""); err != nil {
+			addErr("generated_patterns: bad pattern %q", g)
+		}
+	}
+	for _, t := range c.BuildTags {
+		if t == "" || strings.ContainsAny(t, " ,\t") {
+			addErr("build_tags: invalid tag %q", t)
+		}
+	}
+	if len(c.Configs) > 0 {
+		if _, err := ParseBuildConfigs(strings.Join(c.Configs, ",")); err != nil {
+			addErr("configs: %v", err)
+		}
+	}
+	for _, p := range c.Phases {
+		if !slices.Contains(optionalPhases, p) {
+			addErr("phases: unknown phase %q (known: %s)", p, strings.Join(optionalPhases, ", "))
+		}
+	}
+	if c.GitWindow != nil && *c.GitWindow <= 0 {
+		addErr("git_window: must be positive, got %d", *c.GitWindow)
+	}
+	if _, err := SelectAnalyzers(c.Analyzers); err != nil {
+		addErr("analyzers: %v", err)
+	}
+	for _, f := range c.TaintSpecs {
+		if err := checkSpecFile(f); err != nil {
+			addErr("taint_specs: %v", err)
+		}
+	}
+	for _, f := range c.FlowSemantics {
+		if err := checkSpecFile(f); err != nil {
+			addErr("flow_semantics: %v", err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// PhaseEnabled reports whether an optional phase should run. Without a
+// phase list, every phase except the opt-in ones runs.
+func (c *Config) PhaseEnabled(name string) bool {
+	if c == nil || len(c.Phases) == 0 {
+		return !slices.Contains(optInPhases, name)
+	}
+	return slices.Contains(c.Phases, name)
+}
+
+func checkPkgGlob(g string) error {
+	if _, err := path.Match(strings.TrimSuffix(g, "/..."), ""); err != nil {
+		return fmt.Errorf("bad pattern %q", g)
+	}
+	return nil
+}
+
+func checkSpecFile(f string) error {
+	switch strings.ToLower(filepath.Ext(f)) {
+	case ".yaml", ".yml", ".json":
+	default:
+		return fmt.Errorf("%s: want .yaml, .yml or .json", f)
+	}
+	if _, err := os.Stat(f); err != nil {
+		return fmt.Errorf("%s does not exist", f)
+	}
+	return nil
+}
+
+// matchPkgGlob reports whether pkgPath matches a package glob. A trailing
+// "/..." matches the package itself and all packages below it.
+func matchPkgGlob(pattern, pkgPath string) bool {
+	if base, ok := strings.CutSuffix(pattern, "/..."); ok {
+		if ok, _ := path.Match(base, pkgPath); ok {
+			return true
+		}
+		for p := pkgPath; ; {
+			i := strings.LastIndexByte(p, '/')
+			if i < 0 {
+				return false
+			}
+			p = p[:i]
+			if ok, _ := path.Match(base, p); ok {
+				return true
+			}
+		}
+	}
+	ok, _ := path.Match(pattern, pkgPath)
+	return ok
+}
+
+// isGeneratedFile reports whether a file name matches any generated pattern.
+func isGeneratedFile(base string) bool {
+	for _, p := range generatedPatterns {
+		if ok, _ := filepath.Match(p, base); ok {
+			return true
+		}
+	}
+	return false
+}
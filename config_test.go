@@ -0,0 +1,130 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMatchPkgGlob(t *testing.T) {
+	tests := []struct {
+		pattern, pkg string
+		want         bool
+	}{
+		{"cmd/tool", "cmd/tool", true},
+		{"cmd/tool", "cmd/tool/sub", false},
+		{"cmd/*", "cmd/tool", true},
+		{"cmd/*", "cmd/tool/sub", false},
+		{"internal/...", "internal", true},
+		{"internal/...", "internal/a/b", true},
+		{"internal/...", "internalx", false},
+		{"internal/...", "pkg/internal", false},
+		{"*/testdata/...", "pkg/testdata/x", true},
+		{"*/testdata/...", "testdata", false},
+	}
+	for _, tt := range tests {
+		if got := matchPkgGlob(tt.pattern, tt.pkg); got != tt.want {
+			t.Errorf("matchPkgGlob(%q, %q) = %v, want %v", tt.pattern, tt.pkg, got, tt.want)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "app", "go.mod"), "module example.com/app\n")
+	writeFile(t, filepath.Join(dir, "lib", "go.mod"), "module example.com/lib\n")
+	writeFile(t, filepath.Join(dir, "specs.yaml"), "sources: []\n")
+	cfgPath := filepath.Join(dir, "cpg.yaml")
+	writeFile(t, cfgPath, `
+modules:
+  - dir: app
+  - dir: lib
+    prefix: lib
+include: ["internal/..."]
+phases: [cdg, callgraph, pointsto]
+taint_specs: [specs.yaml]
+`)
+
+	cfg, err := LoadConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if got := cfg.Modules[0]; got.Dir != filepath.Join(dir, "app") || got.Module != "example.com/app" {
+		t.Errorf("primary module = %+v, want dir resolved and module read from go.mod", got)
+	}
+	if got := cfg.TaintSpecs[0]; got != filepath.Join(dir, "specs.yaml") {
+		t.Errorf("taint spec path = %q, want it resolved against the config dir", got)
+	}
+	if !cfg.PhaseEnabled("cdg") || !cfg.PhaseEnabled("pointsto") || cfg.PhaseEnabled("escape") {
+		t.Errorf("PhaseEnabled: want cdg and pointsto on, escape off with phases %v", cfg.Phases)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "app", "go.mod"), "module example.com/app\n")
+	writeFile(t, filepath.Join(dir, "lib", "go.mod"), "module example.com/lib\n")
+
+	tests := []struct {
+		name, yaml string
+		want       []string // substrings of the joined error
+	}{
+		{"unknown field", "modulez: []\n", []string{"field modulez not found"}},
+		{"missing dir", "modules:\n  - module: x\n", []string{"modules[0]: dir is required"}},
+		{"missing prefix", "modules:\n  - dir: app\n  - dir: lib\n", []string{"prefix is required"}},
+		{"zero git window", "git_window: 0\n", []string{"git_window: must be positive, got 0"}},
+		{"all problems at once", `
+modules:
+  - dir: nowhere
+include: ["[bad"]
+phases: [cdg, bogus]
+build_tags: ["a b"]
+git_window: -1
+taint_specs: [specs.txt]
+`, []string{
+			"dir " + filepath.Join(dir, "nowhere") + " does not exist",
+			`include: bad pattern "[bad"`,
+			`unknown phase "bogus"`,
+			`invalid tag "a b"`,
+			"git_window: must be positive",
+			"want .yaml, .yml or .json",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfgPath := filepath.Join(dir, "cpg.yaml")
+			writeFile(t, cfgPath, tt.yaml)
+			_, err := LoadConfig(cfgPath)
+			if err == nil {
+				t.Fatal("LoadConfig succeeded, want error")
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("error %q does not mention %q", err, w)
+				}
+			}
+		})
+	}
+}
+
+func TestPhaseEnabledNilConfig(t *testing.T) {
+	var cfg *Config
+	for _, p := range optionalPhases {
+		want := !slices.Contains(optInPhases, p)
+		if got := cfg.PhaseEnabled(p); got != want {
+			t.Errorf("nil config: PhaseEnabled(%s) = %v, want %v", p, got, want)
+		}
+	}
+}
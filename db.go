@@ -4,46 +4,31 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"zombiezen.com/go/sqlite"
 	"zombiezen.com/go/sqlite/sqlitex"
+
+	"cpg-gen/internal/cpgdiff"
 )
 
 const batchSize = 50000
 
 // WriteDB writes the CPG to a SQLite database file.
-func WriteDB(path string, cpg *CPG, escapeResults []EscapeResult, gitHistory []GitFileHistory, validate bool, prog *Progress) error {
+func WriteDB(path string, cpg *CPG, escapeResults []EscapeResult, checkResults []CheckResult, vetResults []VetResult, gitHistory *GitHistory, validate bool, prog *Progress) error {
 	prog.Log("Writing SQLite to %s ...", path)
 
-	_ = os.Remove(path) // ignore if doesn't exist
-
-	conn, err := sqlite.OpenConn(path, sqlite.OpenCreate, sqlite.OpenReadWrite, sqlite.OpenWAL)
-	if err != nil {
-		return fmt.Errorf("open sqlite: %w", err)
-	}
-	defer func() { _ = conn.Close() }()
-
-	// Performance pragmas
-	if err := sqlitex.ExecuteTransient(conn, "PRAGMA synchronous = NORMAL", nil); err != nil {
-		return err
-	}
-	if err := sqlitex.ExecuteTransient(conn, "PRAGMA temp_store = MEMORY", nil); err != nil {
-		return err
-	}
-	if err := sqlitex.ExecuteTransient(conn, "PRAGMA mmap_size = 268435456", nil); err != nil {
-		return err
-	}
-	if err := sqlitex.ExecuteTransient(conn, "PRAGMA cache_size = -64000", nil); err != nil {
-		return err
-	}
-	if err := sqlitex.ExecuteTransient(conn, "PRAGMA journal_mode = WAL", nil); err != nil {
-		return err
+	var conn *sqlite.Conn
+	var err error
+	if cpg.sink != nil {
+		conn, err = cpg.sink.Finish(prog)
+	} else {
+		conn, err = openDB(path)
 	}
-
-	// Create tables without indexes (deferred creation for speed)
-	if err := createTables(conn); err != nil {
+	if err != nil {
 		return err
 	}
+	defer func() { _ = conn.Close() }()
 
 	// Bulk insert in a transaction
 	endFn, err := sqlitex.ImmediateTransaction(conn)
@@ -51,49 +36,82 @@ func WriteDB(path string, cpg *CPG, escapeResults []EscapeResult, gitHistory []G
 		return fmt.Errorf("begin tx: %w", err)
 	}
 
-	if err := insertNodes(conn, cpg.Nodes, prog); err != nil {
-		endFn(&err)
-		return err
-	}
-	if err := insertEdges(conn, cpg.Edges, prog); err != nil {
-		endFn(&err)
-		return err
+	// In streaming mode nodes, edges, sources, metrics, function summaries
+	// and package lookups are already on disk.
+	if cpg.sink == nil {
+		if err := insertNodes(conn, cpg.Nodes, prog); err != nil {
+			endFn(&err)
+			return err
+		}
+		if err := insertEdges(conn, cpg.Edges, prog); err != nil {
+			endFn(&err)
+			return err
+		}
+		if err := insertSources(conn, cpg.Sources, prog); err != nil {
+			endFn(&err)
+			return err
+		}
+		if err := insertMetrics(conn, cpg.Metrics, prog); err != nil {
+			endFn(&err)
+			return err
+		}
+		if err := insertFunctionSummaries(conn, cpg.FunctionSummaries, prog); err != nil {
+			endFn(&err)
+			return err
+		}
+		if err := insertPackageLookups(conn, cpg.PackageLookups); err != nil {
+			endFn(&err)
+			return err
+		}
 	}
-	if err := insertSources(conn, cpg.Sources, prog); err != nil {
+	if err := insertPackageHashes(conn, cpg.PackageHashes, prog); err != nil {
 		endFn(&err)
 		return err
 	}
-	if err := insertMetrics(conn, cpg.Metrics, prog); err != nil {
-		endFn(&err)
-		return err
+	if incr != nil {
+		if err := mergePreviousCPG(conn, incr, prog); err != nil {
+			endFn(&err)
+			return err
+		}
 	}
 
 	endFn(&err)
 	if err != nil {
 		return fmt.Errorf("commit: %w", err)
 	}
+	if incr != nil {
+		if err := sqlitex.ExecuteTransient(conn, "DETACH DATABASE prev", nil); err != nil {
+			return fmt.Errorf("detach previous DB: %w", err)
+		}
+	}
 
 	// Create flow semantics table for stdlib data-flow modeling
 	prog.Log("Building flow semantics model...")
+	if err := createSpecKeys(conn); err != nil {
+		return fmt.Errorf("spec keys: %w", err)
+	}
 	if err := createFlowSemantics(conn); err != nil {
 		return err
 	}
+	if len(userFlowSemantics) > 0 {
+		prog.Log("  merged %d user flow semantics rules", len(userFlowSemantics))
+	}
 
 	// Heuristic DFG for external calls using flow semantics
 	prog.Log("Inferring DFG for external calls...")
 
 	// Step 1: Precise DFG for functions WITH custom semantics (arg→return)
-	var preciseDFG, fallbackDFG, sideEffectDFG int
+	var preciseDFG, sideEffectDFG, summaryDFG int
 	if err := sqlitex.ExecuteTransient(conn,
 		`INSERT OR IGNORE INTO edges (source, target, kind, properties)
 		 SELECT DISTINCT arg_e.target, site_e.source, 'dfg', '{"heuristic":true}'
 		 FROM edges site_e
-		 JOIN nodes callee ON site_e.target = callee.id
-		 JOIN flow_semantics fs ON callee.package = fs.package AND callee.name = fs.func_name
+		 JOIN spec_keys k ON k.node_id = site_e.target AND k.kind = 'call'
+		 JOIN flow_semantics fs ON fs.package = k.package AND fs.receiver = k.receiver AND fs.func_name = k.name
 		   AND fs.flow_to LIKE 'return:%'
 		 JOIN edges arg_e ON arg_e.source = site_e.source AND arg_e.kind = 'argument'
 		 WHERE site_e.kind = 'call_site'
-		   AND callee.id LIKE 'ext::%'
+		   AND site_e.target LIKE 'ext::%'
 		   AND (fs.flow_from = 'arg:*'
 		        OR fs.flow_from = 'arg:' || json_extract(arg_e.properties, '$.index'))`,
 		&sqlitex.ExecOptions{
@@ -108,8 +126,8 @@ func WriteDB(path string, cpg *CPG, escapeResults []EscapeResult, gitHistory []G
 		`INSERT OR IGNORE INTO edges (source, target, kind, properties)
 		 SELECT DISTINCT src_arg.target, dst_arg.target, 'dfg', '{"heuristic":true,"side_effect":true}'
 		 FROM edges site_e
-		 JOIN nodes callee ON site_e.target = callee.id
-		 JOIN flow_semantics fs ON callee.package = fs.package AND callee.name = fs.func_name
+		 JOIN spec_keys k ON k.node_id = site_e.target AND k.kind = 'call'
+		 JOIN flow_semantics fs ON fs.package = k.package AND fs.receiver = k.receiver AND fs.func_name = k.name
 		   AND fs.flow_from LIKE 'arg:%' AND fs.flow_to LIKE 'arg:%'
 		 JOIN edges src_arg ON src_arg.source = site_e.source AND src_arg.kind = 'argument'
 		   AND (fs.flow_from = 'arg:*'
@@ -117,7 +135,7 @@ func WriteDB(path string, cpg *CPG, escapeResults []EscapeResult, gitHistory []G
 		 JOIN edges dst_arg ON dst_arg.source = site_e.source AND dst_arg.kind = 'argument'
 		   AND fs.flow_to = 'arg:' || json_extract(dst_arg.properties, '$.index')
 		 WHERE site_e.kind = 'call_site'
-		   AND callee.id LIKE 'ext::%'`,
+		   AND site_e.target LIKE 'ext::%'`,
 		&sqlitex.ExecOptions{
 			ResultFunc: func(stmt *sqlite.Stmt) error { return nil },
 		}); err != nil {
@@ -125,30 +143,65 @@ func WriteDB(path string, cpg *CPG, escapeResults []EscapeResult, gitHistory []G
 	}
 	sideEffectDFG = conn.Changes()
 
-	// Step 3: Fallback: all args→return for functions WITHOUT custom semantics
+	// Step 3: Functions WITHOUT custom semantics follow their function
+	// summary: arguments (and the receiver) reach the result or the
+	// memory of other operands only where the callee's body lets them.
+	if err := sqlitex.ExecuteScript(conn, `
+CREATE TEMP VIEW call_operands AS
+  SELECT source AS call_id, target AS node_id, 'arg:' || json_extract(properties, '$.index') AS pos,
+    json_extract(properties, '$.index') AS idx
+  FROM edges WHERE kind = 'argument'
+  UNION ALL
+  SELECT source, target, 'recv', NULL FROM edges WHERE kind = 'receiver';
+
+CREATE TEMP VIEW summary_sites AS
+  SELECT site_e.source AS call_id, fsum.from_pos, fsum.to_pos, fsum.variadic, fsum.kind
+  FROM edges site_e
+  JOIN function_summaries fsum ON fsum.function_id = site_e.target AND fsum.from_pos IS NOT NULL
+  WHERE site_e.kind = 'call_site'
+    AND site_e.target LIKE 'ext::%'
+    AND NOT EXISTS (
+      SELECT 1 FROM spec_keys k
+      JOIN flow_semantics fs ON fs.package = k.package AND fs.receiver = k.receiver AND fs.func_name = k.name
+      WHERE k.node_id = site_e.target AND k.kind = 'call'
+    );
+`, nil); err != nil {
+		return fmt.Errorf("summary dfg views: %w", err)
+	}
+	// A variadic parameter covers its own position and every later argument.
+	const fromOperand = `src.call_id = ss.call_id
+		   AND (src.pos = ss.from_pos
+		        OR ss.variadic AND src.idx >= CAST(substr(ss.from_pos, 5) AS INTEGER))`
 	if err := sqlitex.ExecuteTransient(conn,
 		`INSERT OR IGNORE INTO edges (source, target, kind, properties)
-		 SELECT DISTINCT arg_e.target, site_e.source, 'dfg', '{"heuristic":true}'
-		 FROM edges site_e
-		 JOIN nodes callee ON site_e.target = callee.id
-		 JOIN edges arg_e ON arg_e.source = site_e.source AND arg_e.kind = 'argument'
-		 WHERE site_e.kind = 'call_site'
-		   AND callee.id LIKE 'ext::%'
-		   AND NOT EXISTS (
-		     SELECT 1 FROM flow_semantics fs
-		     WHERE callee.package = fs.package AND callee.name = fs.func_name
-		   )`,
-		&sqlitex.ExecOptions{
-			ResultFunc: func(stmt *sqlite.Stmt) error { return nil },
-		}); err != nil {
-		return fmt.Errorf("fallback heuristic dfg: %w", err)
+		 SELECT DISTINCT src.node_id, ss.call_id, 'dfg', json_object('heuristic', json('true'), 'summary', ss.kind)
+		 FROM summary_sites ss
+		 JOIN call_operands src ON `+fromOperand+`
+		 WHERE ss.to_pos LIKE 'return:%'`,
+		nil); err != nil {
+		return fmt.Errorf("summary heuristic dfg: %w", err)
+	}
+	summaryDFG = conn.Changes()
+	if err := sqlitex.ExecuteTransient(conn,
+		`INSERT OR IGNORE INTO edges (source, target, kind, properties)
+		 SELECT DISTINCT src.node_id, dst.node_id, 'dfg',
+		   json_object('heuristic', json('true'), 'side_effect', json('true'), 'summary', ss.kind)
+		 FROM summary_sites ss
+		 JOIN call_operands src ON `+fromOperand+`
+		 JOIN call_operands dst ON dst.call_id = ss.call_id AND dst.pos = ss.to_pos
+		 WHERE src.node_id <> dst.node_id`,
+		nil); err != nil {
+		return fmt.Errorf("summary side-effect dfg: %w", err)
+	}
+	summaryDFG += conn.Changes()
+	if err := sqlitex.ExecuteScript(conn, `DROP VIEW temp.summary_sites; DROP VIEW temp.call_operands;`, nil); err != nil {
+		return err
 	}
-	fallbackDFG = conn.Changes()
 
-	totalDFG := preciseDFG + sideEffectDFG + fallbackDFG
+	totalDFG := preciseDFG + sideEffectDFG + summaryDFG
 	if totalDFG > 0 {
-		prog.Log("Created %d heuristic DFG edges (%d precise, %d side-effect, %d fallback)",
-			totalDFG, preciseDFG, sideEffectDFG, fallbackDFG)
+		prog.Log("Created %d heuristic DFG edges (%d precise, %d side-effect, %d from function summaries)",
+			totalDFG, preciseDFG, sideEffectDFG, summaryDFG)
 	}
 
 	// Clean up orphan edges before indexing
@@ -170,6 +223,11 @@ func WriteDB(path string, cpg *CPG, escapeResults []EscapeResult, gitHistory []G
 		return err
 	}
 
+	// Line-independent IDs for the nodes created outside the AST walk
+	if err := assignStableIDs(conn, prog); err != nil {
+		return err
+	}
+
 	// EOG: expression evaluation order for call arguments
 	prog.Log("Computing evaluation order edges...")
 	if err := computeEOG(conn, prog); err != nil {
@@ -199,6 +257,9 @@ func WriteDB(path string, cpg *CPG, escapeResults []EscapeResult, gitHistory []G
 	if err := createTaintModel(conn); err != nil {
 		return err
 	}
+	if len(userTaintSpecs) > 0 {
+		prog.Log("  merged %d user taint specs", len(userTaintSpecs))
+	}
 
 	// Additional analysis: API surface, method sets, risk scores, etc.
 	prog.Log("Computing additional analysis...")
@@ -214,6 +275,30 @@ func WriteDB(path string, cpg *CPG, escapeResults []EscapeResult, gitHistory []G
 		}
 	}
 
+	// Apply bounds/nil check diagnostics from the Go compiler
+	if len(checkResults) > 0 {
+		prog.Log("Applying bounds/nil check diagnostics...")
+		if err := applyCheckDiagnostics(conn, checkResults, prog); err != nil {
+			prog.Log("Warning: check diagnostics failed: %v", err)
+		}
+	}
+
+	// Diagnostics of the go/analysis passes as findings
+	if len(vetResults) > 0 {
+		prog.Log("Applying go/analysis diagnostics...")
+		if err := applyVetDiagnostics(conn, vetResults, prog); err != nil {
+			return err
+		}
+	}
+
+	// Unchecked error results found in SSA
+	if len(cpg.UncheckedErrors) > 0 || cpg.sink != nil && cpg.sink.uncheckedErrors > 0 || incr != nil && incr.uncheckedRows > 0 {
+		prog.Log("Applying unchecked errors...")
+		if err := applyUncheckedErrors(conn, cpg.UncheckedErrors, prog); err != nil {
+			return err
+		}
+	}
+
 	// Advanced analysis: stability metrics, risk scores, dead code, etc.
 	prog.Log("Computing advanced analysis...")
 	if err := createAdvancedAnalysis(conn, prog); err != nil {
@@ -245,6 +330,14 @@ func WriteDB(path string, cpg *CPG, escapeResults []EscapeResult, gitHistory []G
 		return err
 	}
 
+	// Side-by-side call graph algorithms (-callgraph-compare)
+	if len(cpg.CallGraphEdges) > 0 || cpg.sink != nil && cpg.sink.callGraphEdges > 0 {
+		prog.Log("Comparing call graph algorithms...")
+		if err := writeCallGraphComparison(conn, cpg.CallGraphEdges, prog); err != nil {
+			return err
+		}
+	}
+
 	// File-level analysis and dependency graph data for visualization
 	prog.Log("Building file and dependency analysis...")
 	if err := createFileAndDepAnalysis(conn, prog); err != nil {
@@ -270,9 +363,17 @@ func WriteDB(path string, cpg *CPG, escapeResults []EscapeResult, gitHistory []G
 	}
 
 	// Git history for diff-aware analysis
-	if len(gitHistory) > 0 {
+	if gitHistory != nil && len(gitHistory.Files) > 0 {
 		prog.Log("Running git history analysis...")
-		if err := applyGitHistory(conn, gitHistory, prog); err != nil {
+		if err := applyGitHistory(conn, gitHistory.Files, prog); err != nil {
+			return err
+		}
+		prog.Log("Running git blame for function ownership...")
+		if err := applyFunctionOwnership(conn, prog); err != nil {
+			return err
+		}
+		prog.Log("Computing co-change coupling...")
+		if err := applyCoChange(conn, gitHistory, prog); err != nil {
 			return err
 		}
 	}
@@ -307,12 +408,25 @@ func WriteDB(path string, cpg *CPG, escapeResults []EscapeResult, gitHistory []G
 		return err
 	}
 
+	// Diff-aware analysis: changed symbols and their blast radius, last so
+	// that every finding is in place
+	if gitHistory != nil && gitHistory.Base != "" {
+		prog.Log("Computing change set since %s...", gitHistory.Base)
+		if err := applyChangeSet(conn, gitHistory, prog); err != nil {
+			return err
+		}
+	}
+
 	if validate {
 		if err := runValidation(conn, prog); err != nil {
 			return err
 		}
 	}
 
+	if incr != nil {
+		_ = os.Remove(incr.PrevPath)
+	}
+
 	// Report file size
 	info, _ := os.Stat(path)
 	if info != nil {
@@ -323,6 +437,67 @@ func WriteDB(path string, cpg *CPG, escapeResults []EscapeResult, gitHistory []G
 	return nil
 }
 
+// openDB creates a fresh database at path with performance pragmas and the
+// base tables. In incremental mode the previous DB is moved aside first and
+// attached as "prev".
+func openDB(path string) (*sqlite.Conn, error) {
+	// Incremental mode: move the previous DB aside so unchanged packages can
+	// be copied from it into the freshly written file. It is only deleted once
+	// the new DB is complete, so a failed run leaves it recoverable.
+	if incr != nil {
+		prevPath := path + ".prev"
+		if err := os.Rename(path, prevPath); err != nil {
+			return nil, fmt.Errorf("moving previous DB aside: %w", err)
+		}
+		incr.PrevPath = prevPath
+	}
+
+	_ = os.Remove(path) // ignore if doesn't exist
+
+	conn, err := sqlite.OpenConn(path, sqlite.OpenCreate, sqlite.OpenReadWrite, sqlite.OpenWAL)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+	ok := false
+	defer func() {
+		if !ok {
+			_ = conn.Close()
+		}
+	}()
+
+	// Performance pragmas
+	if err := sqlitex.ExecuteTransient(conn, "PRAGMA synchronous = NORMAL", nil); err != nil {
+		return nil, err
+	}
+	if err := sqlitex.ExecuteTransient(conn, "PRAGMA temp_store = MEMORY", nil); err != nil {
+		return nil, err
+	}
+	if err := sqlitex.ExecuteTransient(conn, "PRAGMA mmap_size = 268435456", nil); err != nil {
+		return nil, err
+	}
+	if err := sqlitex.ExecuteTransient(conn, "PRAGMA cache_size = -64000", nil); err != nil {
+		return nil, err
+	}
+	if err := sqlitex.ExecuteTransient(conn, "PRAGMA journal_mode = WAL", nil); err != nil {
+		return nil, err
+	}
+
+	// Create tables without indexes (deferred creation for speed)
+	if err := createTables(conn); err != nil {
+		return nil, err
+	}
+
+	if incr != nil {
+		if err := sqlitex.ExecuteTransient(conn, "ATTACH DATABASE ? AS prev",
+			&sqlitex.ExecOptions{Args: []any{incr.PrevPath}}); err != nil {
+			return nil, fmt.Errorf("attach previous DB: %w", err)
+		}
+	}
+
+	ok = true
+	return conn, nil
+}
+
 func createTables(conn *sqlite.Conn) error {
 	ddl := `
 CREATE TABLE nodes (
@@ -336,7 +511,8 @@ CREATE TABLE nodes (
     package TEXT,
     parent_function TEXT,
     type_info TEXT,
-    properties TEXT
+    properties TEXT,
+    stable_id TEXT
 );
 
 CREATE TABLE edges (
@@ -358,7 +534,90 @@ CREATE TABLE metrics (
     fan_in INTEGER,
     fan_out INTEGER,
     loc INTEGER,
-    num_params INTEGER
+    num_params INTEGER,
+    heap_allocs INTEGER,
+    leaking_params INTEGER,
+    inline_cost INTEGER,
+    inline_budget INTEGER,
+    bounds_checks INTEGER,
+    bounds_checks_in_loops INTEGER
+);
+
+CREATE TABLE package_hashes (
+    package TEXT PRIMARY KEY,
+    rel_package TEXT,
+    hash TEXT NOT NULL
+);
+
+CREATE TABLE package_lookups (
+    package TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    key TEXT NOT NULL,
+    id TEXT NOT NULL,
+    PRIMARY KEY (package, kind, key)
+) WITHOUT ROWID;
+
+CREATE TABLE function_summaries (
+    function_id TEXT NOT NULL,
+    from_pos TEXT,
+    to_pos TEXT,
+    variadic INTEGER NOT NULL DEFAULT 0,
+    kind TEXT NOT NULL,
+    UNIQUE (function_id, from_pos, to_pos)
+);
+
+CREATE TABLE escape_decisions (
+    node_id TEXT NOT NULL,
+    function_id TEXT,
+    kind TEXT NOT NULL,
+    detail TEXT
+);
+
+CREATE TABLE escape_explanations (
+    explanation INTEGER NOT NULL,
+    step INTEGER NOT NULL,
+    node_id TEXT NOT NULL,
+    function_id TEXT,
+    summary TEXT NOT NULL,
+    flow TEXT,
+    expr TEXT,
+    reason TEXT,
+    file TEXT,
+    line INTEGER,
+    col INTEGER,
+    site_id TEXT,
+    PRIMARY KEY (explanation, step)
+);
+
+CREATE TABLE compiler_checks (
+    node_id TEXT NOT NULL,
+    function_id TEXT,
+    kind TEXT NOT NULL,
+    detail TEXT,
+    in_loop INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (node_id, kind, detail)
+);
+
+CREATE TABLE unchecked_errors (
+    function_id TEXT NOT NULL,
+    call_id TEXT,
+    callee TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    file TEXT NOT NULL,
+    line INTEGER NOT NULL,
+    col INTEGER NOT NULL,
+    reaches_return INTEGER NOT NULL,
+    PRIMARY KEY (file, line, col, kind)
+);
+
+CREATE TABLE function_ownership (
+    function_id TEXT PRIMARY KEY,
+    primary_author TEXT NOT NULL,
+    primary_lines INTEGER NOT NULL,
+    author_count INTEGER NOT NULL,
+    lines INTEGER NOT NULL,
+    last_modified TEXT,
+    age_days INTEGER
 );
 `
 	return sqlitex.ExecuteScript(conn, ddl, nil)
@@ -377,8 +636,62 @@ CREATE INDEX idx_edges_kind ON edges(kind);
 	return sqlitex.ExecuteScript(conn, indexes, nil)
 }
 
+// assignStableIDs fills stable_id for nodes the AST walk did not name. IDs
+// without a position (packages, files, external stubs, globals) are already
+// stable; basic blocks take their function's stable ID and block index, and
+// alloc sites are numbered per function and allocation kind in source order.
+// A declaration can occur in several files, one per build configuration; the
+// first in file order keeps its ID and the others get a #1, #2... suffix, so
+// stable_id is unique.
+func assignStableIDs(conn *sqlite.Conn, prog *Progress) error {
+	script := `
+UPDATE nodes SET stable_id = id WHERE stable_id IS NULL AND id NOT LIKE '%@%';
+
+WITH numbered AS (
+  SELECT id, stable_id,
+         ROW_NUMBER() OVER (PARTITION BY stable_id ORDER BY file, line, col, id) - 1 AS ordinal
+  FROM nodes WHERE stable_id IS NOT NULL
+)
+UPDATE nodes SET stable_id = numbered.stable_id || '#' || numbered.ordinal
+FROM numbered WHERE nodes.id = numbered.id AND numbered.ordinal > 0;
+
+UPDATE nodes SET stable_id = f.stable_id || '::bb' || json_extract(nodes.properties, '$.index')
+FROM nodes f
+WHERE nodes.kind = 'basic_block' AND nodes.stable_id IS NULL
+  AND f.id = nodes.parent_function AND f.stable_id IS NOT NULL;
+
+WITH ranked AS (
+  SELECT a.id, f.stable_id || '/alloc_' || json_extract(a.properties, '$.alloc_kind') || '#' ||
+         (ROW_NUMBER() OVER (PARTITION BY a.parent_function, json_extract(a.properties, '$.alloc_kind')
+                             ORDER BY a.file, a.line, a.col) - 1) AS stable_id
+  FROM nodes a
+  JOIN nodes f ON f.id = a.parent_function
+  WHERE a.kind = 'alloc_site' AND a.stable_id IS NULL AND f.stable_id IS NOT NULL
+)
+UPDATE nodes SET stable_id = ranked.stable_id FROM ranked WHERE nodes.id = ranked.id;
+
+CREATE UNIQUE INDEX idx_nodes_stable ON nodes(stable_id);
+`
+	if err := sqlitex.ExecuteScript(conn, script, nil); err != nil {
+		return fmt.Errorf("stable IDs: %w", err)
+	}
+
+	var missing int
+	if err := sqlitex.ExecuteTransient(conn, `SELECT COUNT(*) FROM nodes WHERE stable_id IS NULL`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			missing = stmt.ColumnInt(0)
+			return nil
+		}}); err != nil {
+		return fmt.Errorf("count nodes without stable IDs: %w", err)
+	}
+	if missing > 0 {
+		prog.Verbose("  %d nodes without a stable ID", missing)
+	}
+	return nil
+}
+
 func insertNodes(conn *sqlite.Conn, nodes []Node, prog *Progress) error {
-	stmt, err := conn.Prepare(`INSERT OR IGNORE INTO nodes (id, kind, name, file, line, col, end_line, package, parent_function, type_info, properties) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	stmt, err := conn.Prepare(`INSERT OR IGNORE INTO nodes (id, kind, name, file, line, col, end_line, package, parent_function, type_info, properties, stable_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		return fmt.Errorf("prepare node insert: %w", err)
 	}
@@ -396,6 +709,7 @@ func insertNodes(conn *sqlite.Conn, nodes []Node, prog *Progress) error {
 		bindTextOrNull(stmt, 9, n.ParentFunction)
 		bindTextOrNull(stmt, 10, n.TypeInfo)
 		bindTextOrNull(stmt, 11, PropsJSON(n.Properties))
+		bindTextOrNull(stmt, 12, n.StableID)
 
 		if _, err := stmt.Step(); err != nil {
 			return fmt.Errorf("insert node %s: %w", n.ID, err)
@@ -660,6 +974,35 @@ CREATE VIEW v_data_flow AS
   JOIN nodes n2 ON e.target = n2.id
   WHERE e.kind = 'dfg';
 
+-- Field flow summary: which functions write a struct field and which read it.
+-- Stores and loads meet at the field declaration node, so each row says
+-- "a write of T.f in writer may reach a read of T.f in reader". Grouped
+-- fields (X, Y int) share one node; the edge's field property tells them apart.
+CREATE VIEW v_field_flow AS
+  SELECT
+    f.id AS field_id,
+    json_extract(st.properties, '$.field') AS field_name,
+    COALESCE(owner.name, json_extract(f.properties, '$.receiver')) AS type_name,
+    f.package AS package,
+    ws.parent_function AS writer_id,
+    wf.name AS writer_name,
+    rs.parent_function AS reader_id,
+    rf.name AS reader_name,
+    COUNT(DISTINCT ws.id) AS store_sites,
+    COUNT(DISTINCT rs.id) AS load_sites
+  FROM nodes f
+  JOIN edges st ON st.target = f.id AND st.kind = 'field_store'
+  JOIN nodes ws ON ws.id = st.source
+  JOIN edges ld ON ld.source = f.id AND ld.kind = 'field_load'
+    AND json_extract(ld.properties, '$.field') = json_extract(st.properties, '$.field')
+  JOIN nodes rs ON rs.id = ld.target
+  LEFT JOIN nodes wf ON wf.id = ws.parent_function
+  LEFT JOIN nodes rf ON rf.id = rs.parent_function
+  LEFT JOIN edges a ON a.target = f.id AND a.kind = 'ast'
+  LEFT JOIN nodes owner ON owner.id = a.source
+  WHERE f.kind = 'field'
+  GROUP BY f.id, field_name, ws.parent_function, rs.parent_function;
+
 -- Function summary with metrics and call counts
 CREATE VIEW v_function_summary AS
   SELECT
@@ -1283,100 +1626,381 @@ INSERT INTO queries (name, description, sql) VALUES
 	return nil
 }
 
-// applyEscapeAnalysis maps compiler escape annotations to CPG nodes via position matching.
-func applyEscapeAnalysis(conn *sqlite.Conn, results []EscapeResult, prog *Progress) error {
-	// Create temp table for batch matching
-	if err := sqlitex.ExecuteTransient(conn,
-		`CREATE TEMP TABLE escape_info (file TEXT, line INTEGER, col INTEGER, kind TEXT, detail TEXT)`,
-		nil); err != nil {
-		return err
+// applyEscapeAnalysis records the compiler escape decisions resolved to
+// nodes by ResolveEscapes in escape_decisions and their flow chains in
+// escape_explanations, marks the nodes (inlineable, heap_escapes), links
+// variables moved to heap to their function with escapes edges, and fills
+// the heap_allocs, leaking_params and inlining metrics.
+func applyEscapeAnalysis(conn *sqlite.Conn, results []EscapeResult, prog *Progress) error {
+	stmt, err := conn.Prepare(`INSERT INTO escape_decisions (node_id, function_id, kind, detail)
+SELECT id, CASE kind WHEN 'function' THEN id ELSE parent_function END, ?, NULLIF(?, '')
+FROM nodes WHERE id = ?`)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stmt.Finalize() }()
+	xstmt, err := conn.Prepare(`INSERT INTO escape_explanations
+  (explanation, step, node_id, function_id, summary, flow, expr, reason, file, line, col, site_id)
+SELECT ?, ?, id, CASE kind WHEN 'function' THEN id ELSE parent_function END, ?,
+  NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, 0), NULLIF(?, 0), NULLIF(?, '')
+FROM nodes WHERE id = ?`)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = xstmt.Finalize() }()
+	cstmt, err := conn.Prepare(`UPDATE metrics SET inline_cost = ?, inline_budget = ? WHERE function_id = ?`)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = cstmt.Finalize() }()
+
+	type decision struct{ node, kind, detail string }
+	seen := make(map[decision]bool)
+	explanations := 0
+	for _, r := range results {
+		d := decision{r.NodeID, r.Kind, r.Detail}
+		if r.NodeID == "" || seen[d] {
+			continue
+		}
+		seen[d] = true
+
+		if r.Kind == "explanation" {
+			// An explanation without steps still names where the value
+			// goes; keep it as step 0.
+			steps := r.Steps
+			if len(steps) == 0 {
+				steps = []EscapeStep{{}}
+			}
+			explanations++
+			for i, st := range steps {
+				xstmt.BindInt64(1, int64(explanations))
+				xstmt.BindInt64(2, int64(i))
+				xstmt.BindText(3, r.Detail)
+				xstmt.BindText(4, st.Flow)
+				xstmt.BindText(5, st.Expr)
+				xstmt.BindText(6, st.Reason)
+				xstmt.BindText(7, st.RelFile)
+				xstmt.BindInt64(8, int64(st.Line))
+				xstmt.BindInt64(9, int64(st.Col))
+				xstmt.BindText(10, st.NodeID)
+				xstmt.BindText(11, r.NodeID)
+				if _, err := xstmt.Step(); err != nil {
+					return err
+				}
+				_ = xstmt.Reset()
+			}
+			continue
+		}
+
+		stmt.BindText(1, r.Kind)
+		stmt.BindText(2, r.Detail)
+		stmt.BindText(3, r.NodeID)
+		if _, err := stmt.Step(); err != nil {
+			return err
+		}
+		_ = stmt.Reset()
+
+		if r.Cost >= 0 && (r.Kind == "inlineable" || r.Kind == "not_inlineable") {
+			cstmt.BindInt64(1, int64(r.Cost))
+			if r.Budget >= 0 {
+				cstmt.BindInt64(2, int64(r.Budget))
+			} else {
+				cstmt.BindNull(2)
+			}
+			cstmt.BindText(3, r.NodeID)
+			if _, err := cstmt.Step(); err != nil {
+				return err
+			}
+			_ = cstmt.Reset()
+		}
+	}
+
+	if err := sqlitex.ExecuteScript(conn, `
+CREATE INDEX idx_escape_decisions_func ON escape_decisions(function_id);
+CREATE INDEX idx_escape_decisions_node ON escape_decisions(node_id);
+CREATE INDEX idx_escape_explanations_node ON escape_explanations(node_id);
+CREATE INDEX idx_escape_explanations_func ON escape_explanations(function_id);
+`, nil); err != nil {
+		return err
+	}
+
+	// Inlineable functions
+	if err := sqlitex.ExecuteTransient(conn,
+		`INSERT INTO node_properties (node_id, key, value)
+		 SELECT DISTINCT d.node_id, 'inlineable', 'true'
+		 FROM escape_decisions d
+		 JOIN nodes n ON n.id = d.node_id
+		 WHERE d.kind = 'inlineable' AND n.kind = 'function'`,
+		nil); err != nil {
+		return err
+	}
+	inlineable := conn.Changes()
+
+	// Heap-escaping variables and expressions
+	if err := sqlitex.ExecuteTransient(conn,
+		`INSERT INTO node_properties (node_id, key, value)
+		 SELECT DISTINCT node_id, 'heap_escapes', 'true'
+		 FROM escape_decisions
+		 WHERE kind IN ('leaking_param', 'moved_to_heap', 'escapes_to_heap')`,
+		nil); err != nil {
+		return err
+	}
+	escaping := conn.Changes()
+
+	// Stack-bound ones, unless another decision at the node escapes
+	if err := sqlitex.ExecuteTransient(conn,
+		`INSERT INTO node_properties (node_id, key, value)
+		 SELECT DISTINCT d.node_id, 'heap_escapes', 'false'
+		 FROM escape_decisions d
+		 WHERE d.kind = 'does_not_escape'
+		   AND NOT EXISTS (
+		     SELECT 1 FROM node_properties np
+		     WHERE np.node_id = d.node_id AND np.key = 'heap_escapes'
+		   )`,
+		nil); err != nil {
+		return err
+	}
+	notEscaping := conn.Changes()
+
+	// Variables moved to heap → the function whose frame they left
+	if err := sqlitex.ExecuteTransient(conn,
+		`INSERT INTO edges (source, target, kind, properties)
+		 SELECT DISTINCT node_id, function_id, 'escapes', json_object('variable', detail)
+		 FROM escape_decisions
+		 WHERE kind = 'moved_to_heap' AND function_id IS NOT NULL`,
+		nil); err != nil {
+		return err
+	}
+	escapeEdges := conn.Changes()
+
+	// Per-function aggregates; functions the compiler said nothing about
+	// get zero, so NULL means escape analysis did not run.
+	if err := sqlitex.ExecuteTransient(conn,
+		`UPDATE metrics SET
+		   heap_allocs = (SELECT COUNT(DISTINCT d.node_id) FROM escape_decisions d
+		     WHERE d.function_id = metrics.function_id AND d.kind IN ('moved_to_heap', 'escapes_to_heap')),
+		   leaking_params = (SELECT COUNT(DISTINCT d.node_id) FROM escape_decisions d
+		     WHERE d.function_id = metrics.function_id AND d.kind = 'leaking_param')`,
+		nil); err != nil {
+		return err
+	}
+
+	prog.Log("Escape: %d decisions on nodes, %d explanations; %d inlineable functions, %d heap-escaping, %d stack-bound, %d escapes edges",
+		len(seen)-explanations, explanations, inlineable, escaping, notEscaping, escapeEdges)
+	return nil
+}
+
+// applyCheckDiagnostics records the bounds and nil checks resolved to nodes
+// by ResolveChecks in compiler_checks, marks the nodes (bounds_check,
+// nil_check), and fills the bounds_checks and bounds_checks_in_loops
+// metrics. A check is in a loop when a for/range statement of the same
+// function spans its position.
+func applyCheckDiagnostics(conn *sqlite.Conn, results []CheckResult, prog *Progress) error {
+	stmt, err := conn.Prepare(`INSERT OR IGNORE INTO compiler_checks (node_id, function_id, kind, detail)
+SELECT id, parent_function, ?, NULLIF(?, '') FROM nodes WHERE id = ?`)
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		if r.NodeID == "" {
+			continue
+		}
+		stmt.BindText(1, r.Kind)
+		stmt.BindText(2, r.Detail)
+		stmt.BindText(3, r.NodeID)
+		if _, err := stmt.Step(); err != nil {
+			_ = stmt.Finalize()
+			return err
+		}
+		_ = stmt.Reset()
+	}
+	_ = stmt.Finalize()
+
+	if err := sqlitex.ExecuteScript(conn, `
+CREATE INDEX idx_compiler_checks_func ON compiler_checks(function_id);
+CREATE INDEX idx_compiler_checks_node ON compiler_checks(node_id);
+
+UPDATE compiler_checks SET in_loop = 1
+WHERE EXISTS (
+  SELECT 1 FROM nodes n
+  JOIN nodes l ON l.parent_function = n.parent_function AND l.kind = 'for'
+  WHERE n.id = compiler_checks.node_id AND l.file = n.file
+    AND (n.line > l.line OR n.line = l.line AND n.col >= l.col)
+    AND n.line <= l.end_line
+);
+
+INSERT OR IGNORE INTO node_properties (node_id, key, value)
+SELECT node_id, 'bounds_check', MIN(detail) FROM compiler_checks WHERE kind = 'bounds_check' GROUP BY node_id;
+
+INSERT OR IGNORE INTO node_properties (node_id, key, value)
+SELECT node_id, 'nil_check', CASE WHEN MIN(kind) = 'nil_check' THEN 'generated' ELSE 'removed' END
+FROM compiler_checks WHERE kind IN ('nil_check', 'nil_check_removed') GROUP BY node_id;
+
+UPDATE metrics SET
+  bounds_checks = (SELECT COUNT(*) FROM compiler_checks c
+    WHERE c.function_id = metrics.function_id AND c.kind = 'bounds_check'),
+  bounds_checks_in_loops = (SELECT COUNT(*) FROM compiler_checks c
+    WHERE c.function_id = metrics.function_id AND c.kind = 'bounds_check' AND c.in_loop);
+`, nil); err != nil {
+		return err
+	}
+
+	var bounds, inLoops, nilChecks, removed int
+	if err := sqlitex.ExecuteTransient(conn,
+		`SELECT COALESCE(SUM(kind = 'bounds_check'), 0), COALESCE(SUM(kind = 'bounds_check' AND in_loop), 0),
+		   COALESCE(SUM(kind = 'nil_check'), 0), COALESCE(SUM(kind = 'nil_check_removed'), 0)
+		 FROM compiler_checks`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			bounds, inLoops = stmt.ColumnInt(0), stmt.ColumnInt(1)
+			nilChecks, removed = stmt.ColumnInt(2), stmt.ColumnInt(3)
+			return nil
+		}}); err != nil {
+		return err
+	}
+	prog.Log("Checks: %d bounds checks (%d in loops), %d nil checks, %d removed nil checks",
+		bounds, inLoops, nilChecks, removed)
+	return nil
+}
+
+// applyVetDiagnostics stores go/analysis diagnostics as 'vet' findings.
+// Diagnostics that did not resolve to a node are attached to the innermost
+// function around them; details carry the analyzer, the end position and
+// the suggested fixes.
+func applyVetDiagnostics(conn *sqlite.Conn, results []VetResult, prog *Progress) error {
+	stmt, err := conn.Prepare(`INSERT INTO findings (category, severity, node_id, file, line, message, details)
+VALUES ('vet', 'warning', COALESCE(NULLIF(?1, ''),
+  (SELECT n.id FROM nodes n WHERE n.kind = 'function' AND n.file = ?2 AND n.line <= ?3 AND n.end_line >= ?3
+   ORDER BY n.line DESC LIMIT 1)), ?2, ?3, ?4, ?5)`)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stmt.Finalize() }()
+
+	for _, r := range results {
+		details := map[string]any{
+			"analyzer": r.Analyzer,
+			"col":      r.Col,
+		}
+		if r.Category != "" {
+			details["category"] = r.Category
+		}
+		if r.URL != "" {
+			details["url"] = r.URL
+		}
+		if r.EndLine > 0 {
+			details["end_line"], details["end_col"] = r.EndLine, r.EndCol
+		}
+		if len(r.Fixes) > 0 {
+			details["fixes"] = r.Fixes
+		}
+		stmt.BindText(1, r.NodeID)
+		stmt.BindText(2, r.RelFile)
+		stmt.BindInt64(3, int64(r.Line))
+		stmt.BindText(4, r.Analyzer+": "+r.Message)
+		stmt.BindText(5, PropsJSON(details))
+		if _, err := stmt.Step(); err != nil {
+			return fmt.Errorf("insert vet finding: %w", err)
+		}
+		_ = stmt.Reset()
+	}
+
+	var fixes int
+	for _, r := range results {
+		if len(r.Fixes) > 0 {
+			fixes++
+		}
+	}
+	prog.Log("go/analysis: %d findings, %d with suggested fixes", len(results), fixes)
+	return nil
+}
+
+// applyUncheckedErrors writes cpg.UncheckedErrors (already on disk in
+// streaming mode) and adds an unchecked_error finding for each: a warning
+// when the error could have been returned, info otherwise.
+func applyUncheckedErrors(conn *sqlite.Conn, errs []UncheckedError, prog *Progress) error {
+	if err := insertUncheckedErrors(conn, errs); err != nil {
+		return err
+	}
+
+	script := `
+CREATE INDEX idx_unchecked_errors_func ON unchecked_errors(function_id);
+
+INSERT INTO findings (category, severity, node_id, file, line, message, details)
+SELECT 'unchecked_error', CASE WHEN u.reaches_return THEN 'warning' ELSE 'info' END,
+  COALESCE(u.call_id, u.function_id), u.file, u.line,
+  CASE u.kind
+    WHEN 'discarded' THEN 'error from ' || u.callee || ' is discarded'
+    WHEN 'blank' THEN 'error from ' || u.callee || ' is assigned to _'
+    WHEN 'overwritten' THEN 'error from ' || u.callee || ' is overwritten before it is checked'
+    ELSE 'error from deferred ' || u.callee || ' is ignored'
+  END || ' in ' || f.name,
+  json_object('function', u.function_id, 'callee', u.callee, 'kind', u.kind,
+    'reaches_return', json(CASE WHEN u.reaches_return THEN 'true' ELSE 'false' END))
+FROM unchecked_errors u
+JOIN nodes f ON f.id = u.function_id
+ORDER BY u.file, u.line, u.col;
+
+INSERT INTO queries (name, description, sql) VALUES
+  ('unchecked_errors_by_package', 'Unchecked error results per package and kind, with how many are dropped on paths that return another error',
+   'SELECT f.package, u.kind, COUNT(*) AS count, SUM(u.reaches_return) AS returnable FROM unchecked_errors u JOIN nodes f ON f.id = u.function_id GROUP BY f.package, u.kind ORDER BY count DESC');
+`
+	if err := sqlitex.ExecuteScript(conn, script, nil); err != nil {
+		return fmt.Errorf("unchecked errors: %w", err)
+	}
+
+	var total, returnable int
+	sqlitex.ExecuteTransient(conn, `SELECT COUNT(*), COALESCE(SUM(reaches_return), 0) FROM unchecked_errors`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			total, returnable = stmt.ColumnInt(0), stmt.ColumnInt(1)
+			return nil
+		}})
+	prog.Log("Unchecked errors: %d calls, %d of them on paths that return another error", total, returnable)
+	return nil
+}
+
+// insertUncheckedErrors writes unchecked error rows, dropping duplicates
+// from multiple build configurations and generic instances.
+func insertUncheckedErrors(conn *sqlite.Conn, errs []UncheckedError) error {
+	if len(errs) == 0 {
+		return nil
 	}
-
-	stmt, err := conn.Prepare(`INSERT INTO escape_info VALUES (?, ?, ?, ?, ?)`)
+	stmt, err := conn.Prepare(`INSERT OR IGNORE INTO unchecked_errors (function_id, call_id, callee, kind, file, line, col, reaches_return)
+VALUES (?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
-		return err
+		return fmt.Errorf("prepare unchecked error insert: %w", err)
 	}
-	for _, r := range results {
-		stmt.BindText(1, r.RelFile)
-		stmt.BindInt64(2, int64(r.Line))
-		stmt.BindInt64(3, int64(r.Col))
-		stmt.BindText(4, r.Kind)
-		stmt.BindText(5, r.Detail)
+	defer func() { _ = stmt.Finalize() }()
+	for _, u := range errs {
+		stmt.BindText(1, u.Function)
+		stmt.BindText(2, u.CallSite)
+		stmt.BindText(3, u.Callee)
+		stmt.BindText(4, u.Kind)
+		stmt.BindText(5, u.File)
+		stmt.BindInt64(6, int64(u.Line))
+		stmt.BindInt64(7, int64(u.Col))
+		stmt.BindBool(8, u.ReachesReturn)
 		if _, err := stmt.Step(); err != nil {
-			_ = stmt.Finalize()
-			return err
+			return fmt.Errorf("insert unchecked error %s:%d: %w", u.File, u.Line, err)
 		}
 		_ = stmt.Reset()
 	}
-	_ = stmt.Finalize()
-
-	// Match "inlineable" annotations to function nodes
-	if err := sqlitex.ExecuteTransient(conn,
-		`INSERT INTO node_properties (node_id, key, value)
-		 SELECT DISTINCT n.id, 'inlineable', 'true'
-		 FROM escape_info ei
-		 JOIN nodes n ON n.file = ei.file AND n.line = ei.line
-		 WHERE ei.kind = 'inlineable' AND n.kind = 'function'`,
-		&sqlitex.ExecOptions{
-			ResultFunc: func(stmt *sqlite.Stmt) error { return nil },
-		}); err != nil {
-		return err
-	}
-	inlineable := conn.Changes()
-
-	// Match heap-escaping annotations to parameter/local nodes
-	if err := sqlitex.ExecuteTransient(conn,
-		`INSERT INTO node_properties (node_id, key, value)
-		 SELECT DISTINCT n.id, 'heap_escapes', 'true'
-		 FROM escape_info ei
-		 JOIN nodes n ON n.file = ei.file AND n.line = ei.line
-		 WHERE ei.kind IN ('leaking_param', 'moved_to_heap', 'escapes_to_heap')
-		   AND n.kind IN ('parameter', 'local', 'function')`,
-		&sqlitex.ExecOptions{
-			ResultFunc: func(stmt *sqlite.Stmt) error { return nil },
-		}); err != nil {
-		return err
-	}
-	escaping := conn.Changes()
-
-	// Match "does_not_escape" to parameters
-	if err := sqlitex.ExecuteTransient(conn,
-		`INSERT INTO node_properties (node_id, key, value)
-		 SELECT DISTINCT n.id, 'heap_escapes', 'false'
-		 FROM escape_info ei
-		 JOIN nodes n ON n.file = ei.file AND n.line = ei.line
-		 WHERE ei.kind = 'does_not_escape'
-		   AND n.kind IN ('parameter', 'local')
-		   AND NOT EXISTS (
-		     SELECT 1 FROM node_properties np
-		     WHERE np.node_id = n.id AND np.key = 'heap_escapes'
-		   )`,
-		&sqlitex.ExecOptions{
-			ResultFunc: func(stmt *sqlite.Stmt) error { return nil },
-		}); err != nil {
-		return err
-	}
-	notEscaping := conn.Changes()
-
-	// Drop temp table
-	_ = sqlitex.ExecuteTransient(conn, `DROP TABLE IF EXISTS escape_info`, nil)
-
-	prog.Log("Escape: %d inlineable functions, %d heap-escaping, %d stack-bound",
-		inlineable, escaping, notEscaping)
 	return nil
 }
 
 // createFlowSemantics builds a table describing how data flows through known
 // stdlib functions. Used by the heuristic DFG to create precise data-flow edges.
+// Rules from -flow-semantics files are merged into the built-in ones.
 func createFlowSemantics(conn *sqlite.Conn) error {
 	ddl := `
 CREATE TABLE flow_semantics (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     package TEXT NOT NULL,
     func_name TEXT NOT NULL,
+    receiver TEXT NOT NULL DEFAULT '',
     flow_from TEXT NOT NULL,
     flow_to TEXT NOT NULL,
-    description TEXT
+    description TEXT,
+    source_file TEXT NOT NULL DEFAULT 'builtin'
 );
 
 INSERT INTO flow_semantics (package, func_name, flow_from, flow_to, description) VALUES
@@ -1410,8 +2034,6 @@ INSERT INTO flow_semantics (package, func_name, flow_from, flow_to, description)
 ('strconv', 'FormatFloat', 'arg:0', 'return:0', 'Float flows to string'),
 
 -- Encoding: input → encoded/decoded output
-('encoding/base64', 'EncodeToString', 'arg:0', 'return:0', 'Bytes flow to base64 string'),
-('encoding/base64', 'DecodeString', 'arg:0', 'return:0', 'Base64 string flows to bytes'),
 ('encoding/hex', 'EncodeToString', 'arg:0', 'return:0', 'Bytes flow to hex string'),
 ('encoding/hex', 'DecodeString', 'arg:0', 'return:0', 'Hex string flows to bytes'),
 
@@ -1433,13 +2055,13 @@ INSERT INTO flow_semantics (package, func_name, flow_from, flow_to, description)
 ('html', 'UnescapeString', 'arg:0', 'return:0', 'HTML-escaped flows to unescaped'),
 
 -- Path operations
-('filepath', 'Join', 'arg:*', 'return:0', 'Path elements flow to joined path'),
-('filepath', 'Clean', 'arg:0', 'return:0', 'Path flows to cleaned path'),
-('filepath', 'Abs', 'arg:0', 'return:0', 'Path flows to absolute path'),
-('filepath', 'Rel', 'arg:1', 'return:0', 'Target path flows to relative path'),
-('filepath', 'Base', 'arg:0', 'return:0', 'Path flows to base name'),
-('filepath', 'Dir', 'arg:0', 'return:0', 'Path flows to directory'),
-('filepath', 'Ext', 'arg:0', 'return:0', 'Path flows to extension'),
+('path/filepath', 'Join', 'arg:*', 'return:0', 'Path elements flow to joined path'),
+('path/filepath', 'Clean', 'arg:0', 'return:0', 'Path flows to cleaned path'),
+('path/filepath', 'Abs', 'arg:0', 'return:0', 'Path flows to absolute path'),
+('path/filepath', 'Rel', 'arg:1', 'return:0', 'Target path flows to relative path'),
+('path/filepath', 'Base', 'arg:0', 'return:0', 'Path flows to base name'),
+('path/filepath', 'Dir', 'arg:0', 'return:0', 'Path flows to directory'),
+('path/filepath', 'Ext', 'arg:0', 'return:0', 'Path flows to extension'),
 ('path', 'Join', 'arg:*', 'return:0', 'Path elements flow to joined path'),
 ('path', 'Clean', 'arg:0', 'return:0', 'Path flows to cleaned path'),
 ('path', 'Base', 'arg:0', 'return:0', 'Path flows to base name'),
@@ -1467,37 +2089,48 @@ INSERT INTO flow_semantics (package, func_name, flow_from, flow_to, description)
 ('sort', 'Slice', 'arg:0', 'arg:0', 'Slice mutated in place'),
 ('sort', 'Sort', 'arg:0', 'arg:0', 'Sortable mutated in place');
 
+-- Methods: matched on the receiver type (or an interface it implements)
+INSERT INTO flow_semantics (package, receiver, func_name, flow_from, flow_to, description) VALUES
+('encoding/base64', 'Encoding', 'EncodeToString', 'arg:0', 'return:0', 'Bytes flow to base64 string'),
+('encoding/base64', 'Encoding', 'DecodeString', 'arg:0', 'return:0', 'Base64 string flows to bytes'),
+('regexp', 'Regexp', 'MatchString', 'arg:0', 'return:0', 'String flows to match result'),
+('regexp', 'Regexp', 'FindString', 'arg:0', 'return:0', 'String flows to matched substring'),
+('regexp', 'Regexp', 'ReplaceAllString', 'arg:0', 'return:0', 'Source string flows to result'),
+('regexp', 'Regexp', 'ReplaceAllString', 'arg:1', 'return:0', 'Replacement flows to result');
+
 CREATE INDEX idx_flow_sem_pkg ON flow_semantics(package, func_name);
 `
-	return sqlitex.ExecuteScript(conn, ddl, nil)
+	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
+		return err
+	}
+	return insertUserFlowSemantics(conn)
 }
 
 // createTaintModel builds a security-oriented taint specification table and
 // annotates call nodes that target known sources, sinks, barriers, or propagators.
+// Rules from -taint-specs files are merged into the built-in ones.
 func createTaintModel(conn *sqlite.Conn) error {
 	ddl := `
 CREATE TABLE taint_specs (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL DEFAULT 'call',
     package TEXT NOT NULL,
     func_name TEXT NOT NULL,
+    receiver TEXT NOT NULL DEFAULT '',
     role TEXT NOT NULL,
     category TEXT,
-    description TEXT
+    description TEXT,
+    source_file TEXT NOT NULL DEFAULT 'builtin'
 );
 
 -- Sources: functions that introduce external/untrusted data
 INSERT INTO taint_specs (package, func_name, role, category, description) VALUES
-('net/http', 'FormValue', 'source', 'http_input', 'HTTP form value'),
-('net/http', 'PostFormValue', 'source', 'http_input', 'HTTP POST form value'),
 ('net/http', 'ReadRequest', 'source', 'http_input', 'HTTP request read'),
 ('os', 'Getenv', 'source', 'env', 'Environment variable'),
 ('os', 'ReadFile', 'source', 'file_read', 'File read'),
 ('io', 'ReadAll', 'source', 'io_read', 'Reader content'),
 ('io', 'Copy', 'source', 'io_read', 'Stream copy'),
-('bufio', 'ReadString', 'source', 'io_read', 'Buffered read'),
-('bufio', 'ReadLine', 'source', 'io_read', 'Buffered line read'),
 ('encoding/json', 'Unmarshal', 'source', 'deserialization', 'JSON unmarshal'),
-('encoding/json', 'Decode', 'source', 'deserialization', 'JSON stream decode'),
 ('encoding/xml', 'Unmarshal', 'source', 'deserialization', 'XML unmarshal'),
 ('gopkg.in/yaml.v2', 'Unmarshal', 'source', 'deserialization', 'YAML unmarshal'),
 ('gopkg.in/yaml.v3', 'Unmarshal', 'source', 'deserialization', 'YAML unmarshal');
@@ -1506,17 +2139,9 @@ INSERT INTO taint_specs (package, func_name, role, category, description) VALUES
 INSERT INTO taint_specs (package, func_name, role, category, description) VALUES
 ('os/exec', 'Command', 'sink', 'command_injection', 'OS command construction'),
 ('os/exec', 'CommandContext', 'sink', 'command_injection', 'OS command with context'),
-('os/exec', 'Run', 'sink', 'command_injection', 'OS command execution'),
-('os/exec', 'Start', 'sink', 'command_injection', 'OS command start'),
 ('os', 'WriteFile', 'sink', 'file_write', 'File write'),
 ('os', 'Create', 'sink', 'file_write', 'File creation'),
 ('os', 'OpenFile', 'sink', 'file_write', 'File open'),
-('html/template', 'Execute', 'sink', 'template_exec', 'HTML template execution'),
-('html/template', 'ExecuteTemplate', 'sink', 'template_exec', 'HTML template execution'),
-('text/template', 'Execute', 'sink', 'template_exec', 'Text template execution'),
-('database/sql', 'Exec', 'sink', 'sql_injection', 'SQL execution'),
-('database/sql', 'Query', 'sink', 'sql_injection', 'SQL query'),
-('database/sql', 'QueryRow', 'sink', 'sql_injection', 'SQL query single row'),
 ('net/http', 'Redirect', 'sink', 'open_redirect', 'HTTP redirect'),
 ('log', 'Printf', 'sink', 'log_injection', 'Log formatted output'),
 ('log', 'Fatalf', 'sink', 'log_injection', 'Log fatal output');
@@ -1532,8 +2157,8 @@ INSERT INTO taint_specs (package, func_name, role, category, description) VALUES
 ('strconv', 'ParseInt', 'barrier', 'type_conversion', 'String to int64'),
 ('strconv', 'ParseFloat', 'barrier', 'type_conversion', 'String to float'),
 ('strconv', 'ParseBool', 'barrier', 'type_conversion', 'String to bool'),
-('filepath', 'Clean', 'barrier', 'path_sanitize', 'Path sanitization'),
-('filepath', 'Abs', 'barrier', 'path_sanitize', 'Absolute path resolution'),
+('path/filepath', 'Clean', 'barrier', 'path_sanitize', 'Path sanitization'),
+('path/filepath', 'Abs', 'barrier', 'path_sanitize', 'Absolute path resolution'),
 ('path', 'Clean', 'barrier', 'path_sanitize', 'Path sanitization');
 
 -- Propagators: functions that transform data while preserving taint
@@ -1548,29 +2173,87 @@ INSERT INTO taint_specs (package, func_name, role, category, description) VALUES
 ('strings', 'ToUpper', 'propagator', 'string_transform', 'Case conversion'),
 ('strings', 'Split', 'propagator', 'string_transform', 'String splitting'),
 ('bytes', 'Join', 'propagator', 'bytes_concat', 'Bytes concatenation'),
-('encoding/base64', 'EncodeToString', 'propagator', 'encoding', 'Base64 encoding'),
-('encoding/base64', 'DecodeString', 'propagator', 'encoding', 'Base64 decoding'),
 ('encoding/hex', 'EncodeToString', 'propagator', 'encoding', 'Hex encoding');
 
+-- Methods: matched on the receiver type (or an interface it implements)
+INSERT INTO taint_specs (package, receiver, func_name, role, category, description) VALUES
+('net/http', 'Request', 'FormValue', 'source', 'http_input', 'HTTP form value'),
+('net/http', 'Request', 'PostFormValue', 'source', 'http_input', 'HTTP POST form value'),
+('net/http', 'Request', 'Cookie', 'source', 'http_input', 'HTTP cookie'),
+('net/url', 'Values', 'Get', 'source', 'http_input', 'URL query value'),
+('bufio', 'Reader', 'ReadString', 'source', 'io_read', 'Buffered read'),
+('bufio', 'Reader', 'ReadLine', 'source', 'io_read', 'Buffered line read'),
+('bufio', 'Scanner', 'Text', 'source', 'io_read', 'Scanned line'),
+('encoding/json', 'Decoder', 'Decode', 'source', 'deserialization', 'JSON stream decode'),
+('os/exec', 'Cmd', 'Run', 'sink', 'command_injection', 'OS command execution'),
+('os/exec', 'Cmd', 'Start', 'sink', 'command_injection', 'OS command start'),
+('os/exec', 'Cmd', 'Output', 'sink', 'command_injection', 'OS command execution'),
+('os/exec', 'Cmd', 'CombinedOutput', 'sink', 'command_injection', 'OS command execution'),
+('html/template', 'Template', 'Execute', 'sink', 'template_exec', 'HTML template execution'),
+('html/template', 'Template', 'ExecuteTemplate', 'sink', 'template_exec', 'HTML template execution'),
+('text/template', 'Template', 'Execute', 'sink', 'template_exec', 'Text template execution'),
+('database/sql', 'DB', 'Exec', 'sink', 'sql_injection', 'SQL execution'),
+('database/sql', 'DB', 'Query', 'sink', 'sql_injection', 'SQL query'),
+('database/sql', 'DB', 'QueryRow', 'sink', 'sql_injection', 'SQL query single row'),
+('database/sql', 'Tx', 'Exec', 'sink', 'sql_injection', 'SQL execution in transaction'),
+('database/sql', 'Tx', 'Query', 'sink', 'sql_injection', 'SQL query in transaction'),
+('database/sql', 'Tx', 'QueryRow', 'sink', 'sql_injection', 'SQL query single row in transaction'),
+('net/http', 'ResponseWriter', 'Write', 'sink', 'xss', 'Raw HTTP response body write'),
+('regexp', 'Regexp', 'MatchString', 'barrier', 'validation', 'Regex match validation'),
+('regexp', 'Regexp', 'Match', 'barrier', 'validation', 'Regex validation'),
+('encoding/base64', 'Encoding', 'EncodeToString', 'propagator', 'encoding', 'Base64 encoding'),
+('encoding/base64', 'Encoding', 'DecodeString', 'propagator', 'encoding', 'Base64 decoding');
+
+-- Fields: reads of request fields carrying client-controlled data
+INSERT INTO taint_specs (kind, package, receiver, func_name, role, category, description) VALUES
+('field', 'net/http', 'Request', 'URL', 'source', 'http_input', 'Request URL'),
+('field', 'net/http', 'Request', 'Header', 'source', 'http_input', 'Request headers'),
+('field', 'net/http', 'Request', 'Body', 'source', 'http_input', 'Request body'),
+('field', 'net/http', 'Request', 'Form', 'source', 'http_input', 'Parsed form values'),
+('field', 'net/http', 'Request', 'PostForm', 'source', 'http_input', 'Parsed POST form values');
+
 CREATE INDEX idx_taint_specs_role ON taint_specs(role);
 CREATE INDEX idx_taint_specs_pkg ON taint_specs(package, func_name);
+`
+	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
+		return err
+	}
+	if err := insertUserTaintSpecs(conn); err != nil {
+		return err
+	}
 
--- Annotate call nodes that target known taint-relevant functions
+	annotate := `
+-- Sites matching a spec: calls by callee, field reads by the field
+-- (see spec_keys for how receivers and interfaces are matched)
+CREATE TEMP TABLE taint_spec_sites AS
+SELECT cse.source AS node_id, ts.id AS spec_id
+FROM edges cse
+JOIN nodes c ON c.id = cse.source AND c.kind = 'call'
+JOIN spec_keys k ON k.node_id = cse.target AND k.kind = 'call'
+JOIN taint_specs ts ON ts.kind = k.kind AND ts.package = k.package
+  AND ts.receiver = k.receiver AND ts.func_name = k.name
+WHERE cse.kind = 'call_site'
+UNION
+SELECT fl.target, ts.id
+FROM edges fl
+JOIN spec_keys k ON k.node_id = fl.source AND k.kind = 'field'
+JOIN taint_specs ts ON ts.kind = k.kind AND ts.package = k.package
+  AND ts.receiver = k.receiver AND ts.func_name = k.name
+WHERE fl.kind = 'field_load';
+
+-- Annotate sites that reach known taint-relevant functions and fields
 INSERT INTO node_properties (node_id, key, value)
-SELECT DISTINCT c.id, 'taint_role', ts.role
-FROM nodes c
-JOIN edges cse ON cse.source = c.id AND cse.kind = 'call_site'
-JOIN nodes callee ON callee.id = cse.target
-JOIN taint_specs ts ON callee.package = ts.package AND callee.name = ts.func_name
-WHERE c.kind = 'call';
+SELECT DISTINCT s.node_id, 'taint_role', ts.role
+FROM taint_spec_sites s
+JOIN taint_specs ts ON ts.id = s.spec_id;
 
 INSERT INTO node_properties (node_id, key, value)
-SELECT DISTINCT c.id, 'taint_category', ts.category
-FROM nodes c
-JOIN edges cse ON cse.source = c.id AND cse.kind = 'call_site'
-JOIN nodes callee ON callee.id = cse.target
-JOIN taint_specs ts ON callee.package = ts.package AND callee.name = ts.func_name
-WHERE c.kind = 'call';
+SELECT DISTINCT s.node_id, 'taint_category', ts.category
+FROM taint_spec_sites s
+JOIN taint_specs ts ON ts.id = s.spec_id
+WHERE ts.category IS NOT NULL;
+
+DROP TABLE temp.taint_spec_sites;
 
 -- Findings: functions containing both sources and sinks
 INSERT INTO findings (category, severity, node_id, file, line, message, details)
@@ -1591,7 +2274,7 @@ WHERE src_role.key = 'taint_role' AND src_role.value = 'source'
   AND src.parent_function IS NOT NULL
 GROUP BY fn.id;
 `
-	return sqlitex.ExecuteScript(conn, ddl, nil)
+	return sqlitex.ExecuteScript(conn, annotate, nil)
 }
 
 // createSchemaDocs creates a self-documenting table describing the CPG schema,
@@ -1637,6 +2320,7 @@ INSERT INTO schema_docs (category, name, description, example) VALUES
 ('node_kind', 'doc', 'Doc comment', NULL),
 ('node_kind', 'label', 'Label for goto/break/continue', NULL),
 ('node_kind', 'incdec', 'Increment/decrement (x++/x--)', NULL),
+('node_kind', 'alloc_site', 'Abstract heap object from points-to analysis (allocation, make, append, closure, global, external call result)', 'Properties: {"alloc_kind":"make_chan"}'),
 ('node_kind', 'meta_data', 'CPG metadata node', NULL);
 
 -- Edge kinds
@@ -1646,7 +2330,7 @@ INSERT INTO schema_docs (category, name, description, example) VALUES
 ('edge_kind', 'cdg', 'Control dependence: block depends on branch', NULL),
 ('edge_kind', 'dom', 'Dominator tree edge', NULL),
 ('edge_kind', 'pdom', 'Post-dominator tree edge', NULL),
-('edge_kind', 'dfg', 'Data flow: definition→use (intra-procedural)', 'Properties: {"heuristic":true} for external calls'),
+('edge_kind', 'dfg', 'Data flow: definition→use (intra-procedural)', 'Properties: {"heuristic":true} for external calls, with "summary": ssa/opaque when inferred from function_summaries'),
 ('edge_kind', 'call', 'Caller function→callee function', 'Properties: {"dynamic":true} for interface dispatch'),
 ('edge_kind', 'call_site', 'Call AST node→callee function', NULL),
 ('edge_kind', 'param_in', 'Actual argument→formal parameter (inter-procedural)', 'Properties: {"index": N}'),
@@ -1667,13 +2351,25 @@ INSERT INTO schema_docs (category, name, description, example) VALUES
 ('edge_kind', 'branch_target', 'Branch statement→target label', NULL),
 ('edge_kind', 'error_wrap', 'Error wrapping: fmt.Errorf %%w or errors.Join → wrapped error', NULL),
 ('edge_kind', 'capture', 'Closure→captured variable from outer scope', NULL),
-('edge_kind', 'eog', 'Evaluation order: arg[i]→arg[i+1] within call', NULL);
+('edge_kind', 'eog', 'Evaluation order: arg[i]→arg[i+1] within call', NULL),
+('edge_kind', 'field_store', 'Store site→struct field node it writes', 'Properties: {"field": name}'),
+('edge_kind', 'field_load', 'Struct field node→load site that reads it', 'Properties: {"field": name}'),
+('edge_kind', 'co_change', 'File→file or function→function changed in the same commits (git history window; functions by the commit hunks touching their current line ranges)', 'Properties: {"level": "file"|"function", "count": shared commits, "confidence": count / commits changing the source}'),
+('edge_kind', 'escapes', 'Variable moved to heap→function that declares it (compiler escape analysis)', 'Properties: {"variable": name}'),
+('edge_kind', 'points_to', 'Value→alloc_site (or function) it may reference (Andersen points-to)', NULL),
+('edge_kind', 'may_alias', 'Value↔value that may reference the same location (stored once per pair); taint crosses it in both directions', 'Properties: {"object": alloc_site id, "path": ".0[]"}'),
+('edge_kind', 'tests', 'Test function→known-module function it reaches via calls (only without -skip-tests)', 'Properties: {"depth": N}');
 
 -- Node properties (on JSON properties column)
 INSERT INTO schema_docs (category, name, description, example) VALUES
 ('node_property', 'receiver', 'Receiver type for methods', '*Manager'),
 ('node_property', 'generic', 'Function or type has type parameters', 'true'),
-('node_property', 'external', 'External stub node (not in analyzed code)', 'true'),
+('node_property', 'test_kind', 'Function in a _test.go file run by go test', 'test/benchmark/fuzz/example'),
+('node_property', 'configs', 'Build configurations (-configs) the node or edge appears in; absent when present in all', '["windows/amd64"]'),
+('node_property', 'external', 'External stub node (not in analyzed code): an ext:: function, or an ext:: field of an external struct read or written by analyzed code', 'true'),
+('node_property', 'signature', 'External stub: full signature including receiver', 'func (*net/http.Request).FormValue(key string) string'),
+('node_property', 'receiver_type', 'External method stub: package-qualified receiver type', '*net/http.Request'),
+('node_property', 'implements', 'External method stub: interfaces declaring the method that the receiver implements', '[{"package":"io","type":"Reader"}]'),
 ('node_property', 'snippet', 'Code snippet for the node', 'if err != nil {'),
 ('node_property', 'nesting_depth', 'Depth of control structure nesting', '5'),
 ('node_property', 'is_generated', 'File is generated (.pb.go)', 'true'),
@@ -1687,20 +2383,28 @@ INSERT INTO schema_docs (category, name, description, example) VALUES
 ('node_property', 'sync_kind', 'Call is sync primitive', 'mutex_lock'),
 ('node_property', 'struct_tag', 'Struct field tag', 'json:"name,omitempty"'),
 ('node_property', 'inlineable', 'Function can be inlined by compiler', 'true'),
-('node_property', 'heap_escapes', 'Variable escapes to heap (GC pressure)', 'true/false'),
+('node_property', 'bounds_check', 'Index or slice expression keeps a bounds check after BCE (-d=ssa/check_bce)', 'index/slice'),
+('node_property', 'nil_check', 'Nil check the compiler generated or removed at this node (-d=nil)', 'generated/removed'),
+('node_property', 'heap_escapes', 'Variable or expression escapes to heap (GC pressure)', 'true/false'),
 ('node_property', 'taint_role', 'Security taint classification', 'source/sink/barrier/propagator'),
 ('node_property', 'taint_category', 'Taint category detail', 'http_input, sql_injection');
 
 -- Tables
 INSERT INTO schema_docs (category, name, description, example) VALUES
-('table', 'nodes', 'All CPG nodes (AST + SSA)', 'SELECT * FROM nodes WHERE kind=''function'' AND package=''scrape'''),
+('table', 'nodes', 'All CPG nodes (AST + SSA). id embeds the position; stable_id survives edits elsewhere in the file: pkg::Recv.Name for declarations, parent.name for their members, parent/kind#ordinal for statements and expressions; unique, with a #N suffix on repeats of a declaration from other build configurations', 'SELECT * FROM nodes WHERE stable_id=''scrape::Manager.Run'''),
 ('table', 'edges', 'All CPG edges (AST, CFG, DFG, call, type)', 'SELECT * FROM edges WHERE kind=''call'' AND source=:func_id'),
 ('table', 'sources', 'Source file contents', 'SELECT content FROM sources WHERE file=''scrape/manager.go'''),
-('table', 'metrics', 'Function-level metrics', 'SELECT * FROM metrics ORDER BY cyclomatic_complexity DESC'),
+('table', 'metrics', 'Function-level metrics. heap_allocs (variables moved and values escaping to heap), leaking_params and inline_cost come from compiler escape analysis, NULL when it was skipped; inline_budget is set when the cost exceeds it; bounds_checks and bounds_checks_in_loops come from compiler check diagnostics', 'SELECT * FROM metrics ORDER BY heap_allocs DESC'),
 ('table', 'findings', 'Pre-computed analysis findings', 'SELECT * FROM findings WHERE category=''complexity'''),
 ('table', 'queries', 'Parameterized CTE queries for analysis', 'SELECT name, description FROM queries'),
-('table', 'taint_specs', 'Security taint model: known sources/sinks/barriers', 'SELECT * FROM taint_specs WHERE role=''sink'''),
-('table', 'flow_semantics', 'Data flow semantics for stdlib functions', 'SELECT * FROM flow_semantics WHERE package=''fmt'''),
+('table', 'taint_specs', 'Security taint model: known sources/sinks/barriers. kind is call (func_name is a function, or a method of receiver) or field (func_name is a field of receiver). source_file is builtin or the -taint-specs file the rule came from', 'SELECT * FROM taint_specs WHERE role=''sink'''),
+('table', 'flow_semantics', 'Data flow semantics for stdlib functions and methods (receiver, empty for functions). source_file is builtin or the -flow-semantics file the rule came from', 'SELECT * FROM flow_semantics WHERE source_file <> ''builtin'''),
+('table', 'function_summaries', 'Parameter flows per function from SSA: from_pos (recv or arg:N) reaches to_pos (return:N, recv, arg:N or global:pkg.name). Covers known-module functions and ext:: callees; kind is ssa, or opaque for bodiless callees (all args reach all results). A function whose parameters reach nothing has one row with NULL positions', 'SELECT * FROM function_summaries WHERE to_pos LIKE ''global:%'''),
+('table', 'escape_decisions', 'Compiler escape analysis and inlining (-gcflags=-m=2) decisions on nodes: leaking_param, moved_to_heap, escapes_to_heap, does_not_escape, inlineable, not_inlineable (detail is the reason), inlined_call (detail is the callee). function_id is the function node or the enclosing function', 'SELECT * FROM escape_decisions WHERE kind = ''moved_to_heap'''),
+('table', 'function_ownership', 'Per-function git blame over the function line range: primary_author (most lines), author_count, last_modified (newest line) and age_days. Empty when git history is skipped', 'SELECT o.*, n.name FROM function_ownership o JOIN nodes n ON n.id = o.function_id ORDER BY o.author_count DESC LIMIT 20'),
+('table', 'compiler_checks', 'Bounds checks (bounds_check: index or slice) and nil checks (nil_check, nil_check_removed) the compiler reported, on index/slice/selector nodes. in_loop is 1 inside a for/range of the same function', 'SELECT * FROM compiler_checks WHERE kind = ''bounds_check'' AND in_loop'),
+('table', 'escape_explanations', 'Why values escape: the -m=2 flow chain for each escaping node, one row per step. summary is the compiler headline (x escapes to heap in F, parameter p leaks to {heap} for F ...); flow is dst ← src, expr flows because of reason at file:line:col (site_id)', 'SELECT step, flow, expr, reason, line FROM escape_explanations WHERE node_id = :id ORDER BY explanation, step'),
+('table', 'spec_keys', 'Keys (package, receiver, name) under which taint_specs and flow_semantics rows match a function or external field node; via is declared or interface (receiver implements it)', 'SELECT * FROM spec_keys WHERE via = ''interface'''),
 ('table', 'node_properties', 'Vertical property table (extracted from JSON)', 'SELECT * FROM node_properties WHERE key=''receiver'''),
 ('table', 'edge_properties', 'Vertical edge property table', 'SELECT * FROM edge_properties WHERE key=''dynamic'''),
 ('table', 'stats_overview', 'Summary statistics for the entire CPG', 'SELECT * FROM stats_overview'),
@@ -1711,6 +2415,7 @@ INSERT INTO schema_docs (category, name, description, example) VALUES
 INSERT INTO schema_docs (category, name, description, example) VALUES
 ('view', 'v_call_graph', 'Flattened call graph with names', 'SELECT * FROM v_call_graph WHERE caller_package=''scrape'''),
 ('view', 'v_data_flow', 'DFG edges with file/line context', NULL),
+('view', 'v_field_flow', 'Writer→reader function pairs per struct field (via field_store/field_load)', 'SELECT * FROM v_field_flow WHERE field_name = ''ScrapeInterval'''),
 ('view', 'v_function_summary', 'Per-function metrics + call counts', 'SELECT * FROM v_function_summary ORDER BY complexity DESC'),
 ('view', 'v_type_hierarchy', 'Implements/embeds/alias relationships', NULL),
 ('view', 'v_package_deps', 'Aggregated cross-package call edges', NULL),
@@ -1737,10 +2442,15 @@ INSERT INTO schema_docs (category, name, description, example) VALUES
 ('finding', 'large_return', 'Functions returning 4+ values', NULL),
 ('finding', 'bool_params', 'Functions with 2+ boolean parameters (boolean blindness)', NULL),
 ('finding', 'panic_call', 'Functions that call panic() directly', NULL),
+('finding', 'unchecked_error', 'Calls whose error result is never checked (see unchecked_errors); warning when the error could have been returned, info otherwise', 'SELECT * FROM findings WHERE category = ''unchecked_error'' AND severity = ''warning'''),
+('finding', 'vet', 'Diagnostics of go/analysis passes (nilness, shadow, copylocks, printf, ...); details hold the analyzer and suggested fixes', 'SELECT json_extract(details, ''$.analyzer''), COUNT(*) FROM findings WHERE category = ''vet'' GROUP BY 1'),
 ('query', 'package_cohesion', 'Package cohesion analysis', NULL),
 ('query', 'concurrency_profile', 'Per-package concurrency usage', NULL),
 ('query', 'package_impact', 'Transitive package impact analysis', NULL),
 ('query', 'function_neighborhood', 'Direct callers and callees of a function', NULL),
+('query', 'covering_tests', 'Tests that reach a function through the call graph', NULL),
+('query', 'field_flow', 'Functions that write and read a struct field', NULL),
+('query', 'function_flows', 'Parameter flows of a function from its summary', NULL),
 ('query', 'file_complexity_heatmap', 'Total complexity per file for heatmap visualization', NULL),
 ('query', 'type_usage', 'Functions that reference a given type in their signatures', NULL),
 ('table', 'dashboard_complexity_distribution', 'Complexity histogram buckets for chart rendering', NULL),
@@ -1750,10 +2460,14 @@ INSERT INTO schema_docs (category, name, description, example) VALUES
 ('table', 'dashboard_node_distribution', 'Node type distribution for pie/donut chart', NULL),
 ('table', 'dashboard_complexity_vs_loc', 'Scatter plot data: complexity vs LOC per function', NULL),
 ('table', 'dashboard_overview', 'Key-value overview stats for dashboard header cards', NULL),
-('table', 'dashboard_top_functions', 'Top 50 functions by complexity, LOC, fan-in, fan-out for leaderboards', 'SELECT * FROM dashboard_top_functions WHERE metric = ''complexity'' ORDER BY rank'),
-('table', 'dashboard_hotspots', 'Functions ranked by combined hotspot score (complexity + fan-in + findings)', 'SELECT * FROM dashboard_hotspots ORDER BY hotspot_score DESC LIMIT 20'),
+('table', 'dashboard_top_functions', 'Top 50 functions by complexity, LOC, fan-in, fan-out, heap allocations, bounds checks in loops for leaderboards', 'SELECT * FROM dashboard_top_functions WHERE metric = ''complexity'' ORDER BY rank'),
+('table', 'dashboard_hotspots', 'Functions ranked by combined hotspot score (complexity + fan-in + findings, plus author count and recent edits from git blame), with heap_allocs and the function owner alongside', 'SELECT * FROM dashboard_hotspots ORDER BY hotspot_score DESC LIMIT 20'),
 ('table', 'package_coupling', 'Cross-package call coupling matrix (source→target, count)', 'SELECT * FROM package_coupling ORDER BY call_count DESC LIMIT 20'),
 ('table', 'error_chains', 'Functions involved in error wrapping/propagation chains', 'SELECT * FROM error_chains WHERE error_wraps > 0 ORDER BY error_wraps DESC'),
+('table', 'unchecked_errors', 'Calls whose error result is never checked (SSA): kind is discarded, blank (assigned to _), overwritten (assigned, then replaced or dropped before any use) or deferred_close; reaches_return is set when a return reachable from the call passes on another error (an error result that is not the nil constant), i.e. the function propagates errors but drops this one', 'SELECT * FROM unchecked_errors WHERE reaches_return ORDER BY file, line'),
+('table', 'callgraph_edges', 'Function→function edges per call graph algorithm (-callgraph-compare only)', 'SELECT * FROM callgraph_edges WHERE algo = ''cha'' AND dynamic = 1'),
+('table', 'callgraph_comparison', 'Per-algorithm edge counts and edges no other compared algorithm found', 'SELECT * FROM callgraph_comparison'),
+('view', 'v_callgraph_unique', 'Call edges found by exactly one compared algorithm', 'SELECT * FROM v_callgraph_unique WHERE dynamic = 1'),
 ('finding', 'long_param_list', 'Functions with more than 5 parameters', NULL),
 ('finding', 'god_package', 'Packages with more than 50 functions', NULL),
 ('finding', 'high_coupling', 'Packages depending on more than 10 other packages', NULL),
@@ -2208,6 +2922,32 @@ INSERT INTO queries (name, description, sql) VALUES
   WHERE e.source = :function_id AND e.kind = ''call'' AND n.kind = ''function''
   ORDER BY direction, name');
 
+INSERT INTO queries (name, description, sql) VALUES
+('covering_tests',
+ 'Tests, benchmarks, fuzz targets and examples that reach a function through the call graph',
+ 'SELECT n.id, n.name, n.package, n.file, n.line,
+    json_extract(n.properties, ''$.test_kind'') AS test_kind,
+    json_extract(e.properties, ''$.depth'') AS depth
+  FROM edges e JOIN nodes n ON n.id = e.source
+  WHERE e.target = :function_id AND e.kind = ''tests''
+  ORDER BY depth, n.package, n.name');
+
+INSERT INTO queries (name, description, sql) VALUES
+('field_flow',
+ 'Field flow: functions writing a struct field paired with functions reading it',
+ 'SELECT * FROM v_field_flow
+  WHERE field_name = :field_name
+  ORDER BY type_name, writer_name, reader_name');
+
+INSERT INTO queries (name, description, sql) VALUES
+('function_flows',
+ 'Function summary: which parameters reach which results, parameters and globals',
+ 'SELECT n.name, n.package, fs.from_pos, fs.to_pos, fs.variadic, fs.kind
+  FROM function_summaries fs
+  JOIN nodes n ON n.id = fs.function_id
+  WHERE n.id = :function_id
+  ORDER BY fs.from_pos, fs.to_pos');
+
 INSERT INTO queries (name, description, sql) VALUES
 ('file_complexity_heatmap',
  'Complexity heatmap data: total complexity per file for visualization',
@@ -2436,7 +3176,11 @@ CREATE TABLE dashboard_hotspots (
     fan_in INTEGER,
     fan_out INTEGER,
     finding_count INTEGER,
-    hotspot_score REAL NOT NULL
+    hotspot_score REAL NOT NULL,
+    heap_allocs INTEGER,
+    primary_author TEXT,
+    author_count INTEGER,
+    age_days INTEGER
 );
 
 -- Cross-package coupling matrix: how tightly packages are coupled
@@ -2464,11 +3208,11 @@ CREATE TABLE error_chains (
 	// Top functions by complexity
 	if err := sqlitex.ExecuteTransient(conn, `
 INSERT INTO dashboard_top_functions
-  SELECT 'complexity', ROW_NUMBER() OVER (ORDER BY m.cyclomatic_complexity DESC), m.function_id,
+  SELECT 'complexity', ROW_NUMBER() OVER (ORDER BY m.cyclomatic_complexity DESC, m.function_id), m.function_id,
     n.name, n.package, n.file, m.cyclomatic_complexity
   FROM metrics m JOIN nodes n ON n.id = m.function_id
   WHERE m.cyclomatic_complexity > 0
-  ORDER BY m.cyclomatic_complexity DESC LIMIT 50`,
+  ORDER BY m.cyclomatic_complexity DESC, m.function_id LIMIT 50`,
 		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error { return nil }}); err != nil {
 		return fmt.Errorf("top complexity: %w", err)
 	}
@@ -2476,11 +3220,11 @@ INSERT INTO dashboard_top_functions
 	// Top by LOC
 	if err := sqlitex.ExecuteTransient(conn, `
 INSERT INTO dashboard_top_functions
-  SELECT 'loc', ROW_NUMBER() OVER (ORDER BY m.loc DESC), m.function_id,
+  SELECT 'loc', ROW_NUMBER() OVER (ORDER BY m.loc DESC, m.function_id), m.function_id,
     n.name, n.package, n.file, m.loc
   FROM metrics m JOIN nodes n ON n.id = m.function_id
   WHERE m.loc > 0
-  ORDER BY m.loc DESC LIMIT 50`,
+  ORDER BY m.loc DESC, m.function_id LIMIT 50`,
 		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error { return nil }}); err != nil {
 		return fmt.Errorf("top loc: %w", err)
 	}
@@ -2488,11 +3232,11 @@ INSERT INTO dashboard_top_functions
 	// Top by fan-in (most called)
 	if err := sqlitex.ExecuteTransient(conn, `
 INSERT INTO dashboard_top_functions
-  SELECT 'fan_in', ROW_NUMBER() OVER (ORDER BY m.fan_in DESC), m.function_id,
+  SELECT 'fan_in', ROW_NUMBER() OVER (ORDER BY m.fan_in DESC, m.function_id), m.function_id,
     n.name, n.package, n.file, m.fan_in
   FROM metrics m JOIN nodes n ON n.id = m.function_id
   WHERE m.fan_in > 0
-  ORDER BY m.fan_in DESC LIMIT 50`,
+  ORDER BY m.fan_in DESC, m.function_id LIMIT 50`,
 		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error { return nil }}); err != nil {
 		return fmt.Errorf("top fan_in: %w", err)
 	}
@@ -2500,15 +3244,39 @@ INSERT INTO dashboard_top_functions
 	// Top by fan-out (calls the most)
 	if err := sqlitex.ExecuteTransient(conn, `
 INSERT INTO dashboard_top_functions
-  SELECT 'fan_out', ROW_NUMBER() OVER (ORDER BY m.fan_out DESC), m.function_id,
+  SELECT 'fan_out', ROW_NUMBER() OVER (ORDER BY m.fan_out DESC, m.function_id), m.function_id,
     n.name, n.package, n.file, m.fan_out
   FROM metrics m JOIN nodes n ON n.id = m.function_id
   WHERE m.fan_out > 0
-  ORDER BY m.fan_out DESC LIMIT 50`,
+  ORDER BY m.fan_out DESC, m.function_id LIMIT 50`,
 		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error { return nil }}); err != nil {
 		return fmt.Errorf("top fan_out: %w", err)
 	}
 
+	// Top by heap allocations (escape analysis)
+	if err := sqlitex.ExecuteTransient(conn, `
+INSERT INTO dashboard_top_functions
+  SELECT 'heap_allocs', ROW_NUMBER() OVER (ORDER BY m.heap_allocs DESC, m.function_id), m.function_id,
+    n.name, n.package, n.file, m.heap_allocs
+  FROM metrics m JOIN nodes n ON n.id = m.function_id
+  WHERE m.heap_allocs > 0
+  ORDER BY m.heap_allocs DESC, m.function_id LIMIT 50`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error { return nil }}); err != nil {
+		return fmt.Errorf("top heap_allocs: %w", err)
+	}
+
+	// Top by bounds checks left inside loops (check diagnostics)
+	if err := sqlitex.ExecuteTransient(conn, `
+INSERT INTO dashboard_top_functions
+  SELECT 'bounds_checks_in_loops', ROW_NUMBER() OVER (ORDER BY m.bounds_checks_in_loops DESC, m.function_id), m.function_id,
+    n.name, n.package, n.file, m.bounds_checks_in_loops
+  FROM metrics m JOIN nodes n ON n.id = m.function_id
+  WHERE m.bounds_checks_in_loops > 0
+  ORDER BY m.bounds_checks_in_loops DESC, m.function_id LIMIT 50`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error { return nil }}); err != nil {
+		return fmt.Errorf("top bounds_checks_in_loops: %w", err)
+	}
+
 	// Hotspot detection: combined score
 	if err := sqlitex.ExecuteTransient(conn, `
 INSERT INTO dashboard_hotspots
@@ -2521,7 +3289,8 @@ INSERT INTO dashboard_hotspots
       (CAST(m.loc AS REAL) / MAX((SELECT MAX(loc) FROM metrics), 1)) * 20 +
       (CAST(m.fan_in AS REAL) / MAX((SELECT MAX(fan_in) FROM metrics WHERE fan_in > 0), 1)) * 25 +
       (CAST(COALESCE(fc.cnt, 0) AS REAL) / MAX((SELECT MAX(c) FROM (SELECT COUNT(*) as c FROM findings GROUP BY node_id)), 1)) * 25
-    , 2)
+    , 2),
+    m.heap_allocs, NULL, NULL, NULL
   FROM metrics m
   JOIN nodes n ON n.id = m.function_id
   LEFT JOIN (SELECT node_id, COUNT(*) AS cnt FROM findings GROUP BY node_id) fc ON fc.node_id = m.function_id
@@ -2608,7 +3377,7 @@ INSERT INTO findings (node_id, category, severity, message)
 	if err := sqlitex.ExecuteTransient(conn, `
 INSERT INTO queries (name, description, sql) VALUES
   ('hotspot_analysis', 'Find functions with combined high complexity, high fan-in, and many findings',
-   'SELECT function_id, name, package, complexity, fan_in, finding_count, hotspot_score FROM dashboard_hotspots ORDER BY hotspot_score DESC LIMIT 20'),
+   'SELECT function_id, name, package, complexity, fan_in, finding_count, hotspot_score, heap_allocs, primary_author, author_count, age_days FROM dashboard_hotspots ORDER BY hotspot_score DESC LIMIT 20'),
   ('package_coupling_matrix', 'Aggregated cross-package call coupling matrix',
    'SELECT source_package, target_package, call_count FROM package_coupling ORDER BY call_count DESC LIMIT 50'),
   ('error_propagation', 'Functions involved in error wrapping chains',
@@ -2765,10 +3534,10 @@ INSERT INTO dashboard_function_detail
     (SELECT COUNT(*) FROM nodes b WHERE b.kind IN ('if','for','switch','select') AND b.parent_function = n.id),
     (SELECT COUNT(*) FROM nodes r WHERE r.kind = 'return' AND r.parent_function = n.id),
     COALESCE((SELECT COUNT(*) FROM findings fi WHERE fi.node_id = n.id), 0),
-    (SELECT GROUP_CONCAT(DISTINCT caller.name)
+    (SELECT GROUP_CONCAT(DISTINCT caller.name ORDER BY caller.name)
      FROM edges ce JOIN nodes caller ON caller.id = ce.source
      WHERE ce.target = n.id AND ce.kind = 'call' AND caller.kind = 'function'),
-    (SELECT GROUP_CONCAT(DISTINCT callee.name)
+    (SELECT GROUP_CONCAT(DISTINCT callee.name ORDER BY callee.name)
      FROM edges ce JOIN nodes callee ON callee.id = ce.target
      WHERE ce.source = n.id AND ce.kind = 'call' AND callee.kind = 'function')
   FROM nodes n
@@ -3177,15 +3946,16 @@ CREATE TABLE git_file_history (
     last_date TEXT,
     insertions INTEGER NOT NULL DEFAULT 0,
     deletions INTEGER NOT NULL DEFAULT 0,
-    churn INTEGER NOT NULL DEFAULT 0
+    churn INTEGER NOT NULL DEFAULT 0,
+    days_since_edit INTEGER
 );`
 	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
 		return fmt.Errorf("git history DDL: %w", err)
 	}
 
 	stmt, err := conn.Prepare(`INSERT OR IGNORE INTO git_file_history
-		(file, commit_count, author_count, last_author, last_date, insertions, deletions, churn)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+		(file, commit_count, author_count, last_author, last_date, insertions, deletions, churn, days_since_edit)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		return err
 	}
@@ -3200,10 +3970,15 @@ CREATE TABLE git_file_history (
 		stmt.BindInt64(6, int64(h.Insertions))
 		stmt.BindInt64(7, int64(h.Deletions))
 		stmt.BindInt64(8, int64(h.Insertions+h.Deletions))
+		if h.DaysSinceEdit >= 0 {
+			stmt.BindInt64(9, int64(h.DaysSinceEdit))
+		} else {
+			stmt.BindNull(9)
+		}
 		if _, err := stmt.Step(); err != nil {
 			return err
 		}
-		stmt.Reset()
+		_ = stmt.Reset()
 	}
 
 	enrich := `
@@ -3251,21 +4026,439 @@ INSERT INTO schema_docs (category, name, description, example) VALUES
 	}
 
 	var churnFindings int
-	sqlitex.ExecuteTransient(conn, "SELECT COUNT(*) FROM findings WHERE category = 'high_churn_complexity'",
+	if err := sqlitex.ExecuteTransient(conn, "SELECT COUNT(*) FROM findings WHERE category = 'high_churn_complexity'",
 		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
 			churnFindings = stmt.ColumnInt(0)
 			return nil
-		}})
+		}}); err != nil {
+		return fmt.Errorf("count churn findings: %w", err)
+	}
 
 	prog.Log("Git history: %d files, %d high-churn findings", len(history), churnFindings)
 	return nil
 }
 
-// createTaintFlowStates materializes taint propagation by BFS through DFG
-// edges from annotated taint sources. Each reachable node gets a label:
+// functionRanges reads the line range of every function node back from the
+// nodes table, which also holds nodes written by a streaming sink.
+func functionRanges(conn *sqlite.Conn) (map[string]FileRange, error) {
+	funcs := make(map[string]FileRange)
+	err := sqlitex.ExecuteTransient(conn,
+		`SELECT id, file, line, end_line FROM nodes
+		 WHERE kind = 'function' AND file IS NOT NULL AND file != '' AND end_line >= line`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			funcs[stmt.ColumnText(0)] = FileRange{
+				RelFile:   stmt.ColumnText(1),
+				LineRange: LineRange{Start: stmt.ColumnInt(2), End: stmt.ColumnInt(3)},
+			}
+			return nil
+		}})
+	if err != nil {
+		return nil, fmt.Errorf("function ranges: %w", err)
+	}
+	return funcs, nil
+}
+
+// applyFunctionOwnership blames the line range of every function node and
+// stores who owns it in function_ownership. Hotspots touched by several
+// authors or edited recently are scored higher.
+func applyFunctionOwnership(conn *sqlite.Conn, prog *Progress) error {
+	funcs, err := functionRanges(conn)
+	if err != nil {
+		return err
+	}
+
+	// Group ranges by module; files of non-primary modules carry its prefix.
+	mods := modSet.Dirs()
+	ranges := make([]map[string][]LineRange, len(mods))
+	for _, fr := range funcs {
+		best := -1
+		for i, m := range mods {
+			if m.Prefix == "" || strings.HasPrefix(fr.RelFile, m.Prefix+"/") {
+				if best < 0 || len(m.Prefix) > len(mods[best].Prefix) {
+					best = i
+				}
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		if ranges[best] == nil {
+			ranges[best] = make(map[string][]LineRange)
+		}
+		ranges[best][fr.RelFile] = append(ranges[best][fr.RelFile], fr.LineRange)
+	}
+	var blame []GitBlameEntry
+	for i, m := range mods {
+		if len(ranges[i]) > 0 {
+			blame = append(blame, RunGitBlame(m.Dir, m.Prefix, ranges[i], prog)...)
+		}
+	}
+	owners := ComputeOwnership(funcs, blame, time.Now())
+
+	stmt, err := conn.Prepare(`INSERT INTO function_ownership
+		(function_id, primary_author, primary_lines, author_count, lines, last_modified, age_days)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stmt.Finalize() }()
+
+	for _, o := range owners {
+		stmt.BindText(1, o.FunctionID)
+		stmt.BindText(2, o.PrimaryAuthor)
+		stmt.BindInt64(3, int64(o.PrimaryLines))
+		stmt.BindInt64(4, int64(o.AuthorCount))
+		stmt.BindInt64(5, int64(o.Lines))
+		stmt.BindText(6, o.LastModified)
+		if o.AgeDays >= 0 {
+			stmt.BindInt64(7, int64(o.AgeDays))
+		} else {
+			stmt.BindNull(7)
+		}
+		if _, err := stmt.Step(); err != nil {
+			return fmt.Errorf("insert ownership %s: %w", o.FunctionID, err)
+		}
+		_ = stmt.Reset()
+	}
+
+	// Hotspot score: up to 10 points for the author count (relative to the
+	// most-authored function) and up to 10 for an edit within the last year.
+	if err := sqlitex.ExecuteTransient(conn, `
+UPDATE dashboard_hotspots SET
+  primary_author = o.primary_author,
+  author_count = o.author_count,
+  age_days = o.age_days,
+  hotspot_score = ROUND(hotspot_score +
+    (CAST(o.author_count - 1 AS REAL) / MAX((SELECT MAX(author_count) FROM function_ownership) - 1, 1)) * 10 +
+    MAX(0, 1 - CAST(COALESCE(o.age_days, 365) AS REAL) / 365) * 10
+  , 2)
+FROM function_ownership o
+WHERE o.function_id = dashboard_hotspots.function_id`, nil); err != nil {
+		return fmt.Errorf("hotspot ownership: %w", err)
+	}
+
+	var authors int
+	if err := sqlitex.ExecuteTransient(conn, "SELECT COUNT(DISTINCT primary_author) FROM function_ownership",
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			authors = stmt.ColumnInt(0)
+			return nil
+		}}); err != nil {
+		return fmt.Errorf("count owners: %w", err)
+	}
+
+	prog.Log("Function ownership: %d of %d functions blamed, %d primary authors", len(owners), len(funcs), authors)
+	return nil
+}
+
+// applyCoChange stores file and function pairs that change in the same
+// commits as co_change edges, then compares file co-change across packages
+// with static coupling in package_coupling. File pairs that change together
+// often with no call between their packages are reported as hidden coupling.
+func applyCoChange(conn *sqlite.Conn, history *GitHistory, prog *Progress) error {
+	funcs, err := functionRanges(conn)
+	if err != nil {
+		return err
+	}
+	if err := sqlitex.ExecuteTransient(conn,
+		`CREATE TEMP TABLE co_change_pairs (level TEXT, source TEXT, target TEXT, count INTEGER, confidence REAL)`, nil); err != nil {
+		return err
+	}
+	stmt, err := conn.Prepare(`INSERT INTO co_change_pairs VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	for _, level := range []struct {
+		name  string
+		pairs []CoChange
+	}{
+		{"file", FileCoChanges(history)},
+		{"function", FunctionCoChanges(history, funcs)},
+	} {
+		for _, p := range level.pairs {
+			stmt.BindText(1, level.name)
+			stmt.BindText(2, p.Source)
+			stmt.BindText(3, p.Target)
+			stmt.BindInt64(4, int64(p.Count))
+			stmt.BindFloat(5, p.Confidence)
+			if _, err := stmt.Step(); err != nil {
+				_ = stmt.Finalize()
+				return fmt.Errorf("insert co-change pair: %w", err)
+			}
+			_ = stmt.Reset()
+		}
+	}
+	_ = stmt.Finalize()
+
+	script := `
+INSERT OR IGNORE INTO edges (source, target, kind, properties)
+SELECT fs.id, ft.id, 'co_change', json_object('level', 'file', 'count', p.count, 'confidence', ROUND(p.confidence, 3))
+FROM co_change_pairs p
+JOIN nodes fs ON fs.kind = 'file' AND fs.file = p.source
+JOIN nodes ft ON ft.kind = 'file' AND ft.file = p.target
+WHERE p.level = 'file';
+
+INSERT OR IGNORE INTO edges (source, target, kind, properties)
+SELECT p.source, p.target, 'co_change', json_object('level', 'function', 'count', p.count, 'confidence', ROUND(p.confidence, 3))
+FROM co_change_pairs p
+WHERE p.level = 'function';
+
+DROP TABLE temp.co_change_pairs;
+
+-- Logical vs static coupling between packages: file co-changes across
+-- packages next to the calls between them (either direction)
+CREATE TABLE package_co_change (
+    source_package TEXT NOT NULL,
+    target_package TEXT NOT NULL,
+    file_pairs INTEGER NOT NULL,
+    co_changes INTEGER NOT NULL,
+    max_confidence REAL NOT NULL,
+    static_calls INTEGER NOT NULL,
+    PRIMARY KEY (source_package, target_package)
+);
+
+INSERT INTO package_co_change
+SELECT fs.package, ft.package, COUNT(*),
+  SUM(json_extract(e.properties, '$.count')),
+  MAX(json_extract(e.properties, '$.confidence')),
+  COALESCE((SELECT SUM(pc.call_count) FROM package_coupling pc
+            WHERE (pc.source_package = fs.package AND pc.target_package = ft.package)
+               OR (pc.source_package = ft.package AND pc.target_package = fs.package)), 0)
+FROM edges e
+JOIN nodes fs ON fs.id = e.source
+JOIN nodes ft ON ft.id = e.target
+WHERE e.kind = 'co_change' AND json_extract(e.properties, '$.level') = 'file'
+  AND fs.package != ft.package
+GROUP BY fs.package, ft.package;
+
+-- Findings: file pairs that co-change often with no static coupling, once
+-- per pair from the file whose changes more often include the other
+INSERT INTO findings (category, severity, node_id, file, line, message, details)
+SELECT 'hidden_coupling', 'warning', fs.id, fs.file, 1,
+  fs.file || ' and ' || ft.file || ' changed together in ' || json_extract(e.properties, '$.count') ||
+    ' commits (' || CAST(ROUND(json_extract(e.properties, '$.confidence') * 100) AS INTEGER) ||
+    '%) with no calls between ' || fs.package || ' and ' || ft.package,
+  json_object('file', fs.file, 'co_changed_file', ft.file, 'count', json_extract(e.properties, '$.count'),
+              'confidence', json_extract(e.properties, '$.confidence'),
+              'package', fs.package, 'co_changed_package', ft.package)
+FROM edges e
+JOIN nodes fs ON fs.id = e.source
+JOIN nodes ft ON ft.id = e.target
+JOIN edges r ON r.source = e.target AND r.target = e.source AND r.kind = 'co_change'
+JOIN package_co_change pcc ON pcc.source_package = fs.package AND pcc.target_package = ft.package
+WHERE e.kind = 'co_change' AND json_extract(e.properties, '$.level') = 'file'
+  AND (json_extract(e.properties, '$.confidence') > json_extract(r.properties, '$.confidence')
+       OR (json_extract(e.properties, '$.confidence') = json_extract(r.properties, '$.confidence') AND fs.file < ft.file))
+  AND pcc.static_calls = 0
+  AND json_extract(e.properties, '$.count') >= 3
+  AND json_extract(e.properties, '$.confidence') >= 0.5;
+
+INSERT INTO queries (name, description, sql) VALUES
+  ('hidden_function_coupling', 'Function pairs that change in the same commits but never call each other',
+   'SELECT e.source, s.name AS source_name, e.target, t.name AS target_name, json_extract(e.properties, ''$.count'') AS count, json_extract(e.properties, ''$.confidence'') AS confidence FROM edges e JOIN nodes s ON s.id = e.source JOIN nodes t ON t.id = e.target WHERE e.kind = ''co_change'' AND json_extract(e.properties, ''$.level'') = ''function'' AND NOT EXISTS (SELECT 1 FROM edges c WHERE c.kind = ''call'' AND ((c.source = e.source AND c.target = e.target) OR (c.source = e.target AND c.target = e.source))) ORDER BY confidence DESC, count DESC LIMIT 50');
+
+INSERT INTO schema_docs (category, name, description, example) VALUES
+('table', 'package_co_change', 'Logical coupling between packages: file pairs co-changing across them (co_change edges) next to static_calls from package_coupling in either direction', 'SELECT * FROM package_co_change WHERE static_calls = 0 ORDER BY co_changes DESC LIMIT 20'),
+('query', 'hidden_function_coupling', 'Function pairs that co-change without calling each other', NULL);
+`
+	if err := sqlitex.ExecuteScript(conn, script, nil); err != nil {
+		return fmt.Errorf("co-change: %w", err)
+	}
+
+	var fileEdges, funcEdges, hidden int
+	if err := sqlitex.ExecuteTransient(conn,
+		`SELECT
+		   (SELECT COUNT(*) FROM edges WHERE kind = 'co_change' AND json_extract(properties, '$.level') = 'file'),
+		   (SELECT COUNT(*) FROM edges WHERE kind = 'co_change' AND json_extract(properties, '$.level') = 'function'),
+		   (SELECT COUNT(*) FROM findings WHERE category = 'hidden_coupling')`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			fileEdges, funcEdges, hidden = stmt.ColumnInt(0), stmt.ColumnInt(1), stmt.ColumnInt(2)
+			return nil
+		}}); err != nil {
+		return fmt.Errorf("count co-change edges: %w", err)
+	}
+
+	prog.Log("Co-change: %d file and %d function co_change edges, %d hidden coupling findings", fileEdges, funcEdges, hidden)
+	return nil
+}
+
+// applyChangeSet maps the hunks changed since the -changed-since base to the
+// function and type nodes whose line ranges they touch, then computes the
+// blast radius of the change: the transitive callers of each changed
+// function over call edges, the packages affected, and the findings the
+// change introduces, which are the ones history.BaseDB lacks.
+func applyChangeSet(conn *sqlite.Conn, history *GitHistory, prog *Progress) error {
+	ddl := `
+CREATE TABLE change_hunks (
+    file TEXT NOT NULL,
+    start_line INTEGER NOT NULL,
+    end_line INTEGER NOT NULL,
+    lines INTEGER NOT NULL
+);
+
+CREATE TABLE change_set (
+    node_id TEXT PRIMARY KEY,
+    kind TEXT NOT NULL,
+    name TEXT NOT NULL,
+    package TEXT,
+    file TEXT,
+    line INTEGER,
+    end_line INTEGER,
+    change TEXT NOT NULL,
+    changed_lines INTEGER NOT NULL
+);
+
+CREATE TABLE change_impact (
+    changed_id TEXT NOT NULL,
+    function_id TEXT NOT NULL,
+    depth INTEGER NOT NULL,
+    PRIMARY KEY (changed_id, function_id)
+);
+
+CREATE TABLE change_packages (
+    package TEXT PRIMARY KEY,
+    changed_symbols INTEGER NOT NULL,
+    impacted_functions INTEGER NOT NULL
+);
+
+CREATE TABLE change_new_findings (
+    finding_id INTEGER PRIMARY KEY
+);`
+	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
+		return fmt.Errorf("change set DDL: %w", err)
+	}
+
+	stmt, err := conn.Prepare(`INSERT INTO change_hunks (file, start_line, end_line, lines) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stmt.Finalize() }()
+
+	for _, h := range history.Hunks {
+		stmt.BindText(1, h.RelFile)
+		stmt.BindInt64(2, int64(h.Start))
+		stmt.BindInt64(3, int64(h.End))
+		stmt.BindInt64(4, int64(h.Lines))
+		if _, err := stmt.Step(); err != nil {
+			return err
+		}
+		_ = stmt.Reset()
+	}
+
+	// Findings are matched with the base the way cpg-diff matches them:
+	// by category, message and enclosing function symbol, not by line.
+	newIDs, err := cpgdiff.NewFindingIDs(history.BaseDB, conn)
+	if err != nil {
+		return fmt.Errorf("compare findings with the base: %w", err)
+	}
+	newStmt, err := conn.Prepare(`INSERT INTO change_new_findings (finding_id) VALUES (?)`)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = newStmt.Finalize() }()
+	for _, id := range newIDs {
+		newStmt.BindInt64(1, id)
+		if _, err := newStmt.Step(); err != nil {
+			return err
+		}
+		_ = newStmt.Reset()
+	}
+
+	script := `
+CREATE INDEX idx_change_hunks_file ON change_hunks(file);
+
+-- A symbol is added when every line of it changed, modified otherwise;
+-- hunks that only remove lines touch the line before the removal
+INSERT INTO change_set
+SELECT n.id, n.kind, n.name, n.package, n.file, n.line, n.end_line,
+  CASE WHEN SUM(CASE WHEN h.lines > 0 THEN MIN(h.end_line, n.end_line) - MAX(h.start_line, n.line) + 1 ELSE 0 END)
+            >= n.end_line - n.line + 1
+       THEN 'added' ELSE 'modified' END,
+  SUM(CASE WHEN h.lines > 0 THEN MIN(h.end_line, n.end_line) - MAX(h.start_line, n.line) + 1 ELSE 0 END)
+FROM nodes n
+JOIN change_hunks h ON h.file = n.file AND h.start_line <= n.end_line AND h.end_line >= n.line
+WHERE n.kind IN ('function', 'type_decl')
+GROUP BY n.id;
+
+-- Blast radius: transitive callers of changed functions at their shortest
+-- call depth (up to 8, like /impact), other than the function itself when
+-- it is recursive
+WITH RECURSIVE callers(changed_id, id, depth) AS (
+  SELECT node_id, node_id, 0 FROM change_set WHERE kind = 'function'
+  UNION
+  SELECT c.changed_id, e.source, c.depth + 1
+  FROM callers c
+  JOIN edges e ON e.target = c.id AND e.kind = 'call'
+  WHERE c.depth < 8
+)
+INSERT INTO change_impact
+SELECT changed_id, id, MIN(depth) FROM callers
+WHERE depth > 0 AND id != changed_id
+GROUP BY changed_id, id;
+
+INSERT INTO change_packages
+SELECT package, SUM(changed), SUM(impacted) FROM (
+  SELECT package, 1 AS changed, 0 AS impacted FROM change_set
+  UNION ALL
+  SELECT n.package, 0, 1
+  FROM (SELECT DISTINCT function_id FROM change_impact) ci
+  JOIN nodes n ON n.id = ci.function_id
+)
+WHERE package IS NOT NULL
+GROUP BY package;
+
+-- Findings the change introduced in changed code: new since the base, and
+-- in changed lines or on changed symbols
+CREATE VIEW v_changed_code_findings AS
+SELECT f.*
+FROM findings f
+JOIN change_new_findings nf ON nf.finding_id = f.id
+WHERE f.node_id IN (SELECT node_id FROM change_set)
+   OR EXISTS (SELECT 1 FROM change_hunks h
+              WHERE h.file = f.file AND h.lines > 0 AND f.line BETWEEN h.start_line AND h.end_line);
+
+INSERT INTO queries (name, description, sql) VALUES
+  ('blast_radius', 'Changed functions and types since the -changed-since base with their transitive caller and package counts',
+   'SELECT cs.node_id, cs.kind, cs.name, cs.package, cs.change, cs.changed_lines, COUNT(ci.function_id) AS callers, COUNT(DISTINCT n.package) AS caller_packages, MAX(ci.depth) AS max_depth FROM change_set cs LEFT JOIN change_impact ci ON ci.changed_id = cs.node_id LEFT JOIN nodes n ON n.id = ci.function_id GROUP BY cs.node_id ORDER BY callers DESC');
+
+INSERT INTO schema_docs (category, name, description, example) VALUES
+('table', 'change_hunks', 'Go hunks changed between the -changed-since base and the working tree, untracked files as fully added (new-side lines; lines = 0 for pure removals)', 'SELECT * FROM change_hunks ORDER BY file, start_line'),
+('table', 'change_set', 'Function and type_decl nodes touched by changed hunks: change is added (all lines changed) or modified', 'SELECT * FROM change_set ORDER BY package, file, line'),
+('table', 'change_impact', 'Blast radius: transitive callers (call edges, up to depth 8) of each changed function, at the shortest depth', 'SELECT ci.depth, n.name, n.package FROM change_impact ci JOIN nodes n ON n.id = ci.function_id ORDER BY ci.depth'),
+('table', 'change_packages', 'Packages with changed symbols or callers of changed functions', 'SELECT * FROM change_packages ORDER BY impacted_functions DESC'),
+('table', 'change_new_findings', 'Findings the base CPG (-base-db, or generated at the -changed-since base) has no match for, by category, message and enclosing function symbol as in cpg-diff', 'SELECT f.* FROM change_new_findings nf JOIN findings f ON f.id = nf.finding_id'),
+('view', 'v_changed_code_findings', 'Findings the change introduced in changed code: new since the base, and on changed symbols or in changed lines', 'SELECT category, COUNT(*) FROM v_changed_code_findings GROUP BY category'),
+('query', 'blast_radius', 'Per changed symbol: transitive callers and the packages they are in', NULL);
+`
+	if err := sqlitex.ExecuteScript(conn, script, nil); err != nil {
+		return fmt.Errorf("change set: %w", err)
+	}
+
+	var funcs, types, callers, pkgs, findings int
+	if err := sqlitex.ExecuteTransient(conn,
+		`SELECT
+		   (SELECT COUNT(*) FROM change_set WHERE kind = 'function'),
+		   (SELECT COUNT(*) FROM change_set WHERE kind = 'type_decl'),
+		   (SELECT COUNT(DISTINCT function_id) FROM change_impact),
+		   (SELECT COUNT(*) FROM change_packages),
+		   (SELECT COUNT(*) FROM v_changed_code_findings)`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			funcs, types, callers = stmt.ColumnInt(0), stmt.ColumnInt(1), stmt.ColumnInt(2)
+			pkgs, findings = stmt.ColumnInt(3), stmt.ColumnInt(4)
+			return nil
+		}}); err != nil {
+		return fmt.Errorf("count change set: %w", err)
+	}
+
+	prog.Log("Change set since %s: %d functions and %d types changed in %d hunks, %d transitive callers, %d packages affected, %d new findings (%d in changed code)",
+		history.Base, funcs, types, len(history.Hunks), callers, pkgs, len(newIDs), findings)
+	return nil
+}
+
+// createTaintFlowStates materializes taint propagation from annotated taint
+// sources with the IFDS solver in taint.go. Each reachable node gets a label:
 // source, propagated, sanitized, or sink_reached.
 func createTaintFlowStates(conn *sqlite.Conn, prog *Progress) error {
-	ddl := `
+	tables := `
 CREATE TABLE taint_flow_state (
     node_id TEXT NOT NULL,
     label TEXT NOT NULL,
@@ -3274,43 +4467,40 @@ CREATE TABLE taint_flow_state (
     min_hops INTEGER NOT NULL
 );
 
--- BFS through DFG from taint sources (bounded to 8 hops)
-INSERT INTO taint_flow_state (node_id, label, source_id, source_category, min_hops)
-WITH RECURSIVE taint_reach(node_id, source_id, source_category, hop) AS (
-    -- Seed: call nodes annotated as taint sources
-    SELECT np.node_id, np.node_id, COALESCE(cat.value, 'unknown'), 0
-    FROM node_properties np
-    LEFT JOIN node_properties cat ON cat.node_id = np.node_id AND cat.key = 'taint_category'
-    WHERE np.key = 'taint_role' AND np.value = 'source'
-
-    UNION
+-- IFDS function summaries: taint entering a parameter reaches a return or sink
+CREATE TABLE taint_summaries (
+    function_id TEXT NOT NULL,
+    param_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    target_id TEXT NOT NULL,
+    hops INTEGER NOT NULL
+);
 
-    -- Follow DFG edges outward
-    SELECT e.target, tr.source_id, tr.source_category, tr.hop + 1
-    FROM taint_reach tr
-    JOIN edges e ON e.source = tr.node_id AND e.kind = 'dfg'
-    WHERE tr.hop < 8
-)
-SELECT
-  node_id,
-  CASE
-    WHEN EXISTS (SELECT 1 FROM node_properties p
-                 WHERE p.node_id = tr.node_id AND p.key = 'taint_role' AND p.value = 'source')
-      THEN 'source'
-    WHEN EXISTS (SELECT 1 FROM node_properties p
-                 WHERE p.node_id = tr.node_id AND p.key = 'taint_role' AND p.value = 'barrier')
-      THEN 'sanitized'
-    WHEN EXISTS (SELECT 1 FROM node_properties p
-                 WHERE p.node_id = tr.node_id AND p.key = 'taint_role' AND p.value = 'sink')
-      THEN 'sink_reached'
-    ELSE 'propagated'
-  END,
-  source_id, source_category, MIN(hop)
-FROM taint_reach tr
-GROUP BY node_id, source_id;
+-- Witness paths for taint findings, one row per step; edge_kind is the edge
+-- from the previous step (dfg, param_in or param_out), NULL for the source
+CREATE TABLE taint_paths (
+    finding_id INTEGER NOT NULL,
+    path_id INTEGER NOT NULL,
+    step INTEGER NOT NULL,
+    node_id TEXT NOT NULL,
+    edge_kind TEXT,
+    file TEXT,
+    line INTEGER,
+    PRIMARY KEY (finding_id, path_id, step)
+);
+`
+	if err := sqlitex.ExecuteScript(conn, tables, nil); err != nil {
+		return fmt.Errorf("taint flow tables: %w", err)
+	}
+	solver, summaries, err := solveTaint(conn)
+	if err != nil {
+		return fmt.Errorf("taint solver: %w", err)
+	}
 
+	ddl := `
 CREATE INDEX idx_taint_flow_node ON taint_flow_state(node_id);
 CREATE INDEX idx_taint_flow_label ON taint_flow_state(label);
+CREATE INDEX idx_taint_summaries_fn ON taint_summaries(function_id);
 
 -- Findings: unsanitized taint reaching sinks
 INSERT INTO findings (category, severity, node_id, file, line, message, details)
@@ -3331,7 +4521,9 @@ GROUP BY label, source_category
 ORDER BY node_count DESC;
 
 INSERT INTO schema_docs (category, name, description, example) VALUES
-('table', 'taint_flow_state', 'Materialized taint propagation via DFG from sources (8-hop BFS)', 'SELECT * FROM taint_flow_state WHERE label = ''sink_reached'''),
+('table', 'taint_flow_state', 'Context-sensitive taint propagation from sources (IFDS over dfg, param_in, param_out, and may_alias when points-to ran)', 'SELECT * FROM taint_flow_state WHERE label = ''sink_reached'''),
+('table', 'taint_summaries', 'Per-function taint summaries: parameter reaches a return or sink', 'SELECT * FROM taint_summaries WHERE kind = ''sink'''),
+('table', 'taint_paths', 'Step-by-step witness paths (source→sink) for unsanitized_sink and taint_hotspot findings', 'SELECT * FROM taint_paths WHERE finding_id = 1 ORDER BY path_id, step'),
 ('view', 'v_taint_summary', 'Taint flow distribution by label and source category', 'SELECT * FROM v_taint_summary');
 
 INSERT INTO queries (name, description, sql) VALUES
@@ -3354,7 +4546,12 @@ INSERT INTO queries (name, description, sql) VALUES
 			return nil
 		}})
 
-	prog.Log("Taint flow: %d reachable nodes, %d unsanitized sink reaches", totalStates, sinkReached)
+	paths, err := writeTaintPaths(conn, solver)
+	if err != nil {
+		return fmt.Errorf("taint paths: %w", err)
+	}
+
+	prog.Log("Taint flow: %d reachable nodes, %d unsanitized sink reaches, %d function summaries, %d witness paths", totalStates, sinkReached, summaries, paths)
 	return nil
 }
 
@@ -3450,10 +4647,10 @@ CREATE TABLE scip_symbols (
     display_name TEXT
 );
 
--- Functions: scip-go gomod github.com/prometheus/prometheus v0 package/name().
+-- Functions: scip-go gomod <module> v0 package/name().
 INSERT INTO scip_symbols (node_id, scip_id, kind, package, display_name)
 SELECT n.id,
-  'scip-go gomod github.com/prometheus/prometheus v0 ' ||
+  '{{SCIP_PREFIX}}' ||
   REPLACE(n.package, '/', '.') || '/' || n.name || '().',
   'function', n.package, n.name
 FROM nodes n
@@ -3461,10 +4658,10 @@ WHERE n.kind = 'function'
   AND n.name NOT LIKE '%.%'
   AND n.package IS NOT NULL AND n.name != '';
 
--- Methods: scip-go gomod github.com/prometheus/prometheus v0 package/Type#Method().
+-- Methods: scip-go gomod <module> v0 package/Type#Method().
 INSERT INTO scip_symbols (node_id, scip_id, kind, package, display_name)
 SELECT n.id,
-  'scip-go gomod github.com/prometheus/prometheus v0 ' ||
+  '{{SCIP_PREFIX}}' ||
   REPLACE(n.package, '/', '.') || '/' ||
   REPLACE(REPLACE(SUBSTR(n.name, 1, INSTR(n.name, '.') - 1), '(*', ''), ')', '') ||
   '#' || SUBSTR(n.name, INSTR(n.name, '.') + 1) || '().',
@@ -3474,20 +4671,20 @@ WHERE n.kind = 'function'
   AND n.name LIKE '%.%'
   AND n.package IS NOT NULL;
 
--- Types: scip-go gomod github.com/prometheus/prometheus v0 package/TypeName#
+-- Types: scip-go gomod <module> v0 package/TypeName#
 INSERT OR IGNORE INTO scip_symbols (node_id, scip_id, kind, package, display_name)
 SELECT n.id,
-  'scip-go gomod github.com/prometheus/prometheus v0 ' ||
+  '{{SCIP_PREFIX}}' ||
   REPLACE(n.package, '/', '.') || '/' || n.name || '#',
   'type', n.package, n.name
 FROM nodes n
 WHERE n.kind = 'type_decl'
   AND n.package IS NOT NULL AND n.name != '';
 
--- Packages: scip-go gomod github.com/prometheus/prometheus v0 package/
+-- Packages: scip-go gomod <module> v0 package/
 INSERT OR IGNORE INTO scip_symbols (node_id, scip_id, kind, package, display_name)
 SELECT n.id,
-  'scip-go gomod github.com/prometheus/prometheus v0 ' ||
+  '{{SCIP_PREFIX}}' ||
   REPLACE(n.package, '/', '.') || '/',
   'package', n.package, n.name
 FROM nodes n
@@ -3504,6 +4701,9 @@ INSERT INTO queries (name, description, sql) VALUES
 ('scip_lookup', 'Look up SCIP symbol for a node',
  'SELECT s.scip_id, s.kind, s.display_name, n.file, n.line FROM scip_symbols s JOIN nodes n ON n.id = s.node_id WHERE s.display_name LIKE ? ORDER BY s.kind, s.display_name');
 `
+	// Symbols are scoped to the primary module's import path.
+	scipPrefix := "scip-go gomod " + modSet.PrimaryModPath() + " v0 "
+	ddl = strings.ReplaceAll(ddl, "{{SCIP_PREFIX}}", strings.ReplaceAll(scipPrefix, "'", "''"))
 	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
 		return fmt.Errorf("scip symbols: %w", err)
 	}
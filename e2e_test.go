@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// The test binary runs as cpg-gen when this variable is set, so every
+// end-to-end run starts from fresh flags and package state.
+const runMainEnv = "CPG_GEN_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) != "" {
+		args := os.Args[1:]
+		for i, a := range args {
+			if a == "--" {
+				args = args[i+1:]
+				break
+			}
+		}
+		os.Args = append([]string{"cpg-gen"}, args...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fixtureModule copies testdata/fixture into a temporary module and returns
+// its directory. The fixture has no go.mod in the tree, so ./... patterns of
+// this repository do not pick it up.
+func fixtureModule(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	err := filepath.WalkDir("testdata/fixture", func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel("testdata/fixture", path)
+		writeFile(t, filepath.Join(dir, rel), string(content))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/fixture\n\ngo 1.25\n")
+	return dir
+}
+
+// runCPGGen runs cpg-gen with args plus the flags that keep end-to-end
+// tests fast (the compiler-driven phases run go build), and returns its
+// output.
+func runCPGGen(t *testing.T, args ...string) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("end-to-end run")
+	}
+	args = append([]string{"-skip-escape", "-skip-checks", "-skip-tests=false"}, args...)
+	cmd := exec.Command(os.Args[0], append([]string{"-test.run=^$", "--"}, args...)...)
+	// Module loading runs in workspace mode, which rejects -mod=mod.
+	cmd.Env = replaceEnv(append(os.Environ(), runMainEnv+"=1"), "GOFLAGS", "")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("cpg-gen %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return string(out)
+}
+
+// dumpDB returns every row of every table of a database, one line per row,
+// sorted within each table. Full-text index shadow tables are skipped; the
+// indexed text is in sources.
+func dumpDB(t *testing.T, path string) map[string][]string {
+	t.Helper()
+	conn, err := sqlite.OpenConn(path, sqlite.OpenReadOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	var tables []string
+	err = sqlitex.ExecuteTransient(conn,
+		`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+		   AND name NOT IN (SELECT name || suffix FROM sqlite_master,
+		     (SELECT '_data' AS suffix UNION ALL SELECT '_idx' UNION ALL SELECT '_content'
+		      UNION ALL SELECT '_docsize' UNION ALL SELECT '_config')
+		     WHERE sql LIKE 'CREATE VIRTUAL TABLE%')
+		 ORDER BY name`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			tables = append(tables, stmt.ColumnText(0))
+			return nil
+		}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dump := make(map[string][]string, len(tables))
+	for _, table := range tables {
+		var rows []string
+		err := sqlitex.ExecuteTransient(conn, fmt.Sprintf(`SELECT * FROM "%s" ORDER BY 1`, table),
+			&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+				cols := make([]string, stmt.ColumnCount())
+				for i := range cols {
+					cols[i] = stmt.ColumnText(i)
+				}
+				rows = append(rows, strings.Join(cols, "|"))
+				return nil
+			}})
+		if err != nil {
+			t.Fatalf("dump %s: %v", table, err)
+		}
+		slices.Sort(rows)
+		dump[table] = rows
+	}
+	return dump
+}
+
+// diffDumps reports the tables whose rows differ between two dumps, with
+// the first few differing rows of each.
+func diffDumps(t *testing.T, name1, name2 string, d1, d2 map[string][]string) {
+	t.Helper()
+	for table, rows1 := range d1 {
+		rows2, ok := d2[table]
+		if !ok {
+			t.Errorf("table %s only in %s", table, name1)
+			continue
+		}
+		if slices.Equal(rows1, rows2) {
+			continue
+		}
+		only1, only2 := rowsNotIn(rows1, rows2), rowsNotIn(rows2, rows1)
+		t.Errorf("table %s: %d rows in %s, %d in %s\nonly in %s: %q\nonly in %s: %q",
+			table, len(rows1), name1, len(rows2), name2,
+			name1, only1[:min(len(only1), 5)], name2, only2[:min(len(only2), 5)])
+	}
+	for table := range d2 {
+		if _, ok := d1[table]; !ok {
+			t.Errorf("table %s only in %s", table, name2)
+		}
+	}
+}
+
+// rowsNotIn returns the rows of a (sorted) missing from b (sorted),
+// counting duplicates.
+func rowsNotIn(a, b []string) []string {
+	var out []string
+	j := 0
+	for _, r := range a {
+		for j < len(b) && b[j] < r {
+			j++
+		}
+		if j < len(b) && b[j] == r {
+			j++
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// The output is the same for any worker count: shards are merged, lookups
+// included, in package and function order. Points-to runs too, as it
+// depends on which copy of a test variant's functions is analyzed.
+func TestJobsDeterministic(t *testing.T) {
+	dir := fixtureModule(t)
+	out1, out8 := filepath.Join(t.TempDir(), "jobs1.db"), filepath.Join(t.TempDir(), "jobs8.db")
+	runCPGGen(t, "-jobs=1", "-pointsto", dir, out1)
+	runCPGGen(t, "-jobs=8", "-pointsto", dir, out8)
+	diffDumps(t, "-jobs=1", "-jobs=8", dumpDB(t, out1), dumpDB(t, out8))
+}
+
+// Streaming writes the same database as the in-memory graph, side tables
+// included.
+func TestStreamEquivalent(t *testing.T) {
+	dir := fixtureModule(t)
+	mem, stream := filepath.Join(t.TempDir(), "mem.db"), filepath.Join(t.TempDir(), "stream.db")
+	args := []string{"-jobs=4", "-callgraph-compare=static,vta", "-configs=linux/amd64,windows/amd64"}
+	runCPGGen(t, append(args, dir, mem)...)
+	runCPGGen(t, append(args, "-stream", dir, stream)...)
+	diffDumps(t, "memory", "-stream", dumpDB(t, mem), dumpDB(t, stream))
+}
+
+// An incremental update skips the packages a change does not reach and
+// writes the same database as a full run over the changed tree.
+func TestIncrementalEquivalent(t *testing.T) {
+	dir := fixtureModule(t)
+	updated, full := filepath.Join(t.TempDir(), "updated.db"), filepath.Join(t.TempDir(), "full.db")
+	args := []string{"-jobs=4", "-verbose", "-incremental"}
+	runCPGGen(t, append(args, dir, updated)...)
+
+	// api and cmd/app, which imports it, change; store does not.
+	api := filepath.Join(dir, "api", "api.go")
+	src, err := os.ReadFile(api)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, api, strings.Replace(string(src), "// Server wraps a store.",
+		"// Version is the API version.\nconst Version = 2\n\n// Server wraps a store.", 1))
+
+	out := runCPGGen(t, append(args, dir, updated)...)
+	for _, pkg := range []string{"store", "store_test"} {
+		if !strings.Contains(out, "reused: example.com/fixture/"+pkg+"\n") {
+			t.Errorf("unchanged package %s was walked again:\n%s", pkg, out)
+		}
+	}
+	for _, pkg := range []string{"api", "cmd/app"} {
+		if strings.Contains(out, "reused: example.com/fixture/"+pkg+"\n") {
+			t.Errorf("changed package %s was not walked:\n%s", pkg, out)
+		}
+	}
+
+	runCPGGen(t, append(args, dir, full)...)
+	diffDumps(t, "incremental", "full", dumpDB(t, updated), dumpDB(t, full))
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// errorType is the predeclared error interface.
+var errorType = types.Universe.Lookup("error").Type()
+
+// uncheckedErrorExclusions are callees whose error result is ignored by
+// convention: printing to standard output and writing to in-memory
+// buffers, which never fail.
+var uncheckedErrorExclusions = map[string]bool{
+	"fmt.Print":                      true,
+	"fmt.Printf":                     true,
+	"fmt.Println":                    true,
+	"(*bytes.Buffer).Write":          true,
+	"(*bytes.Buffer).WriteByte":      true,
+	"(*bytes.Buffer).WriteRune":      true,
+	"(*bytes.Buffer).WriteString":    true,
+	"(*strings.Builder).Write":       true,
+	"(*strings.Builder).WriteByte":   true,
+	"(*strings.Builder).WriteRune":   true,
+	"(*strings.Builder).WriteString": true,
+	"(hash.Hash).Write":              true,
+	"(hash.Hash32).Write":            true,
+	"(hash.Hash64).Write":            true,
+}
+
+// callContext is the statement a call appears in: an expression statement,
+// or the right-hand side of an assignment or var declaration with the
+// given left-hand sides (one per result of the call).
+type callContext struct {
+	expr bool
+	lhs  []ast.Expr
+}
+
+// funcSyntax holds the call contexts of a function body, and the
+// variables it explicitly discards with "_ = v".
+type funcSyntax struct {
+	calls     map[token.Pos]callContext
+	discarded map[string]bool
+}
+
+// ExtractUncheckedErrors finds calls whose error results are discarded,
+// assigned to _ (directly or later with "_ = err"), or assigned and then
+// overwritten or dropped before any use, plus deferred Close calls and
+// Close calls dropped inside deferred closures, whose error is lost. An
+// error value is checked when any instruction uses it, directly or through
+// phi nodes; errors stored in variables that escape to the heap are not
+// followed.
+func ExtractUncheckedErrors(ssaResult *SSAResult, fset *token.FileSet, posLookup *PosLookup, funcLookup *FuncLookup, cpg *CPG, prog *Progress) {
+	prog.Log("Detecting unchecked errors...")
+
+	counts := make(map[string]int)
+	reused := incr.reusedFuncs(ssaResult, fset, funcLookup)
+	for _, fn := range ssaResult.KnownFuncs(fset) {
+		funcID := ssaFuncNodeID(fn, fset, funcLookup)
+		if funcID == "" || reused[funcID] {
+			continue
+		}
+		for _, u := range findUncheckedErrors(fn) {
+			p := fset.Position(u.common.Pos())
+			rel := modSet.RelFile(p.Filename)
+			if rel == "" {
+				continue
+			}
+			cpg.AddUncheckedError(UncheckedError{
+				Function:      funcID,
+				CallSite:      posLookup.Get(rel, p.Line, p.Column),
+				Callee:        calleeName(u.common),
+				Kind:          u.kind,
+				File:          rel,
+				Line:          p.Line,
+				Col:           p.Column,
+				ReachesReturn: u.reachesReturn,
+			})
+			counts[u.kind]++
+		}
+	}
+
+	prog.Log("Unchecked errors: %d discarded, %d assigned to _, %d overwritten, %d in deferred Close",
+		counts["discarded"], counts["blank"], counts["overwritten"], counts["deferred_close"])
+}
+
+// uncheckedCall is an unchecked error result of a call in one function.
+type uncheckedCall struct {
+	common        *ssa.CallCommon
+	kind          string
+	reachesReturn bool
+}
+
+// findUncheckedErrors returns the calls of fn with an unchecked error
+// result. reachesReturn is set when the function goes on to return another
+// error: the dropped error itself never reaches a return, since that would
+// use it.
+func findUncheckedErrors(fn *ssa.Function) []uncheckedCall {
+	// Close errors dropped inside a deferred closure are lost to the
+	// function deferring it
+	deferredBy := deferringCall(fn)
+
+	var results []uncheckedCall
+	var syntax *funcSyntax // built on first use
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			switch inst := instr.(type) {
+			case *ssa.Call:
+				common := inst.Common()
+				sig := common.Signature()
+				if sig == nil || uncheckedErrorExclusions[calleeName(common)] {
+					continue
+				}
+				for _, k := range errorResults(sig) {
+					if syntax == nil {
+						syntax = parseFuncSyntax(fn.Syntax())
+					}
+					kind := uncheckedKind(inst, k, sig.Results().Len(), syntax)
+					switch {
+					case kind == "":
+					case deferredBy != nil && calleeMethod(common) == "Close":
+						results = append(results, uncheckedCall{common, "deferred_close", returnsOtherError(deferredBy.Block())})
+					default:
+						results = append(results, uncheckedCall{common, kind, returnsOtherError(block)})
+					}
+				}
+			case *ssa.Defer:
+				sig := inst.Call.Signature()
+				if sig != nil && calleeMethod(&inst.Call) == "Close" && len(errorResults(sig)) > 0 {
+					results = append(results, uncheckedCall{&inst.Call, "deferred_close", returnsOtherError(block)})
+				}
+			}
+		}
+	}
+	return results
+}
+
+// uncheckedKind classifies result k of a call with n results, or returns
+// "" when the error is used.
+func uncheckedKind(call *ssa.Call, k, n int, syntax *funcSyntax) string {
+	var v ssa.Value = call
+	if n > 1 {
+		v = nil
+		for _, r := range *call.Referrers() {
+			if e, ok := r.(*ssa.Extract); ok && e.Index == k {
+				v = e
+				break
+			}
+		}
+	}
+	if v != nil && valueUsed(v, make(map[ssa.Value]bool)) {
+		return ""
+	}
+
+	c, ok := syntax.calls[call.Pos()]
+	switch {
+	case !ok:
+		return ""
+	case c.expr:
+		return "discarded"
+	}
+	var lhs ast.Expr
+	if n > 1 && k < len(c.lhs) {
+		lhs = c.lhs[k]
+	} else if n == 1 && len(c.lhs) == 1 {
+		lhs = c.lhs[0]
+	}
+	if lhs == nil {
+		return ""
+	}
+	if id, ok := lhs.(*ast.Ident); ok && (id.Name == "_" || syntax.discarded[id.Name]) {
+		return "blank"
+	}
+	return "overwritten"
+}
+
+// valueUsed reports whether an instruction other than a phi node uses v,
+// directly or through phi nodes.
+func valueUsed(v ssa.Value, seen map[ssa.Value]bool) bool {
+	seen[v] = true
+	refs := v.Referrers()
+	if refs == nil {
+		return false
+	}
+	for _, r := range *refs {
+		switch r := r.(type) {
+		case *ssa.DebugRef:
+			continue
+		case *ssa.Phi:
+			if !seen[r] && valueUsed(r, seen) {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// parseFuncSyntax maps the Lparen of each call that is a statement of its
+// own, or the right-hand side of an assignment or var declaration, to that
+// statement. Calls nested in other expressions are absent.
+func parseFuncSyntax(syntax ast.Node) *funcSyntax {
+	fs := &funcSyntax{
+		calls:     make(map[token.Pos]callContext),
+		discarded: make(map[string]bool),
+	}
+	if syntax == nil {
+		return fs
+	}
+	record := func(lhs []ast.Expr, rhs []ast.Expr) {
+		for i, e := range rhs {
+			call, ok := ast.Unparen(e).(*ast.CallExpr)
+			if !ok {
+				continue
+			}
+			switch {
+			case len(rhs) == 1:
+				fs.calls[call.Lparen] = callContext{lhs: lhs}
+			case i < len(lhs):
+				fs.calls[call.Lparen] = callContext{lhs: lhs[i : i+1]}
+			}
+		}
+	}
+	ast.Inspect(syntax, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ExprStmt:
+			if call, ok := ast.Unparen(n.X).(*ast.CallExpr); ok {
+				fs.calls[call.Lparen] = callContext{expr: true}
+			}
+		case *ast.AssignStmt:
+			record(n.Lhs, n.Rhs)
+			for i, l := range n.Lhs {
+				if id, ok := l.(*ast.Ident); ok && id.Name == "_" && i < len(n.Rhs) {
+					if v, ok := ast.Unparen(n.Rhs[i]).(*ast.Ident); ok {
+						fs.discarded[v.Name] = true
+					}
+				}
+			}
+		case *ast.ValueSpec:
+			lhs := make([]ast.Expr, len(n.Names))
+			for i, name := range n.Names {
+				lhs[i] = name
+			}
+			record(lhs, n.Values)
+		}
+		return true
+	})
+	return fs
+}
+
+// deferringCall returns the defer instruction that defers fn directly, if
+// fn is a closure ("defer func() { ... }()").
+func deferringCall(fn *ssa.Function) *ssa.Defer {
+	parent := fn.Parent()
+	if parent == nil {
+		return nil
+	}
+	for _, block := range parent.Blocks {
+		for _, instr := range block.Instrs {
+			d, ok := instr.(*ssa.Defer)
+			if !ok {
+				continue
+			}
+			switch v := d.Call.Value.(type) {
+			case *ssa.MakeClosure:
+				if v.Fn == fn {
+					return d
+				}
+			case *ssa.Function:
+				if v == fn {
+					return d
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// errorResults returns the indices of the results of sig of type error.
+func errorResults(sig *types.Signature) []int {
+	var idx []int
+	for i := range sig.Results().Len() {
+		if types.Identical(sig.Results().At(i).Type(), errorType) {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// returnsOtherError reports whether a return reachable from block returns
+// an error result of its function that is not the nil constant.
+func returnsOtherError(block *ssa.BasicBlock) bool {
+	errIdx := errorResults(block.Parent().Signature)
+	if len(errIdx) == 0 {
+		return false
+	}
+	seen := map[*ssa.BasicBlock]bool{block: true}
+	work := []*ssa.BasicBlock{block}
+	for len(work) > 0 {
+		b := work[len(work)-1]
+		work = work[:len(work)-1]
+		if n := len(b.Instrs); n > 0 {
+			if ret, ok := b.Instrs[n-1].(*ssa.Return); ok {
+				for _, k := range errIdx {
+					if k < len(ret.Results) && !isNilConst(returnedValue(ret, ret.Results[k])) {
+						return true
+					}
+				}
+			}
+		}
+		for _, s := range b.Succs {
+			if !seen[s] {
+				seen[s] = true
+				work = append(work, s)
+			}
+		}
+	}
+	return false
+}
+
+// returnedValue looks through the result slot a function with defers
+// returns from: "*slot = v; rundefers; t = *slot; return t" returns v.
+// Named results assigned elsewhere are returned as the load itself.
+func returnedValue(ret *ssa.Return, v ssa.Value) ssa.Value {
+	load, ok := v.(*ssa.UnOp)
+	if !ok || load.Op != token.MUL {
+		return v
+	}
+	instrs := ret.Block().Instrs
+	for i := len(instrs) - 1; i >= 0; i-- {
+		if st, ok := instrs[i].(*ssa.Store); ok && st.Addr == load.X {
+			return st.Val
+		}
+	}
+	return v
+}
+
+func isNilConst(v ssa.Value) bool {
+	c, ok := v.(*ssa.Const)
+	return ok && c.IsNil()
+}
+
+// calleeName names the function a call invokes: its full name for static
+// calls, "(T).Method" for interface method calls.
+func calleeName(common *ssa.CallCommon) string {
+	if common.IsInvoke() {
+		return fmt.Sprintf("(%s).%s", types.TypeString(common.Value.Type(), nil), common.Method.Name())
+	}
+	if fn := common.StaticCallee(); fn != nil {
+		return fn.String()
+	}
+	return "dynamic call"
+}
+
+// calleeMethod returns the bare name of the called function or method.
+func calleeMethod(common *ssa.CallCommon) string {
+	if common.IsInvoke() {
+		return common.Method.Name()
+	}
+	if fn := common.StaticCallee(); fn != nil {
+		return fn.Name()
+	}
+	return ""
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"slices"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+const errcheckSrc = `package a
+
+type File struct{}
+
+func (*File) Close() error { return nil }
+
+func open() (*File, error) { return nil, nil }
+func do() error            { return nil }
+func flag() bool           { return false }
+
+func noError() { do() }
+
+func discardedNil() error {
+	do()
+	return nil
+}
+
+func discardedOther() error {
+	do()
+	return do()
+}
+
+func blankLater() error {
+	err := do()
+	_ = err
+	if flag() {
+		return nil
+	}
+	return nil
+}
+
+func overwritten() error {
+	err := do()
+	err = do()
+	return err
+}
+
+func checked() error {
+	if err := do(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func deferredClose() error {
+	f, err := open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return nil
+}
+
+func deferredCloseOther() error {
+	f, _ := open()
+	defer f.Close()
+	return do()
+}
+
+func closure() error {
+	f, err := open()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		f.Close()
+	}()
+	return nil
+}
+`
+
+// buildErrcheckSSA builds errcheckSrc and returns its functions, closures
+// included, by name.
+func buildErrcheckSSA(t *testing.T) map[string]*ssa.Function {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "a.go", errcheckSrc, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, _, err := ssautil.BuildPackage(&types.Config{}, fset, types.NewPackage("a", "a"), []*ast.File{f}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	funcs := make(map[string]*ssa.Function)
+	var add func(fn *ssa.Function)
+	add = func(fn *ssa.Function) {
+		funcs[fn.Name()] = fn
+		for _, anon := range fn.AnonFuncs {
+			add(anon)
+		}
+	}
+	for _, m := range pkg.Members {
+		if fn, ok := m.(*ssa.Function); ok {
+			add(fn)
+		}
+	}
+	return funcs
+}
+
+func TestFindUncheckedErrors(t *testing.T) {
+	funcs := buildErrcheckSSA(t)
+	tests := []struct {
+		fn   string
+		want []string // "callee kind reaches_return"
+	}{
+		{"noError", []string{"a.do discarded false"}},
+		{"discardedNil", []string{"a.do discarded false"}},
+		{"discardedOther", []string{"a.do discarded true"}},
+		{"blankLater", []string{"a.do blank false"}},
+		{"overwritten", []string{"a.do overwritten true"}},
+		{"checked", nil},
+		{"deferredClose", []string{"(*a.File).Close deferred_close false"}},
+		{"deferredCloseOther", []string{"a.open blank true", "(*a.File).Close deferred_close true"}},
+		{"closure$1", []string{"(*a.File).Close deferred_close false"}},
+	}
+	for _, tt := range tests {
+		fn := funcs[tt.fn]
+		if fn == nil {
+			t.Errorf("%s: not built", tt.fn)
+			continue
+		}
+		var got []string
+		for _, u := range findUncheckedErrors(fn) {
+			got = append(got, fmt.Sprintf("%s %s %v", calleeName(u.common), u.kind, u.reachesReturn))
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: unchecked = %q, want %q", tt.fn, got, tt.want)
+		}
+	}
+}
+
+func TestParseFuncSyntax(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "a.go", errcheckSrc, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fn *ast.FuncDecl
+	for _, d := range f.Decls {
+		if d, ok := d.(*ast.FuncDecl); ok && d.Name.Name == "blankLater" {
+			fn = d
+		}
+	}
+	fs := parseFuncSyntax(fn)
+	if !fs.discarded["err"] {
+		t.Error(`"_ = err" not recorded as discarding err`)
+	}
+
+	var calls []string
+	for _, c := range fs.calls {
+		switch {
+		case c.expr:
+			calls = append(calls, "expr")
+		case len(c.lhs) == 1:
+			calls = append(calls, "assign "+c.lhs[0].(*ast.Ident).Name)
+		}
+	}
+	slices.Sort(calls)
+	// "if flag()" is a condition, not a statement of its own
+	if want := []string{"assign err"}; !slices.Equal(calls, want) {
+		t.Errorf("calls = %q, want %q", calls, want)
+	}
+}
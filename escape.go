@@ -9,19 +9,38 @@ import (
 	"strings"
 )
 
-// EscapeResult holds one escape analysis annotation from the Go compiler.
+// EscapeResult holds one escape analysis or inlining annotation from the Go
+// compiler.
 type EscapeResult struct {
 	RelFile string
 	Line    int
 	Col     int
-	Kind    string // "leaking_param", "moved_to_heap", "does_not_escape", "inlineable"
-	Detail  string // variable or function name
+	Kind    string // "leaking_param", "moved_to_heap", "escapes_to_heap", "does_not_escape", "inlineable", "not_inlineable", "inlined_call", "explanation"
+	Detail  string // variable or function name; for not_inlineable the reason, for explanation its summary
+	NodeID  string // CPG node at the position, set by ResolveEscapes
+
+	Cost   int          // inlining cost, -1 if not reported
+	Budget int          // inlining budget, -1 if not reported (only for functions over it)
+	Steps  []EscapeStep // for "explanation": the flow chain, in compiler order
 }
 
-// RunEscapeAnalysis runs `go build -gcflags=-m` on each module directory
-// and parses the compiler's escape analysis decisions.
+// EscapeStep is one "from" line of a -m=2 escape explanation: Expr flows
+// along Flow ("dst ← src") because of Reason, at the given position.
+type EscapeStep struct {
+	Flow    string
+	Expr    string
+	Reason  string
+	RelFile string
+	Line    int
+	Col     int
+	NodeID  string // set by ResolveEscapes
+}
+
+// RunEscapeAnalysis runs `go build -gcflags=-m=2` on each module directory
+// and parses the compiler's escape analysis and inlining decisions,
+// including the flow chains explaining why values escape.
 func RunEscapeAnalysis(prog *Progress) []EscapeResult {
-	prog.Log("Running Go escape analysis (-gcflags=-m) across %d modules...", len(modSet.Dirs()))
+	prog.Log("Running Go escape analysis (-gcflags=-m=2) across %d modules...", len(modSet.Dirs()))
 
 	var allResults []EscapeResult
 
@@ -34,10 +53,55 @@ func RunEscapeAnalysis(prog *Progress) []EscapeResult {
 	return allResults
 }
 
+// ResolveEscapes sets the NodeID of each result from the position lookups
+// of the build configuration the compiler ran in. Inlining decisions about
+// functions resolve to the function at the reported position or, failing
+// that, to the function starting on that line: for methods the compiler
+// reports the receiver's "(", where no node starts. Decisions about
+// variables (leaking and moved-to-heap parameters and locals) resolve to
+// the declaring node; the others to the node starting at the reported
+// position. Explanation steps resolve to the node at their "at" position.
+func ResolveEscapes(results []EscapeResult, posLookup *PosLookup, funcLookup *FuncLookup, prog *Progress) {
+	resolved := 0
+	for i := range results {
+		r := &results[i]
+		switch r.Kind {
+		case "inlineable", "not_inlineable":
+			r.NodeID = funcLookup.Get(r.RelFile, r.Line, r.Col)
+			if r.NodeID == "" {
+				r.NodeID = funcLookup.GetLine(r.RelFile, r.Line)
+			}
+		default:
+			r.NodeID = posLookup.GetDecl(r.RelFile, r.Line, r.Col)
+			if r.NodeID == "" {
+				r.NodeID = posLookup.Get(r.RelFile, r.Line, r.Col)
+			}
+		}
+		if r.NodeID != "" {
+			resolved++
+		}
+		for j := range r.Steps {
+			st := &r.Steps[j]
+			st.NodeID = posLookup.Get(st.RelFile, st.Line, st.Col)
+		}
+	}
+	prog.Log("Escape analysis: resolved %d of %d annotations to nodes", resolved, len(results))
+}
+
+var (
+	escapeLineRe = regexp.MustCompile(`^(?:\./)?([^:]+):(\d+):(\d+): (.+)$`)
+	escapeFromRe = regexp.MustCompile(`^from (.+) \(([^()]+)\) at (?:\./)?([^:]+):(\d+):(\d+)$`)
+	inlineCostRe = regexp.MustCompile(`cost (\d+)(?: exceeds budget (\d+))?`)
+)
+
 func runEscapeForDir(dir, prefix string, prog *Progress) []EscapeResult {
-	cmd := exec.Command("go", "build", "-gcflags=-m", "./...")
+	args := []string{"build", "-gcflags=-m=2"}
+	if tags := effectiveBuildTags(); len(tags) > 0 {
+		args = append(args, "-tags="+strings.Join(tags, ","))
+	}
+	cmd := exec.Command("go", append(args, "./...")...)
 	cmd.Dir = dir
-	cmd.Env = replaceEnv(os.Environ(), "GOFLAGS", "-buildvcs=false")
+	cmd.Env = goCommandEnv(replaceEnv(os.Environ(), "GOFLAGS", "-buildvcs=false"))
 	cmd.Stdout = nil // discard
 
 	stderrPipe, err := cmd.StderrPipe()
@@ -50,9 +114,21 @@ func runEscapeForDir(dir, prefix string, prog *Progress) []EscapeResult {
 		return nil
 	}
 
-	lineRe := regexp.MustCompile(`^(?:\./)?([^:]+):(\d+):(\d+): (.+)$`)
+	// Prefix the file path for non-primary modules
+	relFile := func(file string) string {
+		if prefix != "" {
+			return prefix + "/" + file
+		}
+		return file
+	}
 
 	var results []EscapeResult
+	// Index in results of the explanation being read: -m=2 prints a
+	// summary ending in ":" followed by indented "flow:" and "from" lines
+	// at the same position.
+	explain := -1
+	var flow string
+
 	scanner := bufio.NewScanner(stderrPipe)
 	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
 
@@ -61,56 +137,101 @@ func runEscapeForDir(dir, prefix string, prog *Progress) []EscapeResult {
 		if strings.HasPrefix(text, "#") || strings.HasPrefix(text, "/") {
 			continue
 		}
-		m := lineRe.FindStringSubmatch(text)
+		m := escapeLineRe.FindStringSubmatch(text)
 		if m == nil {
 			continue
 		}
 
-		file := m[1]
+		file := relFile(m[1])
 		line, _ := strconv.Atoi(m[2])
 		col, _ := strconv.Atoi(m[3])
 		msg := m[4]
 
-		var kind, detail string
-		switch {
-		case strings.Contains(msg, "leaking param:"):
-			kind = "leaking_param"
-			if idx := strings.Index(msg, "leaking param:"); idx >= 0 {
-				detail = strings.TrimSpace(msg[idx+len("leaking param:"):])
-			}
-		case strings.Contains(msg, "moved to heap:"):
-			kind = "moved_to_heap"
-			if idx := strings.Index(msg, "moved to heap:"); idx >= 0 {
-				detail = strings.TrimSpace(msg[idx+len("moved to heap:"):])
+		if trimmed := strings.TrimSpace(msg); trimmed != msg && explain >= 0 {
+			e := &results[explain]
+			if e.RelFile == file && e.Line == line && e.Col == col {
+				switch {
+				case strings.HasPrefix(trimmed, "flow: "):
+					flow = strings.TrimSuffix(strings.TrimPrefix(trimmed, "flow: "), ":")
+				case strings.HasPrefix(trimmed, "from "):
+					if fm := escapeFromRe.FindStringSubmatch(trimmed); fm != nil {
+						fl, _ := strconv.Atoi(fm[4])
+						fc, _ := strconv.Atoi(fm[5])
+						e.Steps = append(e.Steps, EscapeStep{
+							Flow:    flow,
+							Expr:    fm[1],
+							Reason:  fm[2],
+							RelFile: relFile(fm[3]),
+							Line:    fl,
+							Col:     fc,
+						})
+					}
+				}
+				continue
 			}
-		case strings.Contains(msg, "escapes to heap"):
-			kind = "escapes_to_heap"
-			detail = strings.TrimSuffix(strings.TrimSpace(msg), " escapes to heap")
-		case strings.Contains(msg, "does not escape"):
-			kind = "does_not_escape"
-			detail = strings.TrimSuffix(strings.TrimSpace(msg), " does not escape")
-		case strings.HasPrefix(msg, "can inline "):
-			kind = "inlineable"
-			detail = strings.TrimPrefix(msg, "can inline ")
-		default:
-			continue
 		}
+		explain, flow = -1, ""
 
-		// Prefix the file path for non-primary modules
-		relFile := file
-		if prefix != "" {
-			relFile = prefix + "/" + file
+		r := EscapeResult{RelFile: file, Line: line, Col: col, Cost: -1, Budget: -1}
+		if !classifyEscapeMessage(&r, msg) {
+			continue
 		}
-
-		results = append(results, EscapeResult{
-			RelFile: relFile,
-			Line:    line,
-			Col:     col,
-			Kind:    kind,
-			Detail:  detail,
-		})
+		if r.Kind == "explanation" {
+			explain = len(results)
+		}
+		results = append(results, r)
 	}
 
 	_ = cmd.Wait()
 	return results
 }
+
+// classifyEscapeMessage fills Kind, Detail and the inlining cost and budget
+// of r from one compiler message, reporting false for messages that are not
+// escape or inlining decisions. Function names keep the compiler's form, so
+// methods read "(*T).Get" and closures "F.func1".
+func classifyEscapeMessage(r *EscapeResult, msg string) bool {
+	switch {
+	case strings.HasSuffix(msg, ":") &&
+		(strings.Contains(msg, " escapes to heap in ") || strings.HasPrefix(msg, "parameter ") && strings.Contains(msg, " leaks to ")):
+		r.Kind = "explanation"
+		r.Detail = strings.TrimSuffix(msg, ":")
+	case strings.Contains(msg, "leaking param:"):
+		r.Kind = "leaking_param"
+		if idx := strings.Index(msg, "leaking param:"); idx >= 0 {
+			r.Detail = strings.TrimSpace(msg[idx+len("leaking param:"):])
+		}
+	case strings.Contains(msg, "moved to heap:"):
+		r.Kind = "moved_to_heap"
+		if idx := strings.Index(msg, "moved to heap:"); idx >= 0 {
+			r.Detail = strings.TrimSpace(msg[idx+len("moved to heap:"):])
+		}
+	case strings.Contains(msg, "escapes to heap"):
+		r.Kind = "escapes_to_heap"
+		r.Detail = strings.TrimSuffix(strings.TrimSpace(msg), " escapes to heap")
+	case strings.Contains(msg, "does not escape"):
+		r.Kind = "does_not_escape"
+		r.Detail = strings.TrimSuffix(strings.TrimSpace(msg), " does not escape")
+	case strings.HasPrefix(msg, "can inline "):
+		// can inline F with cost 3 as: func() { ... }
+		r.Kind = "inlineable"
+		r.Detail, _, _ = strings.Cut(strings.TrimPrefix(msg, "can inline "), " with cost ")
+		if cm := inlineCostRe.FindStringSubmatch(msg); cm != nil {
+			r.Cost, _ = strconv.Atoi(cm[1])
+		}
+	case strings.HasPrefix(msg, "cannot inline "):
+		// cannot inline F: function too complex: cost 197 exceeds budget 80
+		r.Kind = "not_inlineable"
+		_, r.Detail, _ = strings.Cut(msg, ": ")
+		if cm := inlineCostRe.FindStringSubmatch(r.Detail); cm != nil && cm[2] != "" {
+			r.Cost, _ = strconv.Atoi(cm[1])
+			r.Budget, _ = strconv.Atoi(cm[2])
+		}
+	case strings.HasPrefix(msg, "inlining call to "):
+		r.Kind = "inlined_call"
+		r.Detail = strings.TrimPrefix(msg, "inlining call to ")
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+func TestResolveEscapesMethods(t *testing.T) {
+	// a.go:
+	//   5: func Get() int { return 1 }
+	//   7: func (t *T) Get() int { return t.n }
+	//   9: func (t *T) Run() { go func() { t.n++ }() }
+	//  11: x := 1
+	posLookup, funcLookup := NewPosLookup(), NewFuncLookup()
+	funcLookup.Set("a.go", 5, 1, "fn:Get")
+	funcLookup.Set("a.go", 5, 6, "fn:Get")
+	posLookup.Set("a.go", 5, 6, "fn:Get")
+	funcLookup.Set("a.go", 7, 1, "fn:T.Get")
+	funcLookup.Set("a.go", 7, 13, "fn:T.Get")
+	posLookup.Set("a.go", 7, 8, "param:t")
+	posLookup.SetDecl("a.go", 7, 7, "param:t")
+	funcLookup.Set("a.go", 9, 1, "fn:T.Run")
+	funcLookup.Set("a.go", 9, 13, "fn:T.Run")
+	funcLookup.Set("a.go", 9, 25, "fn:lit")
+	posLookup.SetDecl("a.go", 11, 2, "local:x")
+
+	results := []EscapeResult{
+		{RelFile: "a.go", Line: 5, Col: 6, Kind: "inlineable", Detail: "Get"},
+		{RelFile: "a.go", Line: 7, Col: 6, Kind: "inlineable", Detail: "(*T).Get"},
+		{RelFile: "a.go", Line: 9, Col: 6, Kind: "not_inlineable", Detail: "unhandled op GO"},
+		{RelFile: "a.go", Line: 9, Col: 25, Kind: "inlineable", Detail: "(*T).Run.func1"},
+		{RelFile: "a.go", Line: 7, Col: 7, Kind: "leaking_param", Detail: "t"},
+		{RelFile: "a.go", Line: 11, Col: 2, Kind: "moved_to_heap", Detail: "x"},
+		{RelFile: "a.go", Line: 3, Col: 6, Kind: "inlineable", Detail: "gone"},
+	}
+	ResolveEscapes(results, posLookup, funcLookup, NewProgress(false))
+
+	want := []string{"fn:Get", "fn:T.Get", "fn:T.Run", "fn:lit", "param:t", "local:x", ""}
+	for i, r := range results {
+		if r.NodeID != want[i] {
+			t.Errorf("%s %s at %d:%d resolved to %q, want %q", r.Kind, r.Detail, r.Line, r.Col, r.NodeID, want[i])
+		}
+	}
+}
+
+func TestFuncLookupGetLine(t *testing.T) {
+	fl := NewFuncLookup()
+	fl.Set("a.go", 3, 1, "decl")
+	fl.Set("a.go", 3, 20, "lit")
+	if got := fl.GetLine("a.go", 3); got != "decl" {
+		t.Errorf("GetLine = %q, want the declaration registered first", got)
+	}
+	if got := fl.GetLine("b.go", 3); got != "" {
+		t.Errorf("GetLine in another file = %q, want none", got)
+	}
+}
+
+func TestClassifyEscapeMessageInlining(t *testing.T) {
+	tests := []struct {
+		msg          string
+		kind, detail string
+		cost, budget int
+	}{
+		{"can inline Get with cost 4 as: func() int { return 1 }", "inlineable", "Get", 4, -1},
+		{"can inline (*T).Get with cost 7 as: method(t *T) func() int { return t.n }", "inlineable", "(*T).Get", 7, -1},
+		{"can inline (*T).Run.func1 with cost 12 as: func() { t.n++ }", "inlineable", "(*T).Run.func1", 12, -1},
+		{"cannot inline (*T).Big: function too complex: cost 197 exceeds budget 80", "not_inlineable", "function too complex: cost 197 exceeds budget 80", 197, 80},
+		{"cannot inline (*T).Run: unhandled op GO", "not_inlineable", "unhandled op GO", -1, -1},
+		{"inlining call to (*T).Get", "inlined_call", "(*T).Get", -1, -1},
+	}
+	for _, tt := range tests {
+		r := EscapeResult{Cost: -1, Budget: -1}
+		if !classifyEscapeMessage(&r, tt.msg) {
+			t.Errorf("%q: not classified", tt.msg)
+			continue
+		}
+		if r.Kind != tt.kind || r.Detail != tt.detail || r.Cost != tt.cost || r.Budget != tt.budget {
+			t.Errorf("%q: got kind %q detail %q cost %d budget %d, want %q %q %d %d",
+				tt.msg, r.Kind, r.Detail, r.Cost, r.Budget, tt.kind, tt.detail, tt.cost, tt.budget)
+		}
+	}
+	if classifyEscapeMessage(&EscapeResult{}, "some other diagnostic") {
+		t.Error("unrelated message classified")
+	}
+}
+
+// Inlining costs reported at a method's receiver reach the method's metrics.
+func TestApplyEscapeAnalysisMethodInlineCost(t *testing.T) {
+	conn, err := openDB(filepath.Join(t.TempDir(), "cpg.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	err = sqlitex.ExecuteScript(conn, `
+INSERT INTO nodes (id, kind, name, file, line, col) VALUES
+  ('fn:T.Get', 'function', 'T.Get', 'a.go', 7, 1),
+  ('fn:T.Big', 'function', 'T.Big', 'a.go', 9, 1);
+INSERT INTO metrics (function_id) VALUES ('fn:T.Get'), ('fn:T.Big');
+CREATE TABLE node_properties (node_id TEXT NOT NULL, key TEXT NOT NULL, value TEXT NOT NULL);`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	funcLookup := NewFuncLookup()
+	funcLookup.Set("a.go", 7, 1, "fn:T.Get")
+	funcLookup.Set("a.go", 9, 1, "fn:T.Big")
+	var results []EscapeResult
+	for _, m := range []struct {
+		line int
+		msg  string
+	}{
+		{7, "can inline (*T).Get with cost 7 as: method(t *T) func() int { return t.n }"},
+		{9, "cannot inline (*T).Big: function too complex: cost 197 exceeds budget 80"},
+	} {
+		r := EscapeResult{RelFile: "a.go", Line: m.line, Col: 6, Cost: -1, Budget: -1}
+		classifyEscapeMessage(&r, m.msg)
+		results = append(results, r)
+	}
+	prog := NewProgress(false)
+	ResolveEscapes(results, NewPosLookup(), funcLookup, prog)
+	if err := applyEscapeAnalysis(conn, results, prog); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string)
+	err = sqlitex.ExecuteTransient(conn, `SELECT m.function_id, COALESCE(m.inline_cost, 'NULL') || '/' || COALESCE(m.inline_budget, 'NULL') || '/' ||
+  COALESCE((SELECT value FROM node_properties WHERE node_id = m.function_id AND key = 'inlineable'), 'NULL')
+FROM metrics m`, &sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+		got[stmt.ColumnText(0)] = stmt.ColumnText(1)
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"fn:T.Get": "7/NULL/true", "fn:T.Big": "197/80/NULL"}
+	for id, w := range want {
+		if got[id] != w {
+			t.Errorf("%s: inline_cost/inline_budget/inlineable = %s, want %s", id, got[id], w)
+		}
+	}
+}
@@ -109,6 +109,10 @@ export type FunctionDetail = {
   finding_count: number;
   callers: string;
   callees: string;
+  primary_author: string | null;
+  author_count: number | null;
+  last_modified: string | null;
+  age_days: number | null;
 };
 
 export type SourceResponse = {
@@ -144,6 +148,10 @@ export type HotspotRow = {
   fan_out: number;
   finding_count: number;
   hotspot_score: number;
+  heap_allocs: number | null;
+  primary_author: string | null;
+  author_count: number | null;
+  age_days: number | null;
 };
 
 export type ImpactRow = {
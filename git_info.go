@@ -2,9 +2,16 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // GitFileHistory holds per-file git change metrics.
@@ -19,7 +26,37 @@ type GitFileHistory struct {
 	DaysSinceEdit int
 }
 
-// GitBlameEntry holds per-line-range blame data from git blame --porcelain.
+// GitCommit is one commit from git log with the Go files it changed.
+type GitCommit struct {
+	Hash   string // short SHA
+	Author string
+	Date   string // ISO 8601
+	Files  []string
+	Hunks  []GitHunk // changed lines, at their line numbers in the working tree
+}
+
+// GitHistory is the git data of all modules: per-file metrics from the log
+// and the commits they are computed from, newest first, and with
+// -changed-since the hunks changed since the base commit, untracked files
+// included.
+type GitHistory struct {
+	Files   []GitFileHistory
+	Commits []GitCommit
+
+	Base   string // -changed-since ref, "" when not diffing
+	BaseDB string // CPG database of the base, for the findings the change introduces
+	Hunks  []GitHunk
+}
+
+// GitHunk is the new-side line range of one hunk of a git diff. A hunk that
+// only removes lines has Lines == 0 and sits at the line before the removal.
+type GitHunk struct {
+	RelFile string
+	LineRange
+	Lines int
+}
+
+// GitBlameEntry holds per-line blame data from git blame --porcelain.
 type GitBlameEntry struct {
 	RelFile string
 	Line    int
@@ -29,29 +66,34 @@ type GitBlameEntry struct {
 }
 
 // RunGitHistory extracts per-file change frequency from `git log --numstat`
-// across all modules in the ModuleSet.
-func RunGitHistory(prog *Progress) []GitFileHistory {
-	prog.Log("Running git log for file history across %d modules...", len(modSet.Dirs()))
+// over the last window commits of each module in the ModuleSet.
+func RunGitHistory(window int, prog *Progress) *GitHistory {
+	prog.Log("Running git log (last %d commits) for file history across %d modules...", window, len(modSet.Dirs()))
 
-	var allResults []GitFileHistory
+	history := &GitHistory{}
 
 	for _, mod := range modSet.Dirs() {
-		results := runGitHistoryForDir(mod.Dir, mod.Prefix, prog)
-		allResults = append(allResults, results...)
+		files, commits := runGitHistoryForDir(mod.Dir, mod.Prefix, window, prog)
+		hunks := runGitLogHunksForDir(mod.Dir, mod.Prefix, window, prog)
+		for i := range commits {
+			commits[i].Hunks = hunks[commits[i].Hash]
+		}
+		history.Files = append(history.Files, files...)
+		history.Commits = append(history.Commits, commits...)
 	}
 
-	prog.Log("Git history: %d files with change data", len(allResults))
-	return allResults
+	prog.Log("Git history: %d files with change data, %d commits", len(history.Files), len(history.Commits))
+	return history
 }
 
-func runGitHistoryForDir(dir, prefix string, prog *Progress) []GitFileHistory {
-	cmd := exec.Command("git", "log", "--format=%H %aI %aN", "--numstat", "--no-merges", "-n", "500")
+func runGitHistoryForDir(dir, prefix string, window int, prog *Progress) ([]GitFileHistory, []GitCommit) {
+	cmd := exec.Command("git", "log", "--format=%H %aI %aN", "--numstat", "--relative", "--no-merges", "-n", strconv.Itoa(window))
 	cmd.Dir = dir
 
 	out, err := cmd.Output()
 	if err != nil {
 		prog.Verbose("Git history for %s: failed: %v", dir, err)
-		return nil
+		return nil, nil
 	}
 
 	type fileStats struct {
@@ -62,6 +104,7 @@ func runGitHistoryForDir(dir, prefix string, prog *Progress) []GitFileHistory {
 		ins, del   int
 	}
 	files := make(map[string]*fileStats)
+	var commits []GitCommit
 
 	var currentAuthor, currentDate string
 	var currentCommit string
@@ -79,6 +122,7 @@ func runGitHistoryForDir(dir, prefix string, prog *Progress) []GitFileHistory {
 				currentCommit = parts[0][:12]
 				currentDate = parts[1]
 				currentAuthor = parts[2]
+				commits = append(commits, GitCommit{Hash: currentCommit, Author: currentAuthor, Date: currentDate})
 			}
 			continue
 		}
@@ -111,6 +155,9 @@ func runGitHistoryForDir(dir, prefix string, prog *Progress) []GitFileHistory {
 			}
 			files[relFile] = fs
 		}
+		if n := len(commits); n > 0 {
+			commits[n-1].Files = append(commits[n-1].Files, relFile)
+		}
 		fs.commits[currentCommit] = true
 		fs.authors[currentAuthor] = true
 		fs.ins += ins
@@ -122,6 +169,7 @@ func runGitHistoryForDir(dir, prefix string, prog *Progress) []GitFileHistory {
 		}
 	}
 
+	now := time.Now()
 	var results []GitFileHistory
 	for file, fs := range files {
 		results = append(results, GitFileHistory{
@@ -132,20 +180,54 @@ func runGitHistoryForDir(dir, prefix string, prog *Progress) []GitFileHistory {
 			LastDate:    fs.lastDate,
 			Insertions:  fs.ins,
 			Deletions:   fs.del,
+
+			DaysSinceEdit: daysSince(fs.lastDate, now),
 		})
 	}
 
-	return results
+	return results, commits
+}
+
+// LineRange is an inclusive range of source lines.
+type LineRange struct {
+	Start, End int
+}
+
+// FileRange is a function's line range in a file.
+type FileRange struct {
+	RelFile string
+	LineRange
 }
 
-// RunGitBlame extracts per-function blame data using `git blame --porcelain`.
-// Only samples function declaration lines to keep the data manageable.
-func RunGitBlame(dir string, files []string, prog *Progress) []GitBlameEntry {
-	prog.Log("Running git blame for %d files...", len(files))
+// FunctionOwnership summarizes the blame of one function's lines.
+type FunctionOwnership struct {
+	FunctionID    string
+	PrimaryAuthor string // author of the most lines
+	PrimaryLines  int
+	AuthorCount   int
+	Lines         int    // committed lines blamed
+	LastModified  string // ISO 8601, newest author time of any line
+	AgeDays       int
+}
+
+// RunGitBlame runs `git blame --porcelain` over the given line ranges of each
+// file in a module directory. Files are keyed by their CPG path, which
+// carries the module prefix for non-primary modules. Lines not committed yet
+// are skipped.
+func RunGitBlame(dir, prefix string, ranges map[string][]LineRange, prog *Progress) []GitBlameEntry {
+	prog.Verbose("Running git blame for %d files in %s...", len(ranges), dir)
 
 	var results []GitBlameEntry
-	for _, relFile := range files {
-		cmd := exec.Command("git", "blame", "--porcelain", "--", relFile)
+	for relFile, rs := range ranges {
+		path := relFile
+		if prefix != "" {
+			path = strings.TrimPrefix(relFile, prefix+"/")
+		}
+		args := []string{"blame", "--porcelain", "-w"}
+		for _, r := range mergeLineRanges(rs) {
+			args = append(args, "-L", strconv.Itoa(r.Start)+","+strconv.Itoa(r.End))
+		}
+		cmd := exec.Command("git", append(args, "--", path)...)
 		cmd.Dir = dir
 
 		stdout, err := cmd.StdoutPipe()
@@ -159,41 +241,528 @@ func RunGitBlame(dir string, files []string, prog *Progress) []GitBlameEntry {
 		scanner := bufio.NewScanner(stdout)
 		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
 
+		// Porcelain output prints the author of a commit only the first time
+		// the commit appears.
+		type commitInfo struct{ author, date string }
+		commits := make(map[string]*commitInfo)
+		var current *commitInfo
 		var currentLine int
-		var currentAuthor, currentDate, currentCommit string
+		var currentCommit string
 
 		for scanner.Scan() {
 			text := scanner.Text()
 
-			// Header line: "commit_sha orig_line final_line [num_lines]"
-			if len(text) >= 40 && text[0] != '\t' && !strings.HasPrefix(text, "author") &&
-				!strings.HasPrefix(text, "committer") && !strings.HasPrefix(text, "summary") &&
-				!strings.HasPrefix(text, "previous") && !strings.HasPrefix(text, "filename") &&
-				!strings.HasPrefix(text, "boundary") {
-				parts := strings.Fields(text)
-				if len(parts) >= 3 {
-					currentCommit = parts[0][:12]
-					currentLine, _ = strconv.Atoi(parts[2])
-				}
-			} else if strings.HasPrefix(text, "author ") {
-				currentAuthor = strings.TrimPrefix(text, "author ")
-			} else if strings.HasPrefix(text, "author-time ") {
-				currentDate = strings.TrimPrefix(text, "author-time ")
-			} else if len(text) > 0 && text[0] == '\t' {
+			switch {
+			case len(text) > 0 && text[0] == '\t':
 				// Content line — emit entry
+				if current == nil || strings.Trim(currentCommit, "0") == "" {
+					continue
+				}
 				results = append(results, GitBlameEntry{
 					RelFile: relFile,
 					Line:    currentLine,
-					Author:  currentAuthor,
-					Date:    currentDate,
-					Commit:  currentCommit,
+					Author:  current.author,
+					Date:    current.date,
+					Commit:  currentCommit[:12],
 				})
+			case strings.HasPrefix(text, "author "):
+				current.author = strings.TrimPrefix(text, "author ")
+			case strings.HasPrefix(text, "author-time "):
+				if sec, err := strconv.ParseInt(strings.TrimPrefix(text, "author-time "), 10, 64); err == nil {
+					current.date = time.Unix(sec, 0).UTC().Format(time.RFC3339)
+				}
+			default:
+				// Header line: "commit_sha orig_line final_line [num_lines]"
+				parts := strings.Fields(text)
+				if len(parts) < 3 || len(parts[0]) != 40 {
+					continue
+				}
+				currentCommit = parts[0]
+				currentLine, _ = strconv.Atoi(parts[2])
+				current = commits[currentCommit]
+				if current == nil {
+					current = &commitInfo{}
+					commits[currentCommit] = current
+				}
 			}
 		}
 
 		_ = cmd.Wait()
 	}
 
-	prog.Log("Git blame: %d line entries across %d files", len(results), len(files))
+	prog.Verbose("Git blame: %d line entries across %d files", len(results), len(ranges))
+	return results
+}
+
+// mergeLineRanges sorts ranges and merges overlapping ones, so nested
+// functions are blamed once.
+func mergeLineRanges(rs []LineRange) []LineRange {
+	rs = slices.Clone(rs)
+	slices.SortFunc(rs, func(a, b LineRange) int { return a.Start - b.Start })
+	var merged []LineRange
+	for _, r := range rs {
+		if n := len(merged); n > 0 && r.Start <= merged[n-1].End+1 {
+			merged[n-1].End = max(merged[n-1].End, r.End)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// ComputeOwnership aggregates blame entries over each function's line range.
+// The primary author is the one with the most lines, ties broken by name.
+func ComputeOwnership(funcs map[string]FileRange, blame []GitBlameEntry, now time.Time) []FunctionOwnership {
+	byFile := make(map[string]map[int]*GitBlameEntry)
+	for i := range blame {
+		b := &blame[i]
+		if byFile[b.RelFile] == nil {
+			byFile[b.RelFile] = make(map[int]*GitBlameEntry)
+		}
+		byFile[b.RelFile][b.Line] = b
+	}
+
+	var results []FunctionOwnership
+	for id, fr := range funcs {
+		lines := byFile[fr.RelFile]
+		if lines == nil {
+			continue
+		}
+		authors := make(map[string]int)
+		o := FunctionOwnership{FunctionID: id}
+		for l := fr.Start; l <= fr.End; l++ {
+			b := lines[l]
+			if b == nil {
+				continue
+			}
+			authors[b.Author]++
+			o.Lines++
+			if b.Date > o.LastModified { // RFC 3339 in UTC sorts as text
+				o.LastModified = b.Date
+			}
+		}
+		if o.Lines == 0 {
+			continue
+		}
+		for a, n := range authors {
+			if n > o.PrimaryLines || n == o.PrimaryLines && a < o.PrimaryAuthor {
+				o.PrimaryAuthor, o.PrimaryLines = a, n
+			}
+		}
+		o.AuthorCount = len(authors)
+		o.AgeDays = daysSince(o.LastModified, now)
+		results = append(results, o)
+	}
 	return results
 }
+
+// daysSince returns the whole days between an ISO 8601 date and now, or -1
+// if the date cannot be parsed.
+func daysSince(date string, now time.Time) int {
+	t, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return -1
+	}
+	return int(now.Sub(t).Hours() / 24)
+}
+
+// ResolveGitRef returns the commit a ref names in the repository at dir.
+func ResolveGitRef(dir, ref string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("unknown git ref %q in %s", ref, dir)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// GenerateBaseDB builds the CPG of the module in dir as of commit, so that
+// findings of the working tree can be compared with the base. It checks the
+// commit out into a temporary git worktree and runs this binary there with
+// args (flags only), and returns the database path and a cleanup function
+// that removes the worktree and the database.
+func GenerateBaseDB(dir, commit string, args []string, prog *Progress) (string, func(), error) {
+	prog.Log("Generating the base CPG at %.12s...", commit)
+	top, err := gitOutput(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", nil, err
+	}
+	// The module may sit below the repository root; git reports the root
+	// with symlinks resolved.
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	rel, err := filepath.Rel(top, realDir)
+	if err != nil {
+		return "", nil, err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return "", nil, err
+	}
+
+	tmp, err := os.MkdirTemp("", "cpg-base-")
+	if err != nil {
+		return "", nil, err
+	}
+	worktree := filepath.Join(tmp, "src")
+	cleanup := func() {
+		_, _ = gitOutput(top, "worktree", "remove", "--force", worktree)
+		_ = os.RemoveAll(tmp)
+	}
+	if _, err := gitOutput(top, "worktree", "add", "--detach", worktree, commit); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	dbPath := filepath.Join(tmp, "base.db")
+	cmd := exec.Command(exe, append(slices.Clone(args), filepath.Join(worktree, rel), dbPath)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("base CPG at %.12s: %w\n%s", commit, err, out)
+	}
+	return dbPath, cleanup, nil
+}
+
+// gitOutput runs git in dir and returns its trimmed output.
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s in %s: %w: %s", strings.Join(args, " "), dir, err, bytes.TrimSpace(out))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// RunGitDiff collects the Go hunks changed between base and the working tree
+// of each module in the ModuleSet, from `git diff -U0`. Untracked Go files
+// that are not ignored count as fully added.
+func RunGitDiff(base string, prog *Progress) ([]GitHunk, error) {
+	prog.Log("Running git diff against %s across %d modules...", base, len(modSet.Dirs()))
+
+	var allHunks []GitHunk
+	for _, mod := range modSet.Dirs() {
+		hunks, err := runGitDiffForDir(mod.Dir, mod.Prefix, base)
+		if err != nil {
+			return nil, err
+		}
+		allHunks = append(allHunks, hunks...)
+	}
+
+	prog.Log("Git diff: %d changed hunks", len(allHunks))
+	return allHunks, nil
+}
+
+func runGitDiffForDir(dir, prefix, base string) ([]GitHunk, error) {
+	cmd := exec.Command("git", "diff", "-U0", "--no-color", "--no-ext-diff", "--relative", base, "--", "*.go")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff %s in %s: %w", base, dir, err)
+	}
+
+	var hunks []GitHunk
+	for _, p := range parseGitPatches(string(out), prefix) {
+		for _, f := range p.files {
+			if f.newPath == "" {
+				continue // deleted file
+			}
+			for _, s := range f.shifts {
+				hunks = append(hunks, newSideHunk(f.newPath, s))
+			}
+		}
+	}
+
+	// git diff does not see files that were never added
+	cmd = exec.Command("git", "ls-files", "--others", "--exclude-standard", "--", "*.go")
+	cmd.Dir = dir
+	out, err = cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git ls-files in %s: %w", dir, err)
+	}
+	for _, path := range strings.Split(string(out), "\n") {
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			return nil, fmt.Errorf("reading untracked file: %w", err)
+		}
+		n := bytes.Count(data, []byte("\n"))
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			n++
+		}
+		if n == 0 {
+			continue
+		}
+		relFile := path
+		if prefix != "" {
+			relFile = prefix + "/" + relFile
+		}
+		hunks = append(hunks, GitHunk{RelFile: relFile, LineRange: LineRange{Start: 1, End: n}, Lines: n})
+	}
+	return hunks, nil
+}
+
+// lineShift is one hunk of a -U0 diff with the line ranges of both sides, as
+// in "@@ -oldStart,oldLines +newStart,newLines @@". A side without lines
+// starts at the line before the hunk.
+type lineShift struct {
+	oldStart, oldLines int
+	newStart, newLines int
+}
+
+// gitFilePatch is the diff of one file. oldPath is "" for an added file and
+// newPath is "" for a deleted one.
+type gitFilePatch struct {
+	oldPath, newPath string
+	shifts           []lineShift
+}
+
+// gitPatch is the diff of one commit from git log -p, or of a git diff when
+// commit is "".
+type gitPatch struct {
+	commit string // short SHA
+	files  []gitFilePatch
+}
+
+// parseGitPatches parses -U0 git diff or git log -p output whose commit
+// headers are "commit <sha>". Paths get the module prefix.
+func parseGitPatches(out, prefix string) []gitPatch {
+	withPrefix := func(p string) string {
+		if prefix != "" && p != "" {
+			return prefix + "/" + p
+		}
+		return p
+	}
+
+	var patches []gitPatch
+	var file *gitFilePatch
+	inHunks := false // past the file header: "---"/"+++" lines are content
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "commit "):
+			hash := strings.TrimPrefix(line, "commit ")
+			if len(hash) < 12 {
+				continue
+			}
+			patches = append(patches, gitPatch{commit: hash[:12]})
+			file = nil
+		case strings.HasPrefix(line, "diff --git "):
+			if len(patches) == 0 {
+				patches = append(patches, gitPatch{})
+			}
+			p := &patches[len(patches)-1]
+			p.files = append(p.files, gitFilePatch{})
+			file = &p.files[len(p.files)-1]
+			inHunks = false
+			// "diff --git a/old b/new"; refined by the header lines below
+			if oldPath, newPath, ok := strings.Cut(strings.TrimPrefix(line, "diff --git a/"), " b/"); ok {
+				file.oldPath, file.newPath = withPrefix(oldPath), withPrefix(newPath)
+			}
+		case file == nil:
+		case strings.HasPrefix(line, "@@ "):
+			inHunks = true
+			if s, ok := parseHunkHeader(line); ok {
+				file.shifts = append(file.shifts, s)
+			}
+		case inHunks:
+		case line == "--- /dev/null":
+			file.oldPath = ""
+		case line == "+++ /dev/null":
+			file.newPath = ""
+		case strings.HasPrefix(line, "--- a/"):
+			file.oldPath = withPrefix(strings.TrimPrefix(line, "--- a/"))
+		case strings.HasPrefix(line, "+++ b/"):
+			file.newPath = withPrefix(strings.TrimPrefix(line, "+++ b/"))
+		case strings.HasPrefix(line, "new file mode"):
+			file.oldPath = ""
+		case strings.HasPrefix(line, "deleted file mode"):
+			file.newPath = ""
+		case strings.HasPrefix(line, "rename from "):
+			file.oldPath = withPrefix(strings.TrimPrefix(line, "rename from "))
+		case strings.HasPrefix(line, "rename to "):
+			file.newPath = withPrefix(strings.TrimPrefix(line, "rename to "))
+		}
+	}
+	return patches
+}
+
+// parseHunkHeader parses "@@ -old[,count] +new[,count] @@".
+func parseHunkHeader(line string) (lineShift, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return lineShift{}, false
+	}
+	oldStart, oldLines, ok1 := parseHunkRange(fields[1][1:])
+	newStart, newLines, ok2 := parseHunkRange(fields[2][1:])
+	return lineShift{oldStart, oldLines, newStart, newLines}, ok1 && ok2
+}
+
+func parseHunkRange(s string) (start, lines int, ok bool) {
+	startText, countText, hasCount := strings.Cut(s, ",")
+	start, err := strconv.Atoi(startText)
+	if err != nil {
+		return 0, 0, false
+	}
+	lines = 1
+	if hasCount {
+		if lines, err = strconv.Atoi(countText); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, lines, true
+}
+
+// newSideHunk returns the new-side lines of a hunk. A hunk that only removes
+// lines sits at the line before the removal.
+func newSideHunk(relFile string, s lineShift) GitHunk {
+	h := GitHunk{RelFile: relFile, Lines: s.newLines}
+	if s.newLines == 0 {
+		h.LineRange = LineRange{Start: max(s.newStart, 1), End: max(s.newStart, 1)}
+	} else {
+		h.LineRange = LineRange{Start: s.newStart, End: s.newStart + s.newLines - 1}
+	}
+	return h
+}
+
+// runGitLogHunksForDir returns the hunks of the last window commits changing
+// Go files, keyed by short SHA, translated to working tree line numbers so
+// they can be matched against the current function ranges.
+func runGitLogHunksForDir(dir, prefix string, window int, prog *Progress) map[string][]GitHunk {
+	cmd := exec.Command("git", "log", "-p", "-U0", "-M", "--format=commit %H", "--no-color", "--no-ext-diff",
+		"--relative", "--no-merges", "-n", strconv.Itoa(window), "--", "*.go")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		prog.Verbose("Git log hunks for %s: failed: %v", dir, err)
+		return nil
+	}
+
+	// Uncommitted edits shift lines between HEAD and the working tree.
+	var worktree []gitFilePatch
+	diff := exec.Command("git", "diff", "-U0", "--no-color", "--no-ext-diff", "--relative", "HEAD", "--", "*.go")
+	diff.Dir = dir
+	if diffOut, err := diff.Output(); err == nil {
+		for _, p := range parseGitPatches(string(diffOut), prefix) {
+			worktree = append(worktree, p.files...)
+		}
+	}
+
+	return mapCommitHunks(parseGitPatches(string(out), prefix), worktree)
+}
+
+// mapCommitHunks translates the new-side hunks of each commit, newest first,
+// to working tree line numbers by passing them through the diffs of every
+// later commit and then the uncommitted worktree diff. Renames are followed;
+// lines deleted since are dropped. On non-linear history the diffs are
+// applied in log order, which is approximate across merged branches.
+func mapCommitHunks(patches []gitPatch, worktree []gitFilePatch) map[string][]GitHunk {
+	// later maps a path as of the commit being processed to its working tree
+	// path and the diffs applied to it since, newest first. An empty path
+	// marks a file that no longer exists.
+	type fileLater struct {
+		path  string
+		diffs [][]lineShift
+	}
+	later := make(map[string]fileLater)
+	lookup := func(path string) fileLater {
+		if fl, ok := later[path]; ok {
+			return fl
+		}
+		return fileLater{path: path}
+	}
+	// stepBack moves later from the state after a diff to the one before it.
+	stepBack := func(files []gitFilePatch) {
+		before := make(map[string]fileLater, len(files))
+		for _, f := range files {
+			switch {
+			case f.oldPath == "":
+			case f.newPath == "":
+				before[f.oldPath] = fileLater{}
+			default:
+				fl := lookup(f.newPath)
+				if fl.path != "" {
+					fl.diffs = append(slices.Clip(fl.diffs), f.shifts)
+				}
+				before[f.oldPath] = fl
+			}
+		}
+		for _, f := range files {
+			if f.newPath != "" && f.newPath != f.oldPath {
+				later[f.newPath] = fileLater{} // added or renamed to by this diff
+			}
+		}
+		maps.Copy(later, before)
+	}
+
+	stepBack(worktree)
+	hunks := make(map[string][]GitHunk)
+	for _, p := range patches {
+		for _, f := range p.files {
+			if f.newPath == "" {
+				continue
+			}
+			fl := lookup(f.newPath)
+			if fl.path == "" {
+				continue
+			}
+			for _, s := range f.shifts {
+				h := newSideHunk(fl.path, s)
+				r, ok := mapLineRange(h.LineRange, fl.diffs)
+				if !ok {
+					continue
+				}
+				if h.Lines > 0 {
+					h.Lines = r.End - r.Start + 1
+				}
+				h.LineRange = r
+				hunks[p.commit] = append(hunks[p.commit], h)
+			}
+		}
+		stepBack(p.files)
+	}
+	return hunks
+}
+
+// mapLineRange passes a line range through diffs, stored newest first. It
+// reports false when all lines of the range have been deleted.
+func mapLineRange(r LineRange, diffs [][]lineShift) (LineRange, bool) {
+	for i := len(diffs) - 1; i >= 0; i-- {
+		r = LineRange{Start: mapLine(r.Start, diffs[i], false), End: mapLine(r.End, diffs[i], true)}
+		if r.End < r.Start {
+			return r, false
+		}
+	}
+	return r, true
+}
+
+// mapLine translates a line through the hunks of one diff. A line the diff
+// replaces maps to the first line of its replacement, or with last set to the
+// last one; a line it deletes maps to the line after the deletion, or with
+// last set to the line before it.
+func mapLine(line int, shifts []lineShift, last bool) int {
+	delta := 0
+	for _, s := range shifts {
+		oldFirst, newFirst := s.oldStart, s.newStart
+		if s.oldLines == 0 {
+			oldFirst++
+		}
+		if s.newLines == 0 {
+			newFirst++
+		}
+		switch {
+		case line < oldFirst:
+			return line + delta
+		case line < oldFirst+s.oldLines:
+			if last {
+				return newFirst + s.newLines - 1
+			}
+			return newFirst
+		}
+		delta = newFirst + s.newLines - (oldFirst + s.oldLines)
+	}
+	return line + delta
+}
@@ -0,0 +1,492 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// testGit returns a runner for git commands in dir, skipping the test when
+// git is not installed.
+func testGit(t *testing.T, dir string) func(args ...string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	return func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@t", "-c", "commit.gpgsign=false"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+}
+
+func TestMergeLineRanges(t *testing.T) {
+	got := mergeLineRanges([]LineRange{{20, 25}, {1, 10}, {3, 5}, {11, 12}, {14, 15}, {15, 18}})
+	want := []LineRange{{1, 12}, {14, 18}, {20, 25}}
+	if !slices.Equal(got, want) {
+		t.Errorf("mergeLineRanges = %v, want %v", got, want)
+	}
+	if got := mergeLineRanges(nil); len(got) != 0 {
+		t.Errorf("mergeLineRanges(nil) = %v", got)
+	}
+}
+
+func TestMapLine(t *testing.T) {
+	shifts := []lineShift{
+		{oldStart: 5, oldLines: 0, newStart: 6, newLines: 2},   // insert 2 after old 5
+		{oldStart: 10, oldLines: 2, newStart: 11, newLines: 0}, // delete old 10-11
+		{oldStart: 20, oldLines: 1, newStart: 20, newLines: 3}, // replace old 20
+	}
+	tests := []struct {
+		line int
+		last bool
+		want int
+	}{
+		{5, false, 5},
+		{6, false, 8},
+		{9, false, 11},
+		{10, false, 12}, // deleted: the line after
+		{11, true, 11},  // deleted: the line before
+		{12, false, 12},
+		{20, false, 20},
+		{20, true, 22},
+		{21, false, 23},
+	}
+	for _, tt := range tests {
+		if got := mapLine(tt.line, shifts, tt.last); got != tt.want {
+			t.Errorf("mapLine(%d, last=%v) = %d, want %d", tt.line, tt.last, got, tt.want)
+		}
+	}
+}
+
+func TestParseGitPatches(t *testing.T) {
+	out := `commit 0123456789abcdef0123456789abcdef01234567
+
+diff --git a/a.go b/a.go
+index 1111111..2222222 100644
+--- a/a.go
++++ b/a.go
+@@ -3 +3,2 @@ func A() {
+--- removed line that looks like a header
++++ added line that looks like a header
+@@ -10,2 +11,0 @@ func A() {
+-x
+-y
+diff --git a/old.go b/new.go
+similarity index 100%
+rename from old.go
+rename to new.go
+diff --git a/gone.go b/gone.go
+deleted file mode 100644
+--- a/gone.go
++++ /dev/null
+@@ -1,2 +0,0 @@
+commit fedcba9876543210fedcba9876543210fedcba98
+
+diff --git a/b.go b/b.go
+new file mode 100644
+--- /dev/null
++++ b/b.go
+@@ -0,0 +1,4 @@
+`
+	got := parseGitPatches(out, "lib")
+	want := []gitPatch{
+		{commit: "0123456789ab", files: []gitFilePatch{
+			{oldPath: "lib/a.go", newPath: "lib/a.go", shifts: []lineShift{{3, 1, 3, 2}, {10, 2, 11, 0}}},
+			{oldPath: "lib/old.go", newPath: "lib/new.go"},
+			{oldPath: "lib/gone.go", shifts: []lineShift{{1, 2, 0, 0}}},
+		}},
+		{commit: "fedcba987654", files: []gitFilePatch{
+			{newPath: "lib/b.go", shifts: []lineShift{{0, 0, 1, 4}}},
+		}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d patches, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i].commit != want[i].commit || !slices.EqualFunc(got[i].files, want[i].files, func(a, b gitFilePatch) bool {
+			return a.oldPath == b.oldPath && a.newPath == b.newPath && slices.Equal(a.shifts, b.shifts)
+		}) {
+			t.Errorf("patch %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestMapCommitHunks(t *testing.T) {
+	patches := []gitPatch{
+		// newest: renames old.go to new.go and inserts 3 lines at the top
+		{commit: "c3", files: []gitFilePatch{
+			{oldPath: "old.go", newPath: "new.go", shifts: []lineShift{{0, 0, 1, 3}}},
+		}},
+		// deletes lines 20-29 of old.go
+		{commit: "c2", files: []gitFilePatch{
+			{oldPath: "old.go", newPath: "old.go", shifts: []lineShift{{20, 10, 19, 0}}},
+		}},
+		// oldest: edits old.go lines 10-11 and 25, and a file deleted since
+		{commit: "c1", files: []gitFilePatch{
+			{oldPath: "old.go", newPath: "old.go", shifts: []lineShift{{10, 1, 10, 2}, {25, 1, 26, 1}}},
+			{oldPath: "", newPath: "gone.go", shifts: []lineShift{{0, 0, 1, 5}}},
+		}},
+	}
+	// After c3: gone.go deleted, and one line added at the top of new.go.
+	worktree := []gitFilePatch{
+		{oldPath: "gone.go", newPath: ""},
+		{oldPath: "new.go", newPath: "new.go", shifts: []lineShift{{0, 0, 1, 1}}},
+	}
+	// c2 was made when gone.go already existed.
+	patches[1].files = append(patches[1].files, gitFilePatch{oldPath: "gone.go", newPath: "gone.go", shifts: []lineShift{{2, 1, 2, 1}}})
+
+	got := mapCommitHunks(patches, worktree)
+	want := map[string][]GitHunk{
+		"c3": {{RelFile: "new.go", LineRange: LineRange{2, 4}, Lines: 3}},
+		"c2": {{RelFile: "new.go", LineRange: LineRange{23, 23}}},
+		"c1": {{RelFile: "new.go", LineRange: LineRange{14, 15}, Lines: 2}},
+	}
+	for commit, w := range want {
+		if !slices.Equal(got[commit], w) {
+			t.Errorf("%s: hunks = %+v, want %+v", commit, got[commit], w)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("hunks = %+v, want only %v", got, want)
+	}
+}
+
+// The hunks of a real history land on the working tree's lines.
+func TestRunGitLogHunksForDir(t *testing.T) {
+	dir := t.TempDir()
+	git := testGit(t, dir)
+	lines := func(n int, tag string) string {
+		var b strings.Builder
+		for i := 1; i <= n; i++ {
+			b.WriteString(tag + "\n")
+		}
+		return b.String()
+	}
+	path := filepath.Join(dir, "a.go")
+
+	git("init", "-q")
+	writeFile(t, path, lines(10, "a"))
+	git("add", ".")
+	git("commit", "-q", "-m", "first")
+	writeFile(t, path, lines(5, "a")+"x\n"+lines(4, "a"))
+	git("commit", "-q", "-am", "edit line 6")
+	writeFile(t, path, "new\nnew\n"+lines(5, "a")+"x\n"+lines(4, "a"))
+	git("commit", "-q", "-am", "prepend")
+	writeFile(t, path, "wt\nnew\nnew\n"+lines(5, "a")+"x\n"+lines(4, "a"))
+
+	cmd := exec.Command("git", "log", "--format=%H")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes := strings.Fields(string(out)) // newest first
+
+	hunks := runGitLogHunksForDir(dir, "", 10, NewProgress(false))
+	want := [][]GitHunk{
+		{{RelFile: "a.go", LineRange: LineRange{2, 3}, Lines: 2}},
+		{{RelFile: "a.go", LineRange: LineRange{9, 9}, Lines: 1}},
+		{{RelFile: "a.go", LineRange: LineRange{4, 13}, Lines: 10}},
+	}
+	for i, w := range want {
+		if got := hunks[hashes[i][:12]]; !slices.Equal(got, w) {
+			t.Errorf("commit %d: hunks = %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+func TestRunGitDiffForDirUntracked(t *testing.T) {
+	dir := t.TempDir()
+	git := testGit(t, dir)
+	git("init", "-q")
+	writeFile(t, filepath.Join(dir, "a.go"), "package a\n")
+	writeFile(t, filepath.Join(dir, ".gitignore"), "ignored.go\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "first")
+	writeFile(t, filepath.Join(dir, "a.go"), "package a\n\nvar x int\n")
+	writeFile(t, filepath.Join(dir, "sub", "new.go"), "package sub\n\nfunc F() {}") // no trailing newline
+	writeFile(t, filepath.Join(dir, "ignored.go"), "package a\n")
+	writeFile(t, filepath.Join(dir, "notes.txt"), "x\n")
+
+	hunks, err := runGitDiffForDir(dir, "lib", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []GitHunk{
+		{RelFile: "lib/a.go", LineRange: LineRange{2, 3}, Lines: 2},
+		{RelFile: "lib/sub/new.go", LineRange: LineRange{1, 3}, Lines: 3},
+	}
+	if !slices.Equal(hunks, want) {
+		t.Errorf("hunks = %+v, want %+v", hunks, want)
+	}
+}
+
+func TestRunGitBlameOwnership(t *testing.T) {
+	dir := t.TempDir()
+	git := testGit(t, dir)
+	path := filepath.Join(dir, "a.go")
+	commitAs := func(name, date, msg string) {
+		git("-c", "user.name="+name, "-c", "user.email="+name+"@example.com",
+			"commit", "-q", "-a", "--date="+date, "-m", msg)
+	}
+
+	git("init", "-q")
+	writeFile(t, path, "package a\n\nfunc F() {\n\tprintln(1)\n}\n\nfunc G() {\n\tprintln(2)\n\tprintln(3)\n}\n")
+	git("add", ".")
+	commitAs("alice", "2024-01-01T00:00:00Z", "first")
+	writeFile(t, path, "package a\n\nfunc F() {\n\tprintln(1)\n}\n\nfunc G() {\n\tprintln(2)\n\tprintln(30)\n}\n")
+	commitAs("bob", "2024-03-01T00:00:00Z", "edit G")
+	// Uncommitted edits are not blamed.
+	writeFile(t, path, "package a\n\nfunc F() {\n\tprintln(10)\n}\n\nfunc G() {\n\tprintln(2)\n\tprintln(30)\n}\n")
+
+	funcs := map[string]FileRange{
+		"F": {RelFile: "lib/a.go", LineRange: LineRange{3, 5}},
+		"G": {RelFile: "lib/a.go", LineRange: LineRange{7, 10}},
+	}
+	ranges := map[string][]LineRange{"lib/a.go": {funcs["F"].LineRange, funcs["G"].LineRange}}
+	blame := RunGitBlame(dir, "lib", ranges, NewProgress(false))
+	if len(blame) != 6 {
+		t.Fatalf("got %d blame entries, want 6 (7 lines minus 1 uncommitted): %+v", len(blame), blame)
+	}
+	for _, b := range blame {
+		if b.RelFile != "lib/a.go" {
+			t.Errorf("blame entry file = %q, want the prefixed CPG path", b.RelFile)
+		}
+		if b.Line == 4 {
+			t.Errorf("uncommitted line 4 was blamed to %s", b.Author)
+		}
+	}
+
+	now := time.Date(2024, 3, 11, 12, 0, 0, 0, time.UTC)
+	owners := ComputeOwnership(funcs, blame, now)
+	slices.SortFunc(owners, func(a, b FunctionOwnership) int { return strings.Compare(a.FunctionID, b.FunctionID) })
+	want := []FunctionOwnership{
+		{FunctionID: "F", PrimaryAuthor: "alice", PrimaryLines: 2, AuthorCount: 1, Lines: 2,
+			LastModified: "2024-01-01T00:00:00Z", AgeDays: 70},
+		{FunctionID: "G", PrimaryAuthor: "alice", PrimaryLines: 3, AuthorCount: 2, Lines: 4,
+			LastModified: "2024-03-01T00:00:00Z", AgeDays: 10},
+	}
+	if !slices.Equal(owners, want) {
+		t.Errorf("ComputeOwnership =\n%+v\nwant\n%+v", owners, want)
+	}
+}
+
+func TestComputeOwnershipTie(t *testing.T) {
+	funcs := map[string]FileRange{"F": {RelFile: "a.go", LineRange: LineRange{1, 2}}}
+	blame := []GitBlameEntry{
+		{RelFile: "a.go", Line: 1, Author: "zoe", Date: "2024-01-02T00:00:00Z"},
+		{RelFile: "a.go", Line: 2, Author: "amy", Date: "2024-01-01T00:00:00Z"},
+		{RelFile: "b.go", Line: 1, Author: "bob", Date: "2024-01-03T00:00:00Z"},
+	}
+	owners := ComputeOwnership(funcs, blame, time.Date(2024, 1, 2, 23, 0, 0, 0, time.UTC))
+	if len(owners) != 1 || owners[0].PrimaryAuthor != "amy" || owners[0].AuthorCount != 2 ||
+		owners[0].LastModified != "2024-01-02T00:00:00Z" || owners[0].AgeDays != 0 {
+		t.Errorf("ComputeOwnership = %+v, want amy (tie broken by name), 2 authors, 0 days old", owners)
+	}
+}
+
+func TestDaysSince(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		date string
+		want int
+	}{
+		{"2024-03-01T12:00:00Z", 0},
+		{"2024-02-29T12:00:01Z", 0},
+		{"2024-02-29T12:00:00Z", 1},
+		{"2024-03-01T14:00:00+02:00", 0},
+		{"2023-03-01T12:00:00Z", 366},
+		{"2024-03-01", -1},
+		{"", -1},
+	}
+	for _, tt := range tests {
+		if got := daysSince(tt.date, now); got != tt.want {
+			t.Errorf("daysSince(%q) = %d, want %d", tt.date, got, tt.want)
+		}
+	}
+}
+
+// changeSetDB creates a database with the tables applyChangeSet reads and
+// writes next to the graph, filled by script, and returns it and its path.
+func changeSetDB(t *testing.T, script string) (*sqlite.Conn, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cpg.db")
+	conn, err := openDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	err = sqlitex.ExecuteScript(conn, `
+CREATE TABLE findings (id INTEGER PRIMARY KEY AUTOINCREMENT, category TEXT NOT NULL, severity TEXT NOT NULL,
+  node_id TEXT, file TEXT, line INTEGER, message TEXT NOT NULL, details TEXT);
+CREATE TABLE schema_docs (category TEXT, name TEXT, description TEXT, example TEXT);
+CREATE TABLE queries (name TEXT PRIMARY KEY, description TEXT NOT NULL, sql TEXT NOT NULL);
+`+script, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, path
+}
+
+// queryRows returns the rows of query as "|"-joined columns.
+func queryRows(t *testing.T, conn *sqlite.Conn, query string) []string {
+	t.Helper()
+	var rows []string
+	err := sqlitex.ExecuteTransient(conn, query, &sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+		cols := make([]string, stmt.ColumnCount())
+		for i := range cols {
+			cols[i] = stmt.ColumnText(i)
+		}
+		rows = append(rows, strings.Join(cols, "|"))
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestApplyChangeSet(t *testing.T) {
+	// c1 → G and c(i+1) → c(i) up to c10, so c(i) is at depth i and c9, c10
+	// are beyond depth 8; s calls both G and c4 and is at depth 1.
+	calls := []string{"('p::c1', 'p::G', 'call')", "('p::G', 'p::c1', 'call')", "('p::s', 'p::G', 'call')", "('p::s', 'p::c4', 'call')"}
+	var chain []string
+	for i := 1; i <= 10; i++ {
+		pkg := "p"
+		if i > 4 {
+			pkg = "q"
+		}
+		chain = append(chain, fmt.Sprintf("('p::c%d', 'function', 'c%d', '%s', 'c.go', %d, %d, NULL)", i, i, pkg, i*10, i*10+5))
+		if i > 1 {
+			calls = append(calls, fmt.Sprintf("('p::c%d', 'p::c%d', 'call')", i, i-1))
+		}
+	}
+	head, _ := changeSetDB(t, `
+INSERT INTO nodes (id, kind, name, package, file, line, end_line, parent_function) VALUES
+  ('p::F', 'function', 'F', 'p', 'p/a.go', 3, 5, NULL),
+  ('p::G', 'function', 'G', 'p', 'p/a.go', 7, 12, NULL),
+  ('p::G/if#0', 'if', 'if', 'p', 'p/a.go', 9, 9, 'p::G'),
+  ('p::H', 'function', 'H', 'p', 'p/a.go', 14, 16, NULL),
+  ('p::K', 'function', 'K', 'p', 'p/a.go', 18, 22, NULL),
+  ('p::T', 'type_decl', 'T', 'p', 'p/a.go', 24, 26, NULL),
+  ('p::L', 'function', 'L', 'p', 'p/b.go', 3, 5, NULL),
+  ('p::s', 'function', 's', 'p', 'p/s.go', 3, 5, NULL),
+  `+strings.Join(chain, ",\n  ")+`;
+INSERT INTO edges (source, target, kind) VALUES `+strings.Join(calls, ", ")+`;
+INSERT INTO findings (category, severity, node_id, file, line, message) VALUES
+  ('unused_param', 'warning', 'p::F', 'p/a.go', 3, 'unused parameter x in p::F@p/a.go:3:1'),
+  ('complexity', 'warning', 'p::G', 'p/a.go', 7, 'G is complex'),
+  ('nesting', 'info', 'p::G/if#0', 'p/a.go', 9, 'deep if'),
+  ('nesting', 'info', 'p::G/if#0', 'p/a.go', 9, 'deep if'),
+  ('dead_code', 'info', 'p::H', 'p/a.go', 14, 'unreachable H'),
+  ('todo', 'info', NULL, 'p/b.go', 1, 'TODO');`)
+
+	// At the base G was already complex and had one of the deep ifs, at
+	// other lines, and the TODO in b.go existed.
+	_, basePath := changeSetDB(t, `
+INSERT INTO nodes (id, kind, name, package, file, line, end_line, parent_function) VALUES
+  ('p::G', 'function', 'G', 'p', 'p/a.go', 4, 9, NULL),
+  ('p::G/if#0', 'if', 'if', 'p', 'p/a.go', 6, 6, 'p::G');
+INSERT INTO findings (category, severity, node_id, file, line, message) VALUES
+  ('complexity', 'warning', 'p::G', 'p/a.go', 4, 'G is complex'),
+  ('nesting', 'info', 'p::G/if#0', 'p/a.go', 6, 'deep if'),
+  ('todo', 'info', NULL, 'p/b.go', 1, 'TODO');`)
+
+	history := &GitHistory{Base: "HEAD~1", BaseDB: basePath, Hunks: []GitHunk{
+		{RelFile: "p/a.go", LineRange: LineRange{3, 5}, Lines: 3},   // all of F
+		{RelFile: "p/a.go", LineRange: LineRange{9, 9}, Lines: 1},   // one line of G
+		{RelFile: "p/a.go", LineRange: LineRange{20, 20}, Lines: 0}, // a removal inside K
+		{RelFile: "p/a.go", LineRange: LineRange{25, 27}, Lines: 3}, // the end of T and past it
+		{RelFile: "p/b.go", LineRange: LineRange{1, 1}, Lines: 1},   // above L
+	}}
+	if err := applyChangeSet(head, history, NewProgress(false)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := queryRows(t, head, `SELECT node_id, change, changed_lines FROM change_set ORDER BY node_id`),
+		[]string{"p::F|added|3", "p::G|modified|1", "p::K|modified|0", "p::T|modified|2"}; !slices.Equal(got, want) {
+		t.Errorf("change_set = %q, want %q", got, want)
+	}
+	want := []string{"p::c1|1", "p::s|1", "p::c2|2", "p::c3|3", "p::c4|4", "p::c5|5", "p::c6|6", "p::c7|7", "p::c8|8"}
+	if got := queryRows(t, head, `SELECT function_id, depth FROM change_impact WHERE changed_id = 'p::G' ORDER BY depth, function_id`); !slices.Equal(got, want) {
+		t.Errorf("change_impact of G = %q, want %q", got, want)
+	}
+	if got := queryRows(t, head, `SELECT COUNT(*) FROM change_impact WHERE changed_id != 'p::G'`); got[0] != "0" {
+		t.Errorf("%s impact rows for symbols other than G, want none", got[0])
+	}
+	if got, want := queryRows(t, head, `SELECT * FROM change_packages ORDER BY package`),
+		[]string{"p|4|5", "q|0|4"}; !slices.Equal(got, want) {
+		t.Errorf("change_packages = %q, want %q", got, want)
+	}
+
+	// New since the base: F's finding, the second deep if in G, and H's
+	// finding, which is outside the changed code.
+	if got, want := queryRows(t, head, `SELECT f.category, f.node_id FROM change_new_findings nf JOIN findings f ON f.id = nf.finding_id ORDER BY f.id`),
+		[]string{"unused_param|p::F", "nesting|p::G/if#0", "dead_code|p::H"}; !slices.Equal(got, want) {
+		t.Errorf("change_new_findings = %q, want %q", got, want)
+	}
+	if got, want := queryRows(t, head, `SELECT category, node_id FROM v_changed_code_findings ORDER BY id`),
+		[]string{"unused_param|p::F", "nesting|p::G/if#0"}; !slices.Equal(got, want) {
+		t.Errorf("v_changed_code_findings = %q, want %q", got, want)
+	}
+}
+
+// With -changed-since and no -base-db, the base CPG is generated from a
+// worktree of the base commit, and only findings it lacks are listed for
+// the changed code: Load is modified but its findings are inherited, Reset
+// is new and so are its findings.
+func TestChangedSinceNewFindings(t *testing.T) {
+	dir := fixtureModule(t)
+	git := testGit(t, dir)
+	git("init", "-q")
+	git("add", ".")
+	git("commit", "-q", "-m", "base")
+
+	path := filepath.Join(dir, "api", "api.go")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := strings.Replace(string(content), "\tv := os.Getenv(name)\n", "\tname = strings.TrimSpace(name)\n\tv := os.Getenv(name)\n", 1)
+	src += "\n// Reset is a no-op.\nfunc (srv *Server) Reset(force bool) {}\n"
+	writeFile(t, path, src)
+
+	out := filepath.Join(t.TempDir(), "cpg.db")
+	runCPGGen(t, "-skip-vet", "-changed-since=HEAD", dir, out)
+
+	conn, err := sqlite.OpenConn(out, sqlite.OpenReadOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	if got := queryRows(t, conn, `SELECT name FROM change_set ORDER BY name`); !slices.Equal(got, []string{"*Server.Load", "*Server.Reset"}) {
+		t.Errorf("change_set = %q, want Load and Reset", got)
+	}
+	inherited := queryRows(t, conn, `SELECT f.message FROM findings f JOIN nodes n ON n.id = f.node_id
+WHERE (n.id = 'api::*Server.Load@api.go:32:1' OR n.parent_function = 'api::*Server.Load@api.go:32:1')
+  AND f.id NOT IN (SELECT finding_id FROM change_new_findings)`)
+	if len(inherited) == 0 {
+		t.Error("no inherited findings in Load; the fixture no longer tests the base comparison")
+	}
+	got := queryRows(t, conn, `SELECT category, message FROM v_changed_code_findings ORDER BY category`)
+	if len(got) == 0 {
+		t.Fatal("no findings in changed code, want Reset's")
+	}
+	for _, f := range got {
+		if !strings.Contains(f, "Reset") {
+			t.Errorf("changed-code finding %q is not about Reset", f)
+		}
+	}
+}
@@ -19,6 +19,32 @@ func StmtID(pkg, file string, line, col int, kind string) string {
 	return fmt.Sprintf("%s::@%s:%d:%d:%s", pkg, file, line, col, kind)
 }
 
+// SymbolID generates a line-independent ID for a package-level declaration:
+// pkg::Name, or pkg::Recv.Name for methods. The receiver is given without
+// pointer or type parameters, so changing either keeps the ID.
+func SymbolID(pkg, recv, name string) string {
+	if recv != "" {
+		recv = strings.TrimPrefix(recv, "*")
+		if i := strings.IndexByte(recv, '['); i >= 0 {
+			recv = recv[:i]
+		}
+		return fmt.Sprintf("%s::%s.%s", pkg, recv, name)
+	}
+	return fmt.Sprintf("%s::%s", pkg, name)
+}
+
+// MemberID generates a line-independent ID for a named member of a
+// declaration (field, parameter, result, type parameter): parent.name.
+func MemberID(parent, name string) string {
+	return parent + "." + name
+}
+
+// PathID generates a line-independent structural ID for the ordinal-th
+// child of a kind under a parent: parent/kind#ordinal.
+func PathID(parent, kind string, ordinal int) string {
+	return fmt.Sprintf("%s/%s#%d", parent, kind, ordinal)
+}
+
 // PkgID generates a node ID for a package.
 func PkgID(pkgPath string) string {
 	return fmt.Sprintf("pkg::%s", modSet.RelPkg(pkgPath))
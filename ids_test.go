@@ -0,0 +1,133 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+func TestSymbolID(t *testing.T) {
+	tests := []struct {
+		pkg, recv, name string
+		want            string
+	}{
+		{"internal/server", "", "New", "internal/server::New"},
+		{"internal/server", "Server", "Run", "internal/server::Server.Run"},
+		{"internal/server", "*Server", "Run", "internal/server::Server.Run"},
+		{"pkg", "List[T]", "Len", "pkg::List.Len"},
+		{"pkg", "*Map[K, V]", "Get", "pkg::Map.Get"},
+	}
+	for _, tt := range tests {
+		if got := SymbolID(tt.pkg, tt.recv, tt.name); got != tt.want {
+			t.Errorf("SymbolID(%q, %q, %q) = %q, want %q", tt.pkg, tt.recv, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPathID(t *testing.T) {
+	fn := SymbolID("pkg", "", "F")
+	if got, want := PathID(fn, "if", 2), "pkg::F/if#2"; got != want {
+		t.Errorf("PathID = %q, want %q", got, want)
+	}
+	if got, want := PathID(PathID(fn, "if", 0), "call", 1), "pkg::F/if#0/call#1"; got != want {
+		t.Errorf("nested PathID = %q, want %q", got, want)
+	}
+	if got, want := MemberID(fn, "err"), "pkg::F.err"; got != want {
+		t.Errorf("MemberID = %q, want %q", got, want)
+	}
+}
+
+// Structural IDs number children per parent and kind, so they do not depend
+// on line numbers or on children of other kinds.
+func TestVisitorStableIDs(t *testing.T) {
+	v := &astVisitor{
+		parentStack: []string{"file::a.go", "pkg::F@a.go:3:1"},
+		stableIDs:   map[string]string{"pkg::F@a.go:3:1": "pkg::F"},
+		ordinals:    make(map[string]int),
+	}
+	got := []string{
+		v.nextPathID("if"),
+		v.nextPathID("call"),
+		v.nextPathID("if"),
+		v.memberID("param", "x"),
+		v.memberID("param", "_"),
+		v.memberID("param", "_"),
+	}
+	want := []string{"pkg::F/if#0", "pkg::F/call#0", "pkg::F/if#1", "pkg::F.x", "pkg::F/param#0", "pkg::F/param#1"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ID %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	// The file node has no mapping: its own ID is already line-independent.
+	// init functions, which may repeat, are numbered under it.
+	v.parentStack = v.parentStack[:1]
+	got = []string{v.nextPathID("comment"), v.nextPathID("init"), v.nextPathID("init")}
+	want = []string{"file::a.go/comment#0", "file::a.go/init#0", "file::a.go/init#1"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("top-level ID %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+// A declaration in the files of two build configurations gets one stable
+// ID; the later one in file order is numbered, and the IDs derived from it
+// follow.
+func TestAssignStableIDsUnique(t *testing.T) {
+	conn, err := openDB(filepath.Join(t.TempDir(), "cpg.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	err = sqlitex.ExecuteScript(conn, `
+INSERT INTO nodes (id, kind, name, file, line, col, parent_function, properties, stable_id) VALUES
+  ('pkg::init@pkg/a.go:3:1', 'function', 'init', 'pkg/a.go', 3, 1, NULL, NULL, 'file::pkg/a.go/init#0'),
+  ('pkg::init@pkg/a.go:9:1', 'function', 'init', 'pkg/a.go', 9, 1, NULL, NULL, 'file::pkg/a.go/init#1'),
+  ('pkg::init@pkg/b.go:3:1', 'function', 'init', 'pkg/b.go', 3, 1, NULL, NULL, 'file::pkg/b.go/init#0'),
+  ('pkg::F@pkg/f_windows.go:3:1', 'function', 'F', 'pkg/f_windows.go', 3, 1, NULL, NULL, 'pkg::F'),
+  ('pkg::F@pkg/f_linux.go:3:1', 'function', 'F', 'pkg/f_linux.go', 3, 1, NULL, NULL, 'pkg::F'),
+  ('pkg::F@pkg/f_linux.go:3:1::bb0', 'basic_block', 'bb0', 'pkg/f_linux.go', 3, 1, 'pkg::F@pkg/f_linux.go:3:1', '{"index":0}', NULL),
+  ('pkg::F@pkg/f_windows.go:3:1::bb0', 'basic_block', 'bb0', 'pkg/f_windows.go', 3, 1, 'pkg::F@pkg/f_windows.go:3:1', '{"index":0}', NULL),
+  ('ext::fmt.Println', 'function', 'Println', NULL, NULL, NULL, NULL, NULL, NULL);`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := assignStableIDs(conn, NewProgress(false)); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string)
+	err = sqlitex.ExecuteTransient(conn, `SELECT id, stable_id FROM nodes`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			got[stmt.ColumnText(0)] = stmt.ColumnText(1)
+			return nil
+		}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"pkg::init@pkg/a.go:3:1":           "file::pkg/a.go/init#0",
+		"pkg::init@pkg/a.go:9:1":           "file::pkg/a.go/init#1",
+		"pkg::init@pkg/b.go:3:1":           "file::pkg/b.go/init#0",
+		"pkg::F@pkg/f_linux.go:3:1":        "pkg::F",
+		"pkg::F@pkg/f_windows.go:3:1":      "pkg::F#1",
+		"pkg::F@pkg/f_linux.go:3:1::bb0":   "pkg::F::bb0",
+		"pkg::F@pkg/f_windows.go:3:1::bb0": "pkg::F#1::bb0",
+		"ext::fmt.Println":                 "ext::fmt.Println",
+	}
+	for id, w := range want {
+		if got[id] != w {
+			t.Errorf("stable_id of %s = %q, want %q", id, got[id], w)
+		}
+	}
+
+	err = sqlitex.ExecuteTransient(conn,
+		`INSERT INTO nodes (id, kind, name, stable_id) VALUES ('pkg::F@pkg/f.go:1:1', 'function', 'F', 'pkg::F')`, nil)
+	if err == nil {
+		t.Error("inserting a duplicate stable_id succeeded, want the unique index to reject it")
+	}
+}
@@ -0,0 +1,620 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/token"
+	"io"
+	"maps"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/types/objectpath"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// hashSchemaVersion is mixed into every package hash. Bump it whenever the
+// generator's output changes shape so stale DBs are fully regenerated.
+const hashSchemaVersion = "6"
+
+// flagIncremental records the lookups of every walked package in the output
+// DB (package_lookups), so that a later -incremental run can restore them
+// instead of walking an unchanged package. Set by main before any pipeline
+// phase runs.
+var flagIncremental bool
+
+// IncrementalState describes which packages must be regenerated when
+// updating an existing CPG database in place.
+//
+// Unchanged packages are not walked: WalkAST restores their position,
+// function and declaration lookups from the previous DB, and CFG/DFG/CDG,
+// unchecked errors, function summaries and metrics skip them. Call edges are
+// skipped for their functions whose calls are all static, since no other
+// package can change where those go. WriteDB then copies the nodes, edges,
+// sources and side-table rows of the skipped work from the previous DB.
+//
+// Package loading, SSA and the whole-program phases (the call graph
+// algorithm itself, points-to, channels, panics, type relationships, tests,
+// compiler diagnostics, go/analysis and git history) still cover the whole
+// workspace, and WriteDB rebuilds every derived table.
+type IncrementalState struct {
+	PrevPath string          // previous DB, attached during WriteDB
+	Dirty    map[string]bool // full import paths that changed (incl. reverse deps)
+	CleanRel map[string]bool // module-relative paths of unchanged packages
+	Removed  int             // packages present in the previous DB only
+
+	// Function node IDs whose rows are copied from the previous DB, filled
+	// by the phases that skip them (see reusedFuncIDs).
+	funcs   map[string]bool // unchecked errors and function summaries
+	callers map[string]bool // call, call_site, param_in/out, call_to_return edges
+
+	// Read-only connection to the previous DB for restoring lookups.
+	mu   sync.Mutex
+	prev *sqlite.Conn
+	err  error // first restore error, reported by WriteDB
+
+	uncheckedRows int // unchecked_errors rows copied by mergePreviousCPG
+}
+
+// Global instance — nil means a full regeneration. Set in main() after
+// package hashes are compared, before any extraction phase runs.
+var incr *IncrementalState
+
+// SkipPkg reports whether per-function extraction can be skipped for a
+// package because its previous output is reused.
+func (s *IncrementalState) SkipPkg(pkgPath string) bool {
+	if s == nil {
+		return false
+	}
+	return !s.Dirty[pkgPath]
+}
+
+// ComputePackageHashes hashes each known package's compiled files together
+// with the hashes of its dependencies, so a change to any package also
+// changes the hash of every package that (transitively) imports it.
+func ComputePackageHashes(pkgs []*packages.Package) map[string]string {
+	salt := configSalt()
+	memo := make(map[string]string)
+
+	var hashPkg func(p *packages.Package) string
+	hashPkg = func(p *packages.Package) string {
+		if h, ok := memo[p.PkgPath]; ok {
+			return h
+		}
+		memo[p.PkgPath] = "" // cycle guard (import cycles only occur in broken code)
+
+		h := sha256.New()
+		io.WriteString(h, salt)
+		io.WriteString(h, p.PkgPath+"\n")
+
+		if modSet.IsKnownPkg(p.PkgPath) {
+			for _, f := range p.CompiledGoFiles {
+				io.WriteString(h, BaseName(f)+"\n")
+				if data, err := os.ReadFile(f); err == nil {
+					h.Write(data)
+				}
+			}
+		} else if len(p.CompiledGoFiles) > 0 {
+			// External packages live in the module cache or GOROOT, whose
+			// paths carry the module version.
+			io.WriteString(h, p.CompiledGoFiles[0]+"\n")
+		}
+
+		imports := make([]string, 0, len(p.Imports))
+		for path := range p.Imports {
+			imports = append(imports, path)
+		}
+		slices.Sort(imports)
+		for _, path := range imports {
+			io.WriteString(h, path+"="+hashPkg(p.Imports[path])+"\n")
+		}
+
+		sum := hex.EncodeToString(h.Sum(nil))
+		memo[p.PkgPath] = sum
+		return sum
+	}
+
+	hashes := make(map[string]string, len(pkgs))
+	for _, p := range pkgs {
+		hashes[p.PkgPath] = hashPkg(p)
+	}
+	return hashes
+}
+
+// configSalt captures the settings that change generator output for
+// otherwise identical sources.
+func configSalt() string {
+	var b strings.Builder
+	b.WriteString("schema=" + hashSchemaVersion + "\n")
+	b.WriteString("go=" + runtime.Version() + "\n")
+	b.WriteString("skip_tests=" + strconv.FormatBool(flagSkipTests) + "\n")
+	b.WriteString("skip_generated=" + strconv.FormatBool(flagSkipGenerated) + "\n")
+	b.WriteString("generated=" + strings.Join(generatedPatterns, ",") + "\n")
+	b.WriteString("tags=" + strings.Join(buildTags, ",") + "\n")
+	b.WriteString("callgraph=" + callGraphAlgo + "\n")
+	if activeBuild != nil {
+		b.WriteString("build=" + activeBuild.Name + "\n")
+	}
+	for _, m := range modSet.Dirs() {
+		b.WriteString("module=" + m.ModPath + ":" + m.Prefix + "\n")
+	}
+	return b.String()
+}
+
+// PlanIncremental compares the current package hashes against those stored
+// in the previous DB at path. It returns nil when no usable previous DB
+// exists, in which case a full regeneration is required.
+func PlanIncremental(path string, hashes map[string]string, prog *Progress) *IncrementalState {
+	if _, err := os.Stat(path); err != nil {
+		prog.Log("Incremental: no previous DB at %s, doing full generation", path)
+		return nil
+	}
+	prev, err := readPackageHashes(path)
+	if err != nil {
+		prog.Log("Incremental: cannot read package hashes from %s (%v), doing full generation", path, err)
+		return nil
+	}
+	if len(prev) > 0 && !hasPackageLookups(path) {
+		prog.Log("Incremental: %s was not written with -incremental, doing full generation", path)
+		return nil
+	}
+
+	state := &IncrementalState{
+		PrevPath: path,
+		Dirty:    make(map[string]bool),
+		CleanRel: make(map[string]bool),
+	}
+	for pkg, h := range hashes {
+		if prev[pkg] == h {
+			state.CleanRel[modSet.RelPkg(pkg)] = true
+		} else {
+			state.Dirty[pkg] = true
+			prog.Verbose("  changed: %s", pkg)
+		}
+	}
+	for pkg := range prev {
+		if _, ok := hashes[pkg]; !ok {
+			state.Removed++
+		}
+	}
+	prog.Log("Incremental: %d changed, %d unchanged, %d removed packages",
+		len(state.Dirty), len(state.CleanRel), state.Removed)
+
+	if len(state.CleanRel) == 0 {
+		prog.Log("Incremental: nothing to reuse, doing full generation")
+		return nil
+	}
+	return state
+}
+
+// UpToDate reports whether the previous DB can be kept as is.
+func (s *IncrementalState) UpToDate() bool {
+	return s != nil && len(s.Dirty) == 0 && s.Removed == 0
+}
+
+func readPackageHashes(path string) (map[string]string, error) {
+	conn, err := sqlite.OpenConn(path, sqlite.OpenReadOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	hashes := make(map[string]string)
+	err = sqlitex.ExecuteTransient(conn, "SELECT package, hash FROM package_hashes",
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			hashes[stmt.ColumnText(0)] = stmt.ColumnText(1)
+			return nil
+		}})
+	if err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// hasPackageLookups reports whether the DB at path holds the lookups of its
+// packages, which only runs with -incremental record.
+func hasPackageLookups(path string) bool {
+	conn, err := sqlite.OpenConn(path, sqlite.OpenReadOnly)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = conn.Close() }()
+
+	found := false
+	err = sqlitex.ExecuteTransient(conn, "SELECT 1 FROM package_lookups LIMIT 1",
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			found = true
+			return nil
+		}})
+	return err == nil && found
+}
+
+func insertPackageHashes(conn *sqlite.Conn, hashes map[string]string, prog *Progress) error {
+	stmt, err := conn.Prepare(`INSERT OR REPLACE INTO package_hashes (package, rel_package, hash) VALUES (?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("prepare package hash insert: %w", err)
+	}
+	defer func() { _ = stmt.Finalize() }()
+
+	for pkg, h := range hashes {
+		stmt.BindText(1, pkg)
+		stmt.BindText(2, modSet.RelPkg(pkg))
+		stmt.BindText(3, h)
+		if _, err := stmt.Step(); err != nil {
+			return fmt.Errorf("insert package hash %s: %w", pkg, err)
+		}
+		_ = stmt.Reset()
+	}
+
+	prog.Verbose("Inserted %d package hashes", len(hashes))
+	return nil
+}
+
+// PackageLookup is one entry of the lookups WalkAST builds for a package:
+// a position (pos, decl), a function position or line (func, func_line) or
+// a declaration (def, keyed by its object path; locals are not recorded).
+// With -incremental they are stored in package_lookups, so that a later run
+// can restore the lookups of an unchanged package instead of walking it.
+type PackageLookup struct {
+	Package string // module-relative package path
+	Kind    string
+	Key     string
+	ID      string
+}
+
+// packageLookups returns the lookups of one package, as recorded by a
+// later restorePackageLookups.
+func packageLookups(pkg *packages.Package, relPkg string, pl *PosLookup, fl *FuncLookup, dl *DefLookup) []PackageLookup {
+	var rows []PackageLookup
+	add := func(kind string, m map[string]string) {
+		for key, id := range m {
+			rows = append(rows, PackageLookup{relPkg, kind, key, id})
+		}
+	}
+	add("pos", pl.m)
+	add("decl", pl.decls)
+	add("func", fl.m)
+	add("func_line", fl.lines)
+
+	var enc objectpath.Encoder
+	for obj, id := range dl.m {
+		if obj.Pkg() != pkg.Types {
+			continue
+		}
+		if path, err := enc.For(obj); err == nil {
+			rows = append(rows, PackageLookup{relPkg, "def", string(path), id})
+		}
+	}
+	return rows
+}
+
+// restorePackageLookups fills the lookups of pkg from the rows recorded by
+// packageLookups in an earlier run.
+func restorePackageLookups(pkg *packages.Package, rows []PackageLookup, pl *PosLookup, fl *FuncLookup, dl *DefLookup) {
+	for _, r := range rows {
+		switch r.Kind {
+		case "pos":
+			pl.m[r.Key] = r.ID
+		case "decl":
+			pl.decls[r.Key] = r.ID
+		case "func":
+			fl.m[r.Key] = r.ID
+		case "func_line":
+			fl.lines[r.Key] = r.ID
+		case "def":
+			if obj, err := objectpath.Object(pkg.Types, objectpath.Path(r.Key)); err == nil {
+				dl.m[obj] = r.ID
+			}
+		}
+	}
+}
+
+// previousLookups reads the lookups of an unchanged package from the
+// previous DB. Errors are sticky and surface from WriteDB, which would
+// otherwise copy the graph of a package whose lookups are missing.
+func (s *IncrementalState) previousLookups(relPkg string) []PackageLookup {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil
+	}
+	if s.prev == nil {
+		if s.prev, s.err = sqlite.OpenConn(s.PrevPath, sqlite.OpenReadOnly); s.err != nil {
+			s.err = fmt.Errorf("open previous DB: %w", s.err)
+			return nil
+		}
+	}
+	var rows []PackageLookup
+	err := sqlitex.Execute(s.prev, `SELECT kind, key, id FROM package_lookups WHERE package = ?`,
+		&sqlitex.ExecOptions{
+			Args: []any{relPkg},
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				rows = append(rows, PackageLookup{relPkg, stmt.ColumnText(0), stmt.ColumnText(1), stmt.ColumnText(2)})
+				return nil
+			},
+		})
+	if err != nil {
+		s.err = fmt.Errorf("restore lookups of %s: %w", relPkg, err)
+		return nil
+	}
+	return rows
+}
+
+// closePrevious closes the connection opened by previousLookups, once
+// WalkAST is done with it; WriteDB moves the previous DB aside and attaches
+// it instead.
+func (s *IncrementalState) closePrevious() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.prev != nil {
+		_ = s.prev.Close()
+		s.prev = nil
+	}
+}
+
+// previousCalls calls fn for the call edges of reused callers in the
+// previous DB.
+func (s *IncrementalState) previousCalls(fn func(source, target string)) error {
+	if len(s.callers) == 0 {
+		return nil
+	}
+	conn, err := sqlite.OpenConn(s.PrevPath, sqlite.OpenReadOnly)
+	if err != nil {
+		return fmt.Errorf("open previous DB: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	return sqlitex.ExecuteTransient(conn, `SELECT source, target FROM edges WHERE kind = 'call' ORDER BY source, target`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			if source := stmt.ColumnText(0); s.callers[source] {
+				fn(source, stmt.ColumnText(1))
+			}
+			return nil
+		}})
+}
+
+// reusable reports whether fn's own output can be copied from the previous
+// DB: its package is unchanged, and it is neither generic nor an instance
+// (or a closure of one), whose type arguments may come from a changed
+// package.
+func (s *IncrementalState) reusable(fn *ssa.Function) bool {
+	if fn.Pkg == nil || !s.SkipPkg(fn.Pkg.Pkg.Path()) {
+		return false
+	}
+	for f := fn; f != nil; f = f.Parent() {
+		if f.TypeParams().Len() > 0 || len(f.TypeArgs()) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// reusedFuncIDs returns the node IDs of the functions in fns whose rows are
+// copied from the previous DB: every function mapped to the ID is reusable
+// and satisfies ok (if non-nil).
+func (s *IncrementalState) reusedFuncIDs(fns []*ssa.Function, fset *token.FileSet, funcLookup *FuncLookup, ok func(*ssa.Function) bool) map[string]bool {
+	reused := make(map[string]bool)
+	for _, fn := range fns {
+		id := ssaFuncNodeID(fn, fset, funcLookup)
+		if id == "" {
+			continue
+		}
+		r, seen := reused[id]
+		reused[id] = (!seen || r) && s.reusable(fn) && (ok == nil || ok(fn))
+	}
+	maps.DeleteFunc(reused, func(_ string, r bool) bool { return !r })
+	return reused
+}
+
+// reusedFuncs returns the known-module functions whose unchecked errors and
+// summaries are copied from the previous DB, or nil without one.
+func (s *IncrementalState) reusedFuncs(ssaResult *SSAResult, fset *token.FileSet, funcLookup *FuncLookup) map[string]bool {
+	if s == nil {
+		return nil
+	}
+	if s.funcs == nil {
+		s.funcs = s.reusedFuncIDs(ssaResult.KnownFuncs(fset), fset, funcLookup, nil)
+	}
+	return s.funcs
+}
+
+// astEdgeKinds are the edge kinds of the AST walk. Like the nodes of the
+// walk, they belong to the package of their source node.
+const astEdgeKinds = `'ast', 'argument', 'condition', 'defer_order', 'doc', 'error_wrap', 'has_method',
+  'imports', 'init_order', 'initializer', 'next_sibling', 'receiver', 'scope', 'spawn', 'spawn_call',
+  'ref', 'eval_type', 'branch_target'`
+
+// reusedEdgeKinds are the per-function edge kinds whose extraction is
+// skipped for unchanged packages and copied from the previous DB instead.
+// They belong to the package of their source node, except field_load edges,
+// which start at a field declaration and belong to the reading function.
+const reusedEdgeKinds = `'cfg', 'dfg', 'capture', 'cdg', 'dom', 'pdom', 'field_store', 'field_load'`
+
+// mergePreviousCPG copies the output of skipped work for unchanged packages
+// from the previous DB (attached as "prev"): their nodes, AST and
+// per-function edges, sources and lookups, the call edges of reused
+// callers, and the metrics, summaries and unchecked errors of their
+// functions. Nodes and edges of the whole-program phases are regenerated,
+// and derived tables are rebuilt afterwards as usual.
+func mergePreviousCPG(conn *sqlite.Conn, state *IncrementalState, prog *Progress) error {
+	if state.err != nil {
+		return state.err
+	}
+	if err := sqlitex.ExecuteScript(conn, `
+CREATE TEMP TABLE clean_pkgs (package TEXT PRIMARY KEY);
+CREATE TEMP TABLE reused_funcs (id TEXT PRIMARY KEY);
+CREATE TEMP TABLE reused_callers (id TEXT PRIMARY KEY);`, nil); err != nil {
+		return err
+	}
+	for _, t := range []struct {
+		table string
+		ids   map[string]bool
+	}{{"clean_pkgs", state.CleanRel}, {"reused_funcs", state.funcs}, {"reused_callers", state.callers}} {
+		if err := insertIDs(conn, t.table, t.ids); err != nil {
+			return err
+		}
+	}
+
+	// Nodes of the walk and of per-function extraction. External stubs and
+	// allocation sites come from whole-program phases and are regenerated.
+	if err := sqlitex.ExecuteTransient(conn,
+		`INSERT OR IGNORE INTO nodes (id, kind, name, file, line, col, end_line, package, parent_function, type_info, properties, stable_id)
+		 SELECT n.id, n.kind, n.name, n.file, n.line, n.col, n.end_line, n.package, n.parent_function, n.type_info, n.properties, n.stable_id
+		 FROM prev.nodes n
+		 WHERE n.package IN (SELECT package FROM clean_pkgs)
+		   AND n.id NOT LIKE 'ext::%' AND n.kind NOT IN ('alloc_site', 'meta_data')`,
+		nil); err != nil {
+		return fmt.Errorf("reuse nodes: %w", err)
+	}
+	reusedNodes := conn.Changes()
+
+	// External field stubs belong to no analyzed package: keep those that
+	// the field edges of unchanged packages, reused below, point at.
+	if err := sqlitex.ExecuteTransient(conn,
+		`INSERT OR IGNORE INTO nodes (id, kind, name, file, line, col, end_line, package, parent_function, type_info, properties)
+		 SELECT DISTINCT n.id, n.kind, n.name, n.file, n.line, n.col, n.end_line, n.package, n.parent_function, n.type_info, n.properties
+		 FROM prev.nodes n
+		 JOIN prev.edges e ON (e.kind = 'field_load' AND e.source = n.id) OR (e.kind = 'field_store' AND e.target = n.id)
+		 JOIN prev.nodes site ON site.id = CASE e.kind WHEN 'field_load' THEN e.target ELSE e.source END
+		 WHERE n.kind = 'field' AND n.id LIKE 'ext::%'
+		   AND site.package IN (SELECT package FROM clean_pkgs)`,
+		nil); err != nil {
+		return fmt.Errorf("reuse external field stubs: %w", err)
+	}
+	reusedNodes += conn.Changes()
+
+	// Heuristic DFG edges are excluded: they are re-inferred from flow_semantics.
+	if err := sqlitex.ExecuteTransient(conn,
+		`INSERT INTO edges (source, target, kind, properties)
+		 SELECT e.source, e.target, e.kind, e.properties
+		 FROM prev.edges e
+		 JOIN prev.nodes n ON n.id = CASE e.kind WHEN 'field_load' THEN e.target ELSE e.source END
+		 WHERE e.kind IN (`+astEdgeKinds+`, `+reusedEdgeKinds+`)
+		   AND n.package IN (SELECT package FROM clean_pkgs)
+		   AND json_extract(e.properties, '$.heuristic') IS NULL`,
+		nil); err != nil {
+		return fmt.Errorf("reuse edges: %w", err)
+	}
+	reusedEdges := conn.Changes()
+
+	// Call edges of reused callers: call and call_to_return start at the
+	// caller, call_site and param_in at a node in its body, and param_out
+	// ends at one.
+	if err := sqlitex.ExecuteTransient(conn,
+		`INSERT INTO edges (source, target, kind, properties)
+		 SELECT e.source, e.target, e.kind, e.properties
+		 FROM prev.edges e
+		 WHERE e.kind IN ('call', 'call_to_return') AND e.source IN (SELECT id FROM reused_callers)
+		 UNION ALL
+		 SELECT e.source, e.target, e.kind, e.properties
+		 FROM prev.edges e
+		 JOIN prev.nodes n ON n.id = CASE e.kind WHEN 'param_out' THEN e.target ELSE e.source END
+		 WHERE e.kind IN ('call_site', 'param_in', 'param_out')
+		   AND n.parent_function IN (SELECT id FROM reused_callers)`,
+		nil); err != nil {
+		return fmt.Errorf("reuse call edges: %w", err)
+	}
+	reusedEdges += conn.Changes()
+
+	if err := sqlitex.ExecuteScript(conn, `
+INSERT OR IGNORE INTO sources (file, content, package)
+SELECT file, content, package FROM prev.sources
+WHERE file IN (SELECT file FROM prev.nodes WHERE kind = 'file' AND package IN (SELECT package FROM clean_pkgs));
+
+INSERT OR IGNORE INTO package_lookups (package, kind, key, id)
+SELECT package, kind, key, id FROM prev.package_lookups
+WHERE package IN (SELECT package FROM clean_pkgs);
+
+INSERT OR IGNORE INTO function_summaries (function_id, from_pos, to_pos, variadic, kind)
+SELECT function_id, from_pos, to_pos, variadic, kind FROM prev.function_summaries
+WHERE function_id IN (SELECT id FROM reused_funcs);`, nil); err != nil {
+		return fmt.Errorf("reuse sources, lookups and summaries: %w", err)
+	}
+	if err := sqlitex.ExecuteTransient(conn,
+		`INSERT OR IGNORE INTO unchecked_errors (function_id, call_id, callee, kind, file, line, col, reaches_return)
+		 SELECT function_id, call_id, callee, kind, file, line, col, reaches_return FROM prev.unchecked_errors
+		 WHERE function_id IN (SELECT id FROM reused_funcs)`, nil); err != nil {
+		return fmt.Errorf("reuse unchecked errors: %w", err)
+	}
+	state.uncheckedRows = conn.Changes()
+
+	// Metrics of unchanged functions replace the fan-in-only rows created
+	// for them as call targets; fan-in/out are then recounted over the
+	// merged call edges, as is recursion of the reused function nodes.
+	if err := sqlitex.ExecuteTransient(conn,
+		`INSERT OR REPLACE INTO metrics (function_id, cyclomatic_complexity, fan_in, fan_out, loc, num_params)
+		 SELECT m.function_id, m.cyclomatic_complexity, 0, 0, m.loc, m.num_params
+		 FROM prev.metrics m
+		 JOIN nodes n ON n.id = m.function_id
+		 WHERE n.kind = 'function' AND n.package IN (SELECT package FROM clean_pkgs)
+		   AND n.id NOT LIKE 'ext::%'`, nil); err != nil {
+		return fmt.Errorf("reuse metrics: %w", err)
+	}
+	if conn.Changes() > 0 {
+		if err := sqlitex.ExecuteScript(conn, fanInOutScript+`
+UPDATE nodes SET properties = json_remove(properties, '$.recursive')
+WHERE package IN (SELECT package FROM clean_pkgs) AND json_extract(properties, '$.recursive') IS NOT NULL
+  AND id NOT IN (SELECT source FROM edges WHERE kind = 'call' AND source = target);
+UPDATE nodes SET properties = json_set(COALESCE(properties, '{}'), '$.recursive', json('true'))
+WHERE id IN (SELECT source FROM edges WHERE kind = 'call' AND source = target)
+  AND json_extract(properties, '$.recursive') IS NULL;`, nil); err != nil {
+			return fmt.Errorf("fan-in/out: %w", err)
+		}
+	}
+
+	if err := sqlitex.ExecuteScript(conn,
+		`DROP TABLE temp.clean_pkgs; DROP TABLE temp.reused_funcs; DROP TABLE temp.reused_callers;`, nil); err != nil {
+		return err
+	}
+
+	prog.Log("Incremental: reused %d nodes and %d edges from unchanged packages", reusedNodes, reusedEdges)
+	return nil
+}
+
+// insertIDs fills a one-column temp table from a set.
+func insertIDs(conn *sqlite.Conn, table string, ids map[string]bool) error {
+	stmt, err := conn.Prepare(`INSERT INTO temp.` + table + ` VALUES (?)`)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stmt.Finalize() }()
+	for id := range ids {
+		stmt.BindText(1, id)
+		if _, err := stmt.Step(); err != nil {
+			return fmt.Errorf("insert into %s: %w", table, err)
+		}
+		_ = stmt.Reset()
+	}
+	return nil
+}
+
+// insertPackageLookups writes the lookups recorded with -incremental.
+func insertPackageLookups(conn *sqlite.Conn, rows []PackageLookup) error {
+	stmt, err := conn.Prepare(`INSERT OR IGNORE INTO package_lookups (package, kind, key, id) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("prepare package lookup insert: %w", err)
+	}
+	defer func() { _ = stmt.Reset() }()
+	for _, r := range rows {
+		stmt.BindText(1, r.Package)
+		stmt.BindText(2, r.Kind)
+		stmt.BindText(3, r.Key)
+		stmt.BindText(4, r.ID)
+		if _, err := stmt.Step(); err != nil {
+			return fmt.Errorf("insert package lookup: %w", err)
+		}
+		_ = stmt.Reset()
+	}
+	return nil
+}
@@ -0,0 +1,453 @@
+// Package cpgdiff compares two CPG databases built from different commits of
+// the same code base. Functions are matched by stable identity (their SCIP
+// symbol, or package and name), never by the line-bearing node ID, so moving
+// code around does not show up as a change.
+package cpgdiff
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// metricNames are the per-function metrics compared between snapshots;
+// "nodes" counts the CPG nodes inside the function, so most body edits
+// register even when the other metrics stay the same.
+var metricNames = []string{"complexity", "loc", "fan_in", "fan_out", "num_params", "nodes"}
+
+// Report is the semantic difference between two CPG databases.
+type Report struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+
+	Totals Totals `json:"totals"`
+
+	AddedFunctions   []Function       `json:"added_functions"`
+	RemovedFunctions []Function       `json:"removed_functions"`
+	ChangedFunctions []FunctionChange `json:"changed_functions"`
+
+	AddedCalls   []Call `json:"added_calls"`
+	RemovedCalls []Call `json:"removed_calls"`
+
+	NewFindings      []Finding `json:"new_findings"`
+	ResolvedFindings []Finding `json:"resolved_findings"`
+
+	AddedImports    []PackageDep    `json:"added_imports"`
+	RemovedImports  []PackageDep    `json:"removed_imports"`
+	CouplingChanges []CouplingDelta `json:"coupling_changes"`
+}
+
+// Totals holds snapshot-wide counts, old and new.
+type Totals struct {
+	Functions  [2]int `json:"functions"`
+	Complexity [2]int `json:"complexity"`
+	LOC        [2]int `json:"loc"`
+	Calls      [2]int `json:"calls"`
+	Findings   [2]int `json:"findings"`
+}
+
+// Function is a function of the analyzed code in one snapshot. Key is its
+// SCIP symbol without the module prefix ("pkg/Type#Method()."), or package
+// and name in databases without SCIP symbols.
+type Function struct {
+	Key       string         `json:"key"`
+	Name      string         `json:"name"`
+	Package   string         `json:"package"`
+	File      string         `json:"file"`
+	Line      int            `json:"line"`
+	Signature string         `json:"signature"`
+	Metrics   map[string]int `json:"metrics"`
+
+	external bool
+}
+
+// FunctionChange is a function present in both snapshots whose signature
+// or metrics differ. Deltas holds new minus old for the metrics that changed.
+type FunctionChange struct {
+	Key          string         `json:"key"`
+	Name         string         `json:"name"`
+	Package      string         `json:"package"`
+	File         string         `json:"file"`
+	Line         int            `json:"line"`
+	OldSignature string         `json:"old_signature,omitempty"`
+	NewSignature string         `json:"new_signature,omitempty"`
+	Deltas       map[string]int `json:"deltas"`
+}
+
+// Call is a call edge between two functions, by key.
+type Call struct {
+	Caller string `json:"caller"`
+	Callee string `json:"callee"`
+}
+
+// Finding is a finding anchored to the function it is in (or its file when
+// outside any function). Count is how many more (new) or fewer (resolved)
+// identical findings there are.
+type Finding struct {
+	Category string `json:"category"`
+	Severity string `json:"severity"`
+	Anchor   string `json:"anchor"`
+	Message  string `json:"message"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Count    int    `json:"count"`
+}
+
+// PackageDep is an import between two packages.
+type PackageDep struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// CouplingDelta is a change in the number of calls between two packages.
+type CouplingDelta struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Old    int    `json:"old"`
+	New    int    `json:"new"`
+}
+
+// snapshot is what one database contributes to the comparison.
+type snapshot struct {
+	funcs    map[string]*Function
+	keys     map[string]string // node ID -> function key
+	calls    map[Call]bool
+	findings map[string][]Finding // identity -> occurrences
+	rowIDs   map[string][]int64   // identity -> finding IDs of the occurrences, ascending
+	imports  map[PackageDep]bool
+	coupling map[PackageDep]int
+}
+
+// Compare loads the databases at oldPath and newPath and reports what
+// changed between them.
+func Compare(oldPath, newPath string) (*Report, error) {
+	oldSnap, err := load(oldPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", oldPath, err)
+	}
+	newSnap, err := load(newPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", newPath, err)
+	}
+
+	r := &Report{Old: oldPath, New: newPath}
+	for i, s := range []*snapshot{oldSnap, newSnap} {
+		for _, f := range s.funcs {
+			if f.external {
+				continue
+			}
+			r.Totals.Functions[i]++
+			r.Totals.Complexity[i] += f.Metrics["complexity"]
+			r.Totals.LOC[i] += f.Metrics["loc"]
+		}
+		r.Totals.Calls[i] = len(s.calls)
+		for _, fs := range s.findings {
+			r.Totals.Findings[i] += len(fs)
+		}
+	}
+
+	for key, nf := range newSnap.funcs {
+		if nf.external {
+			continue
+		}
+		of, ok := oldSnap.funcs[key]
+		if !ok || of.external {
+			r.AddedFunctions = append(r.AddedFunctions, *nf)
+			continue
+		}
+		if c, changed := diffFunction(of, nf); changed {
+			r.ChangedFunctions = append(r.ChangedFunctions, c)
+		}
+	}
+	for key, of := range oldSnap.funcs {
+		if nf, ok := newSnap.funcs[key]; !of.external && (!ok || nf.external) {
+			r.RemovedFunctions = append(r.RemovedFunctions, *of)
+		}
+	}
+	byFunction := func(a, b Function) int {
+		return cmp.Or(cmp.Compare(a.Package, b.Package), cmp.Compare(a.Name, b.Name), cmp.Compare(a.Key, b.Key))
+	}
+	slices.SortFunc(r.AddedFunctions, byFunction)
+	slices.SortFunc(r.RemovedFunctions, byFunction)
+	// Biggest complexity moves first
+	slices.SortFunc(r.ChangedFunctions, func(a, b FunctionChange) int {
+		return cmp.Or(cmp.Compare(abs(b.Deltas["complexity"]), abs(a.Deltas["complexity"])),
+			cmp.Compare(a.Package, b.Package), cmp.Compare(a.Name, b.Name), cmp.Compare(a.Key, b.Key))
+	})
+
+	r.AddedCalls = setDiff(newSnap.calls, oldSnap.calls, compareCall)
+	r.RemovedCalls = setDiff(oldSnap.calls, newSnap.calls, compareCall)
+	r.AddedImports = setDiff(newSnap.imports, oldSnap.imports, compareDep)
+	r.RemovedImports = setDiff(oldSnap.imports, newSnap.imports, compareDep)
+
+	for dep, n := range newSnap.coupling {
+		if o := oldSnap.coupling[dep]; o != n {
+			r.CouplingChanges = append(r.CouplingChanges, CouplingDelta{Source: dep.Source, Target: dep.Target, Old: o, New: n})
+		}
+	}
+	for dep, o := range oldSnap.coupling {
+		if _, ok := newSnap.coupling[dep]; !ok {
+			r.CouplingChanges = append(r.CouplingChanges, CouplingDelta{Source: dep.Source, Target: dep.Target, Old: o})
+		}
+	}
+	slices.SortFunc(r.CouplingChanges, func(a, b CouplingDelta) int {
+		return cmp.Or(cmp.Compare(a.Source, b.Source), cmp.Compare(a.Target, b.Target))
+	})
+
+	r.NewFindings = findingDiff(newSnap.findings, oldSnap.findings)
+	r.ResolvedFindings = findingDiff(oldSnap.findings, newSnap.findings)
+	return r, nil
+}
+
+func diffFunction(of, nf *Function) (FunctionChange, bool) {
+	c := FunctionChange{
+		Key:     nf.Key,
+		Name:    nf.Name,
+		Package: nf.Package,
+		File:    nf.File,
+		Line:    nf.Line,
+		Deltas:  make(map[string]int),
+	}
+	changed := false
+	if of.Signature != nf.Signature {
+		c.OldSignature, c.NewSignature = of.Signature, nf.Signature
+		changed = true
+	}
+	for _, m := range metricNames {
+		if d := nf.Metrics[m] - of.Metrics[m]; d != 0 {
+			c.Deltas[m] = d
+			changed = true
+		}
+	}
+	return c, changed
+}
+
+// findingDiff returns the findings of a in excess of identical ones in b.
+func findingDiff(a, b map[string][]Finding) []Finding {
+	var out []Finding
+	for id, fs := range a {
+		if n := len(fs) - len(b[id]); n > 0 {
+			f := fs[0]
+			f.Count = n
+			out = append(out, f)
+		}
+	}
+	slices.SortFunc(out, func(x, y Finding) int {
+		return cmp.Or(cmp.Compare(x.Category, y.Category), cmp.Compare(x.File, y.File),
+			cmp.Compare(x.Line, y.Line), cmp.Compare(x.Message, y.Message))
+	})
+	return out
+}
+
+func setDiff[T comparable](a, b map[T]bool, compare func(x, y T) int) []T {
+	var out []T
+	for x := range a {
+		if !b[x] {
+			out = append(out, x)
+		}
+	}
+	slices.SortFunc(out, compare)
+	return out
+}
+
+func compareCall(a, b Call) int {
+	return cmp.Or(cmp.Compare(a.Caller, b.Caller), cmp.Compare(a.Callee, b.Callee))
+}
+
+func compareDep(a, b PackageDep) int {
+	return cmp.Or(cmp.Compare(a.Source, b.Source), cmp.Compare(a.Target, b.Target))
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// scipPrefixRe matches the scheme, manager, module and version that start
+// every SCIP symbol of a database ("scip-go gomod example.com/m v0 ").
+var scipPrefixRe = regexp.MustCompile(`^scip-go gomod \S+ \S+ `)
+
+// nodeIDRe matches the position part of a node ID in finding messages
+// ("pkg::F@file.go:12:1"), which moves with unrelated edits.
+var nodeIDRe = regexp.MustCompile(`@[\w./-]+:\d+:\d+(:\w+)?`)
+
+// NewFindingIDs returns the IDs of the findings in conn with no identical
+// finding in the database at basePath, matched the way Compare matches
+// new findings. When a finding occurs more often than at the base, its
+// occurrences with the highest IDs are the new ones. The result is sorted.
+func NewFindingIDs(basePath string, conn *sqlite.Conn) ([]int64, error) {
+	base, err := load(basePath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", basePath, err)
+	}
+	head, err := loadConn(conn)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for id, rows := range head.rowIDs {
+		if n := len(rows) - len(base.findings[id]); n > 0 {
+			ids = append(ids, rows[len(rows)-n:]...)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func load(path string) (*snapshot, error) {
+	conn, err := sqlite.OpenConn(path, sqlite.OpenReadOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+	return loadConn(conn)
+}
+
+func loadConn(conn *sqlite.Conn) (*snapshot, error) {
+	s := &snapshot{
+		funcs:    make(map[string]*Function),
+		keys:     make(map[string]string),
+		calls:    make(map[Call]bool),
+		findings: make(map[string][]Finding),
+		rowIDs:   make(map[string][]int64),
+		imports:  make(map[PackageDep]bool),
+		coupling: make(map[PackageDep]int),
+	}
+
+	// SCIP symbols are stable across commits; databases written before
+	// they existed fall back to package and name.
+	scip := "''"
+	scipJoin := ""
+	if hasTable(conn, "scip_symbols") {
+		scip, scipJoin = "COALESCE(s.scip_id, '')", "LEFT JOIN scip_symbols s ON s.node_id = n.id"
+	}
+	err := sqlitex.ExecuteTransient(conn, `
+SELECT n.id, n.name, COALESCE(n.package, ''), COALESCE(n.file, ''), COALESCE(n.line, 0), COALESCE(n.type_info, ''), `+scip+`,
+  COALESCE(m.cyclomatic_complexity, 0), COALESCE(m.loc, 0), COALESCE(m.fan_in, 0), COALESCE(m.fan_out, 0), COALESCE(m.num_params, 0),
+  (SELECT COUNT(*) FROM nodes c WHERE c.parent_function = n.id)
+FROM nodes n
+`+scipJoin+`
+LEFT JOIN metrics m ON m.function_id = n.id
+WHERE n.kind = 'function' AND n.name != 'func literal'
+ORDER BY n.package, n.name, n.file, n.line`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			id := stmt.ColumnText(0)
+			f := &Function{
+				Name:      stmt.ColumnText(1),
+				Package:   stmt.ColumnText(2),
+				File:      stmt.ColumnText(3),
+				Line:      stmt.ColumnInt(4),
+				Signature: stmt.ColumnText(5),
+				Metrics:   make(map[string]int, len(metricNames)),
+				external:  strings.HasPrefix(id, "ext::"),
+			}
+			for i, m := range metricNames {
+				f.Metrics[m] = stmt.ColumnInt(7 + i)
+			}
+			// External stubs are named by their full name; others by
+			// symbol, numbered when several share one (init functions).
+			switch {
+			case f.external:
+				f.Key = id
+			case stmt.ColumnText(6) != "":
+				f.Key = scipPrefixRe.ReplaceAllString(stmt.ColumnText(6), "")
+			default:
+				f.Key = f.Package + "." + f.Name
+			}
+			if _, dup := s.funcs[f.Key]; dup {
+				base := f.Key
+				for i := 2; ; i++ {
+					if f.Key = base + "#" + strconv.Itoa(i); s.funcs[f.Key] == nil {
+						break
+					}
+				}
+			}
+			s.funcs[f.Key] = f
+			s.keys[id] = f.Key
+			return nil
+		}})
+	if err != nil {
+		return nil, fmt.Errorf("functions: %w", err)
+	}
+
+	err = sqlitex.ExecuteTransient(conn, `SELECT source, target FROM edges WHERE kind = 'call'`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			caller, callee := s.keys[stmt.ColumnText(0)], s.keys[stmt.ColumnText(1)]
+			if caller != "" && callee != "" {
+				s.calls[Call{Caller: caller, Callee: callee}] = true
+			}
+			return nil
+		}})
+	if err != nil {
+		return nil, fmt.Errorf("calls: %w", err)
+	}
+
+	err = sqlitex.ExecuteTransient(conn, `
+SELECT f.category, f.severity, COALESCE(f.node_id, ''), COALESCE(f.file, ''), COALESCE(f.line, 0), f.message,
+  COALESCE(n.parent_function, ''), f.rowid
+FROM findings f
+LEFT JOIN nodes n ON n.id = f.node_id
+ORDER BY f.rowid`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			f := Finding{
+				Category: stmt.ColumnText(0),
+				Severity: stmt.ColumnText(1),
+				File:     stmt.ColumnText(3),
+				Line:     stmt.ColumnInt(4),
+				Message:  nodeIDRe.ReplaceAllString(stmt.ColumnText(5), ""),
+			}
+			f.Anchor = cmp.Or(s.keys[stmt.ColumnText(2)], s.keys[stmt.ColumnText(6)], f.File)
+			id := f.Category + "\x00" + f.Anchor + "\x00" + f.Message
+			s.findings[id] = append(s.findings[id], f)
+			s.rowIDs[id] = append(s.rowIDs[id], stmt.ColumnInt64(7))
+			return nil
+		}})
+	if err != nil {
+		return nil, fmt.Errorf("findings: %w", err)
+	}
+
+	err = sqlitex.ExecuteTransient(conn, `SELECT source, target FROM edges WHERE kind = 'imports'`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			s.imports[PackageDep{
+				Source: strings.TrimPrefix(stmt.ColumnText(0), "pkg::"),
+				Target: strings.TrimPrefix(stmt.ColumnText(1), "pkg::"),
+			}] = true
+			return nil
+		}})
+	if err != nil {
+		return nil, fmt.Errorf("imports: %w", err)
+	}
+
+	if hasTable(conn, "package_coupling") {
+		err = sqlitex.ExecuteTransient(conn, `SELECT source_package, target_package, call_count FROM package_coupling`,
+			&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+				s.coupling[PackageDep{Source: stmt.ColumnText(0), Target: stmt.ColumnText(1)}] = stmt.ColumnInt(2)
+				return nil
+			}})
+		if err != nil {
+			return nil, fmt.Errorf("package coupling: %w", err)
+		}
+	}
+	return s, nil
+}
+
+func hasTable(conn *sqlite.Conn, name string) bool {
+	found := false
+	_ = sqlitex.ExecuteTransient(conn, `SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?`,
+		&sqlitex.ExecOptions{
+			Args: []any{name},
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				found = true
+				return nil
+			},
+		})
+	return found
+}
@@ -0,0 +1,258 @@
+package cpgdiff
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// schema holds the columns of the CPG tables that Compare reads.
+const schema = `
+CREATE TABLE nodes (id TEXT PRIMARY KEY, kind TEXT, name TEXT, package TEXT, file TEXT, line INTEGER,
+  type_info TEXT, parent_function TEXT);
+CREATE TABLE edges (source TEXT, target TEXT, kind TEXT);
+CREATE TABLE metrics (function_id TEXT PRIMARY KEY, cyclomatic_complexity INTEGER, loc INTEGER,
+  fan_in INTEGER, fan_out INTEGER, num_params INTEGER);
+CREATE TABLE findings (category TEXT, severity TEXT, node_id TEXT, file TEXT, line INTEGER, message TEXT);
+`
+
+const scipTables = `
+CREATE TABLE scip_symbols (node_id TEXT, scip_id TEXT);
+CREATE TABLE package_coupling (source_package TEXT, target_package TEXT, call_count INTEGER);
+`
+
+func writeDB(t *testing.T, name, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	conn, err := sqlite.OpenConn(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	if err := sqlitex.ExecuteScript(conn, schema+script, nil); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return path
+}
+
+// oldDB and newDB are two snapshots of package a. Between them F moves
+// down two lines, G gets a parameter and more branches, the second init
+// function grows, Old is replaced by New, and the imports, calls and
+// findings change accordingly.
+const oldDB = scipTables + `
+INSERT INTO nodes VALUES
+  ('a::F@a.go:3:1', 'function', 'F', 'a', 'a.go', 3, 'func()', NULL),
+  ('a::F@a.go:4:2:call', 'call', 'G', 'a', 'a.go', 4, NULL, 'a::F@a.go:3:1'),
+  ('a::G@a.go:10:1', 'function', 'G', 'a', 'a.go', 10, 'func()', NULL),
+  ('a::init@a.go:20:1', 'function', 'init', 'a', 'a.go', 20, 'func()', NULL),
+  ('a::init@b.go:3:1', 'function', 'init', 'a', 'b.go', 3, 'func()', NULL),
+  ('a::Old@b.go:8:1', 'function', 'Old', 'a', 'b.go', 8, 'func()', NULL),
+  ('ext::fmt.Println', 'function', 'Println', 'fmt', '', 0, 'func(a ...any)', NULL);
+INSERT INTO scip_symbols VALUES
+  ('a::F@a.go:3:1', 'scip-go gomod example.com/m v1 ` + "`example.com/m/a`" + `/F().'),
+  ('a::G@a.go:10:1', 'scip-go gomod example.com/m v1 ` + "`example.com/m/a`" + `/G().'),
+  ('a::init@a.go:20:1', 'scip-go gomod example.com/m v1 ` + "`example.com/m/a`" + `/init().'),
+  ('a::init@b.go:3:1', 'scip-go gomod example.com/m v1 ` + "`example.com/m/a`" + `/init().'),
+  ('a::Old@b.go:8:1', 'scip-go gomod example.com/m v1 ` + "`example.com/m/a`" + `/Old().');
+INSERT INTO metrics VALUES
+  ('a::F@a.go:3:1', 1, 3, 0, 2, 0),
+  ('a::G@a.go:10:1', 2, 5, 1, 1, 0),
+  ('a::init@a.go:20:1', 1, 2, 0, 0, 0),
+  ('a::init@b.go:3:1', 1, 2, 0, 0, 0),
+  ('a::Old@b.go:8:1', 1, 2, 1, 0, 0);
+INSERT INTO edges VALUES
+  ('a::F@a.go:3:1', 'a::G@a.go:10:1', 'call'),
+  ('a::F@a.go:3:1', 'ext::fmt.Println', 'call'),
+  ('a::G@a.go:10:1', 'a::Old@b.go:8:1', 'call'),
+  ('pkg::a', 'pkg::fmt', 'imports');
+INSERT INTO findings VALUES
+  ('unchecked_error', 'warning', 'a::F@a.go:4:2:call', 'a.go', 4, 'error of a::G@a.go:10:1 ignored'),
+  ('large_file', 'info', NULL, 'b.go', 1, 'b.go is large');
+INSERT INTO package_coupling VALUES ('a', 'b', 1);
+`
+
+const newDB = scipTables + `
+INSERT INTO nodes VALUES
+  ('a::F@a.go:5:1', 'function', 'F', 'a', 'a.go', 5, 'func()', NULL),
+  ('a::F@a.go:6:2:call', 'call', 'G', 'a', 'a.go', 6, NULL, 'a::F@a.go:5:1'),
+  ('a::G@a.go:12:1', 'function', 'G', 'a', 'a.go', 12, 'func(n int)', NULL),
+  ('a::init@a.go:22:1', 'function', 'init', 'a', 'a.go', 22, 'func()', NULL),
+  ('a::init@b.go:3:1', 'function', 'init', 'a', 'b.go', 3, 'func()', NULL),
+  ('a::init@b.go:4:2:call', 'call', 'New', 'a', 'b.go', 4, NULL, 'a::init@b.go:3:1'),
+  ('a::New@b.go:8:1', 'function', 'New', 'a', 'b.go', 8, 'func() error', NULL),
+  ('ext::fmt.Println', 'function', 'Println', 'fmt', '', 0, 'func(a ...any)', NULL);
+INSERT INTO scip_symbols VALUES
+  ('a::F@a.go:5:1', 'scip-go gomod example.com/m v2 ` + "`example.com/m/a`" + `/F().'),
+  ('a::G@a.go:12:1', 'scip-go gomod example.com/m v2 ` + "`example.com/m/a`" + `/G().'),
+  ('a::init@a.go:22:1', 'scip-go gomod example.com/m v2 ` + "`example.com/m/a`" + `/init().'),
+  ('a::init@b.go:3:1', 'scip-go gomod example.com/m v2 ` + "`example.com/m/a`" + `/init().'),
+  ('a::New@b.go:8:1', 'scip-go gomod example.com/m v2 ` + "`example.com/m/a`" + `/New().');
+INSERT INTO metrics VALUES
+  ('a::F@a.go:5:1', 1, 3, 0, 2, 0),
+  ('a::G@a.go:12:1', 4, 9, 1, 0, 1),
+  ('a::init@a.go:22:1', 1, 2, 0, 0, 0),
+  ('a::init@b.go:3:1', 1, 3, 0, 1, 0),
+  ('a::New@b.go:8:1', 1, 2, 1, 0, 0);
+INSERT INTO edges VALUES
+  ('a::F@a.go:5:1', 'a::G@a.go:12:1', 'call'),
+  ('a::F@a.go:5:1', 'ext::fmt.Println', 'call'),
+  ('a::init@b.go:3:1', 'a::New@b.go:8:1', 'call'),
+  ('pkg::a', 'pkg::fmt', 'imports'),
+  ('pkg::a', 'pkg::errors', 'imports');
+INSERT INTO findings VALUES
+  ('unchecked_error', 'warning', 'a::F@a.go:6:2:call', 'a.go', 6, 'error of a::G@a.go:12:1 ignored'),
+  ('unchecked_error', 'warning', 'a::init@b.go:4:2:call', 'b.go', 4, 'error of a::New@b.go:8:1 ignored');
+INSERT INTO package_coupling VALUES ('a', 'b', 2), ('a', 'c', 1);
+`
+
+func compareFixtures(t *testing.T) *Report {
+	t.Helper()
+	r, err := Compare(writeDB(t, "old.db", oldDB), writeDB(t, "new.db", newDB))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func functionKeys(fs []Function) []string {
+	var keys []string
+	for _, f := range fs {
+		keys = append(keys, f.Key)
+	}
+	return keys
+}
+
+func TestCompare(t *testing.T) {
+	r := compareFixtures(t)
+
+	// F moved and kept its metrics: matched by SCIP symbol (across module
+	// versions), so it is not reported at all.
+	if got, want := functionKeys(r.AddedFunctions), []string{"`example.com/m/a`/New()."}; !slices.Equal(got, want) {
+		t.Errorf("added functions = %q, want %q", got, want)
+	}
+	if got, want := functionKeys(r.RemovedFunctions), []string{"`example.com/m/a`/Old()."}; !slices.Equal(got, want) {
+		t.Errorf("removed functions = %q, want %q", got, want)
+	}
+
+	// The two init functions share a symbol; the second one in file order
+	// is numbered, and only it changed.
+	if len(r.ChangedFunctions) != 2 {
+		t.Fatalf("changed functions = %+v, want G and the second init", r.ChangedFunctions)
+	}
+	g, init2 := r.ChangedFunctions[0], r.ChangedFunctions[1]
+	if g.Key != "`example.com/m/a`/G()." || g.OldSignature != "func()" || g.NewSignature != "func(n int)" ||
+		g.Line != 12 || g.Deltas["complexity"] != 2 || g.Deltas["loc"] != 4 || g.Deltas["fan_out"] != -1 ||
+		g.Deltas["num_params"] != 1 || len(g.Deltas) != 4 {
+		t.Errorf("G change = %+v", g)
+	}
+	if init2.Key != "`example.com/m/a`/init().#2" || init2.File != "b.go" || init2.OldSignature != "" ||
+		init2.Deltas["loc"] != 1 || init2.Deltas["fan_out"] != 1 || init2.Deltas["nodes"] != 1 || len(init2.Deltas) != 3 {
+		t.Errorf("second init change = %+v", init2)
+	}
+
+	wantAdded := []Call{{Caller: "`example.com/m/a`/init().#2", Callee: "`example.com/m/a`/New()."}}
+	wantRemoved := []Call{{Caller: "`example.com/m/a`/G().", Callee: "`example.com/m/a`/Old()."}}
+	if !slices.Equal(r.AddedCalls, wantAdded) || !slices.Equal(r.RemovedCalls, wantRemoved) {
+		t.Errorf("calls: added %+v removed %+v, want %+v and %+v", r.AddedCalls, r.RemovedCalls, wantAdded, wantRemoved)
+	}
+
+	// The finding in F moved with it; its message names a node ID whose
+	// position changed, which is ignored.
+	if len(r.NewFindings) != 1 || r.NewFindings[0].Anchor != "`example.com/m/a`/init().#2" ||
+		r.NewFindings[0].Message != "error of a::New ignored" || r.NewFindings[0].Count != 1 {
+		t.Errorf("new findings = %+v, want the unchecked error in the second init", r.NewFindings)
+	}
+	if len(r.ResolvedFindings) != 1 || r.ResolvedFindings[0].Category != "large_file" || r.ResolvedFindings[0].Anchor != "b.go" {
+		t.Errorf("resolved findings = %+v, want the file-level large_file finding", r.ResolvedFindings)
+	}
+
+	if want := []PackageDep{{Source: "a", Target: "errors"}}; !slices.Equal(r.AddedImports, want) || len(r.RemovedImports) != 0 {
+		t.Errorf("imports: added %+v removed %+v, want %+v", r.AddedImports, r.RemovedImports, want)
+	}
+	wantCoupling := []CouplingDelta{{Source: "a", Target: "b", Old: 1, New: 2}, {Source: "a", Target: "c", New: 1}}
+	if !slices.Equal(r.CouplingChanges, wantCoupling) {
+		t.Errorf("coupling changes = %+v, want %+v", r.CouplingChanges, wantCoupling)
+	}
+
+	// External stubs are not counted.
+	want := Totals{Functions: [2]int{5, 5}, Complexity: [2]int{6, 8}, LOC: [2]int{14, 19}, Calls: [2]int{3, 3}, Findings: [2]int{2, 2}}
+	if r.Totals != want {
+		t.Errorf("totals = %+v, want %+v", r.Totals, want)
+	}
+}
+
+// Without SCIP symbols, functions are keyed by package and name, and
+// duplicate findings are counted.
+func TestCompareWithoutSCIP(t *testing.T) {
+	oldPath := writeDB(t, "old.db", `
+INSERT INTO nodes VALUES ('a::F@a.go:3:1', 'function', 'F', 'a', 'a.go', 3, 'func()', NULL);
+INSERT INTO findings VALUES ('todo', 'info', 'a::F@a.go:3:1', 'a.go', 3, 'TODO');`)
+	newPath := writeDB(t, "new.db", `
+INSERT INTO nodes VALUES
+  ('a::F@a.go:9:1', 'function', 'F', 'a', 'a.go', 9, 'func()', NULL),
+  ('a::H@a.go:1:1', 'function', 'H', 'a', 'a.go', 1, 'func()', NULL);
+INSERT INTO findings VALUES
+  ('todo', 'info', 'a::F@a.go:9:1', 'a.go', 9, 'TODO'),
+  ('todo', 'info', 'a::F@a.go:9:1', 'a.go', 10, 'TODO'),
+  ('todo', 'info', 'a::F@a.go:9:1', 'a.go', 11, 'TODO');`)
+	r, err := Compare(oldPath, newPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := functionKeys(r.AddedFunctions); !slices.Equal(got, []string{"a.H"}) || len(r.RemovedFunctions)+len(r.ChangedFunctions) != 0 {
+		t.Errorf("added %q, removed %+v, changed %+v; want only a.H added", got, r.RemovedFunctions, r.ChangedFunctions)
+	}
+	if len(r.NewFindings) != 1 || r.NewFindings[0].Anchor != "a.F" || r.NewFindings[0].Count != 2 {
+		t.Errorf("new findings = %+v, want two more TODOs in a.F", r.NewFindings)
+	}
+}
+
+func TestCompareMissingDB(t *testing.T) {
+	if _, err := Compare(filepath.Join(t.TempDir(), "none.db"), writeDB(t, "new.db", "")); err == nil {
+		t.Error("Compare with a missing database succeeded")
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	r := compareFixtures(t)
+	r.Old, r.New = "old.db", "new.db"
+	md := r.Markdown()
+	for _, want := range []string{
+		"# CPG diff\n\n`old.db` → `new.db`\n",
+		"| Functions | 5 | 5 | +0 |\n",
+		"| Cyclomatic complexity | 6 | 8 | +2 |\n",
+		"\n1 functions added, 1 removed, 2 changed; 1 calls added, 1 removed; 1 new findings, 1 resolved.\n",
+		"## Added functions\n\n| Function | Package | Location | Complexity |\n|---|---|---|---:|\n| `New` | a | b.go:8 | 1 |\n",
+		"## Removed functions\n\n| Function | Package | Location | Complexity |\n|---|---|---|---:|\n| `Old` | a | b.go:8 | 1 |\n",
+		"| `G` | a | signature `func()` → `func(n int)`, complexity +2, loc +4, fan_out -1, num_params +1 |\n",
+		"| `init` | a | loc +1, fan_out +1, nodes +1 |\n",
+		"## New calls\n\n- `` `example.com/m/a`/init().#2 `` → ",
+		"## New findings\n\n| Severity | Category | Location | Message |\n|---|---|---|---|\n| warning | unchecked_error | b.go:4 | error of a::New ignored |\n",
+		"| info | large_file | b.go:1 | b.go is large |\n",
+		"- added import `a` → `errors`\n",
+		"| a | b | 1 | 2 |\n| a | c | 0 | 1 |\n",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown output lacks %q:\n%s", want, md)
+		}
+	}
+}
+
+func TestMarkdownTruncates(t *testing.T) {
+	r := &Report{}
+	for i := range maxRows + 3 {
+		r.NewFindings = append(r.NewFindings, Finding{Category: "c", Severity: "info", Message: "m|" + strings.Repeat("x", i), Count: 1})
+	}
+	r.NewFindings[0].Count = 3
+	md := r.Markdown()
+	if !strings.Contains(md, "| … and 3 more | | | |\n") {
+		t.Error("Markdown does not cap the findings table")
+	}
+	if !strings.Contains(md, "| info | c | — | m\\| (×3) |\n") {
+		t.Errorf("Markdown does not escape pipes or show counts:\n%s", md)
+	}
+}
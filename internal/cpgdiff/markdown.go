@@ -0,0 +1,155 @@
+package cpgdiff
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxRows caps each Markdown table; the JSON output is always complete.
+const maxRows = 100
+
+// Markdown renders the report for release reviews.
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# CPG diff\n\n%s → %s\n\n", code(r.Old), code(r.New))
+
+	b.WriteString("## Summary\n\n| | Old | New | Δ |\n|---|---:|---:|---:|\n")
+	for _, row := range []struct {
+		name string
+		v    [2]int
+	}{
+		{"Functions", r.Totals.Functions},
+		{"Cyclomatic complexity", r.Totals.Complexity},
+		{"Lines of code", r.Totals.LOC},
+		{"Call edges", r.Totals.Calls},
+		{"Findings", r.Totals.Findings},
+	} {
+		fmt.Fprintf(&b, "| %s | %d | %d | %+d |\n", row.name, row.v[0], row.v[1], row.v[1]-row.v[0])
+	}
+	fmt.Fprintf(&b, "\n%d functions added, %d removed, %d changed; %d calls added, %d removed; %d new findings, %d resolved.\n",
+		len(r.AddedFunctions), len(r.RemovedFunctions), len(r.ChangedFunctions),
+		len(r.AddedCalls), len(r.RemovedCalls), len(r.NewFindings), len(r.ResolvedFindings))
+
+	writeFunctions(&b, "Added functions", r.AddedFunctions)
+	writeFunctions(&b, "Removed functions", r.RemovedFunctions)
+
+	if len(r.ChangedFunctions) > 0 {
+		b.WriteString("\n## Changed functions\n\n| Function | Package | Changes |\n|---|---|---|\n")
+		for i, c := range r.ChangedFunctions {
+			if i == maxRows {
+				writeMore(&b, len(r.ChangedFunctions)-i)
+				break
+			}
+			var changes []string
+			if c.OldSignature != "" || c.NewSignature != "" {
+				changes = append(changes, fmt.Sprintf("signature %s → %s", code(c.OldSignature), code(c.NewSignature)))
+			}
+			for _, m := range metricNames {
+				if d, ok := c.Deltas[m]; ok {
+					changes = append(changes, fmt.Sprintf("%s %+d", m, d))
+				}
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", code(c.Name), c.Package, strings.Join(changes, ", "))
+		}
+	}
+
+	writeCalls(&b, "New calls", r.AddedCalls)
+	writeCalls(&b, "Removed calls", r.RemovedCalls)
+	writeFindings(&b, "New findings", r.NewFindings)
+	writeFindings(&b, "Resolved findings", r.ResolvedFindings)
+
+	if len(r.AddedImports)+len(r.RemovedImports)+len(r.CouplingChanges) > 0 {
+		b.WriteString("\n## Package dependencies\n\n")
+		for _, d := range r.AddedImports {
+			fmt.Fprintf(&b, "- added import %s → %s\n", code(d.Source), code(d.Target))
+		}
+		for _, d := range r.RemovedImports {
+			fmt.Fprintf(&b, "- removed import %s → %s\n", code(d.Source), code(d.Target))
+		}
+		if len(r.CouplingChanges) > 0 {
+			b.WriteString("\n| Caller package | Callee package | Calls old | Calls new |\n|---|---|---:|---:|\n")
+			for _, c := range r.CouplingChanges {
+				fmt.Fprintf(&b, "| %s | %s | %d | %d |\n", c.Source, c.Target, c.Old, c.New)
+			}
+		}
+	}
+	return b.String()
+}
+
+func writeFunctions(b *strings.Builder, title string, fs []Function) {
+	if len(fs) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n## %s\n\n| Function | Package | Location | Complexity |\n|---|---|---|---:|\n", title)
+	for i, f := range fs {
+		if i == maxRows {
+			writeMore(b, len(fs)-i)
+			break
+		}
+		fmt.Fprintf(b, "| %s | %s | %s:%d | %d |\n", code(f.Name), f.Package, f.File, f.Line, f.Metrics["complexity"])
+	}
+}
+
+func writeCalls(b *strings.Builder, title string, calls []Call) {
+	if len(calls) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n## %s\n\n", title)
+	for i, c := range calls {
+		if i == maxRows {
+			fmt.Fprintf(b, "- … and %d more\n", len(calls)-i)
+			break
+		}
+		fmt.Fprintf(b, "- %s → %s\n", code(c.Caller), code(c.Callee))
+	}
+}
+
+func writeFindings(b *strings.Builder, title string, fs []Finding) {
+	if len(fs) == 0 {
+		return
+	}
+	// Most severe first
+	rank := []string{"error", "warning", "info"}
+	fs = slices.Clone(fs)
+	slices.SortStableFunc(fs, func(x, y Finding) int {
+		return severityRank(rank, x.Severity) - severityRank(rank, y.Severity)
+	})
+	fmt.Fprintf(b, "\n## %s\n\n| Severity | Category | Location | Message |\n|---|---|---|---|\n", title)
+	for i, f := range fs {
+		if i == maxRows {
+			writeMore(b, len(fs)-i)
+			break
+		}
+		msg := strings.ReplaceAll(f.Message, "|", `\|`)
+		if f.Count > 1 {
+			msg += fmt.Sprintf(" (×%d)", f.Count)
+		}
+		loc := cmp.Or(f.Anchor, "—")
+		if f.File != "" {
+			loc = fmt.Sprintf("%s:%d", f.File, f.Line)
+		}
+		fmt.Fprintf(b, "| %s | %s | %s | %s |\n", f.Severity, f.Category, loc, msg)
+	}
+}
+
+func severityRank(rank []string, severity string) int {
+	if i := slices.Index(rank, severity); i >= 0 {
+		return i
+	}
+	return len(rank)
+}
+
+// code formats s as a Markdown code span. SCIP keys quote package paths in
+// backticks, so those need a longer fence.
+func code(s string) string {
+	if strings.Contains(s, "`") {
+		return "`` " + s + " ``"
+	}
+	return "`" + s + "`"
+}
+
+func writeMore(b *strings.Builder, n int) {
+	fmt.Fprintf(b, "| … and %d more | | | |\n", n)
+}
@@ -78,6 +78,10 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		s.handleHotspots(w, r)
 	case r.URL.Path == "/impact":
 		s.handleImpact(w, r)
+	case r.URL.Path == "/impact/tests":
+		s.handleImpactTests(w, r)
+	case r.URL.Path == "/taint/path":
+		s.handleTaintPath(w, r)
 	case r.URL.Path == "/types/interfaces":
 		s.handleTypeInterfaces(w, r)
 	case r.URL.Path == "/types/methods":
@@ -90,6 +94,8 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		s.handleFileOutline(w, r)
 	case r.URL.Path == "/functions":
 		s.handleFunctionsByPackage(w, r)
+	case len(r.URL.Path) > 10 && r.URL.Path[:10] == "/function/" && strings.HasSuffix(r.URL.Path, "/escape"):
+		s.handleFunctionEscape(w, r, strings.TrimSuffix(r.URL.Path[10:], "/escape"))
 	case len(r.URL.Path) > 10 && r.URL.Path[:10] == "/function/":
 		s.handleFunctionDetail(w, r, r.URL.Path[10:])
 	case len(r.URL.Path) > 7 && r.URL.Path[:7] == "/query/":
@@ -234,6 +240,7 @@ func (s *Server) handleGraphNeighborhood(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	defer s.pool.Put(conn)
+	functionID = resolveNodeID(conn, functionID)
 
 	// function_neighborhood: callers and callees
 	stmt, err := conn.Prepare(`SELECT 'caller' AS direction, n.id, n.name, n.package, n.file, n.line
@@ -308,6 +315,44 @@ func clampInt(v, lo, hi int) int {
 	return v
 }
 
+// nullableInt returns an integer column, or nil when it is NULL.
+func nullableInt(stmt *sqlite.Stmt, col string) any {
+	i := stmt.ColumnIndex(col)
+	if stmt.ColumnType(i) == sqlite.TypeNull {
+		return nil
+	}
+	return stmt.ColumnInt64(i)
+}
+
+// resolveNodeID maps a stable ID (nodes.stable_id, unique per database) to
+// the node ID it names in this database. Node IDs, and IDs that match
+// nothing, are returned as is.
+func resolveNodeID(conn *sqlite.Conn, id string) string {
+	stmt, err := conn.Prepare(`SELECT id FROM nodes WHERE id = ?1
+UNION ALL
+SELECT id FROM nodes WHERE stable_id = ?1
+LIMIT 1`)
+	if err != nil {
+		// Databases written before stable IDs
+		return id
+	}
+	defer stmt.Finalize()
+	stmt.BindText(1, id)
+	if ok, err := stmt.Step(); err != nil || !ok {
+		return id
+	}
+	return stmt.ColumnText(0)
+}
+
+// nullableText returns a text column, or nil when it is NULL.
+func nullableText(stmt *sqlite.Stmt, col string) any {
+	i := stmt.ColumnIndex(col)
+	if stmt.ColumnType(i) == sqlite.TypeNull {
+		return nil
+	}
+	return stmt.ColumnText(i)
+}
+
 func parseIntQuery(q map[string][]string, key string, fallback, lo, hi int) (int, error) {
 	raw := ""
 	if vs, ok := q[key]; ok && len(vs) > 0 {
@@ -354,6 +399,7 @@ func (s *Server) handleGraphCall(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer s.pool.Put(conn)
+	functionID = resolveNodeID(conn, functionID)
 
 	type nodeRow struct {
 		ID      string `json:"id"`
@@ -573,6 +619,7 @@ func (s *Server) handleGraphDataflow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer s.pool.Put(conn)
+	nodeID = resolveNodeID(conn, nodeID)
 
 	type sliceNode struct {
 		ID      string `json:"id"`
@@ -594,7 +641,7 @@ func (s *Server) handleGraphDataflow(w http.ResponseWriter, r *http.Request) {
   UNION
   SELECT e.target, s.depth + 1
   FROM slice s JOIN edges e ON e.source = s.id
-  WHERE e.kind IN ('dfg', 'param_out') AND s.depth < ?2
+  WHERE e.kind IN ('dfg', 'param_out', 'field_store', 'field_load') AND s.depth < ?2
 )
 SELECT n.id, n.name, n.kind, n.package, n.file, n.line, MIN(slice.depth) AS depth
 FROM slice JOIN nodes n ON n.id = slice.id
@@ -605,13 +652,13 @@ ORDER BY depth, n.file, n.line`
   UNION
   SELECT e.target, s.depth + 1
   FROM slice s JOIN edges e ON e.source = s.id
-  WHERE e.kind IN ('dfg', 'param_out') AND s.depth < ?2
+  WHERE e.kind IN ('dfg', 'param_out', 'field_store', 'field_load') AND s.depth < ?2
 )
 SELECT DISTINCT e.source, e.target, e.kind
 FROM edges e
 JOIN slice src ON src.id = e.source
 JOIN slice dst ON dst.id = e.target
-WHERE e.kind IN ('dfg', 'param_in', 'param_out')`
+WHERE e.kind IN ('dfg', 'param_in', 'param_out', 'field_store', 'field_load')`
 
 	if direction == "backward" {
 		nodeSQL = `WITH RECURSIVE slice(id, depth) AS (
@@ -619,7 +666,7 @@ WHERE e.kind IN ('dfg', 'param_in', 'param_out')`
   UNION
   SELECT e.source, s.depth + 1
   FROM slice s JOIN edges e ON e.target = s.id
-  WHERE e.kind IN ('dfg', 'param_in') AND s.depth < ?2
+  WHERE e.kind IN ('dfg', 'param_in', 'field_store', 'field_load') AND s.depth < ?2
 )
 SELECT n.id, n.name, n.kind, n.package, n.file, n.line, MIN(slice.depth) AS depth
 FROM slice JOIN nodes n ON n.id = slice.id
@@ -630,13 +677,13 @@ ORDER BY depth, n.file, n.line`
   UNION
   SELECT e.source, s.depth + 1
   FROM slice s JOIN edges e ON e.target = s.id
-  WHERE e.kind IN ('dfg', 'param_in') AND s.depth < ?2
+  WHERE e.kind IN ('dfg', 'param_in', 'field_store', 'field_load') AND s.depth < ?2
 )
 SELECT DISTINCT e.source, e.target, e.kind
 FROM edges e
 JOIN slice src ON src.id = e.source
 JOIN slice dst ON dst.id = e.target
-WHERE e.kind IN ('dfg', 'param_in', 'param_out')`
+WHERE e.kind IN ('dfg', 'param_in', 'param_out', 'field_store', 'field_load')`
 	}
 
 	stmt, err := conn.Prepare(nodeSQL)
@@ -880,7 +927,7 @@ func (s *Server) handleHotspots(w http.ResponseWriter, r *http.Request) {
 	}
 	defer s.pool.Put(conn)
 
-	stmt, err := conn.Prepare("SELECT function_id, name, package, file, complexity, loc, fan_in, fan_out, finding_count, hotspot_score FROM dashboard_hotspots ORDER BY hotspot_score DESC LIMIT ?1")
+	stmt, err := conn.Prepare("SELECT function_id, name, package, file, complexity, loc, fan_in, fan_out, finding_count, hotspot_score, heap_allocs, primary_author, author_count, age_days FROM dashboard_hotspots ORDER BY hotspot_score DESC LIMIT ?1")
 	if err != nil {
 		s.writeErr(w, http.StatusInternalServerError, err.Error())
 		return
@@ -899,16 +946,20 @@ func (s *Server) handleHotspots(w http.ResponseWriter, r *http.Request) {
 			break
 		}
 		rows = append(rows, map[string]any{
-			"function_id":   stmt.GetText("function_id"),
-			"name":          stmt.GetText("name"),
-			"package":       stmt.GetText("package"),
-			"file":          stmt.GetText("file"),
-			"complexity":    stmt.ColumnInt(stmt.ColumnIndex("complexity")),
-			"loc":           stmt.ColumnInt(stmt.ColumnIndex("loc")),
-			"fan_in":        stmt.ColumnInt(stmt.ColumnIndex("fan_in")),
-			"fan_out":       stmt.ColumnInt(stmt.ColumnIndex("fan_out")),
-			"finding_count": stmt.ColumnInt(stmt.ColumnIndex("finding_count")),
-			"hotspot_score": stmt.ColumnFloat(stmt.ColumnIndex("hotspot_score")),
+			"function_id":    stmt.GetText("function_id"),
+			"name":           stmt.GetText("name"),
+			"package":        stmt.GetText("package"),
+			"file":           stmt.GetText("file"),
+			"complexity":     stmt.ColumnInt(stmt.ColumnIndex("complexity")),
+			"loc":            stmt.ColumnInt(stmt.ColumnIndex("loc")),
+			"fan_in":         stmt.ColumnInt(stmt.ColumnIndex("fan_in")),
+			"fan_out":        stmt.ColumnInt(stmt.ColumnIndex("fan_out")),
+			"finding_count":  stmt.ColumnInt(stmt.ColumnIndex("finding_count")),
+			"hotspot_score":  stmt.ColumnFloat(stmt.ColumnIndex("hotspot_score")),
+			"heap_allocs":    nullableInt(stmt, "heap_allocs"),
+			"primary_author": nullableText(stmt, "primary_author"),
+			"author_count":   nullableInt(stmt, "author_count"),
+			"age_days":       nullableInt(stmt, "age_days"),
 		})
 	}
 	s.writeJSON(w, http.StatusOK, rows)
@@ -937,6 +988,7 @@ func (s *Server) handleImpact(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer s.pool.Put(conn)
+	functionID = resolveNodeID(conn, functionID)
 
 	stmt, err := conn.Prepare(`WITH RECURSIVE callers(id, depth) AS (
   SELECT ?1, 0
@@ -982,6 +1034,177 @@ LIMIT ?3`)
 	s.writeJSON(w, http.StatusOK, rows)
 }
 
+// handleImpactTests lists the tests that reach a function, via the "tests"
+// edges emitted when the CPG is generated with -skip-tests=false.
+func (s *Server) handleImpactTests(w http.ResponseWriter, r *http.Request) {
+	functionID := strings.TrimSpace(r.URL.Query().Get("function_id"))
+	if functionID == "" {
+		s.writeErr(w, http.StatusBadRequest, "missing function_id")
+		return
+	}
+	limit, err := parseIntQuery(r.URL.Query(), "limit", 250, 1, 1000)
+	if err != nil {
+		s.writeErr(w, http.StatusBadRequest, "invalid limit")
+		return
+	}
+
+	conn, err := s.conn()
+	if err != nil {
+		s.writeErr(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	defer s.pool.Put(conn)
+	functionID = resolveNodeID(conn, functionID)
+
+	stmt, err := conn.Prepare(`SELECT n.id, n.name, n.package, n.file, n.line,
+  COALESCE(json_extract(n.properties, '$.test_kind'), '') AS test_kind,
+  COALESCE(json_extract(e.properties, '$.depth'), 0) AS depth
+FROM edges e JOIN nodes n ON n.id = e.source
+WHERE e.target = ?1 AND e.kind = 'tests'
+ORDER BY depth, n.package, n.name
+LIMIT ?2`)
+	if err != nil {
+		s.writeErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer stmt.Finalize()
+	stmt.BindText(1, functionID)
+	stmt.BindInt64(2, int64(limit))
+
+	rows := []map[string]any{}
+	for {
+		ok, err := stmt.Step()
+		if err != nil {
+			s.writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !ok {
+			break
+		}
+		rows = append(rows, map[string]any{
+			"id":        stmt.GetText("id"),
+			"name":      stmt.GetText("name"),
+			"package":   stmt.GetText("package"),
+			"file":      stmt.GetText("file"),
+			"line":      stmt.ColumnInt(stmt.ColumnIndex("line")),
+			"test_kind": stmt.GetText("test_kind"),
+			"depth":     stmt.ColumnInt(stmt.ColumnIndex("depth")),
+		})
+	}
+	s.writeJSON(w, http.StatusOK, rows)
+}
+
+func (s *Server) handleTaintPath(w http.ResponseWriter, r *http.Request) {
+	findingID, err := strconv.ParseInt(strings.TrimSpace(r.URL.Query().Get("finding")), 10, 64)
+	if err != nil {
+		s.writeErr(w, http.StatusBadRequest, "missing or invalid finding")
+		return
+	}
+
+	conn, err := s.conn()
+	if err != nil {
+		s.writeErr(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	defer s.pool.Put(conn)
+
+	fstmt, err := conn.Prepare(`SELECT category, severity, node_id, file, line, message
+FROM findings WHERE id = ?1`)
+	if err != nil {
+		s.writeErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer fstmt.Finalize()
+	fstmt.BindInt64(1, findingID)
+	ok, err := fstmt.Step()
+	if err != nil {
+		s.writeErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !ok {
+		s.writeErr(w, http.StatusNotFound, "finding not found")
+		return
+	}
+	finding := map[string]any{
+		"id":       findingID,
+		"category": fstmt.GetText("category"),
+		"severity": fstmt.GetText("severity"),
+		"node_id":  fstmt.GetText("node_id"),
+		"file":     fstmt.GetText("file"),
+		"line":     fstmt.ColumnInt(fstmt.ColumnIndex("line")),
+		"message":  fstmt.GetText("message"),
+	}
+
+	type pathNode struct {
+		Step int    `json:"step"`
+		ID   string `json:"id"`
+		Name string `json:"name"`
+		Kind string `json:"kind"`
+		File string `json:"file"`
+		Line int    `json:"line"`
+	}
+	type pathEdge struct {
+		Source string `json:"source"`
+		Target string `json:"target"`
+		Kind   string `json:"kind"`
+	}
+	type path struct {
+		PathID int        `json:"path_id"`
+		Nodes  []pathNode `json:"nodes"`
+		Edges  []pathEdge `json:"edges"`
+	}
+
+	stmt, err := conn.Prepare(`SELECT p.path_id, p.step, p.node_id, COALESCE(p.edge_kind, '') AS edge_kind,
+  COALESCE(n.name, '') AS name, COALESCE(n.kind, '') AS kind, p.file, p.line
+FROM taint_paths p LEFT JOIN nodes n ON n.id = p.node_id
+WHERE p.finding_id = ?1
+ORDER BY p.path_id, p.step`)
+	if err != nil {
+		s.writeErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer stmt.Finalize()
+	stmt.BindInt64(1, findingID)
+
+	paths := []path{}
+	for {
+		ok, err := stmt.Step()
+		if err != nil {
+			s.writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !ok {
+			break
+		}
+		pathID := stmt.ColumnInt(stmt.ColumnIndex("path_id"))
+		if len(paths) == 0 || paths[len(paths)-1].PathID != pathID {
+			paths = append(paths, path{PathID: pathID, Nodes: []pathNode{}, Edges: []pathEdge{}})
+		}
+		cur := &paths[len(paths)-1]
+		node := pathNode{
+			Step: stmt.ColumnInt(stmt.ColumnIndex("step")),
+			ID:   stmt.GetText("node_id"),
+			Name: stmt.GetText("name"),
+			Kind: stmt.GetText("kind"),
+			File: stmt.GetText("file"),
+			Line: stmt.ColumnInt(stmt.ColumnIndex("line")),
+		}
+		if len(cur.Nodes) > 0 {
+			cur.Edges = append(cur.Edges, pathEdge{
+				Source: cur.Nodes[len(cur.Nodes)-1].ID,
+				Target: node.ID,
+				Kind:   stmt.GetText("edge_kind"),
+			})
+		}
+		cur.Nodes = append(cur.Nodes, node)
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]any{
+		"finding": finding,
+		"paths":   paths,
+	})
+}
+
 func (s *Server) handleTypeInterfaces(w http.ResponseWriter, r *http.Request) {
 	name := strings.TrimSpace(r.URL.Query().Get("name"))
 	limit, err := parseIntQuery(r.URL.Query(), "limit", 200, 1, 500)
@@ -1160,6 +1383,7 @@ func (s *Server) handleXrefs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer s.pool.Put(conn)
+	defID = resolveNodeID(conn, defID)
 
 	stmt, err := conn.Prepare(`SELECT def_id, use_id, use_name, use_kind, use_package, use_file, use_line, edge_kind
 FROM xrefs
@@ -1264,8 +1488,14 @@ func (s *Server) handleFunctionDetail(w http.ResponseWriter, r *http.Request, id
 		return
 	}
 	defer s.pool.Put(conn)
-
-	stmt, err := conn.Prepare("SELECT function_id, name, package, file, line, end_line, signature, complexity, loc, fan_in, fan_out, num_params, num_locals, num_calls, num_branches, num_returns, finding_count, callers, callees FROM dashboard_function_detail WHERE function_id = ?")
+	id = resolveNodeID(conn, id)
+
+	stmt, err := conn.Prepare(`SELECT d.function_id, d.name, d.package, d.file, d.line, d.end_line, d.signature, d.complexity, d.loc, d.fan_in, d.fan_out,
+		d.num_params, d.num_locals, d.num_calls, d.num_branches, d.num_returns, d.finding_count, d.callers, d.callees,
+		o.primary_author, o.author_count, o.last_modified, o.age_days
+		FROM dashboard_function_detail d
+		LEFT JOIN function_ownership o ON o.function_id = d.function_id
+		WHERE d.function_id = ?`)
 	if err != nil {
 		s.writeErr(w, http.StatusInternalServerError, err.Error())
 		return
@@ -1300,10 +1530,160 @@ func (s *Server) handleFunctionDetail(w http.ResponseWriter, r *http.Request, id
 		"finding_count": stmt.ColumnInt(stmt.ColumnIndex("finding_count")),
 		"callers":       stmt.GetText("callers"),
 		"callees":       stmt.GetText("callees"),
+
+		"primary_author": nullableText(stmt, "primary_author"),
+		"author_count":   nullableInt(stmt, "author_count"),
+		"last_modified":  nullableText(stmt, "last_modified"),
+		"age_days":       nullableInt(stmt, "age_days"),
 	}
 	s.writeJSON(w, http.StatusOK, detail)
 }
 
+// handleFunctionEscape returns the compiler escape decisions inside a
+// function, the flow chains explaining them, and its heap_allocs,
+// leaking_params and inlining metrics (null when escape analysis was
+// skipped).
+func (s *Server) handleFunctionEscape(w http.ResponseWriter, r *http.Request, id string) {
+	if id == "" {
+		s.writeErr(w, http.StatusBadRequest, "missing function id")
+		return
+	}
+
+	conn, err := s.conn()
+	if err != nil {
+		s.writeErr(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	defer s.pool.Put(conn)
+	id = resolveNodeID(conn, id)
+
+	mstmt, err := conn.Prepare(`SELECT n.name, m.heap_allocs, m.leaking_params, m.inline_cost, m.inline_budget,
+  EXISTS (SELECT 1 FROM escape_decisions d WHERE d.node_id = n.id AND d.kind = 'inlineable') AS inlineable,
+  COALESCE((SELECT d.detail FROM escape_decisions d WHERE d.node_id = n.id AND d.kind = 'not_inlineable'), '') AS not_inlineable
+FROM nodes n LEFT JOIN metrics m ON m.function_id = n.id
+WHERE n.id = ?1 AND n.kind = 'function'`)
+	if err != nil {
+		s.writeErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer mstmt.Finalize()
+	mstmt.BindText(1, id)
+	if ok, err := mstmt.Step(); err != nil {
+		s.writeErr(w, http.StatusInternalServerError, err.Error())
+		return
+	} else if !ok {
+		s.writeErr(w, http.StatusNotFound, "function not found")
+		return
+	}
+	result := map[string]any{
+		"function_id":    id,
+		"name":           mstmt.GetText("name"),
+		"heap_allocs":    nullableInt(mstmt, "heap_allocs"),
+		"leaking_params": nullableInt(mstmt, "leaking_params"),
+		"inlineable":     mstmt.GetInt64("inlineable") != 0,
+		"inline_cost":    nullableInt(mstmt, "inline_cost"),
+		"inline_budget":  nullableInt(mstmt, "inline_budget"),
+	}
+	if reason := mstmt.GetText("not_inlineable"); reason != "" {
+		result["not_inlineable"] = reason
+	}
+
+	stmt, err := conn.Prepare(`SELECT d.node_id, d.kind, COALESCE(d.detail, '') AS detail,
+  n.kind AS node_kind, n.name, n.file, n.line, n.col
+FROM escape_decisions d JOIN nodes n ON n.id = d.node_id
+WHERE d.function_id = ?1 AND d.kind NOT IN ('inlineable', 'not_inlineable')
+ORDER BY n.line, n.col, d.kind`)
+	if err != nil {
+		s.writeErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer stmt.Finalize()
+	stmt.BindText(1, id)
+
+	decisions := []map[string]any{}
+	for {
+		ok, err := stmt.Step()
+		if err != nil {
+			s.writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !ok {
+			break
+		}
+		decisions = append(decisions, map[string]any{
+			"node_id":   stmt.GetText("node_id"),
+			"kind":      stmt.GetText("kind"),
+			"detail":    stmt.GetText("detail"),
+			"node_kind": stmt.GetText("node_kind"),
+			"name":      stmt.GetText("name"),
+			"file":      stmt.GetText("file"),
+			"line":      stmt.ColumnInt(stmt.ColumnIndex("line")),
+			"col":       stmt.ColumnInt(stmt.ColumnIndex("col")),
+		})
+	}
+	result["decisions"] = decisions
+
+	type step struct {
+		Flow   string `json:"flow"`
+		Expr   string `json:"expr"`
+		Reason string `json:"reason"`
+		Line   int    `json:"line"`
+		Col    int    `json:"col"`
+		SiteID string `json:"site_id"`
+	}
+	type explanation struct {
+		NodeID  string `json:"node_id"`
+		Summary string `json:"summary"`
+		Steps   []step `json:"steps"`
+	}
+	xstmt, err := conn.Prepare(`SELECT explanation, node_id, summary, COALESCE(flow, '') AS flow,
+  COALESCE(expr, '') AS expr, COALESCE(reason, '') AS reason, line, col, COALESCE(site_id, '') AS site_id
+FROM escape_explanations
+WHERE function_id = ?1
+ORDER BY explanation, step`)
+	if err != nil {
+		s.writeErr(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer xstmt.Finalize()
+	xstmt.BindText(1, id)
+
+	explanations := []explanation{}
+	last := int64(-1)
+	for {
+		ok, err := xstmt.Step()
+		if err != nil {
+			s.writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !ok {
+			break
+		}
+		if n := xstmt.GetInt64("explanation"); n != last {
+			last = n
+			explanations = append(explanations, explanation{
+				NodeID:  xstmt.GetText("node_id"),
+				Summary: xstmt.GetText("summary"),
+				Steps:   []step{},
+			})
+		}
+		if xstmt.GetText("expr") == "" {
+			continue
+		}
+		cur := &explanations[len(explanations)-1]
+		cur.Steps = append(cur.Steps, step{
+			Flow:   xstmt.GetText("flow"),
+			Expr:   xstmt.GetText("expr"),
+			Reason: xstmt.GetText("reason"),
+			Line:   xstmt.ColumnInt(xstmt.ColumnIndex("line")),
+			Col:    xstmt.ColumnInt(xstmt.ColumnIndex("col")),
+			SiteID: xstmt.GetText("site_id"),
+		})
+	}
+	result["explanations"] = explanations
+	s.writeJSON(w, http.StatusOK, result)
+}
+
 // handleQueryByName runs a named query from the queries table with query params.
 func (s *Server) handleQueryByName(w http.ResponseWriter, r *http.Request, name string) {
 	if name == "" {
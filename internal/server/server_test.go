@@ -0,0 +1,64 @@
+package server
+
+import (
+	"path/filepath"
+	"testing"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// writeDB creates a database at a temporary path from a script and returns
+// the path.
+func writeDB(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cpg.db")
+	conn, err := sqlite.OpenConn(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	if err := sqlitex.ExecuteScript(conn, script, nil); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResolveNodeID(t *testing.T) {
+	conn, err := sqlite.OpenConn(writeDB(t, `
+CREATE TABLE nodes (id TEXT PRIMARY KEY, stable_id TEXT);
+CREATE UNIQUE INDEX idx_nodes_stable ON nodes(stable_id);
+INSERT INTO nodes VALUES
+  ('pkg::init@pkg/a.go:3:1', 'file::pkg/a.go/init#0'),
+  ('pkg::init@pkg/b.go:3:1', 'file::pkg/b.go/init#0'),
+  ('pkg::F@pkg/f_linux.go:3:1', 'pkg::F'),
+  ('pkg::F@pkg/f_windows.go:3:1', 'pkg::F#1');`), sqlite.OpenReadOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	tests := []struct{ id, want string }{
+		{"file::pkg/a.go/init#0", "pkg::init@pkg/a.go:3:1"},
+		{"file::pkg/b.go/init#0", "pkg::init@pkg/b.go:3:1"},
+		{"pkg::F", "pkg::F@pkg/f_linux.go:3:1"},
+		{"pkg::F#1", "pkg::F@pkg/f_windows.go:3:1"},
+		{"pkg::F@pkg/f_windows.go:3:1", "pkg::F@pkg/f_windows.go:3:1"},
+		{"pkg::G", "pkg::G"},
+	}
+	for _, tt := range tests {
+		if got := resolveNodeID(conn, tt.id); got != tt.want {
+			t.Errorf("resolveNodeID(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+
+	// Databases written before stable IDs
+	old, err := sqlite.OpenConn(writeDB(t, `CREATE TABLE nodes (id TEXT PRIMARY KEY);`), sqlite.OpenReadOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = old.Close() }()
+	if got := resolveNodeID(old, "pkg::F"); got != "pkg::F" {
+		t.Errorf("resolveNodeID without stable_id = %q, want the ID as is", got)
+	}
+}
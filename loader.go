@@ -106,7 +106,11 @@ func findSubModules(dir string) []string {
 // LoadPackages loads all Go packages from all modules via a workspace,
 // filtering to only packages belonging to known modules.
 func LoadPackages(goworkPath string, prog *Progress) (*LoadResult, error) {
-	prog.Log("Loading packages via workspace (%d modules)...", len(modSet.Dirs()))
+	if activeBuild != nil {
+		prog.Log("Loading packages via workspace (%d modules, %s)...", len(modSet.Dirs()), activeBuild.Name)
+	} else {
+		prog.Log("Loading packages via workspace (%d modules)...", len(modSet.Dirs()))
+	}
 
 	fset := token.NewFileSet()
 	cfg := &packages.Config{
@@ -121,8 +125,11 @@ func LoadPackages(goworkPath string, prog *Progress) (*LoadResult, error) {
 			packages.NeedTypesSizes,
 		Dir:   modSet.PrimaryDir(),
 		Fset:  fset,
-		Tests: false,
-		Env:   replaceEnv(os.Environ(), "GOWORK", goworkPath),
+		Tests: !flagSkipTests,
+		Env:   goCommandEnv(replaceEnv(os.Environ(), "GOWORK", goworkPath)),
+	}
+	if tags := effectiveBuildTags(); len(tags) > 0 {
+		cfg.BuildFlags = []string{"-tags=" + strings.Join(tags, ",")}
 	}
 
 	initial, err := packages.Load(cfg, modSet.LoadPatterns()...)
@@ -130,6 +137,16 @@ func LoadPackages(goworkPath string, prog *Progress) (*LoadResult, error) {
 		return nil, fmt.Errorf("packages.Load: %w", err)
 	}
 
+	// With tests, each package with _test.go files is loaded twice: as
+	// itself and as its test variant "p [p.test]", whose files are a
+	// superset. Keep only the test variant so every file is walked once.
+	hasTestVariant := make(map[string]bool)
+	for _, pkg := range initial {
+		if pkg.ID == pkg.PkgPath+" ["+pkg.PkgPath+".test]" {
+			hasTestVariant[pkg.PkgPath] = true
+		}
+	}
+
 	// Filter to known module packages only
 	filtered := make([]*packages.Package, 0, len(initial))
 	var errCount int
@@ -137,6 +154,12 @@ func LoadPackages(goworkPath string, prog *Progress) (*LoadResult, error) {
 		if !modSet.IsKnownPkg(pkg.PkgPath) {
 			continue
 		}
+		if strings.HasSuffix(pkg.ID, ".test") {
+			continue // generated test main package
+		}
+		if pkg.ID == pkg.PkgPath && hasTestVariant[pkg.PkgPath] {
+			continue
+		}
 		if len(pkg.Errors) > 0 {
 			errCount++
 			prog.Verbose("  warning: %s has %d errors: %v", pkg.PkgPath, len(pkg.Errors), pkg.Errors[0])
@@ -196,7 +219,7 @@ func shouldSkipFile(path string) bool {
 	if flagSkipTests && strings.HasSuffix(base, "_test.go") {
 		return true
 	}
-	if flagSkipGenerated && strings.HasSuffix(base, ".pb.go") {
+	if flagSkipGenerated && isGeneratedFile(base) {
 		return true
 	}
 	return false
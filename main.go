@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 )
 
@@ -19,45 +21,195 @@ func main() {
 // (including temp file cleanup) execute even on error paths, unlike os.Exit
 // which skips deferred calls.
 func run() error {
-	skipGenerated := flag.Bool("skip-generated", true, "Skip .pb.go files")
+	configPath := flag.String("config", "", "Path to a YAML project config (modules, package filters, build tags, phases)")
+	skipGenerated := flag.Bool("skip-generated", true, "Skip generated files (.pb.go unless overridden by config)")
 	skipTests := flag.Bool("skip-tests", true, "Skip _test.go files")
 	skipEscape := flag.Bool("skip-escape", false, "Skip Go compiler escape analysis phase")
+	skipChecks := flag.Bool("skip-checks", false, "Skip Go compiler bounds/nil check diagnostics phase")
+	skipVet := flag.Bool("skip-vet", false, "Skip the go/analysis passes phase")
+	analyzers := flag.String("analyzers", "", "Comma-separated go/analysis passes to run, by name (default: all built-in, e.g. nilness,shadow,copylocks,lostcancel,unusedresult,printf)")
+	gitWindow := flag.Int("git-window", 500, "Number of most recent commits analyzed for git history and co-change")
+	changedSince := flag.String("changed-since", "", "Git ref to diff the working tree against: records changed functions and types, their transitive callers and the findings the change introduces in changed code")
+	baseDB := flag.String("base-db", "", "CPG database generated at the -changed-since base, to tell introduced findings from inherited ones (default: generate it from a git worktree of the base; required with several modules)")
 	verbose := flag.Bool("verbose", false, "Print detailed progress")
 	validate := flag.Bool("validate", false, "Run validation queries after write")
+	jobs := flag.Int("jobs", runtime.GOMAXPROCS(0), "Worker count for parallel phases (AST walk, CFG/DFG, CDG); output is identical for any value")
+	configs := flag.String("configs", "", "Comma-separated build configurations to analyze and merge: GOOS/GOARCH, tags=t1+t2 or GOOS/GOARCH:tags=t1+t2 (e.g. linux/amd64,windows/amd64,tags=foo)")
+	callGraph := flag.String("callgraph", "vta", "Call graph algorithm for call edges: static, cha, rta or vta")
+	callGraphCmp := flag.String("callgraph-compare", "", "Comma-separated call graph algorithms to build side by side and store in callgraph_edges (e.g. cha,vta)")
+	pointsTo := flag.Bool("pointsto", false, "Run the whole-program points-to phase (alloc_site nodes, points_to/may_alias edges); also enabled by listing pointsto in the config's phases")
+	stream := flag.Bool("stream", false, "Stream nodes and edges to SQLite while phases run (lower peak memory)")
+	incremental := flag.Bool("incremental", false, "Update an existing output DB written with -incremental, skipping the AST walk and per-function phases of packages unchanged since then and copying their rows (loading, SSA and whole-program phases still cover the whole workspace)")
+	primaryPrefix := flag.String("primary-prefix", "", "Node ID prefix for the primary module (default: unprefixed)")
+	taintSpecs := flag.String("taint-specs", "", "Comma-separated YAML/JSON files of extra taint specs (sources, sinks, barriers, propagators), merged with the built-in ones")
+	flowSemantics := flag.String("flow-semantics", "", "Comma-separated YAML/JSON files of extra flow semantics for external functions, merged with the built-in ones")
 	modules := flag.String("modules", "", "Comma-separated dir:modpath:name triples for additional modules (e.g. ./adapter:sigs.k8s.io/prometheus-adapter:adapter)")
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: cpg-gen [flags] <primary-dir> <output.db>\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: cpg-gen [flags] <primary-dir> <output.db>\n")
+		fmt.Fprintf(os.Stderr, "       cpg-gen -config cpg.yaml [flags] <output.db>\n\n")
 		fmt.Fprintf(os.Stderr, "Generates a Code Property Graph (CPG) SQLite database from Go modules.\n\n")
 		fmt.Fprintf(os.Stderr, "Flags:\n")
 		flag.PrintDefaults()
 	}
 	flag.Parse()
 
-	if flag.NArg() != 2 {
+	// Validate the config before touching anything else, so a typo fails fast.
+	var cfg *Config
+	if *configPath != "" {
+		c, err := LoadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		cfg = c
+	}
+
+	// Flags given explicitly on the command line override the config file.
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+
+	// With modules declared in the config, the primary dir comes from there.
+	wantArgs := 2
+	if cfg != nil && len(cfg.Modules) > 0 {
+		wantArgs = 1
+	}
+	if flag.NArg() != wantArgs {
 		flag.Usage()
-		return fmt.Errorf("expected 2 arguments, got %d", flag.NArg())
+		return fmt.Errorf("expected %d arguments, got %d", wantArgs, flag.NArg())
 	}
 
-	promDir, err := filepath.Abs(flag.Arg(0))
-	if err != nil {
-		return fmt.Errorf("invalid primary dir: %w", err)
+	var primary ModuleInfo
+	var extras []ModuleInfo
+	var outputPath string
+	if wantArgs == 1 {
+		for i, m := range cfg.Modules {
+			info := ModuleInfo{ModPath: m.Module, Dir: m.Dir, Prefix: m.Prefix}
+			if i == 0 {
+				primary = info
+			} else {
+				extras = append(extras, info)
+			}
+		}
+		outputPath = flag.Arg(0)
+	} else {
+		promDir, err := filepath.Abs(flag.Arg(0))
+		if err != nil {
+			return fmt.Errorf("invalid primary dir: %w", err)
+		}
+		primaryModPath, err := modulePathFromGoMod(promDir)
+		if err != nil {
+			return fmt.Errorf("reading primary module path: %w", err)
+		}
+		if primaryModPath == "" {
+			return fmt.Errorf("no module directive in %s", filepath.Join(promDir, "go.mod"))
+		}
+		primary = ModuleInfo{
+			ModPath: primaryModPath,
+			Dir:     promDir,
+			Prefix:  "", // primary module keeps paths unprefixed for backward compat
+		}
+		outputPath = flag.Arg(1)
+	}
+	if setFlags["primary-prefix"] {
+		primary.Prefix = *primaryPrefix
 	}
-	outputPath := flag.Arg(1)
 
 	// Wire skip flags into the package-level config used by shouldSkipFile
 	flagSkipGenerated = *skipGenerated
 	flagSkipTests = *skipTests
+	if cfg != nil {
+		if cfg.SkipGenerated != nil && !setFlags["skip-generated"] {
+			flagSkipGenerated = *cfg.SkipGenerated
+		}
+		if cfg.SkipTests != nil && !setFlags["skip-tests"] {
+			flagSkipTests = *cfg.SkipTests
+		}
+		if len(cfg.GeneratedPatterns) > 0 {
+			generatedPatterns = cfg.GeneratedPatterns
+		}
+		buildTags = cfg.BuildTags
+	}
 
-	prog := NewProgress(*verbose)
+	// Build configurations: -configs overrides the config file's configs.
+	var builds []BuildConfig
+	configSpec := *configs
+	if cfg != nil && !setFlags["configs"] {
+		configSpec = strings.Join(cfg.Configs, ",")
+	}
+	if configSpec != "" {
+		b, err := ParseBuildConfigs(configSpec)
+		if err != nil {
+			return fmt.Errorf("invalid -configs: %w", err)
+		}
+		builds = b
+	}
+	if len(builds) > 1 && *incremental {
+		return fmt.Errorf("-incremental cannot be combined with multiple -configs")
+	}
+	if len(builds) > 0 {
+		activeBuild = &builds[0]
+	}
 
-	// Build ModuleSet from primary dir + extra modules
-	primary := ModuleInfo{
-		ModPath: "github.com/prometheus/prometheus",
-		Dir:     promDir,
-		Prefix:  "", // primary module keeps paths unprefixed for backward compat
+	if !slices.Contains(callGraphAlgos, *callGraph) {
+		return fmt.Errorf("unknown -callgraph %q (want %s)", *callGraph, strings.Join(callGraphAlgos, ", "))
+	}
+	callGraphAlgo = *callGraph
+	for _, algo := range strings.Split(*callGraphCmp, ",") {
+		algo = strings.TrimSpace(algo)
+		if algo == "" || slices.Contains(callGraphCompare, algo) {
+			continue
+		}
+		if !slices.Contains(callGraphAlgos, algo) {
+			return fmt.Errorf("unknown -callgraph-compare algorithm %q (want %s)", algo, strings.Join(callGraphAlgos, ", "))
+		}
+		callGraphCompare = append(callGraphCompare, algo)
 	}
 
-	var extras []ModuleInfo
+	// Taint specs and flow semantics: the flags override the config's lists.
+	var taintFiles, flowFiles []string
+	if cfg != nil {
+		taintFiles, flowFiles = cfg.TaintSpecs, cfg.FlowSemantics
+	}
+	if setFlags["taint-specs"] {
+		taintFiles = splitFileList(*taintSpecs)
+	}
+	if setFlags["flow-semantics"] {
+		flowFiles = splitFileList(*flowSemantics)
+	}
+	specs, err := LoadTaintSpecs(taintFiles)
+	if err != nil {
+		return fmt.Errorf("invalid taint specs:\n%w", err)
+	}
+	flows, err := LoadFlowSemantics(flowFiles)
+	if err != nil {
+		return fmt.Errorf("invalid flow semantics:\n%w", err)
+	}
+	userTaintSpecs, userFlowSemantics = specs, flows
+
+	if *jobs < 1 {
+		return fmt.Errorf("-jobs must be at least 1, got %d", *jobs)
+	}
+	flagJobs = *jobs
+	flagPointsTo = *pointsTo
+	flagIncremental = *incremental
+	if *gitWindow < 1 {
+		return fmt.Errorf("-git-window must be at least 1, got %d", *gitWindow)
+	}
+
+	// go/analysis passes: -analyzers overrides the config's list.
+	var analyzerNames []string
+	if cfg != nil {
+		analyzerNames = cfg.Analyzers
+	}
+	if setFlags["analyzers"] {
+		analyzerNames = splitFileList(*analyzers)
+	}
+	vetPasses, err := SelectAnalyzers(analyzerNames)
+	if err != nil {
+		return fmt.Errorf("-analyzers: %w", err)
+	}
+
+	prog := NewProgress(*verbose)
+
 	if *modules != "" {
 		for _, spec := range strings.Split(*modules, ",") {
 			parts := strings.SplitN(strings.TrimSpace(spec), ":", 3)
@@ -79,8 +231,51 @@ func run() error {
 	}
 
 	modSet = NewModuleSet(primary, extras)
+	if cfg != nil {
+		modSet.SetPackageFilter(cfg.Include, cfg.Exclude)
+	}
 	prog.Log("Analyzing %d modules: %s", len(modSet.Dirs()), moduleNames(modSet))
 
+	// Resolve the diff base up front so a bad ref fails before extraction.
+	var baseCommit string
+	if *changedSince != "" {
+		for i, mod := range modSet.Dirs() {
+			commit, err := ResolveGitRef(mod.Dir, *changedSince)
+			if err != nil {
+				return fmt.Errorf("-changed-since: %w", err)
+			}
+			if i == 0 {
+				baseCommit = commit
+			}
+		}
+	} else if *baseDB != "" {
+		return fmt.Errorf("-base-db requires -changed-since")
+	}
+
+	// Findings the change introduces are the ones the base CPG lacks. Without
+	// -base-db, the base CPG is generated with the same flags; module dirs
+	// from a config or -modules cannot be rewritten to the base checkout.
+	baseDBPath := *baseDB
+	if *changedSince != "" && baseDBPath == "" {
+		if wantArgs == 1 || len(modSet.Dirs()) > 1 {
+			return fmt.Errorf("-changed-since with several modules or config modules requires -base-db, a CPG generated at %s", *changedSince)
+		}
+		var args []string
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "changed-since", "base-db", "incremental", "stream", "validate":
+			default:
+				args = append(args, "-"+f.Name+"="+f.Value.String())
+			}
+		})
+		path, cleanup, err := GenerateBaseDB(primary.Dir, baseCommit, args, prog)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		baseDBPath = path
+	}
+
 	// Create temporary go.work for unified type universe
 	goworkPath, err := CreateTempGoWork(modSet)
 	if err != nil {
@@ -89,14 +284,160 @@ func run() error {
 	defer os.Remove(goworkPath)
 	prog.Verbose("Created workspace: %s", goworkPath)
 
-	cpg := NewCPG()
-
 	// Phase 1: Load packages (all modules, single type universe)
 	loadResult, err := LoadPackages(goworkPath, prog)
 	if err != nil {
 		return err
 	}
 
+	// Phase 1b: Hash packages; in incremental mode, compare with the previous DB
+	pkgHashes := ComputePackageHashes(loadResult.Packages)
+	if *incremental {
+		incr = PlanIncremental(outputPath, pkgHashes, prog)
+		if incr.UpToDate() {
+			prog.Log("Done. %s is up to date.", outputPath)
+			return nil
+		}
+	}
+
+	cpg := NewCPG()
+	if *stream {
+		sink, err := NewDBSink(outputPath, prog)
+		if err != nil {
+			return err
+		}
+		cpg = NewStreamingCPG(sink)
+	}
+	cpg.PackageHashes = pkgHashes
+
+	// Phases 2-7 run once per build configuration; the first configuration
+	// (or the host) is already loaded. Graphs merge through CPG dedup, and
+	// with several configurations each element is tagged with where it
+	// appears.
+	names := make([]string, len(builds))
+	for i, b := range builds {
+		names[i] = b.Name
+	}
+	// Lookups of the first configuration, where escape analysis runs
+	var posLookup *PosLookup
+	var funcLookup *FuncLookup
+	var vetResults []VetResult
+	for i := range max(len(builds), 1) {
+		if i > 0 {
+			activeBuild = &builds[i]
+			if loadResult, err = LoadPackages(goworkPath, prog); err != nil {
+				return err
+			}
+		}
+		if len(builds) > 1 {
+			prog.Log("Configuration %d/%d: %s", i+1, len(builds), names[i])
+			cpg.BeginConfig(i, names[i])
+		}
+		pl, fl := extractGraph(loadResult, cfg, cpg, prog)
+		if i == 0 {
+			posLookup, funcLookup = pl, fl
+
+			// Phase 7a: go/analysis passes over the first configuration,
+			// sharing its type-checked packages
+			if *skipVet || !cfg.PhaseEnabled("vet") {
+				prog.Log("Skipping go/analysis passes")
+			} else {
+				vetResults = RunAnalyzers(loadResult.Packages, loadResult.Fset, vetPasses, prog)
+				ResolveVet(vetResults, pl, prog)
+			}
+		}
+	}
+	if len(builds) > 1 {
+		cpg.TagConfigs(names)
+	}
+
+	// Phase 7b: Fill fan-in/fan-out from the merged call graph
+	if cfg.PhaseEnabled("metrics") {
+		ComputeFanInOut(cpg, prog)
+	}
+
+	// Add META_DATA node with generator info
+	meta := map[string]any{
+		"language":    "go",
+		"version":     "1.0",
+		"generator":   "cpg-gen",
+		"root":        primary.Dir,
+		"module_path": primary.ModPath,
+		"modules":     len(modSet.Dirs()),
+		"callgraph":   callGraphAlgo,
+	}
+	if len(builds) > 0 {
+		meta["configs"] = names
+	}
+	if *changedSince != "" {
+		meta["changed_since"] = *changedSince
+		meta["base_commit"] = baseCommit
+	}
+	cpg.AddNode(Node{
+		ID:         "META_DATA",
+		Kind:       "meta_data",
+		Name:       "CPG Metadata",
+		Properties: meta,
+	})
+
+	// Phase 7c: Escape analysis from Go compiler (all modules), in the first
+	// build configuration
+	if len(builds) > 0 {
+		activeBuild = &builds[0]
+	}
+	var escapeResults []EscapeResult
+	if *skipEscape || !cfg.PhaseEnabled("escape") {
+		prog.Log("Skipping Go escape analysis")
+	} else {
+		escapeResults = RunEscapeAnalysis(prog)
+		ResolveEscapes(escapeResults, posLookup, funcLookup, prog)
+	}
+
+	// Phase 7d: Bounds and nil checks left in by the Go compiler (all
+	// modules), in the same configuration
+	var checkResults []CheckResult
+	if *skipChecks || !cfg.PhaseEnabled("checks") {
+		prog.Log("Skipping Go bounds/nil check diagnostics")
+	} else {
+		checkResults = RunCheckDiagnostics(prog)
+		ResolveChecks(checkResults, posLookup, prog)
+	}
+
+	// Phase 7e: Git history for diff-aware analysis (all modules)
+	var gitHistory *GitHistory
+	if cfg.PhaseEnabled("git") {
+		window := *gitWindow
+		if cfg != nil && cfg.GitWindow != nil && !setFlags["git-window"] {
+			window = *cfg.GitWindow
+		}
+		gitHistory = RunGitHistory(window, prog)
+	}
+
+	// Phase 7f: Hunks changed since -changed-since (all modules)
+	if *changedSince != "" {
+		hunks, err := RunGitDiff(*changedSince, prog)
+		if err != nil {
+			return err
+		}
+		if gitHistory == nil {
+			gitHistory = &GitHistory{}
+		}
+		gitHistory.Base, gitHistory.BaseDB, gitHistory.Hunks = *changedSince, baseDBPath, hunks
+	}
+
+	// Phase 8: Write SQLite
+	if err := WriteDB(outputPath, cpg, escapeResults, checkResults, vetResults, gitHistory, *validate, prog); err != nil {
+		return err
+	}
+
+	prog.Log("Done. %d nodes, %d edges.", cpg.NodeCount(), cpg.EdgeCount())
+	return nil
+}
+
+// extractGraph runs the extraction phases that depend on one package load:
+// AST, SSA, CFG/DFG and the optional graph phases up to function metrics.
+// It returns the load's position and function lookups.
+func extractGraph(loadResult *LoadResult, cfg *Config, cpg *CPG, prog *Progress) (*PosLookup, *FuncLookup) {
 	// Phase 2: Walk AST → nodes + AST edges + position lookup
 	posLookup, funcLookup := WalkAST(loadResult.Packages, loadResult.Fset, cpg, prog)
 
@@ -107,69 +448,77 @@ func run() error {
 	ExtractCFGAndDFG(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
 
 	// Phase 4b: Extract CDG from post-dominator tree
-	ExtractCDG(ssaResult, loadResult.Fset, funcLookup, cpg, prog)
+	if cfg.PhaseEnabled("cdg") {
+		ExtractCDG(ssaResult, loadResult.Fset, funcLookup, cpg, prog)
+	}
 
 	// Phase 4c: Extract channel send→receive flow edges
-	ExtractChannelFlow(ssaResult, loadResult.Fset, posLookup, cpg, prog)
+	if cfg.PhaseEnabled("channels") {
+		ExtractChannelFlow(ssaResult, loadResult.Fset, posLookup, cpg, prog)
+	}
 
 	// Phase 4d: Extract panic/recover flow edges
-	ExtractPanicRecover(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
-
-	// Phase 5: Build VTA call graph → call edges
-	BuildCallGraph(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
-
-	// Phase 6: Extract type relationships (implements, embeds)
-	ExtractTypeRelationships(loadResult.Packages, loadResult.Fset, posLookup, cpg, prog)
-
-	// Phase 7: Compute function metrics
-	ComputeMetrics(loadResult.Packages, loadResult.Fset, funcLookup, cpg, prog)
+	if cfg.PhaseEnabled("panics") {
+		ExtractPanicRecover(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
+	}
 
-	// Phase 7b: Fill fan-in/fan-out from call graph
-	ComputeFanInOut(cpg)
+	// Phase 4e: Find error results that are never checked
+	if cfg.PhaseEnabled("errcheck") {
+		ExtractUncheckedErrors(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
+	}
 
-	// Add META_DATA node with generator info
-	cpg.AddNode(Node{
-		ID:   "META_DATA",
-		Kind: "meta_data",
-		Name: "CPG Metadata",
-		Properties: map[string]any{
-			"language":  "go",
-			"version":   "1.0",
-			"generator": "cpg-gen",
-			"root":      promDir,
-			"modules":   len(modSet.Dirs()),
-		},
-	})
+	// Phase 5: Build VTA call graph → call edges
+	if cfg.PhaseEnabled("callgraph") {
+		BuildCallGraph(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
 
-	// Phase 7c: Escape analysis from Go compiler (all modules)
-	var escapeResults []EscapeResult
-	if *skipEscape {
-		prog.Log("Skipping Go escape analysis (-skip-escape)")
-	} else {
-		escapeResults = RunEscapeAnalysis(prog)
+		// Phase 5b: Link tests to the functions they reach
+		if !flagSkipTests {
+			EmitTestEdges(ssaResult, loadResult.Fset, funcLookup, cpg, prog)
+		}
 	}
 
-	// Phase 7d: Git history for diff-aware analysis (all modules)
-	gitHistory := RunGitHistory(prog)
+	// Phase 5c: Points-to analysis → alloc_site nodes, points_to/may_alias edges
+	// (opt-in; taint follows may_alias when they are present)
+	if flagPointsTo || cfg.PhaseEnabled("pointsto") {
+		ExtractPointsTo(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
+	}
 
-	// Phase 8: Write SQLite
-	if err := WriteDB(outputPath, cpg, escapeResults, gitHistory, *validate, prog); err != nil {
-		return err
+	// Phase 6: Extract type relationships (implements, embeds)
+	if cfg.PhaseEnabled("types") {
+		ExtractTypeRelationships(loadResult.Packages, loadResult.Fset, posLookup, cpg, prog)
 	}
 
-	prog.Log("Done. %d nodes, %d edges.", len(cpg.Nodes), len(cpg.Edges))
-	return nil
+	// Phase 7: Compute function metrics
+	if cfg.PhaseEnabled("metrics") {
+		ComputeMetrics(loadResult.Packages, loadResult.Fset, funcLookup, cpg, prog)
+	}
+	return posLookup, funcLookup
 }
 
 // moduleNames returns a human-readable list of module prefixes.
 func moduleNames(ms *ModuleSet) string {
 	names := make([]string, len(ms.Dirs()))
 	for i, m := range ms.Dirs() {
-		if m.Prefix == "" {
-			names[i] = "prometheus (primary)"
-		} else {
+		switch {
+		case i == 0 && m.Prefix == "":
+			names[i] = m.ModPath + " (primary)"
+		case i == 0:
+			names[i] = m.Prefix + " (primary)"
+		default:
 			names[i] = m.Prefix
 		}
 	}
 	return strings.Join(names, ", ")
 }
+
+// splitFileList splits a comma-separated flag value into file paths,
+// dropping empty entries.
+func splitFileList(s string) []string {
+	var files []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
@@ -16,6 +16,9 @@ func ComputeMetrics(pkgs []*packages.Package, fset *token.FileSet, funcLookup *F
 	var count int
 
 	for _, pkg := range pkgs {
+		if incr.SkipPkg(pkg.PkgPath) {
+			continue // rows reused from the previous DB
+		}
 		for i, file := range pkg.Syntax {
 			if i >= len(pkg.CompiledGoFiles) {
 				continue
@@ -68,12 +71,12 @@ func ComputeMetrics(pkgs []*packages.Package, fset *token.FileSet, funcLookup *F
 				endLine := fset.Position(endPos).Line
 				loc := endLine - line + 1
 
-				cpg.Metrics[funcID] = &Metrics{
+				cpg.SetMetrics(&Metrics{
 					FunctionID:           funcID,
 					CyclomaticComplexity: complexity,
 					LOC:                  loc,
 					NumParams:            countParams(funcType),
-				}
+				})
 				count++
 
 				return true
@@ -1,6 +1,9 @@
 package main
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"maps"
+)
 
 // Node represents a vertex in the Code Property Graph.
 type Node struct {
@@ -14,6 +17,7 @@ type Node struct {
 	ParentFunction string // node ID of enclosing function, or ""
 	TypeInfo       string
 	Properties     map[string]any
+	StableID       string // line-independent ID (see SymbolID, PathID), "" to derive when writing
 }
 
 // Edge represents a directed edge in the Code Property Graph.
@@ -34,43 +38,180 @@ type Metrics struct {
 	NumParams            int
 }
 
+// CallGraphEdge is a function→function edge found by one call graph
+// algorithm, recorded with -callgraph-compare.
+type CallGraphEdge struct {
+	Source, Target string
+	Algo           string
+	Dynamic        bool // interface dispatch
+}
+
+// FunctionSummary is one parameter flow of a function: From ("recv" or
+// "arg:N") reaches To ("return:N", "recv", "arg:N" or "global:pkg.name").
+// Kind is "ssa" for summaries derived from the function body and "opaque"
+// for bodiless functions, whose every argument is assumed to reach every
+// result. A function whose parameters reach nothing has a single
+// FunctionSummary with empty From and To.
+type FunctionSummary struct {
+	Function string
+	From, To string
+	Variadic bool // From is the variadic parameter: it also covers later arguments
+	Kind     string
+}
+
+// UncheckedError is a call whose error result is never checked: Kind is
+// "discarded" (expression statement), "blank" (assigned to _),
+// "overwritten" (assigned, then replaced or dropped before any use) or
+// "deferred_close" (a deferred Close whose error is lost). ReachesReturn
+// reports that the enclosing function returns a non-nil error on some path
+// after the call: it propagates other errors but drops this one.
+type UncheckedError struct {
+	Function      string // enclosing function node ID
+	CallSite      string // call node ID, "" if unresolved
+	Callee        string
+	Kind          string
+	File          string
+	Line, Col     int
+	ReachesReturn bool
+}
+
 // edgeKey is the deduplication key for edges.
 type edgeKey struct {
 	Source, Target, Kind string
 }
 
 // CPG accumulates the entire Code Property Graph in memory before flushing to SQLite.
+//
+// When created with NewStreamingCPG, nodes, edges, sources and the side
+// tables (Metrics, CallGraphEdges, FunctionSummaries, UncheckedErrors,
+// PackageLookups) are instead handed to a DBSink that flushes them to
+// SQLite in batches as phases run; the fields then stay empty and dedup
+// happens on disk.
 type CPG struct {
 	Nodes    []Node
 	Edges    []Edge
-	nodeSeen map[string]struct{}
+	nodeSeen map[string]int // node ID → index in Nodes
 	edgeSeen map[edgeKey]struct{}
 	Sources  map[string]string   // file → content
 	Metrics  map[string]*Metrics // function_id → metrics
+
+	CallGraphEdges    []CallGraphEdge   // -callgraph-compare only
+	FunctionSummaries []FunctionSummary // see SummarizeFunctions
+	UncheckedErrors   []UncheckedError  // see ExtractUncheckedErrors
+
+	PackageHashes  map[string]string // import path → content+deps hash (for -incremental)
+	PackageLookups []PackageLookup   // lookups of walked packages (-incremental only)
+
+	sink *DBSink // non-nil in streaming mode
+
+	// With -configs, the build configuration currently being extracted and
+	// the configurations each node and edge was seen in (see TagConfigs).
+	config      string
+	configIdx   int
+	nodeConfigs map[string][]int
+	edgeConfigs map[edgeKey][]int
 }
 
 // NewCPG creates an empty CPG ready for population.
 func NewCPG() *CPG {
 	return &CPG{
-		nodeSeen: make(map[string]struct{}),
+		nodeSeen: make(map[string]int),
 		edgeSeen: make(map[edgeKey]struct{}),
 		Sources:  make(map[string]string),
 		Metrics:  make(map[string]*Metrics),
 	}
 }
 
+// NewStreamingCPG creates a CPG that streams nodes, edges and sources to sink.
+func NewStreamingCPG(sink *DBSink) *CPG {
+	g := NewCPG()
+	g.sink = sink
+	return g
+}
+
+// BeginConfig starts extraction for the idx-th build configuration. Nodes
+// and edges added until TagConfigs are recorded as present in it.
+func (g *CPG) BeginConfig(idx int, name string) {
+	if g.nodeConfigs == nil {
+		g.nodeConfigs = make(map[string][]int)
+		g.edgeConfigs = make(map[edgeKey][]int)
+	}
+	g.config, g.configIdx = name, idx
+	if g.sink != nil {
+		g.sink.setConfig(idx, name)
+	}
+}
+
+// TagConfigs ends multi-configuration extraction and adds a "configs"
+// property, listing configuration names in order, to every node and edge
+// that was not seen in all of them. Untagged elements exist in every
+// configuration.
+func (g *CPG) TagConfigs(names []string) {
+	g.config = ""
+	if g.sink != nil {
+		g.sink.tagConfigs(len(names))
+		return
+	}
+	tag := func(props map[string]any, idxs []int) map[string]any {
+		list := make([]string, len(idxs))
+		for i, idx := range idxs {
+			list[i] = names[idx]
+		}
+		props = maps.Clone(props) // property maps may be shared between elements
+		if props == nil {
+			props = map[string]any{}
+		}
+		props["configs"] = list
+		return props
+	}
+	for i := range g.Nodes {
+		if idxs := g.nodeConfigs[g.Nodes[i].ID]; len(idxs) > 0 && len(idxs) < len(names) {
+			g.Nodes[i].Properties = tag(g.Nodes[i].Properties, idxs)
+		}
+	}
+	for i := range g.Edges {
+		e := &g.Edges[i]
+		if idxs := g.edgeConfigs[edgeKey{e.Source, e.Target, e.Kind}]; len(idxs) > 0 && len(idxs) < len(names) {
+			e.Properties = tag(e.Properties, idxs)
+		}
+	}
+	g.nodeConfigs, g.edgeConfigs = nil, nil
+}
+
+// recordConfig appends the active configuration to idxs unless present.
+func (g *CPG) recordConfig(idxs []int) []int {
+	if n := len(idxs); n > 0 && idxs[n-1] == g.configIdx {
+		return idxs
+	}
+	return append(idxs, g.configIdx)
+}
+
 // AddNode appends a node, deduplicating by ID (first wins).
 func (g *CPG) AddNode(n Node) {
+	if g.sink != nil {
+		g.sink.addNode(n)
+		return
+	}
+	if g.config != "" {
+		g.nodeConfigs[n.ID] = g.recordConfig(g.nodeConfigs[n.ID])
+	}
 	if _, dup := g.nodeSeen[n.ID]; dup {
 		return
 	}
-	g.nodeSeen[n.ID] = struct{}{}
+	g.nodeSeen[n.ID] = len(g.Nodes)
 	g.Nodes = append(g.Nodes, n)
 }
 
 // AddEdge appends an edge if no edge with the same (source, target, kind) already exists.
 func (g *CPG) AddEdge(e Edge) {
+	if g.sink != nil {
+		g.sink.addEdge(e)
+		return
+	}
 	k := edgeKey{e.Source, e.Target, e.Kind}
+	if g.config != "" {
+		g.edgeConfigs[k] = g.recordConfig(g.edgeConfigs[k])
+	}
 	if _, dup := g.edgeSeen[k]; dup {
 		return
 	}
@@ -78,6 +219,116 @@ func (g *CPG) AddEdge(e Edge) {
 	g.Edges = append(g.Edges, e)
 }
 
+// AddSource records a file's content (first wins). It reports whether the
+// file was new.
+func (g *CPG) AddSource(file, content string) bool {
+	if g.sink != nil {
+		return g.sink.addSource(file, content)
+	}
+	if _, ok := g.Sources[file]; ok {
+		return false
+	}
+	g.Sources[file] = content
+	return true
+}
+
+// SetNodeProperty sets a property on an already-added node.
+func (g *CPG) SetNodeProperty(id, key string, val any) {
+	if g.sink != nil {
+		g.sink.setNodeProperty(id, key, val)
+		return
+	}
+	i, ok := g.nodeSeen[id]
+	if !ok {
+		return
+	}
+	if g.Nodes[i].Properties == nil {
+		g.Nodes[i].Properties = map[string]any{}
+	}
+	g.Nodes[i].Properties[key] = val
+}
+
+// EdgesOfKind calls fn for every edge of the given kind added so far. In
+// incremental mode, call edges also include those of reused callers, which
+// WriteDB copies from the previous DB.
+func (g *CPG) EdgesOfKind(kind string, fn func(source, target string)) error {
+	if kind == "call" && incr != nil {
+		if err := incr.previousCalls(fn); err != nil {
+			return err
+		}
+	}
+	if g.sink != nil {
+		return g.sink.edgesOfKind(kind, fn)
+	}
+	for _, e := range g.Edges {
+		if e.Kind == kind {
+			fn(e.Source, e.Target)
+		}
+	}
+	return nil
+}
+
+// SetMetrics records a function's metrics, replacing earlier ones.
+func (g *CPG) SetMetrics(m *Metrics) {
+	if g.sink != nil {
+		g.sink.addMetrics(*m)
+		return
+	}
+	g.Metrics[m.FunctionID] = m
+}
+
+// AddCallGraphEdge records an edge of a compared call graph.
+func (g *CPG) AddCallGraphEdge(e CallGraphEdge) {
+	if g.sink != nil {
+		g.sink.addCallGraphEdge(e)
+		return
+	}
+	g.CallGraphEdges = append(g.CallGraphEdges, e)
+}
+
+// AddFunctionSummaries records the summary rows of one function.
+func (g *CPG) AddFunctionSummaries(rows []FunctionSummary) {
+	if g.sink != nil {
+		g.sink.addFunctionSummaries(rows)
+		return
+	}
+	g.FunctionSummaries = append(g.FunctionSummaries, rows...)
+}
+
+// AddUncheckedError records a call with an unchecked error result.
+func (g *CPG) AddUncheckedError(u UncheckedError) {
+	if g.sink != nil {
+		g.sink.addUncheckedError(u)
+		return
+	}
+	g.UncheckedErrors = append(g.UncheckedErrors, u)
+}
+
+// AddPackageLookups records the lookups of a walked package.
+func (g *CPG) AddPackageLookups(rows []PackageLookup) {
+	if g.sink != nil {
+		g.sink.addPackageLookups(rows)
+		return
+	}
+	g.PackageLookups = append(g.PackageLookups, rows...)
+}
+
+// NodeCount returns the number of distinct nodes added so far.
+func (g *CPG) NodeCount() int {
+	if g.sink != nil {
+		return g.sink.nodes
+	}
+	return len(g.Nodes)
+}
+
+// EdgeCount returns the number of distinct edges added so far.
+func (g *CPG) EdgeCount() int {
+	if g.sink != nil {
+		return g.sink.edges
+	}
+	return len(g.Edges)
+}
+
 // PropsJSON marshals a properties map to JSON string, or "" if empty.
 func PropsJSON(m map[string]any) string {
 	if len(m) == 0 {
@@ -19,6 +19,11 @@ type ModuleInfo struct {
 // consistent with flagSkipTests/flagSkipGenerated globals.
 type ModuleSet struct {
 	modules []ModuleInfo
+
+	// Optional package globs (see matchPkgGlob). Excluded packages are
+	// treated as external by every phase, exactly like third-party code.
+	include []string
+	exclude []string
 }
 
 // Global instance — set in main() before pipeline runs.
@@ -35,16 +40,45 @@ func NewModuleSet(primary ModuleInfo, extras []ModuleInfo) *ModuleSet {
 	return ms
 }
 
-// IsKnownPkg returns true if pkgPath belongs to any module in the set.
+// SetPackageFilter restricts the set to packages matching include (if
+// non-empty) and not matching exclude.
+func (ms *ModuleSet) SetPackageFilter(include, exclude []string) {
+	ms.include = include
+	ms.exclude = exclude
+}
+
+// IsKnownPkg returns true if pkgPath belongs to any module in the set and
+// passes the package filter.
 func (ms *ModuleSet) IsKnownPkg(pkgPath string) bool {
 	for _, m := range ms.modules {
 		if pkgPath == m.ModPath || strings.HasPrefix(pkgPath, m.ModPath+"/") {
-			return true
+			return ms.selected(pkgPath)
 		}
 	}
 	return false
 }
 
+// selected applies the include/exclude globs. Globs are matched against both
+// the full import path and the module-relative path.
+func (ms *ModuleSet) selected(pkgPath string) bool {
+	if len(ms.include) == 0 && len(ms.exclude) == 0 {
+		return true
+	}
+	rel := ms.RelPkg(pkgPath)
+	matchAny := func(globs []string) bool {
+		for _, g := range globs {
+			if matchPkgGlob(g, pkgPath) || matchPkgGlob(g, rel) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(ms.include) > 0 && !matchAny(ms.include) {
+		return false
+	}
+	return !matchAny(ms.exclude)
+}
+
 // RelPkg strips the module prefix from a full import path and prepends the
 // module's Prefix. Prometheus (Prefix:"") yields "scrape"; adapter (Prefix:"adapter")
 // yields "adapter/pkg/client".
@@ -123,6 +157,14 @@ func (ms *ModuleSet) PrimaryDir() string {
 	return ms.modules[0].Dir
 }
 
+// PrimaryModPath returns the first (primary) module's import path.
+func (ms *ModuleSet) PrimaryModPath() string {
+	if len(ms.modules) == 0 {
+		return ""
+	}
+	return ms.modules[0].ModPath
+}
+
 // Dirs returns all module infos for operations that need to iterate modules
 // (escape analysis, git history).
 func (ms *ModuleSet) Dirs() []ModuleInfo {
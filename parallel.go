@@ -0,0 +1,101 @@
+package main
+
+import (
+	"slices"
+	"sync"
+)
+
+// flagJobs is the worker count for parallel phases, set by main before any
+// pipeline phase runs.
+var flagJobs = 1
+
+// runSharded calls work(i, shard) for every i in [0, n) on up to flagJobs
+// goroutines. Each call writes into its own shard CPG, and shards are merged
+// into cpg strictly in index order, so the merged graph — including which
+// duplicate wins in AddNode/AddEdge — is identical for any worker count.
+//
+// With a single job, work writes straight into cpg, which is equivalent.
+func runSharded(n int, cpg *CPG, work func(i int, shard *CPG)) {
+	runShardedMerge(n, cpg, work, nil)
+}
+
+// runShardedMerge is runSharded with a merged hook, called with i after
+// shard i is merged, in index order. Work that keeps state besides its shard
+// CPG merges that state there, so it is as deterministic as the graph.
+func runShardedMerge(n int, cpg *CPG, work func(i int, shard *CPG), merged func(i int)) {
+	jobs := min(max(flagJobs, 1), n)
+	if jobs <= 1 {
+		for i := range n {
+			work(i, cpg)
+			if merged != nil {
+				merged(i)
+			}
+		}
+		return
+	}
+
+	indices := make(chan int)
+	ready := make(chan int, jobs)
+	results := make([]*CPG, n)
+
+	var wg sync.WaitGroup
+	for range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				shard := NewCPG()
+				work(i, shard)
+				results[i] = shard
+				ready <- i
+			}
+		}()
+	}
+	go func() {
+		for i := range n {
+			indices <- i
+		}
+		close(indices)
+		wg.Wait()
+		close(ready)
+	}()
+
+	// Merge completed shards as soon as all lower indices are merged, so at
+	// most a window of out-of-order shards is held in memory.
+	done := make([]bool, n)
+	next := 0
+	for i := range ready {
+		done[i] = true
+		for next < n && done[next] {
+			cpg.mergeShard(results[next])
+			results[next] = nil
+			if merged != nil {
+				merged(next)
+			}
+			next++
+		}
+	}
+}
+
+// mergeShard replays a shard into g in the shard's insertion order.
+func (g *CPG) mergeShard(s *CPG) {
+	for _, n := range s.Nodes {
+		g.AddNode(n)
+	}
+	for _, e := range s.Edges {
+		g.AddEdge(e)
+	}
+	files := make([]string, 0, len(s.Sources))
+	for f := range s.Sources {
+		files = append(files, f)
+	}
+	slices.Sort(files)
+	for _, f := range files {
+		g.AddSource(f, s.Sources[f])
+	}
+	for id, m := range s.Metrics {
+		if _, ok := g.Metrics[id]; !ok {
+			g.Metrics[id] = m
+		}
+	}
+}
@@ -0,0 +1,898 @@
+package main
+
+import (
+	"cmp"
+	"go/token"
+	"go/types"
+	"slices"
+	"strconv"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Limits that keep the points-to output proportional to the code size.
+const (
+	ptMaxPathLen    = 32 // longer field paths collapse onto their prefix cell
+	ptMaxTargets    = 32 // points_to edges per value node
+	ptMaxAliasGroup = 64 // values sharing one cell beyond this are not paired
+)
+
+// ptObject is an abstract heap object: one allocation site, global
+// variable, function, interface box or external call result.
+type ptObject struct {
+	kind string // alloc, make_chan, make_map, make_slice, append, closure, box, global, func, external
+	typ  types.Type
+	fn   *ssa.Function // closure/func objects: the function; otherwise the allocating function
+	pos  token.Pos
+	name string // globals only
+}
+
+// ptCell is a location inside an object: the object itself (path "") or a
+// field/element reached through FieldAddr (".N"), IndexAddr ("[]") or a
+// channel buffer ("<-").
+type ptCell struct {
+	obj  int
+	path string
+}
+
+// ptCall is a call site whose callees are only known once the callee value
+// (func value or interface receiver) has points-to facts.
+type ptCall struct {
+	common *ssa.CallCommon
+	instr  ssa.CallInstruction
+	bound  map[*ssa.Function]bool
+}
+
+type ptField struct {
+	dst  int
+	step string
+}
+
+// ptNode is a solver variable: an SSA value, a function result slot or a cell.
+type ptNode struct {
+	pts    map[int]struct{} // cell indices
+	delta  []int
+	queued bool
+	succs  map[int]struct{} // subset edges: pts(n) ⊆ pts(succ)
+	loads  []int            // dst ⊇ *n
+	stores []int            // *n ⊇ src
+	fields []ptField        // dst ∋ &(*n).step
+	calls  []*ptCall        // n is the callee value
+}
+
+// PointsTo is an inclusion-based (Andersen-style), field-sensitive,
+// context-insensitive points-to analysis over the SSA of the analyzed
+// modules. Call targets are resolved on the fly from the points-to sets of
+// func values and interface receivers, so it needs no prior call graph.
+type PointsTo struct {
+	prog *ssa.Program
+
+	objects []ptObject
+	cells   []ptCell
+	cellIdx map[ptCell]int
+	cellVar []int // cell index → node
+
+	nodes    []*ptNode
+	work     []int
+	valueVar map[ssa.Value]int
+	results  map[*ssa.Function][]int // per result index
+	tuples   map[*ssa.Call][]int     // per-index results of tuple-returning calls
+	funcObj  map[*ssa.Function]int
+	globObj  map[*ssa.Global]int
+	extObj   map[ssa.CallInstruction]int
+	analyzed map[*ssa.Function]bool
+	ptLike   map[types.Type]bool
+}
+
+// AnalyzePointsTo solves points-to constraints for all known-module
+// functions in ssaResult, plus the functions they transitively call.
+func AnalyzePointsTo(ssaResult *SSAResult, fset *token.FileSet) *PointsTo {
+	a := newPointsTo(ssaResult.Prog)
+	for _, fn := range ssaResult.KnownFuncs(fset) {
+		a.genFunc(fn)
+	}
+	a.solve()
+	return a
+}
+
+func newPointsTo(prog *ssa.Program) *PointsTo {
+	return &PointsTo{
+		prog:     prog,
+		cellIdx:  make(map[ptCell]int),
+		valueVar: make(map[ssa.Value]int),
+		results:  make(map[*ssa.Function][]int),
+		tuples:   make(map[*ssa.Call][]int),
+		funcObj:  make(map[*ssa.Function]int),
+		globObj:  make(map[*ssa.Global]int),
+		extObj:   make(map[ssa.CallInstruction]int),
+		analyzed: make(map[*ssa.Function]bool),
+		ptLike:   make(map[types.Type]bool),
+	}
+}
+
+// --- constraint generation ---
+
+func (a *PointsTo) newNode() int {
+	a.nodes = append(a.nodes, &ptNode{})
+	return len(a.nodes) - 1
+}
+
+func (a *PointsTo) newObject(o ptObject) int {
+	a.objects = append(a.objects, o)
+	return len(a.objects) - 1
+}
+
+// cell returns the index of the cell at path in obj, creating it (and its
+// solver node) on first use.
+func (a *PointsTo) cell(obj int, path string) int {
+	k := ptCell{obj, path}
+	if i, ok := a.cellIdx[k]; ok {
+		return i
+	}
+	a.cells = append(a.cells, k)
+	a.cellVar = append(a.cellVar, a.newNode())
+	a.cellIdx[k] = len(a.cells) - 1
+	return len(a.cells) - 1
+}
+
+// isPtrLike reports whether values of type t can hold references.
+func (a *PointsTo) isPtrLike(t types.Type) bool {
+	if v, ok := a.ptLike[t]; ok {
+		return v
+	}
+	a.ptLike[t] = true // recursion guard (recursive types always go through a reference)
+	var v bool
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		v = u.Kind() == types.UnsafePointer
+	case *types.Struct:
+		for i := range u.NumFields() {
+			if a.isPtrLike(u.Field(i).Type()) {
+				v = true
+				break
+			}
+		}
+	case *types.Array:
+		v = a.isPtrLike(u.Elem())
+	case *types.Tuple:
+		for i := range u.Len() {
+			if a.isPtrLike(u.At(i).Type()) {
+				v = true
+				break
+			}
+		}
+	default: // pointers, slices, maps, chans, funcs, interfaces, type params
+		v = true
+	}
+	a.ptLike[t] = v
+	return v
+}
+
+// node returns the solver variable of an SSA value, or -1 for values that
+// cannot hold references.
+func (a *PointsTo) node(v ssa.Value) int {
+	if n, ok := a.valueVar[v]; ok {
+		return n
+	}
+	switch v := v.(type) {
+	case *ssa.Const, *ssa.Builtin:
+		return -1
+	case *ssa.Global:
+		n := a.newNode()
+		a.valueVar[v] = n
+		obj, ok := a.globObj[v]
+		if !ok {
+			obj = a.newObject(ptObject{kind: "global", typ: deref(v.Type()), pos: v.Pos(), name: v.String()})
+			a.globObj[v] = obj
+		}
+		a.addPts(n, a.cell(obj, ""))
+		return n
+	case *ssa.Function:
+		n := a.newNode()
+		a.valueVar[v] = n
+		obj, ok := a.funcObj[v]
+		if !ok {
+			obj = a.newObject(ptObject{kind: "func", typ: v.Type(), fn: v, pos: v.Pos()})
+			a.funcObj[v] = obj
+		}
+		a.addPts(n, a.cell(obj, ""))
+		return n
+	}
+	if !a.isPtrLike(v.Type()) {
+		a.valueVar[v] = -1
+		return -1
+	}
+	n := a.newNode()
+	a.valueVar[v] = n
+	return n
+}
+
+func (a *PointsTo) copy(dst, src int) {
+	if dst < 0 || src < 0 || dst == src {
+		return
+	}
+	a.addEdge(src, dst)
+}
+
+func (a *PointsTo) load(dst, base int) {
+	if dst < 0 || base < 0 {
+		return
+	}
+	nb := a.nodes[base]
+	nb.loads = append(nb.loads, dst)
+	for c := range nb.pts {
+		a.addEdge(a.cellVar[c], dst)
+	}
+}
+
+func (a *PointsTo) store(base, src int) {
+	if base < 0 || src < 0 {
+		return
+	}
+	nb := a.nodes[base]
+	nb.stores = append(nb.stores, src)
+	for c := range nb.pts {
+		a.addEdge(src, a.cellVar[c])
+	}
+}
+
+// field makes a fresh node holding &(*base).step and returns it.
+func (a *PointsTo) field(base int, step string) int {
+	if base < 0 {
+		return -1
+	}
+	dst := a.newNode()
+	a.fieldInto(dst, base, step)
+	return dst
+}
+
+func (a *PointsTo) fieldInto(dst, base int, step string) {
+	if dst < 0 || base < 0 {
+		return
+	}
+	nb := a.nodes[base]
+	nb.fields = append(nb.fields, ptField{dst, step})
+	for c := range nb.pts {
+		a.addPts(dst, a.subCell(c, step))
+	}
+}
+
+func (a *PointsTo) subCell(c int, step string) int {
+	k := a.cells[c]
+	if len(k.path)+len(step) > ptMaxPathLen {
+		return c
+	}
+	return a.cell(k.obj, k.path+step)
+}
+
+// genFunc generates constraints for fn once. It reports whether fn has a
+// body that is modeled; calls to other functions only produce an opaque
+// external result.
+func (a *PointsTo) genFunc(fn *ssa.Function) bool {
+	if done, ok := a.analyzed[fn]; ok {
+		return done
+	}
+	ok := len(fn.Blocks) > 0 &&
+		(fn.Synthetic != "" || fn.Pkg != nil && modSet.IsKnownPkg(fn.Pkg.Pkg.Path()))
+	a.analyzed[fn] = ok
+	if !ok {
+		return false
+	}
+	if sig := fn.Signature; sig.Results().Len() > 0 {
+		rs := make([]int, sig.Results().Len())
+		for i := range rs {
+			rs[i] = -1
+			if a.isPtrLike(sig.Results().At(i).Type()) {
+				rs[i] = a.newNode()
+			}
+		}
+		a.results[fn] = rs
+	}
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			a.genInstr(fn, instr)
+		}
+	}
+	return true
+}
+
+func (a *PointsTo) genInstr(fn *ssa.Function, instr ssa.Instruction) {
+	switch v := instr.(type) {
+	case *ssa.Alloc:
+		obj := a.newObject(ptObject{kind: "alloc", typ: deref(v.Type()), fn: fn, pos: v.Pos()})
+		a.addPts(a.node(v), a.cell(obj, ""))
+	case *ssa.MakeChan:
+		a.genMake(fn, v, "make_chan")
+	case *ssa.MakeMap:
+		a.genMake(fn, v, "make_map")
+	case *ssa.MakeSlice:
+		a.genMake(fn, v, "make_slice")
+	case *ssa.MakeClosure:
+		closure := v.Fn.(*ssa.Function)
+		obj := a.newObject(ptObject{kind: "closure", typ: v.Type(), fn: closure, pos: v.Pos()})
+		a.addPts(a.node(v), a.cell(obj, ""))
+		if a.genFunc(closure) {
+			for i, b := range v.Bindings {
+				a.copy(a.node(closure.FreeVars[i]), a.node(b))
+			}
+		}
+	case *ssa.MakeInterface:
+		if n := a.node(v.X); n >= 0 {
+			obj := a.newObject(ptObject{kind: "box", typ: v.X.Type(), fn: fn, pos: v.Pos()})
+			a.addPts(a.node(v), a.cell(obj, ""))
+			a.copy(a.cellVar[a.cell(obj, "")], n)
+		}
+	case *ssa.FieldAddr:
+		a.fieldInto(a.node(v), a.node(v.X), "."+strconv.Itoa(v.Field))
+	case *ssa.IndexAddr:
+		a.fieldInto(a.node(v), a.node(v.X), "[]")
+	case *ssa.Field:
+		a.copy(a.node(v), a.node(v.X))
+	case *ssa.Index:
+		a.copy(a.node(v), a.node(v.X))
+	case *ssa.Slice:
+		a.copy(a.node(v), a.node(v.X))
+	case *ssa.ChangeType:
+		a.copy(a.node(v), a.node(v.X))
+	case *ssa.ChangeInterface:
+		a.copy(a.node(v), a.node(v.X))
+	case *ssa.Convert:
+		a.copy(a.node(v), a.node(v.X))
+	case *ssa.MultiConvert:
+		a.copy(a.node(v), a.node(v.X))
+	case *ssa.SliceToArrayPointer:
+		a.copy(a.node(v), a.node(v.X))
+	case *ssa.Range:
+		a.copy(a.node(v), a.node(v.X))
+	case *ssa.Phi:
+		dst := a.node(v)
+		for _, e := range v.Edges {
+			a.copy(dst, a.node(e))
+		}
+	case *ssa.UnOp:
+		switch v.Op {
+		case token.MUL:
+			a.load(a.node(v), a.node(v.X))
+		case token.ARROW:
+			a.load(a.node(v), a.field(a.node(v.X), "<-"))
+		}
+	case *ssa.TypeAssert:
+		if types.IsInterface(v.AssertedType) {
+			a.copy(a.node(v), a.node(v.X))
+		} else {
+			a.load(a.node(v), a.node(v.X)) // unbox
+		}
+	case *ssa.Extract:
+		if call, ok := v.Tuple.(*ssa.Call); ok {
+			if rs := a.tuples[call]; v.Index < len(rs) {
+				a.copy(a.node(v), rs[v.Index])
+				return
+			}
+		}
+		a.copy(a.node(v), a.node(v.Tuple))
+	case *ssa.Lookup:
+		if _, ok := v.X.Type().Underlying().(*types.Map); ok {
+			a.load(a.node(v), a.field(a.node(v.X), "[]"))
+		}
+	case *ssa.Next:
+		if !v.IsString {
+			a.load(a.node(v), a.field(a.node(v.Iter), "[]"))
+		}
+	case *ssa.Select:
+		dst := a.node(v)
+		for _, st := range v.States {
+			buf := a.field(a.node(st.Chan), "<-")
+			if st.Dir == types.RecvOnly {
+				a.load(dst, buf)
+			} else {
+				a.store(buf, a.node(st.Send))
+			}
+		}
+	case *ssa.Store:
+		a.store(a.node(v.Addr), a.node(v.Val))
+	case *ssa.Send:
+		a.store(a.field(a.node(v.Chan), "<-"), a.node(v.X))
+	case *ssa.MapUpdate:
+		buf := a.field(a.node(v.Map), "[]")
+		a.store(buf, a.node(v.Key))
+		a.store(buf, a.node(v.Value))
+	case *ssa.Return:
+		rs := a.results[fn]
+		for i, r := range v.Results {
+			if i < len(rs) {
+				a.copy(rs[i], a.node(r))
+			}
+		}
+	case *ssa.Call:
+		a.genCall(v.Common(), v)
+	case *ssa.Go:
+		a.genCall(v.Common(), v)
+	case *ssa.Defer:
+		a.genCall(v.Common(), v)
+	}
+}
+
+func (a *PointsTo) genMake(fn *ssa.Function, v ssa.Value, kind string) {
+	obj := a.newObject(ptObject{kind: kind, typ: v.Type(), fn: fn, pos: v.Pos()})
+	a.addPts(a.node(v), a.cell(obj, ""))
+}
+
+func (a *PointsTo) genCall(common *ssa.CallCommon, instr ssa.CallInstruction) {
+	if call, ok := instr.(*ssa.Call); ok {
+		if tup, ok := call.Type().(*types.Tuple); ok && tup.Len() > 1 {
+			rs := make([]int, tup.Len())
+			for i := range rs {
+				rs[i] = -1
+				if a.isPtrLike(tup.At(i).Type()) {
+					rs[i] = a.newNode()
+					a.copy(a.node(call), rs[i])
+				}
+			}
+			a.tuples[call] = rs
+		}
+	}
+
+	if common.IsInvoke() {
+		c := &ptCall{common: common, instr: instr, bound: make(map[*ssa.Function]bool)}
+		a.addCall(a.node(common.Value), c)
+		return
+	}
+	switch callee := common.Value.(type) {
+	case *ssa.Builtin:
+		a.genBuiltin(common, instr)
+	case *ssa.Function:
+		a.bind(callee, common, instr, -1)
+	default:
+		c := &ptCall{common: common, instr: instr, bound: make(map[*ssa.Function]bool)}
+		a.addCall(a.node(common.Value), c)
+	}
+}
+
+func (a *PointsTo) genBuiltin(common *ssa.CallCommon, instr ssa.CallInstruction) {
+	switch common.Value.(*ssa.Builtin).Name() {
+	case "append":
+		call, ok := instr.(*ssa.Call)
+		if !ok || len(common.Args) < 2 {
+			return
+		}
+		// The result is the first argument's array, or a new one if it grew.
+		dst := a.node(call)
+		a.copy(dst, a.node(common.Args[0]))
+		obj := a.newObject(ptObject{kind: "append", typ: call.Type(), fn: call.Parent(), pos: call.Pos()})
+		a.addPts(dst, a.cell(obj, ""))
+		elems := a.newNode()
+		a.load(elems, a.field(a.node(common.Args[1]), "[]"))
+		a.store(a.field(dst, "[]"), elems)
+	case "copy":
+		if len(common.Args) < 2 {
+			return
+		}
+		elems := a.newNode()
+		a.load(elems, a.field(a.node(common.Args[1]), "[]"))
+		a.store(a.field(a.node(common.Args[0]), "[]"), elems)
+	}
+}
+
+// bind connects a call site to one callee. recvCell is the interface box
+// the receiver is unboxed from for invoke-mode calls, or -1.
+func (a *PointsTo) bind(callee *ssa.Function, common *ssa.CallCommon, instr ssa.CallInstruction, recvCell int) {
+	if !a.genFunc(callee) {
+		a.externalResult(instr)
+		return
+	}
+	params := callee.Params
+	if recvCell >= 0 {
+		if len(params) == 0 {
+			return
+		}
+		a.copy(a.node(params[0]), a.cellVar[recvCell])
+		params = params[1:]
+	}
+	for i, arg := range common.Args {
+		if i < len(params) {
+			a.copy(a.node(params[i]), a.node(arg))
+		}
+	}
+	call, ok := instr.(*ssa.Call)
+	if !ok {
+		return
+	}
+	rs := a.results[callee]
+	if tup := a.tuples[call]; tup != nil {
+		for i, r := range rs {
+			if i < len(tup) {
+				a.copy(tup[i], r)
+			}
+		}
+	} else if len(rs) == 1 {
+		a.copy(a.node(call), rs[0])
+	}
+}
+
+// externalResult gives an unmodeled call a single opaque result object.
+func (a *PointsTo) externalResult(instr ssa.CallInstruction) {
+	call, ok := instr.(*ssa.Call)
+	if !ok {
+		return
+	}
+	dst := a.node(call)
+	if dst < 0 {
+		return
+	}
+	obj, ok := a.extObj[instr]
+	if !ok {
+		obj = a.newObject(ptObject{kind: "external", typ: call.Type(), fn: call.Parent(), pos: call.Pos()})
+		a.extObj[instr] = obj
+	}
+	a.addPts(dst, a.cell(obj, ""))
+	for _, r := range a.tuples[call] {
+		a.addPts(r, a.cell(obj, ""))
+	}
+}
+
+func (a *PointsTo) addCall(n int, c *ptCall) {
+	if n < 0 {
+		a.externalResult(c.instr)
+		return
+	}
+	nn := a.nodes[n]
+	nn.calls = append(nn.calls, c)
+	for cell := range nn.pts {
+		a.resolve(c, cell)
+	}
+}
+
+// resolve binds a dynamic call site to the callee implied by one cell in
+// the points-to set of its func value or interface receiver.
+func (a *PointsTo) resolve(c *ptCall, cell int) {
+	k := a.cells[cell]
+	if k.path != "" {
+		return
+	}
+	obj := a.objects[k.obj]
+	var callee *ssa.Function
+	recv := -1
+	if c.common.IsInvoke() {
+		if obj.kind != "box" {
+			a.externalResult(c.instr)
+			return
+		}
+		callee = a.lookupMethod(obj.typ, c.common.Method)
+		recv = cell
+	} else {
+		callee = obj.fn
+		if obj.kind != "closure" && obj.kind != "func" {
+			callee = nil
+		}
+	}
+	if callee == nil {
+		a.externalResult(c.instr)
+		return
+	}
+	if recv >= 0 {
+		// The receiver binding depends on the box, so bind per cell.
+		a.bind(callee, c.common, c.instr, recv)
+		return
+	}
+	if !c.bound[callee] {
+		c.bound[callee] = true
+		a.bind(callee, c.common, c.instr, -1)
+	}
+}
+
+func (a *PointsTo) lookupMethod(t types.Type, m *types.Func) (fn *ssa.Function) {
+	defer func() {
+		if recover() != nil { // e.g. uninstantiated generic receivers
+			fn = nil
+		}
+	}()
+	sel := a.prog.MethodSets.MethodSet(t).Lookup(m.Pkg(), m.Name())
+	if sel == nil {
+		return nil
+	}
+	return a.prog.MethodValue(sel)
+}
+
+// --- solver ---
+
+func (a *PointsTo) addPts(n, cell int) {
+	if n < 0 {
+		return
+	}
+	nn := a.nodes[n]
+	if nn.pts == nil {
+		nn.pts = make(map[int]struct{})
+	}
+	if _, ok := nn.pts[cell]; ok {
+		return
+	}
+	nn.pts[cell] = struct{}{}
+	nn.delta = append(nn.delta, cell)
+	if !nn.queued {
+		nn.queued = true
+		a.work = append(a.work, n)
+	}
+}
+
+func (a *PointsTo) addEdge(src, dst int) {
+	ns := a.nodes[src]
+	if ns.succs == nil {
+		ns.succs = make(map[int]struct{})
+	}
+	if _, ok := ns.succs[dst]; ok {
+		return
+	}
+	ns.succs[dst] = struct{}{}
+	for c := range ns.pts {
+		a.addPts(dst, c)
+	}
+}
+
+func (a *PointsTo) solve() {
+	for len(a.work) > 0 {
+		n := a.work[0]
+		a.work = a.work[1:]
+		nn := a.nodes[n]
+		nn.queued = false
+		delta := nn.delta
+		nn.delta = nil
+		for _, c := range delta {
+			for _, f := range nn.fields {
+				a.addPts(f.dst, a.subCell(c, f.step))
+			}
+			for _, dst := range nn.loads {
+				a.addEdge(a.cellVar[c], dst)
+			}
+			for _, src := range nn.stores {
+				a.addEdge(src, a.cellVar[c])
+			}
+			for _, call := range nn.calls {
+				a.resolve(call, c)
+			}
+			for succ := range nn.succs {
+				a.addPts(succ, c)
+			}
+		}
+	}
+}
+
+// --- queries ---
+
+// cellsOf returns the cells v may point to, looking through interface
+// boxes to the values they hold. The result is sorted.
+func (a *PointsTo) cellsOf(v ssa.Value) []int {
+	n, ok := a.valueVar[v]
+	if !ok || n < 0 {
+		return nil
+	}
+	seen := make(map[int]bool)
+	var out []int
+	var walk func(n int)
+	walk = func(n int) {
+		for c := range a.nodes[n].pts {
+			if seen[c] {
+				continue
+			}
+			seen[c] = true
+			if a.objects[a.cells[c].obj].kind == "box" && a.cells[c].path == "" {
+				walk(a.cellVar[c])
+				continue
+			}
+			out = append(out, c)
+		}
+	}
+	walk(n)
+	slices.Sort(out)
+	return out
+}
+
+// --- CPG emission ---
+
+// flagPointsTo enables the points-to phase without a config phase list, set
+// by main before any pipeline phase runs.
+var flagPointsTo bool
+
+// ExtractPointsTo runs the points-to analysis and emits alloc_site nodes,
+// points_to edges from value nodes to the objects they may reference, and
+// may_alias edges between value nodes that share a location.
+func ExtractPointsTo(
+	ssaResult *SSAResult,
+	fset *token.FileSet,
+	posLookup *PosLookup,
+	funcLookup *FuncLookup,
+	cpg *CPG,
+	prog *Progress,
+) {
+	prog.Log("Running points-to analysis...")
+	a := AnalyzePointsTo(ssaResult, fset)
+	prog.Verbose("Points-to: %d functions, %d objects, %d cells, %d variables",
+		len(a.analyzed), len(a.objects), len(a.cells), len(a.nodes))
+
+	var pointsToEdges, allocNodes int
+	objIDs := make(map[int]string) // object → target node ID ("" = not emitted)
+	objectID := func(o int) string {
+		if id, ok := objIDs[o]; ok {
+			return id
+		}
+		id := a.emitObject(o, fset, funcLookup, cpg)
+		objIDs[o] = id
+		if id != "" && a.objects[o].kind != "func" {
+			allocNodes++
+		}
+		return id
+	}
+
+	type holder struct {
+		cell int
+		id   string
+	}
+	var holders []holder
+	for _, fn := range ssaResult.KnownFuncs(fset) {
+		var values []ssa.Value
+		for _, p := range fn.Params {
+			values = append(values, p)
+		}
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				if v, ok := instr.(ssa.Value); ok {
+					values = append(values, v)
+				}
+			}
+		}
+		for _, v := range values {
+			cells := a.cellsOf(v)
+			if len(cells) == 0 {
+				continue
+			}
+			srcID := ptValueNodeID(v, fset, posLookup)
+			if srcID == "" {
+				continue
+			}
+			// Pointers into an object (fields, elements) point_to the object.
+			var targets []string
+			for _, c := range cells {
+				holders = append(holders, holder{c, srcID})
+				if id := objectID(a.cells[c].obj); id != "" {
+					targets = append(targets, id)
+				}
+			}
+			slices.Sort(targets)
+			targets = slices.Compact(targets)
+			if len(targets) > ptMaxTargets {
+				targets = targets[:ptMaxTargets]
+			}
+			for _, t := range targets {
+				cpg.AddEdge(Edge{Source: srcID, Target: t, Kind: "points_to"})
+				pointsToEdges++
+			}
+		}
+	}
+	// may_alias: value nodes that share a cell, pairwise, one edge per pair.
+	slices.SortFunc(holders, func(x, y holder) int {
+		return cmp.Or(cmp.Compare(x.cell, y.cell), cmp.Compare(x.id, y.id))
+	})
+	holders = slices.Compact(holders)
+	var aliasEdges, skippedGroups int
+	for i := 0; i < len(holders); {
+		j := i
+		for j < len(holders) && holders[j].cell == holders[i].cell {
+			j++
+		}
+		group := holders[i:j]
+		i = j
+		if len(group) < 2 {
+			continue
+		}
+		if len(group) > ptMaxAliasGroup {
+			skippedGroups++
+			continue
+		}
+		c := a.cells[group[0].cell]
+		props := map[string]any{}
+		if id := objectID(c.obj); id != "" {
+			props["object"] = id
+		}
+		if c.path != "" {
+			props["path"] = c.path
+		}
+		for x := range group {
+			for y := x + 1; y < len(group); y++ {
+				cpg.AddEdge(Edge{Source: group[x].id, Target: group[y].id, Kind: "may_alias", Properties: props})
+				aliasEdges++
+			}
+		}
+	}
+
+	prog.Log("Points-to: %d objects, %d alloc_site nodes, %d points_to edges, %d may_alias edges",
+		len(a.objects), allocNodes, pointsToEdges, aliasEdges)
+	if skippedGroups > 0 {
+		prog.Verbose("  skipped %d locations shared by more than %d values", skippedGroups, ptMaxAliasGroup)
+	}
+}
+
+// emitObject adds the alloc_site node for an object and returns its ID.
+// Function objects map to their function node; boxes are never emitted.
+func (a *PointsTo) emitObject(o int, fset *token.FileSet, funcLookup *FuncLookup, cpg *CPG) string {
+	obj := a.objects[o]
+	switch obj.kind {
+	case "box":
+		return ""
+	case "func":
+		return ssaFuncNodeID(obj.fn, fset, funcLookup)
+	case "global":
+		id := "alloc::global::" + obj.name
+		node := Node{
+			ID:       id,
+			Kind:     "alloc_site",
+			Name:     obj.name,
+			TypeInfo: types.TypeString(obj.typ, nil),
+			Properties: map[string]any{
+				"alloc_kind": "global",
+			},
+		}
+		if obj.pos.IsValid() {
+			p := fset.Position(obj.pos)
+			if rel := modSet.RelFile(p.Filename); rel != "" {
+				node.File, node.Line, node.Col = rel, p.Line, p.Column
+				node.Package = extractPkgFromPath(rel)
+			}
+		}
+		cpg.AddNode(node)
+		return id
+	}
+
+	if !obj.pos.IsValid() || obj.fn == nil || obj.fn.Pkg == nil {
+		return ""
+	}
+	p := fset.Position(obj.pos)
+	rel := modSet.RelFile(p.Filename)
+	if rel == "" {
+		return ""
+	}
+	relPkg := modSet.RelPkg(obj.fn.Pkg.Pkg.Path())
+	id := StmtID(relPkg, BaseName(rel), p.Line, p.Column, "alloc_"+obj.kind)
+	var name string
+	switch obj.kind {
+	case "alloc":
+		name = "new(" + types.TypeString(obj.typ, nil) + ")"
+	case "closure":
+		name = "func literal"
+	case "external":
+		name = "call result"
+	case "append":
+		name = "append(" + types.TypeString(obj.typ, nil) + ")"
+	default:
+		name = "make(" + types.TypeString(obj.typ, nil) + ")"
+	}
+	node := Node{
+		ID:             id,
+		Kind:           "alloc_site",
+		Name:           name,
+		File:           rel,
+		Line:           p.Line,
+		Col:            p.Column,
+		Package:        relPkg,
+		ParentFunction: ssaFuncNodeID(obj.fn, fset, funcLookup),
+		TypeInfo:       types.TypeString(obj.typ, nil),
+		Properties:     map[string]any{"alloc_kind": obj.kind},
+	}
+	if obj.kind == "closure" {
+		node.ParentFunction = ssaFuncNodeID(obj.fn.Parent(), fset, funcLookup)
+	}
+	cpg.AddNode(node)
+	return id
+}
+
+// ptValueNodeID maps an SSA value to the CPG node at its source position.
+func ptValueNodeID(v ssa.Value, fset *token.FileSet, posLookup *PosLookup) string {
+	pos := v.Pos()
+	if !pos.IsValid() {
+		return ""
+	}
+	p := fset.Position(pos)
+	rel := modSet.RelFile(p.Filename)
+	if rel == "" {
+		return ""
+	}
+	return posLookup.Get(rel, p.Line, p.Column)
+}
@@ -0,0 +1,653 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// sinkBatchSize is the number of pending rows that triggers a flush.
+// Small enough that buffered rows stay well below one package's worth of
+// graph, large enough to amortize transaction overhead.
+const sinkBatchSize = 20000
+
+// DBSink streams CPG nodes, edges, sources and the side tables (metrics,
+// function summaries, unchecked errors, compared call graphs and, with
+// -incremental, package lookups) to
+// SQLite in batches as the pipeline runs, so peak memory no longer grows
+// with the whole workspace.
+//
+// Dedup is disk-backed: nodes rely on the nodes primary key, sources on the
+// sources primary key and edges on a temporary unique index over
+// (source, target, kind), all with INSERT OR IGNORE, which preserves the
+// in-memory CPG's first-wins semantics; side tables dedup on their own keys
+// the way WriteDB does. Deferred node properties are applied with each
+// batch, so nothing the sink holds outlives a flush besides the names of
+// written source files.
+type DBSink struct {
+	conn *sqlite.Conn
+
+	pendingNodes   []Node
+	pendingEdges   []Edge
+	pendingSources map[string]string
+	writtenSources map[string]bool           // files of earlier batches
+	nodeProps      map[string]map[string]any // SetNodeProperty calls since the last flush
+	pendingProps   int                       // number of entries in nodeProps
+	nodes, edges   int                       // distinct rows written so far
+	sources        int                       // distinct source files written so far
+	err            error                     // first flush error, reported by Finish
+
+	pendingMetrics   []Metrics
+	pendingSummaries []FunctionSummary
+	pendingUnchecked []UncheckedError
+	pendingCallGraph []CallGraphEdge
+	pendingLookups   []PackageLookup
+	uncheckedErrors  int // rows queued so far, for WriteDB
+	callGraphEdges   int
+
+	lookups []*diskLookup // closed by Finish
+
+	// -configs membership, recorded in temp tables as rows are written.
+	config       string
+	configIdx    int
+	configTables bool
+	configCount  int // set by tagConfigs; 0 means no tagging
+}
+
+// NewDBSink creates the output database at path and prepares it for streaming.
+func NewDBSink(path string, prog *Progress) (*DBSink, error) {
+	prog.Log("Streaming CPG to %s ...", path)
+	conn, err := openDB(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := sqlitex.ExecuteTransient(conn,
+		`CREATE UNIQUE INDEX stream_edge_dedup ON edges(source, target, kind)`, nil); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("create edge dedup index: %w", err)
+	}
+	return &DBSink{
+		conn:           conn,
+		pendingSources: make(map[string]string),
+		writtenSources: make(map[string]bool),
+		nodeProps:      make(map[string]map[string]any),
+	}, nil
+}
+
+// setConfig flushes rows of the previous configuration and attributes
+// subsequent rows to the configuration idx.
+func (s *DBSink) setConfig(idx int, name string) {
+	s.flush()
+	if s.err == nil && !s.configTables {
+		s.err = sqlitex.ExecuteScript(s.conn, `
+CREATE TEMP TABLE stream_node_configs (id TEXT NOT NULL, ord INTEGER NOT NULL, PRIMARY KEY (id, ord)) WITHOUT ROWID;
+CREATE TEMP TABLE stream_edge_configs (source TEXT NOT NULL, target TEXT NOT NULL, kind TEXT NOT NULL, ord INTEGER NOT NULL, PRIMARY KEY (source, target, kind, ord)) WITHOUT ROWID;
+CREATE TEMP TABLE stream_config_names (ord INTEGER PRIMARY KEY, name TEXT NOT NULL);`, nil)
+	}
+	if s.err == nil {
+		s.err = sqlitex.ExecuteTransient(s.conn, `INSERT INTO temp.stream_config_names (ord, name) VALUES (?, ?)`,
+			&sqlitex.ExecOptions{Args: []any{idx, name}})
+	}
+	s.config, s.configIdx = name, idx
+	s.configTables = true
+}
+
+// tagConfigs ends configuration tracking; Finish applies the tags.
+func (s *DBSink) tagConfigs(n int) {
+	s.flush()
+	s.config = ""
+	s.configCount = n
+}
+
+func (s *DBSink) addNode(n Node) {
+	s.pendingNodes = append(s.pendingNodes, n)
+	s.maybeFlush()
+}
+
+func (s *DBSink) addEdge(e Edge) {
+	s.pendingEdges = append(s.pendingEdges, e)
+	s.maybeFlush()
+}
+
+// addSource queues a file's content unless it is already pending or
+// written. Only the names of written files are kept, not their content.
+func (s *DBSink) addSource(file, content string) bool {
+	if _, ok := s.pendingSources[file]; ok || s.writtenSources[file] {
+		return false
+	}
+	s.pendingSources[file] = content
+	return true
+}
+
+func (s *DBSink) addMetrics(m Metrics) {
+	s.pendingMetrics = append(s.pendingMetrics, m)
+	s.maybeFlush()
+}
+
+func (s *DBSink) addFunctionSummaries(rows []FunctionSummary) {
+	s.pendingSummaries = append(s.pendingSummaries, rows...)
+	s.maybeFlush()
+}
+
+func (s *DBSink) addUncheckedError(u UncheckedError) {
+	s.pendingUnchecked = append(s.pendingUnchecked, u)
+	s.uncheckedErrors++
+	s.maybeFlush()
+}
+
+func (s *DBSink) addPackageLookups(rows []PackageLookup) {
+	s.pendingLookups = append(s.pendingLookups, rows...)
+	s.maybeFlush()
+}
+
+func (s *DBSink) addCallGraphEdge(e CallGraphEdge) {
+	s.pendingCallGraph = append(s.pendingCallGraph, e)
+	s.callGraphEdges++
+	s.maybeFlush()
+}
+
+// setNodeProperty defers the update to the next flush, which runs after the
+// node itself has been inserted.
+func (s *DBSink) setNodeProperty(id, key string, val any) {
+	props := s.nodeProps[id]
+	if props == nil {
+		props = make(map[string]any)
+		s.nodeProps[id] = props
+	}
+	if _, ok := props[key]; !ok {
+		s.pendingProps++
+	}
+	props[key] = val
+	s.maybeFlush()
+}
+
+func (s *DBSink) maybeFlush() {
+	if s.pending() >= sinkBatchSize {
+		s.flush()
+	}
+}
+
+// pending returns the number of queued rows; sources count once each.
+func (s *DBSink) pending() int {
+	return len(s.pendingNodes) + len(s.pendingEdges) + len(s.pendingSources) + s.pendingProps +
+		len(s.pendingMetrics) + len(s.pendingSummaries) + len(s.pendingUnchecked) + len(s.pendingCallGraph) + len(s.pendingLookups)
+}
+
+// flush writes all pending rows in one transaction. Errors are sticky and
+// surface from Finish, keeping AddNode/AddEdge error-free for callers.
+func (s *DBSink) flush() {
+	if s.err != nil {
+		return
+	}
+	if s.pending() == 0 {
+		return
+	}
+	endFn, err := sqlitex.ImmediateTransaction(s.conn)
+	if err != nil {
+		s.err = fmt.Errorf("begin tx: %w", err)
+		return
+	}
+	err = s.writePending()
+	if err == nil {
+		err = s.writeNodeProps()
+	}
+	if err == nil {
+		err = s.writeSideTables()
+	}
+	endFn(&err)
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.pendingNodes = s.pendingNodes[:0]
+	s.pendingEdges = s.pendingEdges[:0]
+	for file := range s.pendingSources {
+		s.writtenSources[file] = true
+	}
+	clear(s.pendingSources)
+	clear(s.nodeProps)
+	s.pendingProps = 0
+	s.pendingMetrics = s.pendingMetrics[:0]
+	s.pendingSummaries = s.pendingSummaries[:0]
+	s.pendingUnchecked = s.pendingUnchecked[:0]
+	s.pendingCallGraph = s.pendingCallGraph[:0]
+	s.pendingLookups = s.pendingLookups[:0]
+}
+
+func (s *DBSink) writePending() error {
+	nodeStmt, err := s.conn.Prepare(`INSERT OR IGNORE INTO nodes (id, kind, name, file, line, col, end_line, package, parent_function, type_info, properties, stable_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("prepare node insert: %w", err)
+	}
+	defer func() { _ = nodeStmt.Finalize() }()
+	for _, n := range s.pendingNodes {
+		nodeStmt.BindText(1, n.ID)
+		nodeStmt.BindText(2, n.Kind)
+		nodeStmt.BindText(3, n.Name)
+		bindTextOrNull(nodeStmt, 4, n.File)
+		bindIntOrNull(nodeStmt, 5, n.Line)
+		bindIntOrNull(nodeStmt, 6, n.Col)
+		bindIntOrNull(nodeStmt, 7, n.EndLine)
+		bindTextOrNull(nodeStmt, 8, n.Package)
+		bindTextOrNull(nodeStmt, 9, n.ParentFunction)
+		bindTextOrNull(nodeStmt, 10, n.TypeInfo)
+		bindTextOrNull(nodeStmt, 11, PropsJSON(n.Properties))
+		bindTextOrNull(nodeStmt, 12, n.StableID)
+		if _, err := nodeStmt.Step(); err != nil {
+			return fmt.Errorf("insert node %s: %w", n.ID, err)
+		}
+		s.nodes += s.conn.Changes()
+		_ = nodeStmt.Reset()
+	}
+	if s.config != "" {
+		if err := s.writeNodeConfigs(); err != nil {
+			return err
+		}
+	}
+
+	edgeStmt, err := s.conn.Prepare(`INSERT OR IGNORE INTO edges (source, target, kind, properties) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("prepare edge insert: %w", err)
+	}
+	defer func() { _ = edgeStmt.Finalize() }()
+	for _, e := range s.pendingEdges {
+		edgeStmt.BindText(1, e.Source)
+		edgeStmt.BindText(2, e.Target)
+		edgeStmt.BindText(3, e.Kind)
+		bindTextOrNull(edgeStmt, 4, PropsJSON(e.Properties))
+		if _, err := edgeStmt.Step(); err != nil {
+			return fmt.Errorf("insert edge %s→%s: %w", e.Source, e.Target, err)
+		}
+		s.edges += s.conn.Changes()
+		_ = edgeStmt.Reset()
+	}
+	if s.config != "" {
+		if err := s.writeEdgeConfigs(); err != nil {
+			return err
+		}
+	}
+
+	srcStmt, err := s.conn.Prepare(`INSERT OR IGNORE INTO sources (file, content, package) VALUES (?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("prepare source insert: %w", err)
+	}
+	defer func() { _ = srcStmt.Finalize() }()
+	for file, content := range s.pendingSources {
+		srcStmt.BindText(1, file)
+		srcStmt.BindText(2, content)
+		bindTextOrNull(srcStmt, 3, extractPkgFromPath(file))
+		if _, err := srcStmt.Step(); err != nil {
+			return fmt.Errorf("insert source %s: %w", file, err)
+		}
+		s.sources += s.conn.Changes()
+		_ = srcStmt.Reset()
+	}
+	return nil
+}
+
+func (s *DBSink) writeNodeConfigs() error {
+	stmt, err := s.conn.Prepare(`INSERT OR IGNORE INTO temp.stream_node_configs (id, ord) VALUES (?, ?)`)
+	if err != nil {
+		return fmt.Errorf("prepare node config insert: %w", err)
+	}
+	defer func() { _ = stmt.Finalize() }()
+	for _, n := range s.pendingNodes {
+		stmt.BindText(1, n.ID)
+		stmt.BindInt64(2, int64(s.configIdx))
+		if _, err := stmt.Step(); err != nil {
+			return fmt.Errorf("insert node config %s: %w", n.ID, err)
+		}
+		_ = stmt.Reset()
+	}
+	return nil
+}
+
+func (s *DBSink) writeEdgeConfigs() error {
+	stmt, err := s.conn.Prepare(`INSERT OR IGNORE INTO temp.stream_edge_configs (source, target, kind, ord) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("prepare edge config insert: %w", err)
+	}
+	defer func() { _ = stmt.Finalize() }()
+	for _, e := range s.pendingEdges {
+		stmt.BindText(1, e.Source)
+		stmt.BindText(2, e.Target)
+		stmt.BindText(3, e.Kind)
+		stmt.BindInt64(4, int64(s.configIdx))
+		if _, err := stmt.Step(); err != nil {
+			return fmt.Errorf("insert edge config %s→%s: %w", e.Source, e.Target, err)
+		}
+		_ = stmt.Reset()
+	}
+	return nil
+}
+
+// applyConfigTags mirrors CPG.TagConfigs on disk: rows seen in fewer than
+// all configurations get a "configs" property listing them in order.
+func (s *DBSink) applyConfigTags() (err error) {
+	endFn, err := sqlitex.ImmediateTransaction(s.conn)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer endFn(&err)
+
+	err = sqlitex.ExecuteTransient(s.conn, `
+UPDATE nodes SET properties = json_set(COALESCE(properties, '{}'), '$.configs', (
+  SELECT json_group_array(name) FROM (
+    SELECT cn.name FROM temp.stream_node_configs c
+    JOIN temp.stream_config_names cn ON cn.ord = c.ord
+    WHERE c.id = nodes.id ORDER BY c.ord)))
+WHERE id IN (SELECT id FROM temp.stream_node_configs GROUP BY id HAVING COUNT(*) < ?1)`,
+		&sqlitex.ExecOptions{Args: []any{s.configCount}})
+	if err != nil {
+		return fmt.Errorf("tag node configs: %w", err)
+	}
+	err = sqlitex.ExecuteTransient(s.conn, `
+WITH partial AS (
+  SELECT source, target, kind FROM temp.stream_edge_configs
+  GROUP BY source, target, kind HAVING COUNT(*) < ?1)
+UPDATE edges SET properties = json_set(COALESCE(properties, '{}'), '$.configs', (
+  SELECT json_group_array(name) FROM (
+    SELECT cn.name FROM temp.stream_edge_configs c
+    JOIN temp.stream_config_names cn ON cn.ord = c.ord
+    WHERE c.source = edges.source AND c.target = edges.target AND c.kind = edges.kind
+    ORDER BY c.ord)))
+WHERE (source, target, kind) IN (SELECT source, target, kind FROM partial)`,
+		&sqlitex.ExecOptions{Args: []any{s.configCount}})
+	if err != nil {
+		return fmt.Errorf("tag edge configs: %w", err)
+	}
+	return sqlitex.ExecuteScript(s.conn, `
+DROP TABLE temp.stream_node_configs;
+DROP TABLE temp.stream_edge_configs;
+DROP TABLE temp.stream_config_names;`, nil)
+}
+
+// edgesOfKind flushes pending rows and streams back all edges of one kind.
+func (s *DBSink) edgesOfKind(kind string, fn func(source, target string)) error {
+	s.flush()
+	if s.err != nil {
+		return s.err
+	}
+	return sqlitex.ExecuteTransient(s.conn, `SELECT source, target FROM edges WHERE kind = ?`,
+		&sqlitex.ExecOptions{
+			Args: []any{kind},
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				fn(stmt.ColumnText(0), stmt.ColumnText(1))
+				return nil
+			},
+		})
+}
+
+// Finish flushes remaining rows, drops the dedup index, closes the
+// position lookups and hands the connection over to WriteDB.
+func (s *DBSink) Finish(prog *Progress) (*sqlite.Conn, error) {
+	s.flush()
+	if s.err == nil && s.configCount > 0 {
+		s.err = s.applyConfigTags()
+	}
+	// Like insertFunctionSummaries, a function keeps its "no flows" row only
+	// if no configuration found a flow.
+	if s.err == nil {
+		s.err = sqlitex.ExecuteTransient(s.conn, `
+DELETE FROM function_summaries WHERE from_pos IS NULL
+  AND function_id IN (SELECT function_id FROM function_summaries WHERE from_pos IS NOT NULL)`, nil)
+	}
+	if s.err == nil {
+		s.err = sqlitex.ExecuteTransient(s.conn, `DROP INDEX stream_edge_dedup`, nil)
+	}
+	for _, l := range s.lookups {
+		if err := l.close(); err != nil && s.err == nil {
+			s.err = err
+		}
+	}
+	s.lookups = nil
+	if s.err != nil {
+		_ = s.conn.Close()
+		return nil, fmt.Errorf("streaming sink: %w", s.err)
+	}
+	prog.Log("Streamed %d nodes, %d edges, %d source files", s.nodes, s.edges, s.sources)
+	return s.conn, nil
+}
+
+// writeNodeProps applies the pending SetNodeProperty calls inside the
+// current flush transaction.
+func (s *DBSink) writeNodeProps() error {
+	if s.pendingProps == 0 {
+		return nil
+	}
+	stmt, err := s.conn.Prepare(`UPDATE nodes SET properties = json_set(COALESCE(properties, '{}'), '$.' || ?2, json(?3)) WHERE id = ?1`)
+	if err != nil {
+		return fmt.Errorf("prepare node property update: %w", err)
+	}
+	defer func() { _ = stmt.Finalize() }()
+	for id, props := range s.nodeProps {
+		for key, val := range props {
+			b, err := json.Marshal(val)
+			if err != nil {
+				continue
+			}
+			stmt.BindText(1, id)
+			stmt.BindText(2, key)
+			stmt.BindText(3, string(b))
+			if _, err := stmt.Step(); err != nil {
+				return fmt.Errorf("update node %s: %w", id, err)
+			}
+			_ = stmt.Reset()
+		}
+	}
+	return nil
+}
+
+// writeSideTables writes the pending per-function rows inside the current
+// flush transaction, with the dedup rules WriteDB applies in memory mode.
+func (s *DBSink) writeSideTables() error {
+	// ComputeMetrics overwrites a function's metrics in later configurations.
+	stmt, err := s.conn.Prepare(`INSERT OR REPLACE INTO metrics (function_id, cyclomatic_complexity, fan_in, fan_out, loc, num_params) VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("prepare metrics insert: %w", err)
+	}
+	defer func() { _ = stmt.Finalize() }()
+	for _, m := range s.pendingMetrics {
+		stmt.BindText(1, m.FunctionID)
+		stmt.BindInt64(2, int64(m.CyclomaticComplexity))
+		stmt.BindInt64(3, int64(m.FanIn))
+		stmt.BindInt64(4, int64(m.FanOut))
+		stmt.BindInt64(5, int64(m.LOC))
+		stmt.BindInt64(6, int64(m.NumParams))
+		if _, err := stmt.Step(); err != nil {
+			return fmt.Errorf("insert metric %s: %w", m.FunctionID, err)
+		}
+		_ = stmt.Reset()
+	}
+
+	// The unique key ignores "no flows" rows (NULL positions), so those are
+	// deduplicated here; Finish drops them for functions that have flows.
+	sumStmt, err := s.conn.Prepare(`INSERT OR IGNORE INTO function_summaries (function_id, from_pos, to_pos, variadic, kind)
+SELECT ?1, NULLIF(?2, ''), NULLIF(?3, ''), ?4, ?5
+WHERE ?2 != '' OR NOT EXISTS (SELECT 1 FROM function_summaries
+  WHERE function_id = ?1 AND from_pos IS NULL AND to_pos IS NULL AND variadic = ?4 AND kind = ?5)`)
+	if err != nil {
+		return fmt.Errorf("prepare function summary insert: %w", err)
+	}
+	defer func() { _ = sumStmt.Finalize() }()
+	for _, fs := range s.pendingSummaries {
+		sumStmt.BindText(1, fs.Function)
+		sumStmt.BindText(2, fs.From)
+		sumStmt.BindText(3, fs.To)
+		sumStmt.BindBool(4, fs.Variadic)
+		sumStmt.BindText(5, fs.Kind)
+		if _, err := sumStmt.Step(); err != nil {
+			return fmt.Errorf("insert function summary %s: %w", fs.Function, err)
+		}
+		_ = sumStmt.Reset()
+	}
+
+	if err := insertUncheckedErrors(s.conn, s.pendingUnchecked); err != nil {
+		return err
+	}
+	if err := insertPackageLookups(s.conn, s.pendingLookups); err != nil {
+		return err
+	}
+	if len(s.pendingCallGraph) > 0 {
+		if err := sqlitex.ExecuteScript(s.conn, callGraphEdgesDDL, nil); err != nil {
+			return fmt.Errorf("create callgraph_edges: %w", err)
+		}
+		if err := insertCallGraphEdges(s.conn, s.pendingCallGraph); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fanInOut is ComputeFanInOut on disk: it counts call edges into the
+// metrics rows, adding rows for call endpoints without metrics, and marks
+// directly recursive functions.
+func (s *DBSink) fanInOut() error {
+	s.flush()
+	if s.err != nil {
+		return s.err
+	}
+	err := sqlitex.ExecuteScript(s.conn, fanInOutScript, nil)
+	if err != nil {
+		return fmt.Errorf("fan-in/out: %w", err)
+	}
+	var recursive []string
+	err = sqlitex.ExecuteTransient(s.conn, `SELECT DISTINCT source FROM edges WHERE kind = 'call' AND source = target`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			recursive = append(recursive, stmt.ColumnText(0))
+			return nil
+		}})
+	if err != nil {
+		return fmt.Errorf("recursive functions: %w", err)
+	}
+	for _, id := range recursive {
+		s.setNodeProperty(id, "recursive", true)
+	}
+	return nil
+}
+
+// newDiskLookup opens a lookup table closed by Finish. On error it returns
+// nil, and the caller keeps its lookups in memory; the error surfaces from
+// Finish.
+func (s *DBSink) newDiskLookup() *diskLookup {
+	if s.err != nil {
+		return nil
+	}
+	l, err := openDiskLookup()
+	if err != nil {
+		s.err = err
+		return nil
+	}
+	s.lookups = append(s.lookups, l)
+	return l
+}
+
+// diskLookup is the streaming-mode store of PosLookup and FuncLookup: two
+// string maps, "m" and "aux", in a private temporary SQLite database. WalkAST
+// merges each package's lookups into it once the package is walked, so
+// positions are held in memory for one package at a time.
+type diskLookup struct {
+	mu   sync.Mutex
+	conn *sqlite.Conn
+	err  error // first error, reported by close
+}
+
+func openDiskLookup() (*diskLookup, error) {
+	// An empty path opens a private on-disk database, deleted on close.
+	conn, err := sqlite.OpenConn("", sqlite.OpenCreate, sqlite.OpenReadWrite)
+	if err != nil {
+		return nil, fmt.Errorf("open lookup database: %w", err)
+	}
+	for _, pragma := range []string{"PRAGMA journal_mode = OFF", "PRAGMA synchronous = OFF"} {
+		if err == nil {
+			err = sqlitex.ExecuteTransient(conn, pragma, nil)
+		}
+	}
+	if err == nil {
+		err = sqlitex.ExecuteScript(conn, `
+CREATE TABLE m (key TEXT PRIMARY KEY, id TEXT NOT NULL) WITHOUT ROWID;
+CREATE TABLE aux (key TEXT PRIMARY KEY, id TEXT NOT NULL) WITHOUT ROWID;`, nil)
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("create lookup tables: %w", err)
+	}
+	return &diskLookup{conn: conn}, nil
+}
+
+// put stores entries in table ("m" or "aux"). Keys already present keep
+// their ID unless replace is set.
+func (l *diskLookup) put(table string, entries map[string]string, replace bool) {
+	if len(entries) == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return
+	}
+	verb := "INSERT OR IGNORE"
+	if replace {
+		verb = "INSERT OR REPLACE"
+	}
+	err := func() (err error) {
+		endFn, err := sqlitex.ImmediateTransaction(l.conn)
+		if err != nil {
+			return err
+		}
+		defer endFn(&err)
+		stmt, err := l.conn.Prepare(verb + " INTO " + table + " (key, id) VALUES (?, ?)")
+		if err != nil {
+			return err
+		}
+		for key, id := range entries {
+			stmt.BindText(1, key)
+			stmt.BindText(2, id)
+			if _, err := stmt.Step(); err != nil {
+				return err
+			}
+			_ = stmt.Reset()
+		}
+		return nil
+	}()
+	if err != nil {
+		l.err = fmt.Errorf("store lookup: %w", err)
+	}
+}
+
+// get returns the ID stored for key in table, or "".
+func (l *diskLookup) get(table, key string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return ""
+	}
+	stmt, err := l.conn.Prepare("SELECT id FROM " + table + " WHERE key = ?")
+	if err != nil {
+		l.err = fmt.Errorf("look up position: %w", err)
+		return ""
+	}
+	defer func() { _ = stmt.Reset() }()
+	stmt.BindText(1, key)
+	row, err := stmt.Step()
+	if err != nil {
+		l.err = fmt.Errorf("look up position: %w", err)
+		return ""
+	}
+	if !row {
+		return ""
+	}
+	return stmt.ColumnText(0)
+}
+
+func (l *diskLookup) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.conn.Close(); err != nil && l.err == nil {
+		l.err = fmt.Errorf("close lookup database: %w", err)
+	}
+	return l.err
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// TaintSpecRule is one user-supplied taint_specs row. A rule names a function
+// (FuncName), a method (Receiver plus Method) or a struct field read
+// (Receiver plus Field). Receiver is a type name in Package, with or without
+// "*"; it may also name an interface, matching every implementation.
+type TaintSpecRule struct {
+	Package     string `yaml:"package" json:"package"`
+	FuncName    string `yaml:"func_name" json:"func_name"`
+	Receiver    string `yaml:"receiver" json:"receiver"`
+	Method      string `yaml:"method" json:"method"`
+	Field       string `yaml:"field" json:"field"`
+	Role        string `yaml:"role" json:"role"`
+	Category    string `yaml:"category" json:"category"`
+	Description string `yaml:"description" json:"description"`
+
+	Kind string `yaml:"-" json:"-"` // "call" or "field", set by the loader
+	File string `yaml:"-" json:"-"` // set by the loader
+}
+
+// FlowRule is one user-supplied flow_semantics row, naming a function or a
+// method like TaintSpecRule. From is "arg:N" or "arg:*"; To is "arg:N" or
+// "return:N". Argument positions exclude the receiver.
+type FlowRule struct {
+	Package     string `yaml:"package" json:"package"`
+	FuncName    string `yaml:"func_name" json:"func_name"`
+	Receiver    string `yaml:"receiver" json:"receiver"`
+	Method      string `yaml:"method" json:"method"`
+	From        string `yaml:"from" json:"from"`
+	To          string `yaml:"to" json:"to"`
+	Description string `yaml:"description" json:"description"`
+
+	File string `yaml:"-" json:"-"` // set by the loader
+}
+
+// User rules loaded from -taint-specs and -flow-semantics, set by main
+// before WriteDB. They are merged with the built-in defaults.
+var (
+	userTaintSpecs    []TaintSpecRule
+	userFlowSemantics []FlowRule
+)
+
+var (
+	taintRoles  = []string{"source", "sink", "barrier", "propagator"}
+	flowFromPos = regexp.MustCompile(`^arg:(\*|[0-9]+)$`)
+	flowToPos   = regexp.MustCompile(`^(arg|return):[0-9]+$`)
+)
+
+// LoadTaintSpecs reads taint spec rules from YAML or JSON files, each holding
+// a list of rules. All problems are reported together.
+func LoadTaintSpecs(paths []string) ([]TaintSpecRule, error) {
+	var rules []TaintSpecRule
+	var errs []error
+	for _, p := range paths {
+		var list []TaintSpecRule
+		if err := decodeSpecFile(p, &list); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for i := range list {
+			r := &list[i]
+			r.File = p
+			r.Kind = "call"
+			name, err := specFuncName(r.FuncName, r.Receiver, r.Method)
+			if r.Field != "" {
+				r.Kind = "field"
+				name, err = r.Field, nil
+				switch {
+				case r.FuncName != "" || r.Method != "":
+					err = errors.New("set either func_name, method or field")
+				case r.Receiver == "":
+					err = errors.New("field needs a receiver")
+				}
+			}
+			if err == nil && r.Package == "" {
+				err = errors.New("package is required")
+			}
+			if err == nil && !slices.Contains(taintRoles, r.Role) {
+				err = fmt.Errorf("role %q: want one of source, sink, barrier, propagator", r.Role)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: rule %d: %w", p, i, err))
+				continue
+			}
+			r.FuncName = name
+			r.Receiver = strings.TrimPrefix(r.Receiver, "*")
+			rules = append(rules, *r)
+		}
+	}
+	return rules, errors.Join(errs...)
+}
+
+// LoadFlowSemantics reads flow semantics rules from YAML or JSON files, each
+// holding a list of rules. All problems are reported together.
+func LoadFlowSemantics(paths []string) ([]FlowRule, error) {
+	var rules []FlowRule
+	var errs []error
+	for _, p := range paths {
+		var list []FlowRule
+		if err := decodeSpecFile(p, &list); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for i := range list {
+			r := &list[i]
+			r.File = p
+			name, err := specFuncName(r.FuncName, r.Receiver, r.Method)
+			if err == nil && r.Package == "" {
+				err = errors.New("package is required")
+			}
+			if err == nil && !flowFromPos.MatchString(r.From) {
+				err = fmt.Errorf("from %q: want arg:N or arg:*", r.From)
+			}
+			if err == nil && !flowToPos.MatchString(r.To) {
+				err = fmt.Errorf("to %q: want arg:N or return:N", r.To)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: rule %d: %w", p, i, err))
+				continue
+			}
+			r.FuncName = name
+			r.Receiver = strings.TrimPrefix(r.Receiver, "*")
+			rules = append(rules, *r)
+		}
+	}
+	return rules, errors.Join(errs...)
+}
+
+// decodeSpecFile decodes a YAML or JSON rule list, rejecting unknown keys.
+// JSON is decoded as YAML, of which it is a subset.
+func decodeSpecFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) { // io.EOF: empty file
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return nil
+}
+
+// specFuncName returns the name a rule matches on: func_name for functions,
+// or method for methods, which also need a receiver type.
+func specFuncName(funcName, receiver, method string) (string, error) {
+	switch {
+	case funcName != "" && method != "":
+		return "", errors.New("set either func_name or receiver and method, not both")
+	case method != "" && receiver == "":
+		return "", errors.New("method needs a receiver")
+	case method != "":
+		return method, nil
+	case funcName == "":
+		return "", errors.New("func_name or receiver and method is required")
+	}
+	return funcName, nil
+}
+
+// createSpecKeys builds spec_keys, the (package, receiver, name) keys under
+// which taint_specs and flow_semantics rows match a callee or field node:
+//
+//   - every function by its declared receiver ("" for plain functions),
+//     without "*" and type arguments, and its unqualified name;
+//   - external methods also by each interface their receiver implements
+//     that declares the method (the "implements" stub property);
+//   - external struct fields (ext:: field stubs) by their struct type.
+func createSpecKeys(conn *sqlite.Conn) error {
+	return sqlitex.ExecuteScript(conn, `
+CREATE TABLE spec_keys (
+    node_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    package TEXT NOT NULL,
+    receiver TEXT NOT NULL,
+    name TEXT NOT NULL,
+    via TEXT NOT NULL
+);
+
+INSERT INTO spec_keys (node_id, kind, package, receiver, name, via)
+SELECT id, CASE kind WHEN 'field' THEN 'field' ELSE 'call' END, package,
+  CASE instr(recv, '[') WHEN 0 THEN recv ELSE substr(recv, 1, instr(recv, '[') - 1) END,
+  CASE WHEN decl = '' OR external THEN name ELSE substr(name, length(decl) + 2) END,
+  'declared'
+FROM (
+  SELECT id, kind, package, name,
+    COALESCE(json_extract(properties, '$.receiver'), '') AS decl,
+    ltrim(COALESCE(json_extract(properties, '$.receiver'), ''), '*') AS recv,
+    COALESCE(json_extract(properties, '$.external'), 0) AS external
+  FROM nodes
+  WHERE package IS NOT NULL
+    AND (kind = 'function' OR kind = 'field' AND id LIKE 'ext::%')
+);
+
+INSERT INTO spec_keys (node_id, kind, package, receiver, name, via)
+SELECT n.id, 'call', json_extract(i.value, '$.package'), json_extract(i.value, '$.type'), n.name, 'interface'
+FROM nodes n, json_each(n.properties, '$.implements') i
+WHERE n.kind = 'function' AND n.id LIKE 'ext::%';
+
+CREATE INDEX idx_spec_keys_node ON spec_keys(node_id, kind);
+CREATE INDEX idx_spec_keys_name ON spec_keys(package, name);
+`, nil)
+}
+
+// insertUserTaintSpecs merges userTaintSpecs into taint_specs. A user rule
+// replaces a built-in rule for the same function, method or field and role.
+func insertUserTaintSpecs(conn *sqlite.Conn) error {
+	del, err := conn.Prepare(`DELETE FROM taint_specs
+WHERE kind = ? AND package = ? AND receiver = ? AND func_name = ? AND role = ? AND source_file = 'builtin'`)
+	if err != nil {
+		return fmt.Errorf("prepare taint spec delete: %w", err)
+	}
+	defer func() { _ = del.Finalize() }()
+	ins, err := conn.Prepare(`INSERT INTO taint_specs (kind, package, receiver, func_name, role, category, description, source_file)
+VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?)`)
+	if err != nil {
+		return fmt.Errorf("prepare taint spec insert: %w", err)
+	}
+	defer func() { _ = ins.Finalize() }()
+
+	for _, r := range userTaintSpecs {
+		del.BindText(1, r.Kind)
+		del.BindText(2, r.Package)
+		del.BindText(3, r.Receiver)
+		del.BindText(4, r.FuncName)
+		del.BindText(5, r.Role)
+		if _, err := del.Step(); err != nil {
+			return fmt.Errorf("replace taint spec %s.%s: %w", r.Package, r.FuncName, err)
+		}
+		_ = del.Reset()
+
+		ins.BindText(1, r.Kind)
+		ins.BindText(2, r.Package)
+		ins.BindText(3, r.Receiver)
+		ins.BindText(4, r.FuncName)
+		ins.BindText(5, r.Role)
+		ins.BindText(6, r.Category)
+		ins.BindText(7, r.Description)
+		ins.BindText(8, r.File)
+		if _, err := ins.Step(); err != nil {
+			return fmt.Errorf("insert taint spec %s.%s: %w", r.Package, r.FuncName, err)
+		}
+		_ = ins.Reset()
+	}
+	return nil
+}
+
+// insertUserFlowSemantics merges userFlowSemantics into flow_semantics. A
+// user rule replaces a built-in rule with the same function and positions.
+func insertUserFlowSemantics(conn *sqlite.Conn) error {
+	del, err := conn.Prepare(`DELETE FROM flow_semantics
+WHERE package = ? AND func_name = ? AND receiver = ? AND flow_from = ? AND flow_to = ?
+  AND source_file = 'builtin'`)
+	if err != nil {
+		return fmt.Errorf("prepare flow semantics delete: %w", err)
+	}
+	defer func() { _ = del.Finalize() }()
+	ins, err := conn.Prepare(`INSERT INTO flow_semantics (package, func_name, receiver, flow_from, flow_to, description, source_file)
+VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), ?)`)
+	if err != nil {
+		return fmt.Errorf("prepare flow semantics insert: %w", err)
+	}
+	defer func() { _ = ins.Finalize() }()
+
+	for _, r := range userFlowSemantics {
+		del.BindText(1, r.Package)
+		del.BindText(2, r.FuncName)
+		del.BindText(3, r.Receiver)
+		del.BindText(4, r.From)
+		del.BindText(5, r.To)
+		if _, err := del.Step(); err != nil {
+			return fmt.Errorf("replace flow rule %s.%s: %w", r.Package, r.FuncName, err)
+		}
+		_ = del.Reset()
+
+		ins.BindText(1, r.Package)
+		ins.BindText(2, r.FuncName)
+		ins.BindText(3, r.Receiver)
+		ins.BindText(4, r.From)
+		ins.BindText(5, r.To)
+		ins.BindText(6, r.Description)
+		ins.BindText(7, r.File)
+		if _, err := ins.Step(); err != nil {
+			return fmt.Errorf("insert flow rule %s.%s: %w", r.Package, r.FuncName, err)
+		}
+		_ = ins.Reset()
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSpecFuncName(t *testing.T) {
+	tests := []struct {
+		funcName, receiver, method string
+		want                       string
+		wantErr                    string
+	}{
+		{"Getenv", "", "", "Getenv", ""},
+		{"", "Request", "FormValue", "FormValue", ""},
+		{"", "*Request", "FormValue", "FormValue", ""},
+		{"Getenv", "", "FormValue", "", "not both"},
+		{"", "", "FormValue", "", "method needs a receiver"},
+		{"", "Request", "", "", "is required"},
+		{"", "", "", "", "is required"},
+	}
+	for _, tt := range tests {
+		got, err := specFuncName(tt.funcName, tt.receiver, tt.method)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("specFuncName(%q, %q, %q) error = %v, want %q", tt.funcName, tt.receiver, tt.method, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("specFuncName(%q, %q, %q) = %q, %v, want %q", tt.funcName, tt.receiver, tt.method, got, err, tt.want)
+		}
+	}
+}
+
+func TestLoadTaintSpecs(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "specs.yaml")
+	writeFile(t, yamlPath, `
+- package: os
+  func_name: Getenv
+  role: source
+  category: env
+- package: net/http
+  receiver: Request
+  method: FormValue
+  role: source
+`)
+	jsonPath := filepath.Join(dir, "specs.json")
+	writeFile(t, jsonPath, `[{"package": "os/exec", "func_name": "Command", "role": "sink"}]`)
+
+	rules, err := LoadTaintSpecs([]string{yamlPath, jsonPath})
+	if err != nil {
+		t.Fatalf("LoadTaintSpecs: %v", err)
+	}
+	want := []TaintSpecRule{
+		{Package: "os", FuncName: "Getenv", Role: "source", Category: "env", Kind: "call", File: yamlPath},
+		{Package: "net/http", FuncName: "FormValue", Receiver: "Request", Method: "FormValue", Role: "source", Kind: "call", File: yamlPath},
+		{Package: "os/exec", FuncName: "Command", Role: "sink", Kind: "call", File: jsonPath},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("got %d rules, want %d: %+v", len(rules), len(want), rules)
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("rule %d = %+v, want %+v", i, rules[i], want[i])
+		}
+	}
+}
+
+func TestLoadTaintSpecsErrors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bad.yaml")
+	writeFile(t, path, `
+- package: os
+  func_name: Getenv
+  role: origin
+- func_name: Getenv
+  role: source
+- package: os
+  role: sink
+- package: os
+  func_name: Getenv
+  role: source
+`)
+	rules, err := LoadTaintSpecs([]string{path, filepath.Join(dir, "missing.yaml")})
+	if err == nil {
+		t.Fatal("LoadTaintSpecs succeeded, want error")
+	}
+	for _, w := range []string{
+		`rule 0: role "origin"`,
+		"rule 1: package is required",
+		"rule 2: func_name or receiver and method is required",
+		"reading " + filepath.Join(dir, "missing.yaml"),
+	} {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("error %q does not mention %q", err, w)
+		}
+	}
+	if len(rules) != 1 {
+		t.Errorf("got %d valid rules, want the 1 valid rule kept", len(rules))
+	}
+
+	writeFile(t, path, "- package: os\n  funcname: Getenv\n  role: source\n")
+	if _, err := LoadTaintSpecs([]string{path}); err == nil || !strings.Contains(err.Error(), "funcname") {
+		t.Errorf("unknown key: error = %v, want it rejected", err)
+	}
+}
+
+func TestLoadFlowSemantics(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "flows.yaml")
+	writeFile(t, path, `
+- package: strings
+  func_name: Join
+  from: "arg:*"
+  to: "return:0"
+- package: bytes
+  receiver: Buffer
+  method: Write
+  from: "arg:0"
+  to: "arg:1"
+`)
+	rules, err := LoadFlowSemantics([]string{path})
+	if err != nil {
+		t.Fatalf("LoadFlowSemantics: %v", err)
+	}
+	if len(rules) != 2 || rules[0].FuncName != "Join" || rules[1].FuncName != "Write" || rules[1].Receiver != "Buffer" {
+		t.Errorf("rules = %+v", rules)
+	}
+
+	writeFile(t, path, `
+- package: strings
+  func_name: Join
+  from: "return:0"
+  to: "arg:0"
+- package: strings
+  func_name: Join
+  from: "arg:0"
+  to: "return:*"
+`)
+	_, err = LoadFlowSemantics([]string{path})
+	if err == nil {
+		t.Fatal("LoadFlowSemantics succeeded, want error")
+	}
+	for _, w := range []string{`rule 0: from "return:0"`, `rule 1: to "return:*"`} {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("error %q does not mention %q", err, w)
+		}
+	}
+}
+
+func TestLoadTaintSpecsReceivers(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "specs.yaml")
+	writeFile(t, path, `
+- package: net/http
+  receiver: "*Request"
+  method: FormValue
+  role: source
+- package: net/http
+  receiver: Request
+  field: URL
+  role: source
+  category: http
+- package: io
+  receiver: Reader
+  method: Read
+  role: source
+`)
+	rules, err := LoadTaintSpecs([]string{path})
+	if err != nil {
+		t.Fatalf("LoadTaintSpecs: %v", err)
+	}
+	want := []TaintSpecRule{
+		{Package: "net/http", FuncName: "FormValue", Receiver: "Request", Method: "FormValue", Role: "source", Kind: "call", File: path},
+		{Package: "net/http", FuncName: "URL", Receiver: "Request", Field: "URL", Role: "source", Category: "http", Kind: "field", File: path},
+		{Package: "io", FuncName: "Read", Receiver: "Reader", Method: "Read", Role: "source", Kind: "call", File: path},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("got %d rules, want %d: %+v", len(rules), len(want), rules)
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("rule %d = %+v, want %+v", i, rules[i], want[i])
+		}
+	}
+
+	writeFile(t, path, `
+- package: net/http
+  field: URL
+  role: source
+- package: net/http
+  receiver: Request
+  method: FormValue
+  field: URL
+  role: source
+`)
+	_, err = LoadTaintSpecs([]string{path})
+	if err == nil {
+		t.Fatal("LoadTaintSpecs succeeded, want error")
+	}
+	for _, w := range []string{"rule 0: field needs a receiver", "rule 1: set either func_name, method or field"} {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("error %q does not mention %q", err, w)
+		}
+	}
+}
+
+func TestLoadFlowSemanticsPointerReceiver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flows.yaml")
+	writeFile(t, path, "- package: bytes\n  receiver: \"*Buffer\"\n  method: Write\n  from: \"arg:0\"\n  to: \"arg:1\"\n")
+	rules, err := LoadFlowSemantics([]string{path})
+	if err != nil {
+		t.Fatalf("LoadFlowSemantics: %v", err)
+	}
+	if len(rules) != 1 || rules[0].Receiver != "Buffer" {
+		t.Errorf("rules = %+v, want receiver Buffer without the pointer", rules)
+	}
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"cmp"
 	"go/token"
 	"go/types"
+	"slices"
+	"strings"
+	"sync/atomic"
 
 	"golang.org/x/tools/go/packages"
 	"golang.org/x/tools/go/ssa"
@@ -13,6 +17,53 @@ import (
 type SSAResult struct {
 	Prog     *ssa.Program
 	AllFuncs map[*ssa.Function]bool
+
+	pkgIDs map[*ssa.Package]string // package ID, which tells test variants apart
+	sorted []*ssa.Function         // cache for KnownFuncs
+}
+
+// KnownFuncs returns the non-synthetic functions of the analyzed modules in a
+// stable order (file, position, name). AllFuncs is a map, so phases that need
+// deterministic output — notably the parallel ones — iterate this instead.
+func (r *SSAResult) KnownFuncs(fset *token.FileSet) []*ssa.Function {
+	if r.sorted != nil {
+		return r.sorted
+	}
+	type keyed struct {
+		fn   *ssa.Function
+		pos  token.Position
+		name string
+		pkg  string
+	}
+	var fns []keyed
+	for fn := range r.AllFuncs {
+		if fn.Pkg == nil || fn.Synthetic != "" {
+			continue
+		}
+		if !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) {
+			continue
+		}
+		fns = append(fns, keyed{fn, fset.Position(fn.Pos()), fn.String(), r.pkgIDs[fn.Pkg]})
+	}
+	slices.SortFunc(fns, func(a, b keyed) int {
+		return cmp.Or(
+			cmp.Compare(a.pos.Filename, b.pos.Filename),
+			cmp.Compare(a.pos.Offset, b.pos.Offset),
+			cmp.Compare(a.name, b.name),
+			cmp.Compare(a.pkg, b.pkg),
+		)
+	})
+	// A package loaded both as itself and as its test variant yields two
+	// copies of each function; they map to the same nodes, so keep one:
+	// that of the package itself, whose ID sorts first.
+	fns = slices.CompactFunc(fns, func(a, b keyed) bool {
+		return a.pos == b.pos && a.name == b.name
+	})
+	r.sorted = make([]*ssa.Function, len(fns))
+	for i, k := range fns {
+		r.sorted[i] = k.fn
+	}
+	return r.sorted
 }
 
 // BuildSSA constructs the SSA representation from loaded packages.
@@ -49,9 +100,17 @@ func BuildSSA(pkgs []*packages.Package, prog *Progress) *SSAResult {
 
 	prog.Log("Built SSA for %d functions across %d modules", count, len(modSet.Dirs()))
 
+	pkgIDs := make(map[*ssa.Package]string, len(ssaPkgs))
+	for i, sp := range ssaPkgs {
+		if sp != nil {
+			pkgIDs[sp] = pkgs[i].ID
+		}
+	}
+
 	return &SSAResult{
 		Prog:     ssaProg,
 		AllFuncs: allFuncs,
+		pkgIDs:   pkgIDs,
 	}
 }
 
@@ -66,26 +125,36 @@ func ExtractCFGAndDFG(
 ) {
 	prog.Log("Extracting CFG + DFG...")
 
-	var cfgEdges, dfgEdges, bbNodes, captureEdges int
-	var ssaPromFuncs, ssaWithBlocks, ssaMatched int
-
-	for fn := range ssaResult.AllFuncs {
-		if fn.Pkg == nil || fn.Synthetic != "" {
-			continue
-		}
-		if !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) {
-			continue
+	type cfgStats struct {
+		cfgEdges, dfgEdges, bbNodes, captureEdges int
+		fieldStores, fieldLoads                   int
+		ssaPromFuncs, ssaWithBlocks, ssaMatched   int
+	}
+	funcs := ssaResult.KnownFuncs(fset)
+	stats := make([]cfgStats, len(funcs))
+	var misses atomic.Int32
+
+	runSharded(len(funcs), cpg, func(fi int, shard *CPG) {
+		fn := funcs[fi]
+		var cfgEdges, dfgEdges, bbNodes, captureEdges int
+		var fieldStores, fieldLoads int
+		var ssaPromFuncs, ssaWithBlocks, ssaMatched int
+		defer func() {
+			stats[fi] = cfgStats{cfgEdges, dfgEdges, bbNodes, captureEdges, fieldStores, fieldLoads, ssaPromFuncs, ssaWithBlocks, ssaMatched}
+		}()
+		if incr.SkipPkg(fn.Pkg.Pkg.Path()) {
+			return // reused from the previous DB
 		}
 		ssaPromFuncs++
 		if len(fn.Blocks) == 0 {
-			continue
+			return
 		}
 		ssaWithBlocks++
 
 		// Find the function's node ID via position
 		funcNodeID := ssaFuncNodeID(fn, fset, funcLookup)
 		if funcNodeID == "" {
-			if ssaWithBlocks-ssaMatched <= 5 {
+			if misses.Add(1) <= 5 {
 				pos := fn.Pos()
 				if pos.IsValid() {
 					p := fset.Position(pos)
@@ -95,7 +164,7 @@ func ExtractCFGAndDFG(
 					prog.Verbose("  SSA miss (no pos): %s", fn.String())
 				}
 			}
-			continue
+			return
 		}
 		ssaMatched++
 
@@ -116,7 +185,7 @@ func ExtractCFGAndDFG(
 				}
 				varID := posLookup.Get(relFile, p.Line, p.Column)
 				if varID != "" {
-					cpg.AddEdge(Edge{
+					shard.AddEdge(Edge{
 						Source: funcNodeID, Target: varID, Kind: "capture",
 						Properties: map[string]any{
 							"var_name":     fv.Name(),
@@ -137,7 +206,7 @@ func ExtractCFGAndDFG(
 			// Determine position from first instruction with valid pos
 			line, col, file := blockPos(block, fset)
 
-			cpg.AddNode(Node{
+			shard.AddNode(Node{
 				ID:             bbID,
 				Kind:           "basic_block",
 				Name:           block.Comment,
@@ -154,7 +223,7 @@ func ExtractCFGAndDFG(
 		}
 
 		// CFG entry edge: function → first block
-		cpg.AddEdge(Edge{
+		shard.AddEdge(Edge{
 			Source: funcNodeID, Target: blockIDs[0],
 			Kind:       "cfg",
 			Properties: map[string]any{"label": "entry"},
@@ -164,7 +233,7 @@ func ExtractCFGAndDFG(
 		// CFG exit edges: terminal blocks (no successors) → function
 		for i, block := range fn.Blocks {
 			if len(block.Succs) == 0 {
-				cpg.AddEdge(Edge{
+				shard.AddEdge(Edge{
 					Source: blockIDs[i], Target: funcNodeID,
 					Kind:       "cfg",
 					Properties: map[string]any{"label": "exit"},
@@ -187,7 +256,7 @@ func ExtractCFGAndDFG(
 						}
 					}
 				}
-				cpg.AddEdge(Edge{
+				shard.AddEdge(Edge{
 					Source:     blockIDs[i],
 					Target:     blockIDs[succ.Index],
 					Kind:       "cfg",
@@ -198,16 +267,57 @@ func ExtractCFGAndDFG(
 		}
 
 		// DFG edges: definition → use (intra-procedural)
+		emitUses := func(defNodeID string, val ssa.Value) {
+			refs := val.Referrers()
+			if refs == nil {
+				return
+			}
+			for _, ref := range *refs {
+				useFile, useLine, useCol := instrPos(ref, fset)
+				if useFile == "" {
+					continue
+				}
+				useNodeID := posLookup.Get(useFile, useLine, useCol)
+				if useNodeID == "" || useNodeID == defNodeID {
+					continue
+				}
+
+				props := map[string]any{}
+				if name := ssaValueName(val); name != "" {
+					props["var_name"] = name
+				}
+				shard.AddEdge(Edge{
+					Source:     defNodeID,
+					Target:     useNodeID,
+					Kind:       "dfg",
+					Properties: props,
+				})
+				dfgEdges++
+			}
+		}
+
+		// Parameters are definitions too: param_in edges enter a callee at
+		// its parameter nodes, and these edges carry the value onward.
+		for _, param := range fn.Params {
+			if !param.Pos().IsValid() {
+				continue
+			}
+			p := fset.Position(param.Pos())
+			relFile := modSet.RelFile(p.Filename)
+			if relFile == "" {
+				continue
+			}
+			if paramID := posLookup.Get(relFile, p.Line, p.Column); paramID != "" {
+				emitUses(paramID, param)
+			}
+		}
+
 		for _, block := range fn.Blocks {
 			for _, instr := range block.Instrs {
 				val, ok := instr.(ssa.Value)
 				if !ok {
 					continue
 				}
-				refs := val.Referrers()
-				if refs == nil {
-					continue
-				}
 
 				defFile, defLine, defCol := instrPos(instr, fset)
 				if defFile == "" {
@@ -217,35 +327,102 @@ func ExtractCFGAndDFG(
 				if defNodeID == "" {
 					continue
 				}
+				emitUses(defNodeID, val)
+			}
+		}
 
-				for _, ref := range *refs {
-					useFile, useLine, useCol := instrPos(ref, fset)
-					if useFile == "" {
+		// Field-sensitive heap flow: store site → field declaration → load
+		// site. Routing through the shared field node links every write of
+		// T.f to every read of T.f, across functions and packages. Fields of
+		// external types route through an ext:: stub instead.
+		for _, block := range fn.Blocks {
+			for _, instr := range block.Instrs {
+				switch instr := instr.(type) {
+				case *ssa.Store:
+					fa, ok := instr.Addr.(*ssa.FieldAddr)
+					if !ok {
 						continue
 					}
-					useNodeID := posLookup.Get(useFile, useLine, useCol)
-					if useNodeID == "" || useNodeID == defNodeID {
+					field := structField(fa.X.Type(), fa.Field)
+					fieldID := fieldNodeID(field, fset, posLookup)
+					stub, ext := extFieldNode(fa.X.Type(), field)
+					if ext {
+						fieldID = stub.ID
+					}
+					if fieldID == "" {
 						continue
 					}
-
-					props := map[string]any{}
-					if name := ssaValueName(val); name != "" {
-						props["var_name"] = name
+					siteID := ssaSiteID(instr, fset, posLookup)
+					if siteID == "" {
+						if val, ok := instr.Val.(ssa.Instruction); ok {
+							siteID = ssaSiteID(val, fset, posLookup)
+						}
+					}
+					if siteID == "" {
+						continue
+					}
+					if ext {
+						shard.AddNode(stub)
+					}
+					shard.AddEdge(Edge{
+						Source: siteID, Target: fieldID, Kind: "field_store",
+						Properties: map[string]any{"field": field.Name()},
+					})
+					fieldStores++
+				case *ssa.UnOp, *ssa.Field:
+					var field *types.Var
+					var owner types.Type
+					switch instr := instr.(type) {
+					case *ssa.UnOp:
+						if fa, ok := instr.X.(*ssa.FieldAddr); ok && instr.Op == token.MUL {
+							field, owner = structField(fa.X.Type(), fa.Field), fa.X.Type()
+						}
+					case *ssa.Field:
+						field, owner = structField(instr.X.Type(), instr.Field), instr.X.Type()
+					}
+					fieldID := fieldNodeID(field, fset, posLookup)
+					stub, ext := extFieldNode(owner, field)
+					if ext {
+						fieldID = stub.ID
+					}
+					if fieldID == "" {
+						continue
+					}
+					siteID := ssaSiteID(instr, fset, posLookup)
+					if siteID == "" {
+						continue
+					}
+					if ext {
+						shard.AddNode(stub)
 					}
-					cpg.AddEdge(Edge{
-						Source:     defNodeID,
-						Target:     useNodeID,
-						Kind:       "dfg",
-						Properties: props,
+					shard.AddEdge(Edge{
+						Source: fieldID, Target: siteID, Kind: "field_load",
+						Properties: map[string]any{"field": field.Name()},
 					})
-					dfgEdges++
+					fieldLoads++
 				}
 			}
 		}
+	})
+
+	var cfgEdges, dfgEdges, bbNodes, captureEdges int
+	var fieldStores, fieldLoads int
+	var ssaPromFuncs, ssaWithBlocks, ssaMatched int
+	for _, st := range stats {
+		cfgEdges += st.cfgEdges
+		dfgEdges += st.dfgEdges
+		bbNodes += st.bbNodes
+		captureEdges += st.captureEdges
+		fieldStores += st.fieldStores
+		fieldLoads += st.fieldLoads
+		ssaPromFuncs += st.ssaPromFuncs
+		ssaWithBlocks += st.ssaWithBlocks
+		ssaMatched += st.ssaMatched
 	}
 
 	prog.Log("SSA: %d Prometheus funcs, %d with blocks, %d matched to AST", ssaPromFuncs, ssaWithBlocks, ssaMatched)
 	prog.Log("Created %d basic_block nodes, %d CFG edges, %d DFG edges, %d capture edges", bbNodes, cfgEdges, dfgEdges, captureEdges)
+	prog.Log("Created %d field_store and %d field_load edges", fieldStores, fieldLoads)
 }
 
 // ExtractChannelFlow finds channel send→receive pairs by tracking MakeChan
@@ -568,6 +745,73 @@ func ssaFuncNodeID(fn *ssa.Function, fset *token.FileSet, funcLookup *FuncLookup
 	return funcLookup.Get(relFile, p.Line, p.Column)
 }
 
+// ssaSiteID returns the AST node at an SSA instruction's position, or "".
+func ssaSiteID(instr ssa.Instruction, fset *token.FileSet, posLookup *PosLookup) string {
+	file, line, col := instrPos(instr, fset)
+	if file == "" {
+		return ""
+	}
+	return posLookup.Get(file, line, col)
+}
+
+// structField returns field i of the struct that t is or points to, or nil.
+func structField(t types.Type, i int) *types.Var {
+	st, ok := deref(t).Underlying().(*types.Struct)
+	if !ok || i >= st.NumFields() {
+		return nil
+	}
+	return st.Field(i)
+}
+
+// fieldNodeID returns the field node WalkAST created for a struct field
+// declaration, or "" for fields of anonymous structs and of types outside
+// the known modules. Fields of generic types resolve through their origin,
+// whose declaration position they share.
+func fieldNodeID(field *types.Var, fset *token.FileSet, posLookup *PosLookup) string {
+	if field == nil || !field.Pos().IsValid() {
+		return ""
+	}
+	p := fset.Position(field.Pos())
+	rel := modSet.RelFile(p.Filename)
+	if rel == "" {
+		return ""
+	}
+	id := posLookup.Get(rel, p.Line, p.Column)
+	if !strings.HasSuffix(id, ":field") {
+		return ""
+	}
+	return id
+}
+
+// extFieldNode returns a stub node for a field of a named struct type outside
+// the known modules (such as net/http.Request.URL), or false for other
+// fields. The stubs give field_store and field_load edges an endpoint that
+// taint specs can name, like the ext:: function stubs BuildCallGraph creates
+// for external callees.
+func extFieldNode(owner types.Type, field *types.Var) (Node, bool) {
+	if field == nil || field.Pkg() == nil || modSet.IsKnownPkg(field.Pkg().Path()) {
+		return Node{}, false
+	}
+	named, ok := deref(owner).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return Node{}, false
+	}
+	pkgPath := named.Obj().Pkg().Path()
+	typeName := named.Obj().Name()
+	return Node{
+		ID:       "ext::" + pkgPath + "." + typeName + "." + field.Name(),
+		Kind:     "field",
+		Name:     field.Name(),
+		Package:  modSet.RelPkg(pkgPath),
+		TypeInfo: types.TypeString(field.Type(), nil),
+		Properties: map[string]any{
+			"external":  true,
+			"receiver":  typeName,
+			"full_name": pkgPath + "." + typeName + "." + field.Name(),
+		},
+	}, true
+}
+
 // blockPos returns the position of the first instruction with a valid Pos in a block.
 func blockPos(block *ssa.BasicBlock, fset *token.FileSet) (line, col int, relFile string) {
 	for _, instr := range block.Instrs {
@@ -609,8 +853,8 @@ func ssaValueName(v ssa.Value) string {
 		return val.Name()
 	case *ssa.FieldAddr:
 		// Field name from the struct type
-		if st, ok := deref(val.X.Type()).Underlying().(*types.Struct); ok && val.Field < st.NumFields() {
-			return st.Field(val.Field).Name()
+		if field := structField(val.X.Type(), val.Field); field != nil {
+			return field.Name()
 		}
 	}
 	return ""
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"go/token"
+	"maps"
+	"slices"
+	"strconv"
+
+	"golang.org/x/tools/go/ssa"
+	"zombiezen.com/go/sqlite"
+)
+
+// Function summaries describe, per function, which parameters reach which
+// results, which reach the memory of other parameters, and which reach
+// package-level variables. They are derived from SSA bodies bottom-up over
+// static calls, so a call's effect follows its callee's own summary. The
+// callees of dependencies (module cache and standard library) are
+// summarized on demand from the source loaded with them; only bodiless
+// functions fall back to "every argument reaches every result".
+//
+// WriteDB uses the summaries of external callees to infer DFG edges at call
+// sites that have no flow_semantics rule.
+
+// paramFlows is a function summary in SSA parameter indices (the receiver,
+// if any, is parameter 0). Bit i of a mask stands for parameter i; only the
+// first 64 parameters are tracked.
+type paramFlows struct {
+	results []uint64          // result k → parameters reaching it
+	params  []uint64          // parameter j → parameters reaching its memory
+	globals map[string]uint64 // global (pkg.name) → parameters reaching it
+	opaque  bool              // no body: every parameter reaches every result
+}
+
+func (a *paramFlows) equal(b *paramFlows) bool {
+	if a.opaque != b.opaque || !slices.Equal(a.results, b.results) || !slices.Equal(a.params, b.params) ||
+		len(a.globals) != len(b.globals) {
+		return false
+	}
+	for g, m := range a.globals {
+		if b.globals[g] != m {
+			return false
+		}
+	}
+	return true
+}
+
+// summarizer computes paramFlows with a worklist: a function is
+// re-analyzed whenever the summary of one of its callees grows, starting
+// from empty summaries, so recursive functions converge to a fixpoint.
+type summarizer struct {
+	flows   map[*ssa.Function]*paramFlows
+	callers map[*ssa.Function]map[*ssa.Function]bool
+	work    []*ssa.Function
+	queued  map[*ssa.Function]bool
+}
+
+func newSummarizer() *summarizer {
+	return &summarizer{
+		flows:   make(map[*ssa.Function]*paramFlows),
+		callers: make(map[*ssa.Function]map[*ssa.Function]bool),
+		queued:  make(map[*ssa.Function]bool),
+	}
+}
+
+func (s *summarizer) enqueue(fn *ssa.Function) {
+	if !s.queued[fn] {
+		s.queued[fn] = true
+		s.work = append(s.work, fn)
+	}
+}
+
+// summaryOf returns callee's current summary and records that caller
+// depends on it, scheduling callee if it has not been seen.
+func (s *summarizer) summaryOf(callee, caller *ssa.Function) *paramFlows {
+	if s.callers[callee] == nil {
+		s.callers[callee] = make(map[*ssa.Function]bool)
+	}
+	s.callers[callee][caller] = true
+	pf, ok := s.flows[callee]
+	if !ok {
+		pf = &paramFlows{
+			results: make([]uint64, callee.Signature.Results().Len()),
+			params:  make([]uint64, len(callee.Params)),
+		}
+		s.flows[callee] = pf
+		s.enqueue(callee)
+	}
+	return pf
+}
+
+func (s *summarizer) solve() {
+	for len(s.work) > 0 {
+		fn := s.work[0]
+		s.work = s.work[1:]
+		s.queued[fn] = false
+		pf := s.analyze(fn)
+		if old, ok := s.flows[fn]; ok && old.equal(pf) {
+			continue
+		}
+		s.flows[fn] = pf
+		// Callers are a set; sort them so the order of re-analysis, and
+		// with it the run time, does not depend on map iteration.
+		callers := slices.SortedFunc(maps.Keys(s.callers[fn]), func(a, b *ssa.Function) int {
+			return cmp.Compare(a.String(), b.String())
+		})
+		for _, c := range callers {
+			s.enqueue(c)
+		}
+	}
+}
+
+// analyze computes fn's summary from its body and the current summaries of
+// its static callees.
+func (s *summarizer) analyze(fn *ssa.Function) *paramFlows {
+	pf := &paramFlows{
+		results: make([]uint64, fn.Signature.Results().Len()),
+		params:  make([]uint64, len(fn.Params)),
+		globals: make(map[string]uint64),
+	}
+	paramIdx := make(map[ssa.Value]int, len(fn.Params))
+	var all uint64
+	for i, p := range fn.Params {
+		paramIdx[p] = i
+		if i < 64 {
+			all |= 1 << i
+		}
+	}
+	if len(fn.Blocks) == 0 {
+		pf.opaque = true
+		for k := range pf.results {
+			pf.results[k] = all
+		}
+		return pf
+	}
+
+	taint := make(map[ssa.Value]uint64)
+	for i, p := range fn.Params {
+		if i < 64 {
+			taint[p] = 1 << i
+		}
+	}
+	mark := func(v ssa.Value, m uint64) bool {
+		if v == nil || taint[v]|m == taint[v] {
+			return false
+		}
+		taint[v] |= m
+		return true
+	}
+	// store records that m reaches the memory addr points into: the object
+	// it is rooted at, and the parameter or global that object is.
+	store := func(addr ssa.Value, m uint64) bool {
+		if m == 0 {
+			return false
+		}
+		root := flowRoot(addr)
+		if j, ok := paramIdx[root]; ok {
+			pf.params[j] |= m &^ (1 << j)
+		}
+		if g, ok := root.(*ssa.Global); ok && g.Pkg != nil {
+			pf.globals[modSet.RelPkg(g.Pkg.Pkg.Path())+"."+g.Name()] |= m
+		}
+		return mark(root, m)
+	}
+	callResults := make(map[*ssa.Call][]uint64)
+
+	for changed := true; changed; {
+		changed = false
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				switch instr := instr.(type) {
+				case *ssa.Store:
+					changed = store(instr.Addr, taint[instr.Val]) || changed
+				case *ssa.MapUpdate:
+					changed = store(instr.Map, taint[instr.Key]|taint[instr.Value]) || changed
+				case *ssa.Send:
+					changed = store(instr.Chan, taint[instr.X]) || changed
+				case *ssa.Extract:
+					m := taint[instr.Tuple]
+					if call, ok := instr.Tuple.(*ssa.Call); ok && instr.Index < len(callResults[call]) {
+						m = callResults[call][instr.Index]
+					}
+					changed = mark(instr, m) || changed
+				case ssa.CallInstruction:
+					common := instr.Common()
+					args := common.Args
+					var res []uint64
+					callee := common.StaticCallee()
+					switch {
+					case callee != nil && len(args) == len(callee.Params):
+						sum := s.summaryOf(callee, fn)
+						from := func(m uint64) uint64 {
+							var out uint64
+							for p, a := range args {
+								if p < 64 && m&(1<<p) != 0 {
+									out |= taint[a]
+								}
+							}
+							return out
+						}
+						res = make([]uint64, len(sum.results))
+						for k, m := range sum.results {
+							res[k] = from(m)
+						}
+						for j, m := range sum.params {
+							changed = store(args[j], from(m)) || changed
+						}
+						for g, m := range sum.globals {
+							if t := from(m); t != 0 {
+								pf.globals[g] |= t
+							}
+						}
+					default:
+						// Dynamic calls, builtins and closures with bound
+						// variables: every operand reaches the result.
+						var m uint64
+						if common.IsInvoke() {
+							m = taint[common.Value]
+						}
+						for _, a := range args {
+							m |= taint[a]
+						}
+						if b, ok := common.Value.(*ssa.Builtin); ok && b.Name() == "copy" && len(args) == 2 {
+							changed = store(args[0], taint[args[1]]) || changed
+						}
+						res = []uint64{m}
+					}
+					if call, ok := instr.(*ssa.Call); ok {
+						callResults[call] = res
+						var m uint64
+						for _, r := range res {
+							m |= r
+						}
+						changed = mark(call, m) || changed
+					}
+				default:
+					v, ok := instr.(ssa.Value)
+					if !ok {
+						continue
+					}
+					var m uint64
+					for _, op := range instr.Operands(nil) {
+						if *op != nil {
+							m |= taint[*op]
+						}
+					}
+					changed = mark(v, m) || changed
+				}
+			}
+		}
+	}
+
+	for _, b := range fn.Blocks {
+		if ret, ok := b.Instrs[len(b.Instrs)-1].(*ssa.Return); ok {
+			for k, r := range ret.Results {
+				if k < len(pf.results) {
+					pf.results[k] |= taint[r]
+				}
+			}
+		}
+	}
+	for g, m := range pf.globals {
+		if m == 0 {
+			delete(pf.globals, g)
+		}
+	}
+	return pf
+}
+
+// flowRoot returns the object an address or reference points into: stores
+// through p.f, p[i], p.items[i] or *p all reach p.
+func flowRoot(v ssa.Value) ssa.Value {
+	for {
+		switch x := v.(type) {
+		case *ssa.FieldAddr:
+			v = x.X
+		case *ssa.IndexAddr:
+			v = x.X
+		case *ssa.Slice:
+			v = x.X
+		case *ssa.ChangeType:
+			v = x.X
+		case *ssa.Convert:
+			v = x.X
+		case *ssa.UnOp:
+			if x.Op != token.MUL {
+				return v
+			}
+			v = x.X
+		default:
+			return v
+		}
+	}
+}
+
+// summaryPos names SSA parameter i of fn the way flow_semantics does:
+// "recv" for a method's receiver, otherwise "arg:N" counting from the
+// first non-receiver parameter.
+func summaryPos(fn *ssa.Function, i int) string {
+	if fn.Signature.Recv() != nil {
+		if i == 0 {
+			return "recv"
+		}
+		i--
+	}
+	return "arg:" + strconv.Itoa(i)
+}
+
+// rows flattens pf into FunctionSummary rows for the node id.
+func (pf *paramFlows) rows(id string, fn *ssa.Function) []FunctionSummary {
+	kind := "ssa"
+	if pf.opaque {
+		kind = "opaque"
+	}
+	variadic := func(i int) bool {
+		return fn.Signature.Variadic() && i == len(fn.Params)-1
+	}
+	var out []FunctionSummary
+	add := func(m uint64, to string) {
+		for i := range min(len(fn.Params), 64) {
+			if m&(1<<i) != 0 {
+				out = append(out, FunctionSummary{id, summaryPos(fn, i), to, variadic(i), kind})
+			}
+		}
+	}
+	for k, m := range pf.results {
+		add(m, "return:"+strconv.Itoa(k))
+	}
+	for j, m := range pf.params {
+		add(m, summaryPos(fn, j))
+	}
+	globals := make([]string, 0, len(pf.globals))
+	for g := range pf.globals {
+		globals = append(globals, g)
+	}
+	slices.Sort(globals)
+	for _, g := range globals {
+		add(pf.globals[g], "global:"+g)
+	}
+	if len(out) == 0 {
+		out = append(out, FunctionSummary{Function: id, Kind: kind})
+	}
+	return out
+}
+
+// SummarizeFunctions computes the summaries of the known-module functions
+// and of the external callees in ext (the ext:: stubs of BuildCallGraph)
+// and adds them to the CPG.
+func SummarizeFunctions(ssaResult *SSAResult, ext []*ssa.Function, fset *token.FileSet, funcLookup *FuncLookup, cpg *CPG, prog *Progress) {
+	s := newSummarizer()
+	known := ssaResult.KnownFuncs(fset)
+	// Reused functions keep their previous rows; they are only analyzed
+	// when a changed function calls them.
+	reused := incr.reusedFuncs(ssaResult, fset, funcLookup)
+	known = slices.DeleteFunc(slices.Clone(known), func(fn *ssa.Function) bool {
+		return reused[ssaFuncNodeID(fn, fset, funcLookup)]
+	})
+	for _, fn := range known {
+		s.flows[fn] = &paramFlows{
+			results: make([]uint64, fn.Signature.Results().Len()),
+			params:  make([]uint64, len(fn.Params)),
+		}
+		s.enqueue(fn)
+	}
+	for _, fn := range ext {
+		if _, ok := s.flows[fn]; !ok {
+			s.flows[fn] = &paramFlows{
+				results: make([]uint64, fn.Signature.Results().Len()),
+				params:  make([]uint64, len(fn.Params)),
+			}
+			s.enqueue(fn)
+		}
+	}
+	s.solve()
+
+	var knownCount, extCount, opaque, flows int
+	emit := func(id string, fn *ssa.Function) {
+		pf := s.flows[fn]
+		rows := pf.rows(id, fn)
+		if rows[0].From != "" {
+			flows += len(rows)
+		}
+		if pf.opaque {
+			opaque++
+		}
+		cpg.AddFunctionSummaries(rows)
+	}
+	for _, fn := range known {
+		if id := ssaFuncNodeID(fn, fset, funcLookup); id != "" {
+			emit(id, fn)
+			knownCount++
+		}
+	}
+	for _, fn := range ext {
+		emit("ext::"+fn.String(), fn)
+		extCount++
+	}
+	prog.Log("Summarized %d functions (%d known-module, %d external, %d opaque; %d analyzed in total): %d parameter flows",
+		knownCount+extCount, knownCount, extCount, opaque, len(s.flows), flows)
+}
+
+// insertFunctionSummaries writes cpg.FunctionSummaries, dropping duplicates
+// from multiple build configurations. A function keeps its "no flows" row
+// only if no configuration found a flow.
+func insertFunctionSummaries(conn *sqlite.Conn, sums []FunctionSummary, prog *Progress) error {
+	hasFlows := make(map[string]bool)
+	for _, fs := range sums {
+		if fs.From != "" {
+			hasFlows[fs.Function] = true
+		}
+	}
+	stmt, err := conn.Prepare(`INSERT OR IGNORE INTO function_summaries (function_id, from_pos, to_pos, variadic, kind)
+VALUES (?, NULLIF(?, ''), NULLIF(?, ''), ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("prepare function summary insert: %w", err)
+	}
+	defer func() { _ = stmt.Finalize() }()
+
+	seen := make(map[FunctionSummary]bool, len(sums))
+	for _, fs := range sums {
+		if seen[fs] || fs.From == "" && hasFlows[fs.Function] {
+			continue
+		}
+		seen[fs] = true
+		stmt.BindText(1, fs.Function)
+		stmt.BindText(2, fs.From)
+		stmt.BindText(3, fs.To)
+		stmt.BindBool(4, fs.Variadic)
+		stmt.BindText(5, fs.Kind)
+		if _, err := stmt.Step(); err != nil {
+			return fmt.Errorf("insert function summary %s: %w", fs.Function, err)
+		}
+		_ = stmt.Reset()
+	}
+	prog.Verbose("Inserted %d function summary rows", len(seen))
+	return nil
+}
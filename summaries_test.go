@@ -0,0 +1,195 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"slices"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+const summariesSrc = `package a
+
+var G int
+
+type T struct{ n int }
+
+func Id(x int) int { return x }
+
+func Swap(a, b int) (int, int) { return b, a }
+
+func Const(x int) int { return 1 }
+
+func SetPtr(p *int, v int) { *p = v }
+
+func SetField(t *T, v int) { t.n = v }
+
+func CallSetPtr(p *int, v int) { SetPtr(p, v) }
+
+func (t *T) Get() int { return t.n }
+
+func (t *T) Set(v int) { t.n = v }
+
+func SetGlobal(v int) { G = v }
+
+func CallSetGlobal(v int) { SetGlobal(v) }
+
+func Sum(base int, xs ...int) int {
+	s := 0
+	for _, x := range xs {
+		s += x
+	}
+	return s
+}
+
+func CallSum(a, b, c int) int { return Sum(a, b, c) }
+
+func Ext(x, y int) int
+
+func CallExt(x int) int { return Ext(x, 1) }
+
+func Fact(n, acc int) int {
+	if n == 0 {
+		return acc
+	}
+	return Fact(n-1, acc*n)
+}
+
+func Even(n, x int) int {
+	if n == 0 {
+		return 0
+	}
+	return Odd(n-1, x)
+}
+
+func Odd(n, x int) int {
+	if n == 0 {
+		return x
+	}
+	return Even(n-1, x)
+}
+`
+
+// buildSummariesSSA builds summariesSrc and returns its functions in
+// source order.
+func buildSummariesSSA(t *testing.T) []*ssa.Function {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "a.go", summariesSrc, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, _, err := ssautil.BuildPackage(&types.Config{}, fset, types.NewPackage("a", "a"), []*ast.File{f}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var funcs []*ssa.Function
+	for _, m := range pkg.Members {
+		if fn, ok := m.(*ssa.Function); ok && fn.Name() != "init" {
+			funcs = append(funcs, fn)
+		}
+	}
+	for _, m := range pkg.Members {
+		if tn, ok := m.(*ssa.Type); ok {
+			mset := pkg.Prog.MethodSets.MethodSet(types.NewPointer(tn.Type()))
+			for i := range mset.Len() {
+				funcs = append(funcs, pkg.Prog.MethodValue(mset.At(i)))
+			}
+		}
+	}
+	slices.SortFunc(funcs, func(a, b *ssa.Function) int { return cmp.Compare(a.Pos(), b.Pos()) })
+	return funcs
+}
+
+func TestSummarizeFunctions(t *testing.T) {
+	funcs := buildSummariesSSA(t)
+	// Seed every function and solve together, the way SummarizeFunctions
+	// does; Even is analyzed before Odd has a summary.
+	s := newSummarizer()
+	for _, fn := range funcs {
+		s.flows[fn] = &paramFlows{
+			results: make([]uint64, fn.Signature.Results().Len()),
+			params:  make([]uint64, len(fn.Params)),
+		}
+		s.enqueue(fn)
+	}
+	s.solve()
+
+	got := make(map[string][]string)
+	for _, fn := range funcs {
+		name := fn.Name()
+		if recv := fn.Signature.Recv(); recv != nil {
+			name = "T." + name
+		}
+		for _, r := range s.flows[fn].rows(name, fn) {
+			got[name] = append(got[name], fmt.Sprintf("%s>%s %v %s", r.From, r.To, r.Variadic, r.Kind))
+		}
+	}
+
+	tests := []struct {
+		fn   string
+		want []string
+	}{
+		// param → return
+		{"Id", []string{"arg:0>return:0 false ssa"}},
+		{"Swap", []string{"arg:1>return:0 false ssa", "arg:0>return:1 false ssa"}},
+		{"Const", []string{"> false ssa"}},
+		// param → param through pointer writes, also across a call
+		{"SetPtr", []string{"arg:1>arg:0 false ssa"}},
+		{"SetField", []string{"arg:1>arg:0 false ssa"}},
+		{"CallSetPtr", []string{"arg:1>arg:0 false ssa"}},
+		// receiver
+		{"T.Get", []string{"recv>return:0 false ssa"}},
+		{"T.Set", []string{"arg:0>recv false ssa"}},
+		// package-level variables, also across a call
+		{"SetGlobal", []string{"arg:0>global:a.G false ssa"}},
+		{"CallSetGlobal", []string{"arg:0>global:a.G false ssa"}},
+		// variadic: the slice parameter is flagged, and the arguments
+		// packed into it at a call site reach the callee's result
+		{"Sum", []string{"arg:1>return:0 true ssa"}},
+		{"CallSum", []string{"arg:1>return:0 false ssa", "arg:2>return:0 false ssa"}},
+		// bodiless: every parameter reaches every result
+		{"Ext", []string{"arg:0>return:0 false opaque", "arg:1>return:0 false opaque"}},
+		{"CallExt", []string{"arg:0>return:0 false ssa"}},
+		// recursion: acc*n reaches the result only after Fact's own
+		// summary includes acc
+		{"Fact", []string{"arg:0>return:0 false ssa", "arg:1>return:0 false ssa"}},
+		// mutual recursion: x reaches Even's result through Odd
+		{"Even", []string{"arg:1>return:0 false ssa"}},
+		{"Odd", []string{"arg:1>return:0 false ssa"}},
+	}
+	for _, tt := range tests {
+		if !slices.Equal(got[tt.fn], tt.want) {
+			t.Errorf("%s: summary = %q, want %q", tt.fn, got[tt.fn], tt.want)
+		}
+	}
+}
+
+// Summaries of callees reached only from other functions are computed on
+// demand, the way external callees are.
+func TestSummarizerOnDemand(t *testing.T) {
+	var callExt, ext *ssa.Function
+	for _, fn := range buildSummariesSSA(t) {
+		switch fn.Name() {
+		case "CallExt":
+			callExt = fn
+		case "Ext":
+			ext = fn
+		}
+	}
+	s := newSummarizer()
+	s.enqueue(callExt)
+	s.solve()
+	if pf := s.flows[ext]; pf == nil || !pf.opaque {
+		t.Errorf("Ext summary = %+v, want an opaque summary computed on demand", pf)
+	}
+	if pf := s.flows[callExt]; pf == nil || !slices.Equal(pf.results, []uint64{1}) {
+		t.Errorf("CallExt summary = %+v, want arg 0 reaching the result", pf)
+	}
+}
@@ -0,0 +1,580 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Taint analysis is an IFDS-style tabulation over the interprocedural
+// supergraph of the written CPG: intra-procedural dfg edges (including the
+// heuristic ones for external calls), param_in edges into callees and
+// param_out edges back to call sites. When the points-to phase ran, taint
+// also crosses may_alias edges, so a value stored through one pointer taints
+// the reads through its aliases. It runs on the database rather than the
+// in-memory CPG so that packages reused by incremental runs take part.
+//
+// A fact is a tainted node together with the context it was derived in:
+// either a taint source in the same function, or a parameter of the enclosing
+// function the taint entered through. Facts under a parameter context are
+// shared by every caller; they become function summaries (parameter reaches
+// return) that are applied only at the call sites that actually passed taint
+// in, so a tainted argument at one call site no longer taints the result of
+// every other call to the same function.
+
+// taintGraph is the supergraph the solver walks.
+type taintGraph struct {
+	dfg      map[string][]string        // def → uses
+	paramIn  map[string][]taintParam    // argument value → formal parameters
+	callees  map[string][]string        // call site → known-module callee functions
+	callers  map[string][]string        // function → its call sites (param_out)
+	alias    map[string][]string        // value → values that may share its location (both directions)
+	returns  map[string]string          // return statement → enclosing function
+	roles    map[string]map[string]bool // node → taint roles (source, sink, barrier, ...)
+	category map[string]string          // node → taint_category
+}
+
+type taintParam struct {
+	param, fn string
+}
+
+// taintFact is a tainted node under a context: a source node, or the
+// parameter through which taint entered the node's function.
+type taintFact struct {
+	ctx, node string
+}
+
+// taintEntry is a call site where taint under ctx entered a parameter.
+type taintEntry struct {
+	site, ctx string
+}
+
+// taintArg is the argument node of a taintEntry and the fewest hops from
+// the entry's context to the parameter.
+type taintArg struct {
+	node string
+	hops int
+}
+
+// taintStep records how a fact got its current hop count: along an edge
+// of kind from another fact, or through the summary of param ("summary").
+type taintStep struct {
+	from  taintFact
+	kind  string
+	param string
+}
+
+// taintPathStep is one node of a witness path and the kind of the edge
+// leading to it ("" for the first node).
+type taintPathStep struct {
+	node, kind string
+}
+
+// taintState is one taint_flow_state row before labeling.
+type taintState struct {
+	node, source string
+	hops         int
+}
+
+// taintSummary is one summary edge: taint entering param reaches target
+// (a return statement or a sink) within the function.
+type taintSummary struct {
+	fn, param, kind, target string
+	hops                    int
+}
+
+func loadTaintGraph(conn *sqlite.Conn) (*taintGraph, error) {
+	g := &taintGraph{
+		dfg:      make(map[string][]string),
+		paramIn:  make(map[string][]taintParam),
+		callees:  make(map[string][]string),
+		callers:  make(map[string][]string),
+		alias:    make(map[string][]string),
+		returns:  make(map[string]string),
+		roles:    make(map[string]map[string]bool),
+		category: make(map[string]string),
+	}
+	queries := []struct {
+		sql string
+		row func(stmt *sqlite.Stmt)
+	}{
+		{`SELECT source, target FROM edges WHERE kind = 'dfg'`, func(stmt *sqlite.Stmt) {
+			g.dfg[stmt.ColumnText(0)] = append(g.dfg[stmt.ColumnText(0)], stmt.ColumnText(1))
+		}},
+		{`SELECT e.source, e.target, p.parent_function FROM edges e
+		  JOIN nodes p ON p.id = e.target
+		  WHERE e.kind = 'param_in' AND p.parent_function IS NOT NULL`, func(stmt *sqlite.Stmt) {
+			arg := stmt.ColumnText(0)
+			g.paramIn[arg] = append(g.paramIn[arg], taintParam{stmt.ColumnText(1), stmt.ColumnText(2)})
+		}},
+		{`SELECT e.source, e.target FROM edges e
+		  JOIN nodes f ON f.id = e.target AND f.kind = 'function'
+		  WHERE e.kind = 'call_site'`, func(stmt *sqlite.Stmt) {
+			g.callees[stmt.ColumnText(0)] = append(g.callees[stmt.ColumnText(0)], stmt.ColumnText(1))
+		}},
+		{`SELECT source, target FROM edges WHERE kind = 'param_out'`, func(stmt *sqlite.Stmt) {
+			g.callers[stmt.ColumnText(0)] = append(g.callers[stmt.ColumnText(0)], stmt.ColumnText(1))
+		}},
+		{`SELECT source, target FROM edges WHERE kind = 'may_alias'`, func(stmt *sqlite.Stmt) {
+			x, y := stmt.ColumnText(0), stmt.ColumnText(1)
+			g.alias[x] = append(g.alias[x], y)
+			g.alias[y] = append(g.alias[y], x)
+		}},
+		{`SELECT id, parent_function FROM nodes
+		  WHERE kind = 'return' AND parent_function IS NOT NULL`, func(stmt *sqlite.Stmt) {
+			g.returns[stmt.ColumnText(0)] = stmt.ColumnText(1)
+		}},
+		{`SELECT node_id, key, value FROM node_properties
+		  WHERE key IN ('taint_role', 'taint_category')`, func(stmt *sqlite.Stmt) {
+			id := stmt.ColumnText(0)
+			if stmt.ColumnText(1) == "taint_category" {
+				g.category[id] = stmt.ColumnText(2)
+				return
+			}
+			if g.roles[id] == nil {
+				g.roles[id] = make(map[string]bool)
+			}
+			g.roles[id][stmt.ColumnText(2)] = true
+		}},
+	}
+	for _, q := range queries {
+		err := sqlitex.ExecuteTransient(conn, q.sql, &sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				q.row(stmt)
+				return nil
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Sorted adjacency keeps the worklist order, and thus tie-breaking
+	// between equal-length paths, independent of SQLite's row order.
+	for _, m := range []map[string][]string{g.dfg, g.callees, g.callers, g.alias} {
+		for k, v := range m {
+			slices.Sort(v)
+			m[k] = slices.Compact(v)
+		}
+	}
+	for k, v := range g.paramIn {
+		slices.SortFunc(v, func(a, b taintParam) int { return cmp.Compare(a.param, b.param) })
+		g.paramIn[k] = slices.Compact(v)
+	}
+	return g, nil
+}
+
+// taintSolver holds the tabulation state.
+type taintSolver struct {
+	g       *taintGraph
+	sources map[string]bool
+	facts   map[taintFact]int                  // fact → fewest hops from its context
+	pred    map[taintFact]taintStep            // fact → how it got those hops
+	work    []taintFact                        // facts whose hop count changed
+	entries map[string]map[taintEntry]taintArg // parameter → call sites that tainted it
+	retHops map[string]int                     // parameter → hops from parameter to the call site via a return (summary)
+	retFrom map[string]string                  // parameter → return statement of its best summary
+	params  map[string]string                  // parameter → function
+
+	// Filled by results.
+	reachVia map[string]map[string]taintEntry // parameter → source → entry on the fewest-hop chain
+	best     map[[2]string]taintFact          // (node, source) → fact with the fewest hops
+}
+
+func newTaintSolver(g *taintGraph) *taintSolver {
+	s := &taintSolver{
+		g:       g,
+		sources: make(map[string]bool),
+		facts:   make(map[taintFact]int),
+		pred:    make(map[taintFact]taintStep),
+		entries: make(map[string]map[taintEntry]taintArg),
+		retHops: make(map[string]int),
+		retFrom: make(map[string]string),
+		params:  make(map[string]string),
+	}
+	var seeds []string
+	for id, roles := range g.roles {
+		if roles["source"] {
+			seeds = append(seeds, id)
+		}
+	}
+	slices.Sort(seeds)
+	for _, id := range seeds {
+		s.sources[id] = true
+		s.propagate(taintFact{id, id}, 0, taintStep{})
+	}
+	return s
+}
+
+func (s *taintSolver) propagate(f taintFact, hops int, step taintStep) {
+	if old, ok := s.facts[f]; ok && old <= hops {
+		return
+	}
+	s.facts[f] = hops
+	if f.node != f.ctx {
+		s.pred[f] = step
+	}
+	s.work = append(s.work, f)
+}
+
+// enter records that taint under ctx reaches param through the argument arg
+// of site, and applies the parameter's summary if one is already known.
+func (s *taintSolver) enter(ctx, site, arg string, p taintParam, hops int) {
+	s.params[p.param] = p.fn
+	es := s.entries[p.param]
+	if es == nil {
+		es = make(map[taintEntry]taintArg)
+		s.entries[p.param] = es
+	}
+	e := taintEntry{site, ctx}
+	if old, ok := es[e]; ok && old.hops <= hops {
+		return
+	}
+	es[e] = taintArg{arg, hops}
+	s.propagate(taintFact{p.param, p.param}, 0, taintStep{})
+	if r, ok := s.retHops[p.param]; ok {
+		s.propagate(taintFact{ctx, site}, hops+r, taintStep{taintFact{ctx, arg}, "summary", p.param})
+	}
+}
+
+// summarize records that taint entering param reaches the function's call
+// sites after hops via ret, and returns it to every site that passed taint in.
+func (s *taintSolver) summarize(param, ret string, hops int) {
+	if old, ok := s.retHops[param]; ok && old <= hops {
+		return
+	}
+	s.retHops[param] = hops
+	s.retFrom[param] = ret
+	es := s.entries[param]
+	keys := slices.SortedFunc(maps.Keys(es), compareTaintEntries)
+	for _, e := range keys {
+		a := es[e]
+		s.propagate(taintFact{e.ctx, e.site}, a.hops+hops, taintStep{taintFact{e.ctx, a.node}, "summary", param})
+	}
+}
+
+func compareTaintEntries(a, b taintEntry) int {
+	return cmp.Or(cmp.Compare(a.site, b.site), cmp.Compare(a.ctx, b.ctx))
+}
+
+func (s *taintSolver) solve() {
+	g := s.g
+	for len(s.work) > 0 {
+		f := s.work[0]
+		s.work = s.work[1:]
+		hops := s.facts[f]
+		n := f.node
+		if n != f.ctx && g.roles[n]["barrier"] {
+			continue // sanitized: recorded, but not propagated
+		}
+
+		if fn, ok := g.returns[n]; ok {
+			if s.sources[f.ctx] {
+				// Taint generated in this function flows to every caller.
+				for _, site := range g.callers[fn] {
+					s.propagate(taintFact{f.ctx, site}, hops+1, taintStep{from: f, kind: "param_out"})
+				}
+			} else if s.params[f.ctx] == fn {
+				// Taint that crossed an alias into another function does
+				// not make this return part of the parameter's summary.
+				s.summarize(f.ctx, n, hops+1)
+			}
+		}
+
+		for _, use := range g.dfg[n] {
+			// An argument to a known-module callee flows through the
+			// callee's parameter and summary, not straight to the result.
+			entered := false
+			if callees := g.callees[use]; len(callees) > 0 {
+				for _, p := range g.paramIn[n] {
+					if slices.Contains(callees, p.fn) {
+						s.enter(f.ctx, use, n, p, hops+1)
+						entered = true
+					}
+				}
+			}
+			// Sinks and barriers are judged at the call itself, even when
+			// the callee is a known-module function.
+			if !entered || g.roles[use]["sink"] || g.roles[use]["barrier"] {
+				s.propagate(taintFact{f.ctx, use}, hops+1, taintStep{from: f, kind: "dfg"})
+			}
+		}
+		for _, other := range g.alias[n] {
+			s.propagate(taintFact{f.ctx, other}, hops+1, taintStep{from: f, kind: "may_alias"})
+		}
+	}
+}
+
+// sourceReach resolves, for each parameter context, the sources that reach
+// it and the fewest hops, following chains of calls between parameters. The
+// entry on each fewest-hop chain is kept in reachVia for witness paths.
+func (s *taintSolver) sourceReach() map[string]map[string]int {
+	reach := make(map[string]map[string]int)
+	s.reachVia = make(map[string]map[string]taintEntry)
+	relax := func(param, source string, hops int, via taintEntry) bool {
+		r := reach[param]
+		if r == nil {
+			r = make(map[string]int)
+			reach[param] = r
+			s.reachVia[param] = make(map[string]taintEntry)
+		}
+		if old, ok := r[source]; ok && (old < hops || old == hops && compareTaintEntries(s.reachVia[param][source], via) <= 0) {
+			return false
+		}
+		r[source] = hops
+		s.reachVia[param][source] = via
+		return true
+	}
+	for changed := true; changed; {
+		changed = false
+		for param, es := range s.entries {
+			for e, a := range es {
+				if s.sources[e.ctx] {
+					changed = relax(param, e.ctx, a.hops, e) || changed
+					continue
+				}
+				for source, hs := range reach[e.ctx] {
+					changed = relax(param, source, hs+a.hops, e) || changed
+				}
+			}
+		}
+	}
+	return reach
+}
+
+// results flattens facts to per-(node, source) states and collects the
+// summary edges of parameter contexts.
+func (s *taintSolver) results() ([]taintState, []taintSummary) {
+	reach := s.sourceReach()
+	bestHops := make(map[[2]string]int)
+	s.best = make(map[[2]string]taintFact)
+	record := func(f taintFact, source string, hops int) {
+		k := [2]string{f.node, source}
+		old, ok := bestHops[k]
+		if !ok || hops < old || hops == old && f.ctx < s.best[k].ctx {
+			bestHops[k] = hops
+			s.best[k] = f
+		}
+	}
+	var summaries []taintSummary
+	for f, h := range s.facts {
+		if s.sources[f.ctx] {
+			record(f, f.ctx, h)
+			continue
+		}
+		for source, hs := range reach[f.ctx] {
+			record(f, source, hs+h)
+		}
+		kind := ""
+		if _, ok := s.g.returns[f.node]; ok {
+			kind = "return"
+		} else if s.g.roles[f.node]["sink"] {
+			kind = "sink"
+		}
+		if kind != "" {
+			summaries = append(summaries, taintSummary{s.params[f.ctx], f.ctx, kind, f.node, h})
+		}
+	}
+
+	states := make([]taintState, 0, len(bestHops))
+	for k, h := range bestHops {
+		states = append(states, taintState{k[0], k[1], h})
+	}
+	slices.SortFunc(states, func(a, b taintState) int {
+		return cmp.Or(cmp.Compare(a.node, b.node), cmp.Compare(a.source, b.source))
+	})
+	slices.SortFunc(summaries, func(a, b taintSummary) int {
+		return cmp.Or(cmp.Compare(a.fn, b.fn), cmp.Compare(a.param, b.param), cmp.Compare(a.target, b.target))
+	})
+	return states, summaries
+}
+
+// path returns the witness path from f's context to f.node, expanding
+// summaries into the callee's own path from parameter to return.
+func (s *taintSolver) path(f taintFact) []taintPathStep {
+	if f.node == f.ctx {
+		return []taintPathStep{{f.node, ""}}
+	}
+	st := s.pred[f]
+	p := s.path(st.from)
+	if st.kind != "summary" {
+		return append(p, taintPathStep{f.node, st.kind})
+	}
+	p = append(p, taintPathStep{st.param, "param_in"})
+	p = append(p, s.path(taintFact{st.param, s.retFrom[st.param]})[1:]...)
+	return append(p, taintPathStep{f.node, "param_out"})
+}
+
+// witness returns the fewest-hop path from source to node, or nil if the
+// solver found no flow between them.
+func (s *taintSolver) witness(source, node string) []taintPathStep {
+	f, ok := s.best[[2]string{node, source}]
+	if !ok {
+		return nil
+	}
+	return s.witnessFact(f, source)
+}
+
+func (s *taintSolver) witnessFact(f taintFact, source string) []taintPathStep {
+	if s.sources[f.ctx] {
+		return s.path(f)
+	}
+	via, ok := s.reachVia[f.ctx][source]
+	if !ok {
+		return nil
+	}
+	p := s.witnessFact(taintFact{via.ctx, s.entries[f.ctx][via].node}, source)
+	if p == nil {
+		return nil
+	}
+	p = append(p, taintPathStep{f.ctx, "param_in"})
+	return append(p, s.path(f)[1:]...)
+}
+
+// taintLabel classifies a reached node the way taint_flow_state reports it.
+func (g *taintGraph) taintLabel(node string) string {
+	switch roles := g.roles[node]; {
+	case roles["source"]:
+		return "source"
+	case roles["barrier"]:
+		return "sanitized"
+	case roles["sink"]:
+		return "sink_reached"
+	}
+	return "propagated"
+}
+
+// solveTaint runs the IFDS taint solver and fills taint_flow_state and
+// taint_summaries. The returned solver answers witness path queries.
+func solveTaint(conn *sqlite.Conn) (s *taintSolver, summaryCount int, err error) {
+	g, err := loadTaintGraph(conn)
+	if err != nil {
+		return nil, 0, fmt.Errorf("load taint graph: %w", err)
+	}
+	s = newTaintSolver(g)
+	s.solve()
+	states, summaries := s.results()
+
+	endFn, err := sqlitex.ImmediateTransaction(conn)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer endFn(&err)
+
+	stmt, err := conn.Prepare(`INSERT INTO taint_flow_state (node_id, label, source_id, source_category, min_hops)
+VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return nil, 0, fmt.Errorf("prepare taint state insert: %w", err)
+	}
+	defer func() { _ = stmt.Finalize() }()
+	for _, st := range states {
+		category := g.category[st.source]
+		if category == "" {
+			category = "unknown"
+		}
+		stmt.BindText(1, st.node)
+		stmt.BindText(2, g.taintLabel(st.node))
+		stmt.BindText(3, st.source)
+		stmt.BindText(4, category)
+		stmt.BindInt64(5, int64(st.hops))
+		if _, err := stmt.Step(); err != nil {
+			return nil, 0, fmt.Errorf("insert taint state %s: %w", st.node, err)
+		}
+		_ = stmt.Reset()
+	}
+
+	sumStmt, err := conn.Prepare(`INSERT INTO taint_summaries (function_id, param_id, kind, target_id, hops)
+VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return nil, 0, fmt.Errorf("prepare taint summary insert: %w", err)
+	}
+	defer func() { _ = sumStmt.Finalize() }()
+	for _, sum := range summaries {
+		sumStmt.BindText(1, sum.fn)
+		sumStmt.BindText(2, sum.param)
+		sumStmt.BindText(3, sum.kind)
+		sumStmt.BindText(4, sum.target)
+		sumStmt.BindInt64(5, int64(sum.hops))
+		if _, err := sumStmt.Step(); err != nil {
+			return nil, 0, fmt.Errorf("insert taint summary %s: %w", sum.param, err)
+		}
+		_ = sumStmt.Reset()
+	}
+	return s, len(summaries), nil
+}
+
+// maxTaintPaths caps the witness paths stored for one taint_hotspot finding
+// (one per source/sink pair, fewest hops first).
+const maxTaintPaths = 3
+
+// writeTaintPaths stores witness paths for unsanitized_sink findings (the
+// fewest-hop path from the finding's source to its sink) and taint_hotspot
+// findings (paths between sources and sinks of the same function, if any).
+// Returns the number of paths stored.
+func writeTaintPaths(conn *sqlite.Conn, s *taintSolver) (count int, err error) {
+	type pathRef struct {
+		finding      int64
+		source, sink string
+	}
+	var refs []pathRef
+	err = sqlitex.ExecuteTransient(conn, `SELECT id, json_extract(details, '$.source'), json_extract(details, '$.sink'),
+  json_extract(details, '$.hops')
+FROM findings WHERE category = 'unsanitized_sink'
+UNION ALL
+SELECT f.id, tfs.source_id, tfs.node_id, tfs.min_hops
+FROM findings f
+JOIN nodes snk ON snk.parent_function = f.node_id
+JOIN taint_flow_state tfs ON tfs.node_id = snk.id AND tfs.label = 'sink_reached'
+JOIN nodes src ON src.id = tfs.source_id AND src.parent_function = f.node_id
+WHERE f.category = 'taint_hotspot'
+ORDER BY 1, 4, 2, 3`, &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			refs = append(refs, pathRef{stmt.ColumnInt64(0), stmt.ColumnText(1), stmt.ColumnText(2)})
+			return nil
+		},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("select taint findings: %w", err)
+	}
+
+	endFn, err := sqlitex.ImmediateTransaction(conn)
+	if err != nil {
+		return 0, err
+	}
+	defer endFn(&err)
+
+	stmt, err := conn.Prepare(`INSERT INTO taint_paths (finding_id, path_id, step, node_id, edge_kind, file, line)
+VALUES (?1, ?2, ?3, ?4, NULLIF(?5, ''), (SELECT file FROM nodes WHERE id = ?4), (SELECT line FROM nodes WHERE id = ?4))`)
+	if err != nil {
+		return 0, fmt.Errorf("prepare taint path insert: %w", err)
+	}
+	defer func() { _ = stmt.Finalize() }()
+
+	perFinding := make(map[int64]int)
+	for _, ref := range refs {
+		if perFinding[ref.finding] >= maxTaintPaths {
+			continue
+		}
+		path := s.witness(ref.source, ref.sink)
+		if path == nil {
+			continue
+		}
+		perFinding[ref.finding]++
+		for i, step := range path {
+			stmt.BindInt64(1, ref.finding)
+			stmt.BindInt64(2, int64(perFinding[ref.finding]))
+			stmt.BindInt64(3, int64(i))
+			stmt.BindText(4, step.node)
+			stmt.BindText(5, step.kind)
+			if _, err := stmt.Step(); err != nil {
+				return 0, fmt.Errorf("insert taint path for finding %d: %w", ref.finding, err)
+			}
+			_ = stmt.Reset()
+		}
+		count++
+	}
+	return count, nil
+}
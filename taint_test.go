@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"cpg-gen/internal/server"
+)
+
+// newTestTaintGraph returns an empty supergraph for hand-built solver tests.
+func newTestTaintGraph() *taintGraph {
+	return &taintGraph{
+		dfg:      make(map[string][]string),
+		paramIn:  make(map[string][]taintParam),
+		callees:  make(map[string][]string),
+		callers:  make(map[string][]string),
+		alias:    make(map[string][]string),
+		returns:  make(map[string]string),
+		roles:    make(map[string]map[string]bool),
+		category: make(map[string]string),
+	}
+}
+
+func (g *taintGraph) flow(nodes ...string) {
+	for i := 1; i < len(nodes); i++ {
+		g.dfg[nodes[i-1]] = append(g.dfg[nodes[i-1]], nodes[i])
+	}
+}
+
+func (g *taintGraph) role(node, role string) {
+	if g.roles[node] == nil {
+		g.roles[node] = make(map[string]bool)
+	}
+	g.roles[node][role] = true
+}
+
+// call wires arg → site as an argument of a call to fn, whose parameter is
+// param, and returns from fn back to site.
+func (g *taintGraph) call(site, arg, fn, param string) {
+	g.flow(arg, site)
+	g.callees[site] = append(g.callees[site], fn)
+	g.paramIn[arg] = append(g.paramIn[arg], taintParam{param, fn})
+	g.callers[fn] = append(g.callers[fn], site)
+}
+
+func solveTestGraph(g *taintGraph) (*taintSolver, map[[2]string]int, []taintSummary) {
+	s := newTaintSolver(g)
+	s.solve()
+	states, summaries := s.results()
+	hops := make(map[[2]string]int)
+	for _, st := range states {
+		hops[[2]string{st.node, st.source}] = st.hops
+	}
+	return s, hops, summaries
+}
+
+func TestTaintIntraprocedural(t *testing.T) {
+	g := newTestTaintGraph()
+	g.role("src", "source")
+	g.role("sink", "sink")
+	g.role("clean", "barrier")
+	g.flow("src", "a", "sink")
+	g.flow("src", "clean", "after")
+
+	_, hops, _ := solveTestGraph(g)
+	want := map[[2]string]int{
+		{"src", "src"}:   0,
+		{"a", "src"}:     1,
+		{"sink", "src"}:  2,
+		{"clean", "src"}: 1,
+	}
+	for k, h := range want {
+		if got, ok := hops[k]; !ok || got != h {
+			t.Errorf("state %v: hops = %d (present %v), want %d", k, got, ok, h)
+		}
+	}
+	if _, ok := hops[[2]string{"after", "src"}]; ok {
+		t.Error("taint propagated past a barrier")
+	}
+	if got := g.taintLabel("clean"); got != "sanitized" {
+		t.Errorf("barrier label = %q, want sanitized", got)
+	}
+	if got := g.taintLabel("sink"); got != "sink_reached" {
+		t.Errorf("sink label = %q, want sink_reached", got)
+	}
+}
+
+// id(p) returns p; it is called with tainted data at callA and with clean
+// data at callB. Only callA's result may be tainted.
+func TestTaintContextSensitive(t *testing.T) {
+	g := newTestTaintGraph()
+	g.role("src", "source")
+	g.flow("src", "argA")
+	g.call("callA", "argA", "id", "p")
+	g.flow("callA", "resA")
+	g.flow("clean", "argB")
+	g.call("callB", "argB", "id", "p")
+	g.flow("callB", "resB")
+	g.flow("p", "ret")
+	g.returns["ret"] = "id"
+
+	s, hops, summaries := solveTestGraph(g)
+	if got, ok := hops[[2]string{"resA", "src"}]; !ok || got != 5 {
+		t.Errorf("resA: hops = %d (present %v), want 5", got, ok)
+	}
+	for _, n := range []string{"callB", "resB"} {
+		if _, ok := hops[[2]string{n, "src"}]; ok {
+			t.Errorf("%s tainted through another call site's argument", n)
+		}
+	}
+	if got, ok := hops[[2]string{"ret", "src"}]; !ok || got != 3 {
+		t.Errorf("ret inside callee: hops = %d (present %v), want 3", got, ok)
+	}
+
+	wantSummary := []taintSummary{{fn: "id", param: "p", kind: "return", target: "ret", hops: 1}}
+	if !slices.Equal(summaries, wantSummary) {
+		t.Errorf("summaries = %+v, want %+v", summaries, wantSummary)
+	}
+
+	wantPath := []taintPathStep{
+		{"src", ""}, {"argA", "dfg"}, {"p", "param_in"}, {"ret", "dfg"},
+		{"callA", "param_out"}, {"resA", "dfg"},
+	}
+	if got := s.witness("src", "resA"); !slices.Equal(got, wantPath) {
+		t.Errorf("witness(src, resA) = %v, want %v", got, wantPath)
+	}
+	if got := s.witness("src", "resB"); got != nil {
+		t.Errorf("witness(src, resB) = %v, want nil", got)
+	}
+}
+
+// A source inside a callee taints the result at every call site.
+func TestTaintSourceInCallee(t *testing.T) {
+	g := newTestTaintGraph()
+	g.role("src", "source")
+	g.flow("src", "ret")
+	g.returns["ret"] = "get"
+	g.callers["get"] = []string{"c1", "c2"}
+
+	_, hops, summaries := solveTestGraph(g)
+	for _, n := range []string{"c1", "c2"} {
+		if got, ok := hops[[2]string{n, "src"}]; !ok || got != 2 {
+			t.Errorf("%s: hops = %d (present %v), want 2", n, got, ok)
+		}
+	}
+	if len(summaries) != 0 {
+		t.Errorf("summaries = %+v, want none for source contexts", summaries)
+	}
+}
+
+// Taint entering a parameter that reaches a sink inside the callee is
+// reported at the sink under the caller's source, via the parameter chain.
+func TestTaintSinkInCallee(t *testing.T) {
+	g := newTestTaintGraph()
+	g.role("src", "source")
+	g.role("exec", "sink")
+	g.flow("src", "arg")
+	g.call("site", "arg", "run", "p")
+	g.flow("p", "exec")
+
+	s, hops, summaries := solveTestGraph(g)
+	if got, ok := hops[[2]string{"exec", "src"}]; !ok || got != 3 {
+		t.Errorf("exec: hops = %d (present %v), want 3", got, ok)
+	}
+	wantSummary := []taintSummary{{fn: "run", param: "p", kind: "sink", target: "exec", hops: 1}}
+	if !slices.Equal(summaries, wantSummary) {
+		t.Errorf("summaries = %+v, want %+v", summaries, wantSummary)
+	}
+	wantPath := []taintPathStep{{"src", ""}, {"arg", "dfg"}, {"p", "param_in"}, {"exec", "dfg"}}
+	if got := s.witness("src", "exec"); !slices.Equal(got, wantPath) {
+		t.Errorf("witness(src, exec) = %v, want %v", got, wantPath)
+	}
+}
+
+// Of two flows to the same node, the one with fewer hops wins regardless of
+// discovery order.
+func TestTaintFewestHops(t *testing.T) {
+	g := newTestTaintGraph()
+	g.role("src", "source")
+	g.flow("src", "a", "b", "c", "x")
+	g.flow("src", "x")
+
+	s, hops, _ := solveTestGraph(g)
+	if got := hops[[2]string{"x", "src"}]; got != 1 {
+		t.Errorf("x: hops = %d, want 1", got)
+	}
+	if got := s.witness("src", "x"); len(got) != 2 {
+		t.Errorf("witness(src, x) = %v, want the direct edge", got)
+	}
+}
+
+// The witness of an interprocedural unsanitized_sink finding is served by
+// /taint/path from source to sink.
+func TestTaintPathServer(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "cpg.db")
+	conn, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sqlitex.ExecuteScript(conn, `
+CREATE TABLE findings (id INTEGER PRIMARY KEY AUTOINCREMENT, category TEXT NOT NULL, severity TEXT NOT NULL,
+  node_id TEXT, file TEXT, line INTEGER, message TEXT NOT NULL, details TEXT);
+CREATE TABLE node_properties (node_id TEXT NOT NULL, key TEXT NOT NULL, value TEXT NOT NULL);
+CREATE TABLE schema_docs (category TEXT, name TEXT, description TEXT, example TEXT);
+CREATE TABLE queries (name TEXT PRIMARY KEY, description TEXT NOT NULL, sql TEXT NOT NULL);
+INSERT INTO nodes (id, kind, name, file, line, col, parent_function) VALUES
+  ('main::main', 'function', 'main', 'main.go', 5, 1, NULL),
+  ('src', 'call', 'os.Getenv', 'main.go', 6, 7, 'main::main'),
+  ('cmd', 'local', 'cmd', 'main.go', 6, 2, 'main::main'),
+  ('arg', 'identifier', 'cmd', 'main.go', 7, 6, 'main::main'),
+  ('site', 'call', 'run', 'main.go', 7, 2, 'main::main'),
+  ('main::run', 'function', 'run', 'main.go', 10, 1, NULL),
+  ('p', 'parameter', 'p', 'main.go', 10, 10, 'main::run'),
+  ('exec', 'call', 'exec.Command', 'main.go', 11, 2, 'main::run');
+INSERT INTO edges (source, target, kind) VALUES
+  ('src', 'cmd', 'dfg'),
+  ('cmd', 'arg', 'dfg'),
+  ('arg', 'site', 'dfg'),
+  ('site', 'main::run', 'call_site'),
+  ('arg', 'p', 'param_in'),
+  ('p', 'exec', 'dfg');
+INSERT INTO node_properties VALUES
+  ('src', 'taint_role', 'source'),
+  ('src', 'taint_category', 'env'),
+  ('exec', 'taint_role', 'sink');`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := createTaintFlowStates(conn, NewProgress(false)); err != nil {
+		t.Fatal(err)
+	}
+	var findingID int64
+	err = sqlitex.ExecuteTransient(conn, `SELECT id FROM findings WHERE category = 'unsanitized_sink'`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			findingID = stmt.ColumnInt64(0)
+			return nil
+		}})
+	if err != nil || findingID == 0 {
+		t.Fatalf("no unsanitized_sink finding (err %v)", err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	srv, err := server.New(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/taint/path?"+query, nil))
+		return rec
+	}
+
+	rec := get("finding=" + strconv.FormatInt(findingID, 10))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/taint/path: status %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Finding struct {
+			Category string `json:"category"`
+			NodeID   string `json:"node_id"`
+		} `json:"finding"`
+		Paths []struct {
+			Nodes []struct {
+				Step int    `json:"step"`
+				ID   string `json:"id"`
+				Line int    `json:"line"`
+			} `json:"nodes"`
+			Edges []struct {
+				Source, Target, Kind string
+			} `json:"edges"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Finding.Category != "unsanitized_sink" || resp.Finding.NodeID != "exec" {
+		t.Errorf("finding = %+v, want the unsanitized_sink at exec", resp.Finding)
+	}
+	if len(resp.Paths) != 1 {
+		t.Fatalf("got %d paths, want 1", len(resp.Paths))
+	}
+	var ids []string
+	for i, n := range resp.Paths[0].Nodes {
+		if n.Step != i {
+			t.Errorf("node %d has step %d", i, n.Step)
+		}
+		ids = append(ids, n.ID)
+	}
+	if want := []string{"src", "cmd", "arg", "p", "exec"}; !slices.Equal(ids, want) {
+		t.Errorf("path = %v, want %v (source to sink)", ids, want)
+	}
+	var kinds []string
+	for i, e := range resp.Paths[0].Edges {
+		if e.Source != ids[i] || e.Target != ids[i+1] {
+			t.Errorf("edge %d = %s→%s, want %s→%s", i, e.Source, e.Target, ids[i], ids[i+1])
+		}
+		kinds = append(kinds, e.Kind)
+	}
+	if want := []string{"dfg", "dfg", "param_in", "dfg"}; !slices.Equal(kinds, want) {
+		t.Errorf("edge kinds = %v, want %v", kinds, want)
+	}
+	if n := resp.Paths[0].Nodes; n[0].Line != 6 || n[len(n)-1].Line != 11 {
+		t.Errorf("path lines %d..%d, want 6..11", n[0].Line, n[len(n)-1].Line)
+	}
+
+	for query, status := range map[string]int{
+		"finding=" + strconv.FormatInt(findingID+100, 10): http.StatusNotFound,
+		"finding=x": http.StatusBadRequest,
+		"":          http.StatusBadRequest,
+	} {
+		if rec := get(query); rec.Code != status {
+			t.Errorf("/taint/path?%s: status %d, want %d", query, rec.Code, status)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Package api serves the store.
+package api
+
+import (
+	"os"
+	"strings"
+
+	"example.com/fixture/store"
+)
+
+// Server wraps a store.
+type Server struct {
+	s    *store.Store
+	keys chan string
+}
+
+// NewServer returns a server over a new store.
+func NewServer() *Server {
+	s := store.New()
+	return &Server{s: s, keys: make(chan string)}
+}
+
+// Handle stores a request and returns the normalized key.
+func (srv *Server) Handle(req string) string {
+	key, value, _ := strings.Cut(req, "=")
+	key = strings.ToLower(key)
+	srv.s.Put(key, value)
+	return key
+}
+
+// Load stores the value of an environment variable and writes it to path.
+func (srv *Server) Load(name, path string) error {
+	v := os.Getenv(name)
+	srv.s.Put(name, v)
+	return os.WriteFile(path, []byte(v), 0o644)
+}
+
+// Upper returns the values of keys in upper case.
+func (srv *Server) Upper(keys []string) []string {
+	vals, err := store.Lookup(srv.s, keys...)
+	if err != nil {
+		return nil
+	}
+	return store.Map(vals, strings.ToUpper)
+}
+
+// Serve handles requests until done is closed.
+func (srv *Server) Serve(reqs <-chan string, done <-chan struct{}) {
+	go func() {
+		for {
+			select {
+			case r := <-reqs:
+				srv.keys <- srv.Handle(r)
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// Config holds settings read from the environment.
+type Config struct {
+	Dir string
+}
+
+// Save writes the directory named by DIR to path. The value is stored
+// through one pointer and read through another.
+func Save(path string) error {
+	c := &Config{}
+	alias := c
+	alias.Dir = os.Getenv("DIR")
+	return os.WriteFile(path, []byte(c.Dir), 0o644)
+}
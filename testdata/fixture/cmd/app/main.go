@@ -0,0 +1,17 @@
+package main
+
+import (
+	"os"
+
+	"example.com/fixture/api"
+)
+
+func main() {
+	srv := api.NewServer()
+	srv.Handle("a=1")
+	if err := srv.Load("HOME", os.Args[1]); err != nil {
+		os.Exit(1)
+	}
+	_ = srv.Upper([]string{"a"})
+	os.Remove(os.Args[1] + ".tmp")
+}
@@ -0,0 +1,15 @@
+package store_test
+
+import (
+	"testing"
+
+	"example.com/fixture/store"
+)
+
+func TestLookup(t *testing.T) {
+	s := store.New()
+	s.Put("a", "1")
+	if _, err := store.Lookup(s, "a", "b"); err == nil {
+		t.Fatal("Lookup of a missing key succeeded")
+	}
+}
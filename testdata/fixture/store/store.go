@@ -0,0 +1,98 @@
+// Package store is a small key-value store used as an end-to-end fixture.
+package store
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrNotFound is returned for missing keys.
+var ErrNotFound = errors.New("not found")
+
+var registry []string
+
+func init() {
+	registry = append(registry, "store")
+}
+
+func init() {
+	registry = append(registry, "store2")
+}
+
+// Getter reads values.
+type Getter interface {
+	Get(key string) (string, error)
+}
+
+// Store is a map guarded by a mutex.
+type Store struct {
+	mu    sync.Mutex
+	items map[string]string
+	hooks []func(string)
+}
+
+// New returns an empty store.
+func New() *Store {
+	return &Store{items: make(map[string]string)}
+}
+
+// Get returns the value of key.
+func (s *Store) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.items[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return v, nil
+}
+
+// Put stores a value and runs the hooks.
+func (s *Store) Put(key, value string) {
+	s.mu.Lock()
+	s.items[key] = value
+	hooks := s.hooks
+	s.mu.Unlock()
+	for _, h := range hooks {
+		h(key)
+	}
+}
+
+// OnPut registers a hook.
+func (s *Store) OnPut(h func(string)) {
+	s.hooks = append(s.hooks, h)
+}
+
+// Map applies f to every element.
+func Map[T, U any](xs []T, f func(T) U) []U {
+	out := make([]U, 0, len(xs))
+	for _, x := range xs {
+		out = append(out, f(x))
+	}
+	return out
+}
+
+// Watch sends every key put into s on the returned channel.
+func Watch(s *Store) <-chan string {
+	ch := make(chan string, 1)
+	s.OnPut(func(k string) {
+		select {
+		case ch <- k:
+		default:
+		}
+	})
+	return ch
+}
+
+// Lookup reads several keys.
+func Lookup(g Getter, keys ...string) ([]string, error) {
+	var out []string
+	for i := 0; i < len(keys); i++ {
+		v, err := g.Get(keys[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
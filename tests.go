@@ -0,0 +1,139 @@
+package main
+
+import (
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// testKind classifies a top-level function declared in a _test.go file the
+// way `go test` does: "test", "benchmark", "fuzz" or "example". It returns ""
+// for helpers and TestMain.
+func testKind(name string, sig *types.Signature) string {
+	if sig == nil || sig.Recv() != nil || sig.TypeParams().Len() > 0 {
+		return ""
+	}
+	params := sig.Params()
+	switch {
+	case hasTestPrefix(name, "Example"):
+		if params.Len() == 0 && sig.Results().Len() == 0 {
+			return "example"
+		}
+	case hasTestPrefix(name, "Benchmark"):
+		if params.Len() == 1 && isTestingPtr(params.At(0).Type(), "B") {
+			return "benchmark"
+		}
+	case hasTestPrefix(name, "Fuzz"):
+		if params.Len() == 1 && isTestingPtr(params.At(0).Type(), "F") {
+			return "fuzz"
+		}
+	case hasTestPrefix(name, "Test") && name != "TestMain":
+		if params.Len() == 1 && isTestingPtr(params.At(0).Type(), "T") {
+			return "test"
+		}
+	}
+	return ""
+}
+
+// hasTestPrefix reports whether name is prefix, or prefix followed by a
+// character that is not a lower-case letter (TestFoo, Test_foo, but not Testify).
+func hasTestPrefix(name, prefix string) bool {
+	rest, ok := strings.CutPrefix(name, prefix)
+	if !ok {
+		return false
+	}
+	if rest == "" {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(rest)
+	return !unicode.IsLower(r)
+}
+
+// isTestingPtr reports whether t is *testing.<name>.
+func isTestingPtr(t types.Type, name string) bool {
+	ptr, ok := t.(*types.Pointer)
+	if !ok {
+		return false
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "testing" && obj.Name() == name
+}
+
+// EmitTestEdges links every test, benchmark, fuzz target and example to the
+// known-module functions it transitively reaches through call edges, so
+// "which tests cover this function?" is a single edge lookup. Helpers in
+// _test.go files are traversed but not linked. Must run after BuildCallGraph.
+func EmitTestEdges(ssaResult *SSAResult, fset *token.FileSet, funcLookup *FuncLookup, cpg *CPG, prog *Progress) {
+	var testIDs []string
+	isTest := make(map[string]bool)
+	inTestFile := make(map[string]bool)
+	for _, fn := range ssaResult.KnownFuncs(fset) {
+		if !strings.HasSuffix(fset.Position(fn.Pos()).Filename, "_test.go") {
+			continue
+		}
+		id := ssaFuncNodeID(fn, fset, funcLookup)
+		if id == "" {
+			continue
+		}
+		inTestFile[id] = true
+		if fn.Parent() != nil {
+			continue // closures are reached through their enclosing test
+		}
+		if testKind(fn.Name(), fn.Signature) != "" && !isTest[id] {
+			isTest[id] = true
+			testIDs = append(testIDs, id)
+		}
+	}
+	if len(testIDs) == 0 {
+		return
+	}
+
+	callees := make(map[string][]string)
+	err := cpg.EdgesOfKind("call", func(source, target string) {
+		callees[source] = append(callees[source], target)
+	})
+	if err != nil {
+		prog.Log("Warning: reading call edges for test coverage: %v", err)
+		return
+	}
+	for _, targets := range callees {
+		slices.Sort(targets)
+	}
+
+	var edgeCount int
+	covered := make(map[string]bool)
+	for _, testID := range testIDs {
+		// Breadth-first, so depth is the shortest call distance from the test.
+		depth := map[string]int{testID: 0}
+		queue := []string{testID}
+		for len(queue) > 0 {
+			cur := queue[0]
+			queue = queue[1:]
+			for _, next := range callees[cur] {
+				if _, seen := depth[next]; seen {
+					continue
+				}
+				depth[next] = depth[cur] + 1
+				queue = append(queue, next)
+				if inTestFile[next] || strings.HasPrefix(next, "ext::") {
+					continue
+				}
+				cpg.AddEdge(Edge{
+					Source: testID, Target: next, Kind: "tests",
+					Properties: map[string]any{"depth": depth[next]},
+				})
+				covered[next] = true
+				edgeCount++
+			}
+		}
+	}
+
+	prog.Log("Linked %d tests to %d covered functions (%d tests edges)", len(testIDs), len(covered), edgeCount)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"go/token"
+	"go/types"
+	"testing"
+)
+
+func TestHasTestPrefix(t *testing.T) {
+	tests := []struct {
+		name, prefix string
+		want         bool
+	}{
+		{"Test", "Test", true},
+		{"TestFoo", "Test", true},
+		{"Test_foo", "Test", true},
+		{"Test2", "Test", true},
+		{"Testify", "Test", false},
+		{"TestÄpfel", "Test", true},
+		{"Testüber", "Test", false},
+		{"MyTest", "Test", false},
+		{"ExampleFoo_bar", "Example", true},
+	}
+	for _, tt := range tests {
+		if got := hasTestPrefix(tt.name, tt.prefix); got != tt.want {
+			t.Errorf("hasTestPrefix(%q, %q) = %v, want %v", tt.name, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+// testingPtr returns *testing.<name> for a synthetic testing package.
+func testingPtr(pkg *types.Package, name string) types.Type {
+	obj := types.NewTypeName(token.NoPos, pkg, name, nil)
+	return types.NewPointer(types.NewNamed(obj, types.NewStruct(nil, nil), nil))
+}
+
+func TestTestKind(t *testing.T) {
+	testingPkg := types.NewPackage("testing", "testing")
+	otherPkg := types.NewPackage("example.com/testing", "testing")
+	sig := func(params ...types.Type) *types.Signature {
+		vars := make([]*types.Var, len(params))
+		for i, p := range params {
+			vars[i] = types.NewParam(token.NoPos, nil, "", p)
+		}
+		return types.NewSignatureType(nil, nil, nil, types.NewTuple(vars...), nil, false)
+	}
+	tPtr := testingPtr(testingPkg, "T")
+	bPtr := testingPtr(testingPkg, "B")
+	fPtr := testingPtr(testingPkg, "F")
+	mPtr := testingPtr(testingPkg, "M")
+
+	recv := types.NewVar(token.NoPos, nil, "s", types.Typ[types.Int])
+	method := types.NewSignatureType(recv, nil, nil, types.NewTuple(types.NewParam(token.NoPos, nil, "", tPtr)), nil, false)
+	withResult := types.NewSignatureType(nil, nil, nil, nil, types.NewTuple(types.NewParam(token.NoPos, nil, "", types.Typ[types.Int])), false)
+
+	tests := []struct {
+		name string
+		sig  *types.Signature
+		want string
+	}{
+		{"TestFoo", sig(tPtr), "test"},
+		{"Test", sig(tPtr), "test"},
+		{"TestMain", sig(mPtr), ""},
+		{"TestMain", sig(tPtr), ""},
+		{"Testify", sig(tPtr), ""},
+		{"TestFoo", sig(bPtr), ""},
+		{"TestFoo", sig(testingPtr(otherPkg, "T")), ""},
+		{"TestFoo", sig(tPtr, tPtr), ""},
+		{"TestFoo", method, ""},
+		{"BenchmarkFoo", sig(bPtr), "benchmark"},
+		{"BenchmarkFoo", sig(tPtr), ""},
+		{"FuzzFoo", sig(fPtr), "fuzz"},
+		{"Example", sig(), "example"},
+		{"ExampleT_Method", sig(), "example"},
+		{"ExampleFoo", sig(tPtr), ""},
+		{"ExampleFoo", withResult, ""},
+		{"helper", sig(tPtr), ""},
+		{"TestFoo", nil, ""},
+	}
+	for _, tt := range tests {
+		if got := testKind(tt.name, tt.sig); got != tt.want {
+			t.Errorf("testKind(%s, %v) = %q, want %q", tt.name, tt.sig, got, tt.want)
+		}
+	}
+}
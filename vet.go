@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/analysis/passes/appends"
+	"golang.org/x/tools/go/analysis/passes/assign"
+	"golang.org/x/tools/go/analysis/passes/atomic"
+	"golang.org/x/tools/go/analysis/passes/bools"
+	"golang.org/x/tools/go/analysis/passes/composite"
+	"golang.org/x/tools/go/analysis/passes/copylock"
+	"golang.org/x/tools/go/analysis/passes/defers"
+	"golang.org/x/tools/go/analysis/passes/errorsas"
+	"golang.org/x/tools/go/analysis/passes/httpresponse"
+	"golang.org/x/tools/go/analysis/passes/ifaceassert"
+	"golang.org/x/tools/go/analysis/passes/lostcancel"
+	"golang.org/x/tools/go/analysis/passes/nilfunc"
+	"golang.org/x/tools/go/analysis/passes/nilness"
+	"golang.org/x/tools/go/analysis/passes/printf"
+	"golang.org/x/tools/go/analysis/passes/shadow"
+	"golang.org/x/tools/go/analysis/passes/shift"
+	"golang.org/x/tools/go/analysis/passes/sigchanyzer"
+	"golang.org/x/tools/go/analysis/passes/slog"
+	"golang.org/x/tools/go/analysis/passes/stdmethods"
+	"golang.org/x/tools/go/analysis/passes/stringintconv"
+	"golang.org/x/tools/go/analysis/passes/structtag"
+	"golang.org/x/tools/go/analysis/passes/testinggoroutine"
+	"golang.org/x/tools/go/analysis/passes/tests"
+	"golang.org/x/tools/go/analysis/passes/timeformat"
+	"golang.org/x/tools/go/analysis/passes/unmarshal"
+	"golang.org/x/tools/go/analysis/passes/unreachable"
+	"golang.org/x/tools/go/analysis/passes/unsafeptr"
+	"golang.org/x/tools/go/analysis/passes/unusedresult"
+	"golang.org/x/tools/go/analysis/passes/unusedwrite"
+	"golang.org/x/tools/go/analysis/passes/waitgroup"
+	"golang.org/x/tools/go/packages"
+)
+
+// vetAnalyzers are the go/analysis passes -analyzers can select, by
+// analyzer name: the go vet suite without the assembly, cgo and build tag
+// checks, plus nilness, shadow and unusedwrite. All run by default.
+var vetAnalyzers = []*analysis.Analyzer{
+	appends.Analyzer,
+	assign.Analyzer,
+	atomic.Analyzer,
+	bools.Analyzer,
+	composite.Analyzer,
+	copylock.Analyzer,
+	defers.Analyzer,
+	errorsas.Analyzer,
+	httpresponse.Analyzer,
+	ifaceassert.Analyzer,
+	lostcancel.Analyzer,
+	nilfunc.Analyzer,
+	nilness.Analyzer,
+	printf.Analyzer,
+	shadow.Analyzer,
+	shift.Analyzer,
+	sigchanyzer.Analyzer,
+	slog.Analyzer,
+	stdmethods.Analyzer,
+	stringintconv.Analyzer,
+	structtag.Analyzer,
+	testinggoroutine.Analyzer,
+	tests.Analyzer,
+	timeformat.Analyzer,
+	unmarshal.Analyzer,
+	unreachable.Analyzer,
+	unsafeptr.Analyzer,
+	unusedresult.Analyzer,
+	unusedwrite.Analyzer,
+	waitgroup.Analyzer,
+}
+
+// VetResult holds one diagnostic reported by a go/analysis pass.
+type VetResult struct {
+	Analyzer string
+	Category string // the analyzer's own sub-category, often ""
+	Message  string
+	URL      string
+	RelFile  string
+	Line     int
+	Col      int
+	EndLine  int // 0 when the diagnostic has no end
+	EndCol   int
+	Fixes    []VetFix
+	NodeID   string // CPG node at the position, set by ResolveVet
+}
+
+// VetFix is one suggested fix of a diagnostic: a set of edits to apply
+// together.
+type VetFix struct {
+	Message string    `json:"message"`
+	Edits   []VetEdit `json:"edits"`
+}
+
+// VetEdit replaces the text between two positions of a file with NewText.
+type VetEdit struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Col     int    `json:"col"`
+	EndLine int    `json:"end_line"`
+	EndCol  int    `json:"end_col"`
+	NewText string `json:"new_text"`
+}
+
+// SelectAnalyzers returns the analyzers named in names, or all of
+// vetAnalyzers when names is empty.
+func SelectAnalyzers(names []string) ([]*analysis.Analyzer, error) {
+	if len(names) == 0 {
+		return vetAnalyzers, nil
+	}
+	var selected []*analysis.Analyzer
+	for _, name := range names {
+		i := slices.IndexFunc(vetAnalyzers, func(a *analysis.Analyzer) bool { return a.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown analyzer %q (known: %s)", name, strings.Join(analyzerNames(), ", "))
+		}
+		if !slices.Contains(selected, vetAnalyzers[i]) {
+			selected = append(selected, vetAnalyzers[i])
+		}
+	}
+	return selected, nil
+}
+
+func analyzerNames() []string {
+	names := make([]string, len(vetAnalyzers))
+	for i, a := range vetAnalyzers {
+		names[i] = a.Name
+	}
+	return names
+}
+
+// RunAnalyzers applies the analyzers to the loaded packages. The passes
+// share the type-checked syntax of the load (dependencies included, for
+// the analyzers that export facts), so nothing is parsed or type-checked
+// again. Diagnostics in files the AST walk skips are dropped.
+func RunAnalyzers(pkgs []*packages.Package, fset *token.FileSet, analyzers []*analysis.Analyzer, prog *Progress) []VetResult {
+	prog.Log("Running %d go/analysis passes over %d packages...", len(analyzers), len(pkgs))
+
+	graph, err := checker.Analyze(analyzers, pkgs, nil)
+	if err != nil {
+		prog.Log("Warning: go/analysis: %v", err)
+		return nil
+	}
+
+	type key struct {
+		pos      token.Pos
+		analyzer string
+		message  string
+	}
+	seen := make(map[key]bool)
+	var results []VetResult
+	var failed int
+	for _, act := range graph.Roots {
+		if act.Err != nil {
+			failed++
+			prog.Verbose("  %s: %v", act, act.Err)
+			continue
+		}
+		for _, d := range act.Diagnostics {
+			k := key{d.Pos, act.Analyzer.Name, d.Message}
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+
+			p := fset.Position(d.Pos)
+			if shouldSkipFile(p.Filename) {
+				continue
+			}
+			rel := modSet.RelFile(p.Filename)
+			if rel == "" {
+				continue
+			}
+			r := VetResult{
+				Analyzer: act.Analyzer.Name,
+				Category: d.Category,
+				Message:  d.Message,
+				URL:      d.URL,
+				RelFile:  rel,
+				Line:     p.Line,
+				Col:      p.Column,
+			}
+			if d.End.IsValid() {
+				e := fset.Position(d.End)
+				r.EndLine, r.EndCol = e.Line, e.Column
+			}
+			for _, f := range d.SuggestedFixes {
+				fix := VetFix{Message: f.Message}
+				for _, e := range f.TextEdits {
+					start, end := fset.Position(e.Pos), fset.Position(e.Pos)
+					if e.End.IsValid() {
+						end = fset.Position(e.End)
+					}
+					fix.Edits = append(fix.Edits, VetEdit{
+						File:    modSet.RelFile(start.Filename),
+						Line:    start.Line,
+						Col:     start.Column,
+						EndLine: end.Line,
+						EndCol:  end.Column,
+						NewText: string(e.NewText),
+					})
+				}
+				r.Fixes = append(r.Fixes, fix)
+			}
+			results = append(results, r)
+		}
+	}
+
+	slices.SortFunc(results, func(a, b VetResult) int {
+		if c := strings.Compare(a.RelFile, b.RelFile); c != 0 {
+			return c
+		}
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		if a.Col != b.Col {
+			return a.Col - b.Col
+		}
+		return strings.Compare(a.Analyzer, b.Analyzer)
+	})
+
+	if failed > 0 {
+		prog.Log("  %d analyzer runs failed (packages with type errors)", failed)
+	}
+	prog.Log("go/analysis: %d diagnostics", len(results))
+	return results
+}
+
+// ResolveVet sets the NodeID of each result to the node starting at its
+// position, or the variable declared there. Diagnostics matching neither
+// are attached to their enclosing function when written.
+func ResolveVet(results []VetResult, posLookup *PosLookup, prog *Progress) {
+	resolved := 0
+	for i := range results {
+		r := &results[i]
+		r.NodeID = posLookup.Get(r.RelFile, r.Line, r.Col)
+		if r.NodeID == "" {
+			r.NodeID = posLookup.GetDecl(r.RelFile, r.Line, r.Col)
+		}
+		if r.NodeID != "" {
+			resolved++
+		}
+	}
+	prog.Log("go/analysis: resolved %d of %d diagnostics to nodes", resolved, len(results))
+}
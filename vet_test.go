@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+func TestSelectAnalyzers(t *testing.T) {
+	all, err := SelectAnalyzers(nil)
+	if err != nil || len(all) != len(vetAnalyzers) {
+		t.Fatalf("SelectAnalyzers(nil) = %d analyzers, %v; want all %d", len(all), err, len(vetAnalyzers))
+	}
+
+	got, err := SelectAnalyzers([]string{"shadow", "printf", "shadow"})
+	if err != nil {
+		t.Fatalf("SelectAnalyzers: %v", err)
+	}
+	var names []string
+	for _, a := range got {
+		names = append(names, a.Name)
+	}
+	if strings.Join(names, ",") != "shadow,printf" {
+		t.Errorf("selected %v, want [shadow printf] in the given order without duplicates", names)
+	}
+
+	_, err = SelectAnalyzers([]string{"printf", "golint"})
+	if err == nil || !strings.Contains(err.Error(), `unknown analyzer "golint"`) || !strings.Contains(err.Error(), "nilness") {
+		t.Errorf("unknown analyzer: error = %v, want it named with the known ones listed", err)
+	}
+}
+
+// The built-in analyzers and their requirements must form a valid set, and
+// names must be unique for -analyzers to select them.
+func TestVetAnalyzersValid(t *testing.T) {
+	if err := analysis.Validate(vetAnalyzers); err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, name := range analyzerNames() {
+		if seen[name] {
+			t.Errorf("duplicate analyzer name %q", name)
+		}
+		seen[name] = true
+	}
+}